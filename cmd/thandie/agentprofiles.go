@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// agentProfilesCmd represents: `thandie agent-profiles`
+var agentProfilesCmd = &cobra.Command{
+	Use:   "agent-profiles",
+	Short: "List the agent profiles and show which one is active",
+	Long: `List the agent profiles: the config files in ~/.config/thandie/profiles,
+each a complete config (workspaces, tokens, notifications) used instead of
+config.yml when selected with --agent-profile <name> or THANDIE_PROFILE=<name>,
+e.g. to keep work and personal machines' roles apart. The active profile is
+marked with *.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := agentProfilesDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", dir, err)
+			exit(exitError)
+		}
+
+		var names []string
+		for _, e := range entries {
+			if name, ok := strings.CutSuffix(e.Name(), ".yml"); ok && !e.IsDir() {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Printf("No agent profiles in %s\n", dir)
+			fmt.Println("Create one with 'thandie init --agent-profile <name>'")
+			return
+		}
+
+		marker := func(active bool) string {
+			if active {
+				return "*"
+			}
+			return " "
+		}
+		fmt.Printf("%s (default) config.yml\n", marker(agentProfile == ""))
+		for _, name := range names {
+			fmt.Printf("%s %s\n", marker(name == agentProfile), name)
+		}
+	},
+}
+
+func init() {
+	// Attach the `agent-profiles` command to the root: thandie agent-profiles
+	rootCmd.AddCommand(agentProfilesCmd)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/audit"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// auditCached prints the results of the last audit instead of rerunning the tools
+	auditCached bool
+)
+
+// auditCmd represents: `thandie audit`
+var auditCmd = &cobra.Command{
+	Use:   "audit [directory]",
+	Short: "Run dependency vulnerability scanners across the workspace",
+	Long: `Run govulncheck (Go), npm audit (npm) and pip-audit (Python) in every git
+repository they apply to, or only in the given directory. Tools that aren't
+installed are skipped and reported. At most scanner.concurrency repositories
+are audited at once.
+
+Results are stored with the scan metadata and printed sorted by severity.
+Exits with code 10 if any vulnerability is found.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		result := loadScanResult(wsPath)
+
+		infos := result.DirectoryInfos
+		if len(args) == 1 {
+			info, err := findDirectory(wsPath, result.DirectoryInfos, args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			// Slice of the cached result so the audit is stored in place
+			for i := range result.DirectoryInfos {
+				if result.DirectoryInfos[i].Path == info.Path {
+					infos = result.DirectoryInfos[i : i+1]
+					break
+				}
+			}
+		}
+
+		if !auditCached {
+			audit.Run(context.Background(), infos, getScannerConfig(wsPath).Concurrency)
+			if cacheInstance, err := cache.New(); err != nil {
+				logger.Warn("failed to initialize cache", "error", err)
+			} else if err := cacheInstance.Save(result); err != nil {
+				logger.Warn("failed to save audit results to cache", "error", err)
+			}
+		}
+
+		if printAuditReport(wsPath, infos) > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `audit` command: thandie audit
+	auditCmd.Flags().BoolVar(&auditCached, "cached", false, "Show the results of the last audit without rerunning the tools")
+	rootCmd.AddCommand(auditCmd)
+}
+
+// printAuditReport prints every vulnerability sorted by severity, followed by
+// per-repository totals, and returns the number of vulnerabilities
+func printAuditReport(wsPath string, infos []scanner.DirectoryInfo) int {
+	type row struct {
+		repo string
+		vuln scanner.Vulnerability
+	}
+
+	var rows []row
+	var notes []string
+	audited := 0
+	for _, info := range infos {
+		a := info.Audit
+		if a == nil {
+			continue
+		}
+		audited++
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+		for _, v := range a.Vulnerabilities {
+			rows = append(rows, row{repo: name, vuln: v})
+		}
+		if len(a.Missing) > 0 {
+			notes = append(notes, fmt.Sprintf("%s: not installed: %s", name, strings.Join(a.Missing, ", ")))
+		}
+		for _, e := range a.Errors {
+			notes = append(notes, fmt.Sprintf("%s: %s", name, e))
+		}
+	}
+
+	if audited == 0 {
+		fmt.Println("No audit results. Run 'thandie audit' without --cached.")
+		return 0
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		ri, rj := scanner.SeverityRank(rows[i].vuln.Severity), scanner.SeverityRank(rows[j].vuln.Severity)
+		if ri != rj {
+			return ri < rj
+		}
+		return rows[i].repo < rows[j].repo
+	})
+
+	for _, r := range rows {
+		fmt.Printf("  %-8s  %s  %-24s  %-20s  %s\n",
+			severityLabel(r.vuln.Severity), padWidth(r.repo, 24), r.vuln.Package, r.vuln.ID, r.vuln.Summary)
+	}
+
+	if len(notes) > 0 {
+		fmt.Println("\nSkipped:")
+		for _, note := range notes {
+			fmt.Printf("  %s\n", note)
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Printf("\nNo vulnerabilities found in %d repositories.\n", audited)
+		return 0
+	}
+
+	counts := make(map[string]int)
+	for _, r := range rows {
+		counts[r.vuln.Severity]++
+	}
+	var parts []string
+	for _, severity := range []string{scanner.SeverityCritical, scanner.SeverityHigh, scanner.SeverityModerate, scanner.SeverityLow, scanner.SeverityUnknown} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	fmt.Printf("\n%d vulnerabilities (%s) in %d repositories audited.\n", len(rows), strings.Join(parts, ", "), audited)
+	return len(rows)
+}
+
+// severityLabel returns a colored, upper-cased severity
+func severityLabel(severity string) string {
+	label := fmt.Sprintf("%-8s", strings.ToUpper(severity))
+	switch severity {
+	case scanner.SeverityCritical, scanner.SeverityHigh:
+		return colorize(label, colorRed)
+	case scanner.SeverityModerate:
+		return colorize(label, colorYellow)
+	}
+	return colorize(label, colorGray)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// auditLogSince only shows records newer than this duration
+	auditLogSince time.Duration
+
+	// auditLogAction only shows records whose action starts with this prefix
+	auditLogAction string
+
+	// auditLogLimit is the maximum number of records shown
+	auditLogLimit int
+
+	// auditLogFormat is the output format: table or json
+	auditLogFormat string
+)
+
+// auditLogCmd represents: `thandie audit-log`
+var auditLogCmd = &cobra.Command{
+	Use:   "audit-log",
+	Short: "Review the log of write actions",
+	Long: `Every action that changes something outside thandie's own cache (writing
+the config, installing hooks, storing or removing secrets, sending
+notifications and emails) appends a record of who ran it, when, on what, and
+whether it succeeded to an append-only audit log.`,
+}
+
+// auditLogShowCmd represents: `thandie audit-log show`
+var auditLogShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print recent audit log records, newest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		records, err := auditlog.Read()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		var shown []auditlog.Record
+		for i := len(records) - 1; i >= 0 && (auditLogLimit <= 0 || len(shown) < auditLogLimit); i-- {
+			record := records[i]
+			if auditLogSince > 0 && time.Since(record.Time) > auditLogSince {
+				break
+			}
+			if auditLogAction != "" && !strings.HasPrefix(record.Action, auditLogAction) {
+				continue
+			}
+			shown = append(shown, record)
+		}
+
+		switch auditLogFormat {
+		case "table":
+		case "json":
+			if err := printJSON(shown); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected table or json)\n", auditLogFormat)
+			exit(exitError)
+		}
+		if len(shown) == 0 {
+			path, _ := auditlog.Path()
+			fmt.Printf("No matching audit records in %s\n", path)
+			return
+		}
+		for _, record := range shown {
+			// Pad before coloring so escape codes don't break the alignment
+			result := colorize(fmt.Sprintf("%-7s", record.Result), colorGreen)
+			if record.Result == auditlog.ResultError {
+				result = colorize(fmt.Sprintf("%-7s", record.Result), colorRed)
+			}
+			line := fmt.Sprintf("%s  %-10s %-16s %s %s", record.Time.Local().Format("2006-01-02 15:04:05"), record.User, record.Action, result, record.Target)
+			if record.Detail != "" {
+				line += " (" + record.Detail + ")"
+			}
+			if record.Error != "" {
+				line += ": " + record.Error
+			}
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	// Attach the `audit-log` command and its subcommands: thandie audit-log show
+	auditLogShowCmd.Flags().DurationVar(&auditLogSince, "since", 0, "Only show records from this long ago, e.g. 24h")
+	auditLogShowCmd.Flags().StringVar(&auditLogAction, "action", "", "Only show actions starting with this, e.g. hooks or secrets.set")
+	auditLogShowCmd.Flags().IntVar(&auditLogLimit, "limit", 50, "Maximum number of records to show (0 for all)")
+	auditLogShowCmd.Flags().StringVar(&auditLogFormat, "format", "table", "Output format: table or json")
+	addJSONFlags(auditLogShowCmd)
+	auditLogCmd.AddCommand(auditLogShowCmd)
+	rootCmd.AddCommand(auditLogCmd)
+}
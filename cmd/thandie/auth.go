@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/auth"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// authUseDeviceFlow forces the device flow even when a PAT could be entered
+	authUseDeviceFlow bool
+)
+
+// authCmd represents: `thandie auth`
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Authenticate with git hosting providers",
+}
+
+// authLoginCmd represents: `thandie auth login github|gitlab`
+var authLoginCmd = &cobra.Command{
+	Use:   "login github|gitlab",
+	Short: "Log in to a provider and store the token in the OS keychain",
+	Long: `Log in to GitHub or GitLab, validate that the token has the scopes
+enrichment needs, and store it in the OS keychain.
+
+GitHub logins use the device flow when providers.github.client_id is set
+(or --device is given); otherwise a personal access token is requested.
+
+Required scopes:
+  github: repo, read:org
+  gitlab: read_api`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"github", "gitlab"},
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireWritable("storing login tokens")
+
+		provider := args[0]
+		providerCfg := cfg.Provider(provider)
+		if providerCfg == nil {
+			fmt.Fprintf(os.Stderr, "Error: unsupported provider %q (expected github or gitlab)\n", provider)
+			exit(exitError)
+		}
+
+		ctx := context.Background()
+
+		var token string
+		var err error
+		if provider == "github" && (authUseDeviceFlow || providerCfg.ClientID != "") {
+			token, err = githubDeviceLogin(ctx, providerCfg.WebURL, providerCfg.ClientID)
+		} else {
+			token, err = readSecret(fmt.Sprintf("Personal access token for %s (scopes: %s): ",
+				provider, strings.Join(auth.RequiredScopes[provider], ", ")))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		if token == "" {
+			fmt.Fprintln(os.Stderr, "Error: token must not be empty")
+			exit(exitError)
+		}
+
+		info, err := auth.Validate(ctx, provider, providerCfg.URL, token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error validating token: %v\n", err)
+			exit(exitCheckFailed)
+		}
+		if len(info.MissingScopes) > 0 {
+			fmt.Fprintf(os.Stderr, "Error: token is missing required scopes: %s\n", strings.Join(info.MissingScopes, ", "))
+			exit(exitCheckFailed)
+		}
+
+		alias := provider
+		if secrets.IsRef(providerCfg.Token) {
+			alias = strings.TrimPrefix(providerCfg.Token, secrets.RefPrefix)
+		}
+		err = secrets.Set(alias, token)
+		auditlog.Write("auth.login", provider, "keychain:"+alias, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		fmt.Printf("✓ Logged in to %s as %s\n", provider, info.Login)
+		if !info.ScopesKnown {
+			fmt.Println("  Note: the provider did not report token scopes; enrichment may be limited")
+		}
+		if !secrets.IsRef(providerCfg.Token) {
+			fmt.Printf("  Token stored in keychain; set providers.%s.token to %s in your config to use it\n", provider, secrets.Ref(alias))
+		}
+	},
+}
+
+func init() {
+	// Attach the `auth` command and its subcommands: thandie auth login
+	authLoginCmd.Flags().BoolVar(&authUseDeviceFlow, "device", false, "Use the OAuth device flow (GitHub only, requires providers.github.client_id)")
+	authCmd.AddCommand(authLoginCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+// githubDeviceLogin walks the user through the GitHub device flow and returns the access token
+func githubDeviceLogin(ctx context.Context, webURL, clientID string) (string, error) {
+	if clientID == "" {
+		return "", fmt.Errorf("device flow requires providers.github.client_id to be set")
+	}
+
+	code, err := auth.StartGitHubDeviceFlow(ctx, webURL, clientID)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Open %s and enter the code: %s\n", code.VerificationURI, code.UserCode)
+	fmt.Println("Waiting for authorization...")
+
+	return auth.PollGitHubDeviceFlow(ctx, webURL, clientID, code)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/bench"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// benchSynthetic is the number of synthetic directories to generate
+	benchSynthetic int
+
+	// benchSaveBaseline stores the result as the baseline for its size
+	benchSaveBaseline bool
+
+	// benchTolerance is the slowdown, in percent, allowed before a metric counts as a regression
+	benchTolerance float64
+
+	// benchKeep keeps the synthetic workspace instead of deleting it
+	benchKeep bool
+)
+
+// benchCmd represents: `thandie bench`
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark scanning on a synthetic workspace",
+	Long: `Generate a synthetic workspace of --synthetic directories (git repositories
+with a commit, some dirty, and some plain directories) in a temporary
+directory, then measure a cold scan, a warm rescan, writing the cache and
+memory use.
+
+Results are compared against the baseline stored for the same size with
+--save-baseline. Exits with code 10 if a scan, the cache write or allocations
+are more than --tolerance percent worse than the baseline.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if benchSynthetic < 1 {
+			fmt.Fprintln(os.Stderr, "Error: --synthetic must be at least 1")
+			exit(exitError)
+		}
+		dir, err := os.MkdirTemp("", "thandie-bench-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create temporary directory: %v\n", err)
+			exit(exitError)
+		}
+
+		code := runBench(dir)
+		if benchKeep {
+			fmt.Printf("\nSynthetic workspace kept at %s\n", dir)
+		} else if err := os.RemoveAll(dir); err != nil {
+			logger.Warn("failed to remove synthetic workspace", "path", dir, "error", err)
+		}
+		if code != exitOK {
+			exit(code)
+		}
+	},
+}
+
+func init() {
+	// Attach the `bench` command to the root: thandie bench
+	benchCmd.Flags().IntVar(&benchSynthetic, "synthetic", 1000, "Number of synthetic directories to generate")
+	benchCmd.Flags().BoolVar(&benchSaveBaseline, "save-baseline", false, "Store the result as the baseline for this size")
+	benchCmd.Flags().Float64Var(&benchTolerance, "tolerance", 25, "Percent a metric may exceed its baseline before it counts as a regression")
+	benchCmd.Flags().BoolVar(&benchKeep, "keep", false, "Keep the synthetic workspace instead of deleting it")
+	rootCmd.AddCommand(benchCmd)
+}
+
+// runBench generates the synthetic workspace in dir, measures it and prints
+// the comparison with the baseline. Returns the exit code.
+func runBench(dir string) int {
+	concurrency := 4
+	if cfg != nil && cfg.Scanner.Concurrency > 0 {
+		concurrency = cfg.Scanner.Concurrency
+	}
+
+	fmt.Printf("Generating %d synthetic directories...\n", benchSynthetic)
+	start := time.Now()
+	workspace := filepath.Join(dir, "workspace")
+	if err := bench.Generate(workspace, benchSynthetic, concurrency); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to generate workspace: %v\n", err)
+		return exitError
+	}
+	fmt.Printf("Generated in %s\n\n", time.Since(start).Round(time.Millisecond))
+
+	result, err := bench.Run(workspace, filepath.Join(dir, "cache"), concurrency)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: benchmark failed: %v\n", err)
+		return exitError
+	}
+
+	baselinePath, err := bench.BaselinePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	baselines, err := bench.LoadBaselines(baselinePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	baseline, hasBaseline := baselines[bench.Key(benchSynthetic)]
+
+	regressions := printBench(result, baseline, hasBaseline, benchTolerance)
+
+	if benchSaveBaseline {
+		baselines[bench.Key(benchSynthetic)] = result
+		if err := baselines.Save(baselinePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitError
+		}
+		fmt.Printf("\nSaved as the baseline for %d directories.\n", benchSynthetic)
+		return exitOK
+	}
+	if !hasBaseline {
+		fmt.Printf("\nNo baseline for %d directories; run with --save-baseline to record one.\n", benchSynthetic)
+		return exitOK
+	}
+	if regressions > 0 {
+		fmt.Printf("\n%d metric(s) regressed by more than %.0f%%.\n", regressions, benchTolerance)
+		return exitCheckFailed
+	}
+	return exitOK
+}
+
+// printBench prints the result next to the baseline and returns the number
+// of metrics that regressed beyond tolerance percent
+func printBench(result, baseline bench.Result, hasBaseline bool, tolerance float64) int {
+	regressions := 0
+	// minDelta ignores changes too small to be more than noise, e.g. 2ms -> 3ms
+	row := func(label, current string, value, base, minDelta float64, budgeted bool) {
+		if !hasBaseline || base <= 0 {
+			fmt.Printf("  %-14s %12s\n", label, current)
+			return
+		}
+		change := (value - base) / base * 100
+		line := fmt.Sprintf("%+.1f%%", change)
+		if budgeted && change > tolerance && value-base >= minDelta {
+			regressions++
+			line = colorize(line+" regression", colorRed)
+		}
+		fmt.Printf("  %-14s %12s %12s  %s\n", label, current, formatBenchValue(label, base), line)
+	}
+
+	fmt.Printf("  %-14s %12s", "", "current")
+	if hasBaseline {
+		fmt.Printf(" %12s  change (baseline from %s)", "baseline", baseline.RecordedAt.Local().Format("2006-01-02"))
+	}
+	fmt.Println()
+	const minDuration, minBytes = float64(10 * time.Millisecond), float64(1 << 20)
+	row("Directories", fmt.Sprint(result.Directories), 0, 0, 0, false)
+	row("Cold scan", formatBenchValue("Cold scan", float64(result.ColdScan)), float64(result.ColdScan), float64(baseline.ColdScan), minDuration, true)
+	row("Throughput", formatBenchValue("Throughput", result.Throughput()), result.Throughput(), baseline.Throughput(), 0, false)
+	row("Warm scan", formatBenchValue("Warm scan", float64(result.WarmScan)), float64(result.WarmScan), float64(baseline.WarmScan), minDuration, true)
+	row("Cache write", formatBenchValue("Cache write", float64(result.CacheWrite)), float64(result.CacheWrite), float64(baseline.CacheWrite), minDuration, true)
+	row("Allocated", formatBenchValue("Allocated", float64(result.AllocBytes)), float64(result.AllocBytes), float64(baseline.AllocBytes), minBytes, true)
+	row("Live heap", formatBenchValue("Live heap", float64(result.HeapBytes)), float64(result.HeapBytes), float64(baseline.HeapBytes), 0, false)
+	return regressions
+}
+
+// formatBenchValue formats a metric of printBench for display
+func formatBenchValue(label string, value float64) string {
+	switch label {
+	case "Throughput":
+		return fmt.Sprintf("%.0f dirs/s", value)
+	case "Allocated", "Live heap":
+		return fmt.Sprintf("%.1f MiB", value/(1<<20))
+	}
+	return time.Duration(value).Round(time.Millisecond).String()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/branch"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// branchFrom is the start point of created branches (HEAD if empty)
+	branchFrom string
+
+	// branchAll creates the branch in every repository instead of a group
+	branchAll bool
+)
+
+// branchCmd represents: `thandie branch`
+var branchCmd = &cobra.Command{
+	Use:   "branch",
+	Short: "Coordinate branches across repositories",
+}
+
+// branchCreateCmd represents: `thandie branch create`
+var branchCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create and check out the same branch in a group of repositories",
+	Long: `Create a branch and check it out in every git repository of --group (see
+groups in the config), or of the whole workspace with --all, for changes that
+span several repositories.
+
+The branch starts at HEAD, or at --from (e.g. origin/main). Repositories that
+already have the branch just check it out. Uncommitted changes are carried
+over as with 'git checkout -b'; repositories where git refuses are reported as
+failed and left as they were.
+
+Exits with code 1 if the branch couldn't be created in any repository.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireWritable("creating branches")
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		name := args[0]
+		if err := branch.ValidName(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		if groupName == "" && !branchAll {
+			fmt.Fprintln(os.Stderr, "Error: specify the repositories with --group <name> or --all")
+			exit(exitError)
+		}
+
+		result := loadScanResult(wsPath)
+		repoPolicy := getPolicy(wsPath)
+		selected := make(map[string]bool)
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			selected[info.Path] = true
+		}
+
+		created, switched, failed := 0, 0, 0
+		for i := range result.DirectoryInfos {
+			info := &result.DirectoryInfos[i]
+			if !selected[info.Path] || info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			repoName, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				repoName = info.Path
+			}
+
+			if err := repoPolicy.Check(policy.ActionBranch, info.Path, info.GitMetadata.RemoteURL); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", repoName, err)
+				continue
+			}
+
+			status, err := branch.Create(info.Path, name, branchFrom)
+			auditlog.Write("branch.create", info.Path, name, err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", repoName, err)
+				failed++
+				continue
+			}
+			info.GitMetadata.CurrentBranch = name
+			if status == branch.StatusSwitched {
+				fmt.Printf("  ✓ %s: switched to existing %s\n", repoName, name)
+				switched++
+				continue
+			}
+			// A new branch has no upstream yet
+			info.GitMetadata.Upstream, info.GitMetadata.Ahead, info.GitMetadata.Behind = "", 0, 0
+			info.GitMetadata.UnpushedCommits = nil
+			fmt.Printf("  ✓ %s: created %s\n", repoName, name)
+			created++
+		}
+
+		// Keep list and reports accurate until the next scan
+		if created+switched > 0 {
+			if cacheInstance, err := cache.New(); err != nil {
+				logger.Warn("failed to initialize cache", "error", err)
+			} else if err := cacheInstance.Save(result); err != nil {
+				logger.Warn("failed to save branches to cache", "error", err)
+			}
+		}
+
+		fmt.Printf("\n%s: created in %d, switched in %d, failed in %d repositories.\n", name, created, switched, failed)
+		if failed > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	// Attach the `branch` command and its subcommands: thandie branch create
+	branchCreateCmd.Flags().StringVar(&branchFrom, "from", "", "Start the branch at this commit or ref instead of HEAD")
+	branchCreateCmd.Flags().BoolVar(&branchAll, "all", false, "Create the branch in every repository of the workspace")
+	addGroupFlag(branchCreateCmd)
+	branchCmd.AddCommand(branchCreateCmd)
+	rootCmd.AddCommand(branchCmd)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// cacheGCOlderThan overrides cache.max_age_days for one run
+	cacheGCOlderThan int
+
+	// cacheGCDryRun reports what would be removed without removing it
+	cacheGCDryRun bool
+)
+
+// autoGCInterval is how often a scan collects cache garbage
+const autoGCInterval = 24 * time.Hour
+
+// autoGCMissingGrace is how long after a workspace disappears a scan removes
+// its cache, so that an unmounted drive or a renamed directory that comes
+// back doesn't lose its history
+const autoGCMissingGrace = 7 * 24 * time.Hour
+
+// cacheCmd represents: `thandie cache`
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the scan cache files",
+}
+
+// cacheGCCmd represents: `thandie cache gc`
+var cacheGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove the caches of deleted and long-unscanned workspaces",
+	Long: `Remove the scan caches of workspaces whose directory no longer exists
+or that weren't scanned for cache.max_age_days days (default 90, 0 keeps them),
+and the file indexes of repositories no remaining cache lists, then report the
+space reclaimed.
+
+A scan also does this at most once a day unless cache.auto_gc is false; it
+waits a week before removing the cache of a workspace that disappeared, in case
+it is on a drive that isn't mounted.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if configErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to load config: %v\n", configErr)
+			exit(exitConfigError)
+		}
+		if !cacheGCDryRun {
+			requireWritable("removing caches")
+		}
+		maxAge := cfg.Cache.MaxAgeDays
+		if cmd.Flags().Changed("older-than") {
+			maxAge = cacheGCOlderThan
+		}
+
+		cacheInstance, err := cache.New()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize cache: %v\n", err)
+			exit(exitError)
+		}
+		report, indexes, err := collectCacheGarbage(cacheInstance, cache.GCOptions{
+			MaxAge: time.Duration(maxAge) * 24 * time.Hour,
+			DryRun: cacheGCDryRun,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		verb := "Removed"
+		if cacheGCDryRun {
+			verb = "Would remove"
+		}
+		for _, e := range report.Removed {
+			fmt.Printf("%s %s (%s, last scanned %s)\n", verb, e.Workspace, e.Reason, e.ScannedAt.Format("2006-01-02"))
+		}
+		for _, path := range report.Skipped {
+			fmt.Fprintf(os.Stderr, "Warning: left %s alone, it couldn't be read\n", filepath.Base(path))
+		}
+		fmt.Printf("%s %d workspace caches and %d file indexes, %s\n",
+			verb, len(report.Removed), indexes.files, formatSize(report.Reclaimed()+indexes.size))
+	},
+}
+
+// prunedIndexes counts the file indexes collectCacheGarbage removed
+type prunedIndexes struct {
+	files int
+	size  int64
+}
+
+// collectCacheGarbage removes the scan caches opts selects, then the file
+// indexes of repositories that none of the remaining caches list. File
+// indexes are left alone when a cache couldn't be read, as its repositories
+// are unknown.
+func collectCacheGarbage(c *cache.Cache, opts cache.GCOptions) (*cache.GCReport, prunedIndexes, error) {
+	var pruned prunedIndexes
+	report, err := c.GC(opts, time.Now())
+	if err != nil {
+		return report, pruned, fmt.Errorf("failed to collect cache garbage: %w", err)
+	}
+	if len(report.Skipped) > 0 {
+		return report, pruned, nil
+	}
+
+	var repos []string
+	for _, result := range report.Kept {
+		for _, info := range result.DirectoryInfos {
+			repos = append(repos, info.Path)
+		}
+	}
+	indexDir, err := search.IndexDir()
+	if err != nil {
+		return report, pruned, err
+	}
+	pruned.files, pruned.size, err = search.Prune(indexDir, repos, opts.DryRun)
+	return report, pruned, err
+}
+
+// autoCollectCacheGarbage runs collectCacheGarbage after a scan if
+// cache.auto_gc is on, read-only mode is off and it hasn't run for
+// autoGCInterval, recording the run in the modification time of a marker
+// file in the cache directory
+func autoCollectCacheGarbage(c *cache.Cache) {
+	if cfg == nil || !cfg.Cache.AutoGC || readOnly() {
+		return
+	}
+	marker := filepath.Join(c.GetCacheDir(), ".last_gc")
+	if fi, err := os.Stat(marker); err == nil && time.Since(fi.ModTime()) < autoGCInterval {
+		return
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		scanLog.Warn("failed to record cache gc", "error", err)
+		return
+	}
+	now := time.Now()
+	_ = os.Chtimes(marker, now, now)
+
+	report, indexes, err := collectCacheGarbage(c, cache.GCOptions{
+		MaxAge:       time.Duration(cfg.Cache.MaxAgeDays) * 24 * time.Hour,
+		MissingGrace: autoGCMissingGrace,
+	})
+	if err != nil {
+		scanLog.Warn("cache gc failed", "error", err)
+		return
+	}
+	for _, e := range report.Removed {
+		scanLog.Info("removed workspace cache", "workspace", e.Workspace, "reason", e.Reason)
+	}
+	if len(report.Removed) > 0 || indexes.files > 0 {
+		scanLog.Info("cache gc finished", "caches", len(report.Removed), "file_indexes", indexes.files,
+			"reclaimed", formatSize(report.Reclaimed()+indexes.size))
+	}
+}
+
+// formatSize formats a byte count for display, e.g. 12.3 MiB
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	// Attach the `cache` command to the root: thandie cache
+	rootCmd.AddCommand(cacheCmd)
+
+	// Attach `gc` under cache: thandie cache gc
+	cacheGCCmd.Flags().IntVar(&cacheGCOlderThan, "older-than", 0, "Remove caches not scanned for this many days, overriding cache.max_age_days (0 keeps them)")
+	cacheGCCmd.Flags().BoolVar(&cacheGCDryRun, "dry-run", false, "Report what would be removed without removing it")
+	cacheCmd.AddCommand(cacheGCCmd)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// commandsCmd represents: `thandie commands [query]`
+var commandsCmd = &cobra.Command{
+	Use:   "commands [query]",
+	Short: "List every command and flag, fuzzy-matched against a query",
+	Long: `List every command, subcommand and flag with a one-line description, so
+features stay discoverable as they grow. With a query, only the matching
+entries are listed, best first: the characters of the query must appear in
+order in the command line, e.g. 'rsig' finds 'thandie report signing', or
+the query must appear in the description, e.g. 'sort' finds 'thandie list
+--sort'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries := commandEntries(rootCmd)
+		query := strings.ToLower(strings.Join(args, ""))
+		if query != "" {
+			entries = rankEntries(query, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No command matches %q\n", strings.Join(args, " "))
+			return
+		}
+
+		width := 0
+		for _, e := range entries {
+			width = max(width, displayWidth(e.line))
+		}
+		for _, e := range entries {
+			fmt.Printf("  %s  %s\n", padWidth(e.line, width), colorize(e.short, colorGray))
+		}
+	},
+}
+
+func init() {
+	// Attach the `commands` command to the root: thandie commands [query]
+	rootCmd.AddCommand(commandsCmd)
+}
+
+// commandEntry is a command or flag listed by `thandie commands`
+type commandEntry struct {
+	line  string // e.g. "thandie list --sort"
+	path  string // The command, e.g. "list"
+	flag  string // The flag, if the entry is one, e.g. "sort"
+	short string
+	score int
+}
+
+// commandEntries returns the visible commands below and including c, each
+// followed by its own flags, sorted by command line
+func commandEntries(c *cobra.Command) []commandEntry {
+	var entries []commandEntry
+	if c.IsAvailableCommand() || c == rootCmd {
+		flags := c.LocalNonPersistentFlags()
+		if c == rootCmd {
+			flags = c.PersistentFlags()
+		} else {
+			entries = append(entries, commandEntry{line: c.CommandPath(), path: commandPath(c), short: c.Short})
+		}
+		flags.VisitAll(func(f *pflag.Flag) {
+			if !f.Hidden {
+				entries = append(entries, commandEntry{line: c.CommandPath() + " --" + f.Name, path: commandPath(c), flag: f.Name, short: f.Usage})
+			}
+		})
+	}
+	for _, sub := range c.Commands() {
+		if sub.IsAvailableCommand() {
+			entries = append(entries, commandEntries(sub)...)
+		}
+	}
+	if c == rootCmd {
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].line < entries[j].line })
+	}
+	return entries
+}
+
+// commandPath returns the path of c below the root as segments separated by
+// slashes, e.g. "report/signing", for fuzzy matching
+func commandPath(c *cobra.Command) string {
+	return strings.ReplaceAll(strings.TrimPrefix(strings.TrimPrefix(c.CommandPath(), rootCmd.Name()), " "), " ", "/")
+}
+
+// rankEntries returns the entries matching query, best first. Fuzzy matches
+// on a flag name or command path rank above matches in the description, and
+// a flag matched through its command ranks just below the command.
+func rankEntries(query string, entries []commandEntry) []commandEntry {
+	const fuzzyBonus = 1000
+	var ranked []commandEntry
+	for _, e := range entries {
+		if score, ok := search.Score(query, e.flag); ok && e.flag != "" {
+			e.score = score + fuzzyBonus
+		} else if score, ok := search.Score(query, e.path); ok && e.path != "" {
+			e.score = score + fuzzyBonus
+			if e.flag != "" {
+				e.score--
+			}
+		} else if !strings.Contains(strings.ToLower(e.short), query) {
+			continue
+		}
+		ranked = append(ranked, e)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	return ranked
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	compareProvider        string
+	compareOrg             string
+	compareClone           bool
+	compareProtocol        string
+	compareIncludeArchived bool
+)
+
+// compareCmd represents: `thandie compare`
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare provider repositories with the local workspace",
+	Long: `List the repositories you have access to on a provider (optionally limited
+to an organization or group), mark which are already cloned in the workspace
+by matching normalized remote URLs, and offer to clone the missing ones.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if compareProtocol != "ssh" && compareProtocol != "https" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --protocol %q (expected ssh or https)\n", compareProtocol)
+			exit(exitError)
+		}
+
+		client, err := providers.NewClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		provider, err := providers.New(client, compareProvider, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+
+		repos, err := provider.ListRepos(context.Background(), compareOrg)
+		if err != nil {
+			logger.Error("failed to list provider repositories", "provider", compareProvider, "org", compareOrg, "error", err)
+			exit(exitError)
+		}
+
+		// Index local clones by normalized remote
+		local := make(map[string]string)
+		for _, info := range loadScanResult(wsPath).DirectoryInfos {
+			if info.GitMetadata != nil && info.GitMetadata.RemoteURL != "" {
+				local[gitremote.Normalize(info.GitMetadata.RemoteURL)] = info.Path
+			}
+		}
+
+		sort.Slice(repos, func(i, j int) bool { return repos[i].FullName < repos[j].FullName })
+
+		var missing []providers.Repo
+		cloned := 0
+		for _, repo := range repos {
+			if repo.Archived && !compareIncludeArchived {
+				continue
+			}
+			if localPath, ok := local[gitremote.Normalize(repo.WebURL)]; ok {
+				cloned++
+				fmt.Printf("  ✓ %s  %s\n", repo.FullName, localPath)
+				continue
+			}
+			missing = append(missing, repo)
+			fmt.Printf("  ✗ %s\n", repo.FullName)
+		}
+
+		fmt.Printf("\n%d repositories, %d cloned, %d missing\n", cloned+len(missing), cloned, len(missing))
+
+		if len(missing) == 0 {
+			return
+		}
+		if !compareClone {
+			if readOnly() || !term.IsTerminal(int(os.Stdin.Fd())) {
+				return
+			}
+			fmt.Printf("Clone %d missing repositories into %s? (y/N): ", len(missing), wsPath)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				return
+			}
+		}
+
+		requireWritable("cloning repositories")
+		if failed := cloneRepos(wsPath, missing, compareProtocol); failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d of %d clones failed\n", failed, len(missing))
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	// Attach the `compare` command to the root: thandie compare
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&compareProvider, "provider", "github", "Provider to list repositories from (github or gitlab)")
+	compareCmd.Flags().StringVar(&compareOrg, "org", "", "Organization (GitHub) or group (GitLab) to list; defaults to all repositories you can access")
+	compareCmd.Flags().BoolVar(&compareClone, "clone", false, "Clone missing repositories without prompting")
+	compareCmd.Flags().StringVar(&compareProtocol, "protocol", "ssh", "Protocol used to clone missing repositories (ssh or https)")
+	compareCmd.Flags().BoolVar(&compareIncludeArchived, "include-archived", false, "Include archived repositories")
+}
+
+// cloneRepos clones each repo into wsPath using the git CLI, so the user's SSH
+// keys and credential helpers apply. Destinations that already exist are
+// skipped. Returns the number of failed clones.
+func cloneRepos(wsPath string, repos []providers.Repo, protocol string) int {
+	failed := 0
+	dests := cloneDestinations(repos)
+	for _, repo := range repos {
+		cloneURL := repo.SSHURL
+		if protocol == "https" {
+			cloneURL = repo.CloneURL
+		}
+
+		dest := filepath.Join(wsPath, dests[repo.FullName])
+		if _, err := os.Stat(dest); err == nil {
+			fmt.Printf("  - %s: %s already exists, skipping\n", repo.FullName, dest)
+			continue
+		}
+
+		fmt.Printf("  cloning %s into %s\n", repo.FullName, dest)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			logger.Error("clone failed", "repo", repo.FullName, "error", err)
+			failed++
+			continue
+		}
+		gitCmd := exec.Command("git", "clone", "--quiet", cloneURL, dest)
+		gitCmd.Stdout = os.Stdout
+		gitCmd.Stderr = os.Stderr
+		if err := gitCmd.Run(); err != nil {
+			logger.Error("clone failed", "repo", repo.FullName, "url", cloneURL, "error", err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// cloneDestinations returns the directory, relative to the workspace, that
+// each repository is cloned into, keyed by full name: its name, or its full
+// owner/name path when repositories of different owners (e.g. GitLab
+// subgroups) share the name
+func cloneDestinations(repos []providers.Repo) map[string]string {
+	byName := make(map[string]int)
+	for _, repo := range repos {
+		byName[path.Base(repo.FullName)]++
+	}
+	dests := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		name := path.Base(repo.FullName)
+		if byName[name] > 1 {
+			name = repo.FullName
+		}
+		dests[repo.FullName] = filepath.FromSlash(name)
+	}
+	return dests
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/activity"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// conflictsOpen opens the first conflict in the editor
+	conflictsOpen bool
+
+	// conflictsMergetool runs git mergetool in the repository
+	conflictsMergetool bool
+)
+
+// conflictsCmd represents: `thandie conflicts [dir]`
+var conflictsCmd = &cobra.Command{
+	Use:   "conflicts [dir]",
+	Short: "List the files with merge conflicts and open them to resolve",
+	Long: `List the files with unresolved conflicts of every repository in the
+middle of a merge, rebase, cherry-pick or revert, or of the given directory
+(see 'thandie show' for how to name it), with the line of each file's first
+conflict marker.
+
+--open opens the first conflicted file in $VISUAL or $EDITOR at its first
+conflict, and --mergetool runs 'git mergetool' in the repository instead.
+Both need a single repository: the one given, or the only one with
+conflicts.
+
+Conflicts come from the last scan, so rescan after resolving some; the
+line numbers are read from the files as they are now.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		infos := loadScanResult(wsPath).DirectoryInfos
+		if len(args) == 1 {
+			info, err := findDirectory(wsPath, infos, args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			infos = []scanner.DirectoryInfo{*info}
+		}
+		var conflicted []scanner.DirectoryInfo
+		for _, info := range infos {
+			if info.GitMetadata != nil && info.GitMetadata.Conflicts > 0 {
+				conflicted = append(conflicted, info)
+			}
+		}
+		if len(conflicted) == 0 {
+			fmt.Println("No merge conflicts in the last scan.")
+			return
+		}
+
+		if conflictsOpen || conflictsMergetool {
+			if len(conflicted) > 1 {
+				fmt.Fprintf(os.Stderr, "Error: %d repositories have conflicts; name one, e.g. 'thandie conflicts %s --open'\n",
+					len(conflicted), filepath.Base(conflicted[0].Path))
+				exit(exitError)
+			}
+			resolveConflicts(conflicted[0])
+			return
+		}
+
+		for i, info := range conflicted {
+			if i > 0 {
+				fmt.Println()
+			}
+			git := info.GitMetadata
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			fmt.Printf("%s %s\n", name, colorize(inProgressText(git), colorRed))
+			for _, file := range git.ConflictFiles {
+				line := firstConflict(filepath.Join(info.Path, file))
+				switch {
+				case line > 0:
+					fmt.Printf("  %s:%d\n", file, line)
+				case line < 0:
+					fmt.Printf("  %s %s\n", file, colorize("(removed since the scan)", colorGray))
+				default:
+					fmt.Printf("  %s\n", file)
+				}
+			}
+			if hidden := git.Conflicts - len(git.ConflictFiles); hidden > 0 {
+				fmt.Printf("  ... and %d more\n", hidden)
+			}
+		}
+	},
+}
+
+// resolveConflicts opens the first conflicted file of info in the editor at
+// its first conflict marker, or runs git mergetool with --mergetool
+func resolveConflicts(info scanner.DirectoryInfo) {
+	if conflictsMergetool {
+		requireWritable("git mergetool")
+		cmd := exec.Command("git", "mergetool")
+		cmd.Dir = info.Path
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: git mergetool: %v\n", err)
+			exit(exitError)
+		}
+		return
+	}
+
+	// Prefer a file with conflict markers over one without, e.g. binary
+	path, line := "", -1
+	for _, file := range info.GitMetadata.ConflictFiles {
+		candidate := filepath.Join(info.Path, file)
+		if n := firstConflict(candidate); n > line {
+			path, line = candidate, n
+		}
+		if line > 0 {
+			break
+		}
+	}
+	if line < 0 {
+		fmt.Printf("The conflicted files of %s no longer exist; rescan it.\n", filepath.Base(info.Path))
+		return
+	}
+	if err := openInEditorAt(path, line); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", path, err)
+		exit(exitError)
+	}
+	if err := activity.RecordOpened(info.Path); err != nil {
+		logger.Warn("failed to record opened repository", "error", err)
+	}
+}
+
+// firstConflict returns the line of the first conflict marker in the file at
+// path, 0 if it has none (e.g. a binary file or one deleted on one side) and
+// -1 if the file no longer exists
+func firstConflict(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return -1
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for n := 1; lines.Scan(); n++ {
+		if strings.HasPrefix(lines.Text(), "<<<<<<< ") {
+			return n
+		}
+	}
+	return 0
+}
+
+func init() {
+	// Attach the `conflicts` command to the root: thandie conflicts [dir]
+	conflictsCmd.Flags().BoolVar(&conflictsOpen, "open", false, "Open the first conflicted file in $VISUAL or $EDITOR at its first conflict")
+	conflictsCmd.Flags().BoolVar(&conflictsMergetool, "mergetool", false, "Run 'git mergetool' in the repository")
+	rootCmd.AddCommand(conflictsCmd)
+}
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/gitwatch"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/power"
+	"github.com/ThandieOps/thandie-agent/internal/rpc"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/schedule"
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+	"github.com/ThandieOps/thandie-agent/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+// daemonLog is the daemon component's logger
+var daemonLog = logger.For("daemon")
+
+var (
+	// daemonInterval is how often to scan when no schedule is configured
+	daemonInterval time.Duration
+)
+
+// daemonCmd represents: `thandie daemon`
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled jobs in the foreground",
+	Long: `Run the jobs listed under schedule in the config until interrupted:
+
+  schedule:
+    - cron: "0 9 * * 1-5"
+      job: scan
+    - cron: "0 18 * * 5"
+      job: report
+
+cron is a five-field expression (minute hour day-of-month month day-of-week)
+and also accepts @hourly, @daily, @weekly, @monthly and @every <duration>.
+job is scan (scan --if-changed), report (report summary --email), notify
+(notify --alerts) or any other thandie command line, e.g. "audit" or
+"test --filter lang:go". Jobs run against the same workspace and profile as
+the daemon.
+
+Each job's output is appended to its own log under the thandie cache
+directory (jobs/<job>.log). A job that is still running when it comes due
+again is skipped.
+
+Heavy jobs (scan, audit, test, terraform) are deferred, and retried every
+minute, while on battery or a metered connection, during power.active_hours
+(e.g. 09:00-18:00) or until the user has been idle for power.idle_minutes.
+Set power.scan_on_battery or power.scan_on_metered to true to run them anyway.
+A job deferred for longer than power.max_defer (default 6h) runs regardless.
+
+Without a schedule the daemon scans every --interval.
+
+While running, the daemon answers queries from 'thandie status', 'thandie
+prompt' and other tools on a unix socket in the thandie cache directory
+(daemon.sock), serving scan results from memory. Only one daemon runs at a
+time.
+
+With webhook.listen set (e.g. 127.0.0.1:8787), the daemon also accepts POST
+requests on /hooks/rescan from CI or provider push webhooks and immediately
+rescans the repository they name: the repository of a GitHub, Gitea, GitLab
+or Bitbucket push payload, or ?path=<repository> / ?remote=<url>. Requests
+must authenticate with webhook.secret (a value or keychain:<alias>), either
+as "Authorization: Bearer <secret>", in X-Gitlab-Token, or as the
+X-Hub-Signature-256 HMAC GitHub and Gitea send.
+
+The daemon also watches the index and HEAD of the scanned repositories and
+rescans a repository within seconds of a commit, checkout or staging change
+in it. To stay within the system's file watch limits, only the
+git_watch.max_repos (default 256) most recently active repositories are
+watched; the others are refreshed by scheduled scans. Set git_watch.enabled
+to false to rely on scheduled scans alone.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+
+		entries := cfg.Schedule
+		if len(entries) == 0 {
+			if daemonInterval < time.Minute {
+				fmt.Fprintln(os.Stderr, "Error: --interval must be at least 1m")
+				exit(exitError)
+			}
+			entries = []config.ScheduleEntry{{Cron: fmt.Sprintf("@every %s", daemonInterval), Job: "scan"}}
+		}
+		jobs, err := schedule.Load(entries)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+
+		policy, err := power.NewPolicy(cfg.Power)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to locate thandie executable: %v\n", err)
+			exit(exitError)
+		}
+		logDir, err := schedule.LogDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to resolve job log directory: %v\n", err)
+			exit(exitError)
+		}
+
+		var globalArgs []string
+		if workspacePath != "" {
+			globalArgs = append(globalArgs, "--workspace", workspacePath)
+		}
+		if workspaceProfile != "" {
+			globalArgs = append(globalArgs, "--profile", workspaceProfile)
+		}
+		if readOnlyFlag {
+			globalArgs = append(globalArgs, "--read-only")
+		}
+
+		var hookListener net.Listener
+		var hookSecret string
+		if cfg.Webhook.Listen != "" {
+			if hookSecret, err = secrets.Resolve(cfg.Webhook.Secret); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to resolve webhook.secret: %v\n", err)
+				exit(exitConfigError)
+			}
+			if hookSecret == "" {
+				fmt.Fprintln(os.Stderr, "Error: webhook.secret is required when webhook.listen is set")
+				exit(exitConfigError)
+			}
+			if hookListener, err = net.Listen("tcp", cfg.Webhook.Listen); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to listen for webhooks: %v\n", err)
+				exit(exitError)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		status := newDaemonStatus(jobs)
+		if server, err := rpc.Listen(); errors.Is(err, rpc.ErrAlreadyRunning) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		} else if err != nil {
+			daemonLog.Warn("not answering queries, falling back to caches", "error", err)
+		} else {
+			serveDaemon(server, status)
+			go server.Serve(ctx)
+			daemonLog.Info("answering queries", "socket", server.Path())
+		}
+		if hookListener != nil {
+			go serveWebhook(ctx, hookListener, hookSecret)
+			fmt.Printf("Accepting rescan webhooks on http://%s%s\n", hookListener.Addr(), webhook.RescanPath)
+		}
+
+		if cfg.GitWatch.Enabled && cfg.GitWatch.MaxRepos > 0 {
+			go watchRepositories(ctx, cfg.GitWatch.MaxRepos)
+		}
+
+		fmt.Printf("Running %d scheduled job(s), logs in %s\n", len(jobs), logDir)
+		runDaemon(ctx, jobs, policy, exe, globalArgs, logDir, status)
+		fmt.Println("Daemon stopped.")
+	},
+}
+
+func init() {
+	// Attach the `daemon` command to the root: thandie daemon
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 15*time.Minute, "Scan interval when no schedule is configured")
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// deferRetry is how often a deferred job checks whether it can run
+const deferRetry = time.Minute
+
+// runDaemon runs jobs as they come due until ctx is cancelled, then waits
+// for running jobs to finish. Heavy jobs wait while policy defers them.
+// Progress is recorded in status.
+func runDaemon(ctx context.Context, jobs []schedule.Job, policy *power.Policy, exe string, globalArgs []string, logDir string, status *daemonStatus) {
+	next := make([]time.Time, len(jobs))
+	defer func() {
+		for i := range next {
+			status.setNext(i, time.Time{})
+		}
+	}()
+	for i, job := range jobs {
+		next[i] = job.Cron.Next(time.Now())
+		status.setNext(i, next[i])
+		if next[i].IsZero() {
+			daemonLog.Warn("scheduled job never runs", "job", job.Name)
+			continue
+		}
+		daemonLog.Info("scheduled job", "job", job.Name, "next", next[i].Format(time.RFC3339))
+	}
+
+	deferredSince := make(map[int]time.Time)
+	var mu sync.Mutex
+	running := make(map[int]bool)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		var wake time.Time
+		for _, t := range next {
+			if !t.IsZero() && (wake.IsZero() || t.Before(wake)) {
+				wake = t
+			}
+		}
+		if wake.IsZero() {
+			daemonLog.Warn("no scheduled jobs left to run")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for i, job := range jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+
+			if job.Heavy() {
+				if reason := policy.DeferReason(now, power.Current()); reason != "" {
+					since, ok := deferredSince[i]
+					if !ok {
+						since = now
+						deferredSince[i] = now
+					}
+					if policy.MaxDefer == 0 || now.Sub(since) < policy.MaxDefer {
+						daemonLog.Info("deferring scheduled job", "job", job.Name, "reason", reason)
+						next[i] = now.Add(deferRetry)
+						status.setNext(i, next[i])
+						continue
+					}
+					daemonLog.Info("running scheduled job deferred past max_defer", "job", job.Name, "reason", reason, "deferred", now.Sub(since).Round(time.Minute))
+				}
+				delete(deferredSince, i)
+			}
+			next[i] = job.Cron.Next(now)
+			status.setNext(i, next[i])
+
+			mu.Lock()
+			busy := running[i]
+			running[i] = true
+			mu.Unlock()
+			if busy {
+				daemonLog.Warn("skipping scheduled job, previous run still in progress", "job", job.Name)
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					mu.Lock()
+					delete(running, i)
+					mu.Unlock()
+				}()
+
+				daemonLog.Info("running scheduled job", "job", job.Name)
+				start := time.Now()
+				status.started(i, start)
+				code, err := job.Run(ctx, exe, globalArgs, logDir)
+				status.finished(i, code, err)
+				switch {
+				case err != nil:
+					daemonLog.Warn("scheduled job failed to start", "job", job.Name, "error", err)
+				case code != exitOK:
+					daemonLog.Warn("scheduled job failed", "job", job.Name, "exit_code", code, "duration", time.Since(start).Round(time.Millisecond), "log", job.LogPath(logDir))
+				default:
+					daemonLog.Info("scheduled job finished", "job", job.Name, "duration", time.Since(start).Round(time.Millisecond))
+				}
+			}()
+		}
+	}
+}
+
+// daemonState is what the daemon reports about itself over its socket
+type daemonState struct {
+	PID     int        `json:"pid"`
+	Started time.Time  `json:"started"`
+	Jobs    []jobState `json:"jobs"`
+}
+
+// jobState is the progress of a scheduled job
+type jobState struct {
+	Name     string    `json:"name"`
+	Next     time.Time `json:"next,omitzero"`
+	Running  bool      `json:"running,omitempty"`
+	LastRun  time.Time `json:"last_run,omitzero"`
+	LastExit int       `json:"last_exit,omitempty"` // -1 if the job failed to start
+}
+
+// daemonStatus is the daemon's state, updated by runDaemon
+type daemonStatus struct {
+	mu    sync.Mutex
+	state daemonState
+}
+
+// newDaemonStatus returns the status of a daemon running jobs
+func newDaemonStatus(jobs []schedule.Job) *daemonStatus {
+	s := &daemonStatus{state: daemonState{PID: os.Getpid(), Started: time.Now()}}
+	for _, job := range jobs {
+		s.state.Jobs = append(s.state.Jobs, jobState{Name: job.Name})
+	}
+	return s
+}
+
+// setNext records when job i runs next
+func (s *daemonStatus) setNext(i int, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Jobs[i].Next = next
+}
+
+// started records that job i started at t
+func (s *daemonStatus) started(i int, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Jobs[i].Running, s.state.Jobs[i].LastRun = true, t
+}
+
+// finished records the outcome of job i
+func (s *daemonStatus) finished(i, code int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Jobs[i].Running, s.state.Jobs[i].LastExit = false, code
+	if err != nil {
+		s.state.Jobs[i].LastExit = -1
+	}
+}
+
+// snapshot returns a copy of the state
+func (s *daemonStatus) snapshot() daemonState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.state
+	state.Jobs = slices.Clone(s.state.Jobs)
+	return state
+}
+
+// scanResults keeps the scan results the daemon serves in memory. A
+// workspace's result is read again only when scan jobs rewrite its cache file.
+type scanResults struct {
+	mu      sync.Mutex
+	cache   *cache.Cache
+	entries map[string]scanEntry
+}
+
+// scanEntry is a scan result and the modification time of its cache file
+type scanEntry struct {
+	modTime time.Time
+	result  *cache.ScanResult
+}
+
+// get returns the cached scan result of wsPath
+func (r *scanResults) get(wsPath string) (*cache.ScanResult, error) {
+	info, err := os.Stat(r.cache.GetCacheFilePath(wsPath))
+	if err != nil {
+		return nil, fmt.Errorf("no cached scan result found for workspace: %s", wsPath)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.entries[wsPath]; ok && entry.modTime.Equal(info.ModTime()) {
+		return entry.result, nil
+	}
+	result, err := r.cache.LoadScanResult(wsPath)
+	if err != nil {
+		return nil, err
+	}
+	r.entries[wsPath] = scanEntry{modTime: info.ModTime(), result: result}
+	return result, nil
+}
+
+// serveDaemon registers the queries the daemon answers:
+//
+//	status       the daemon's jobs (daemonState)
+//	scan_result  the cached scan of workspace (default: the daemon's)
+//	repo         the directory of that scan containing path, or null
+//	logs         the daemon's recent log messages, oldest first
+func serveDaemon(server *rpc.Server, status *daemonStatus) {
+	server.Handle("status", func(req rpc.Request) (any, error) {
+		return status.snapshot(), nil
+	})
+	server.Handle("logs", func(req rpc.Request) (any, error) {
+		return logger.Recent(0), nil
+	})
+
+	cacheInstance, err := cache.New()
+	if err != nil {
+		daemonLog.Warn("failed to initialize cache, not serving scan results", "error", err)
+		return
+	}
+	results := &scanResults{cache: cacheInstance, entries: make(map[string]scanEntry)}
+	workspace := func(req rpc.Request) string {
+		if req.Workspace != "" {
+			return req.Workspace
+		}
+		return getWorkspacePath()
+	}
+	server.Handle("scan_result", func(req rpc.Request) (any, error) {
+		return results.get(workspace(req))
+	})
+	server.Handle("repo", func(req rpc.Request) (any, error) {
+		result, err := results.get(workspace(req))
+		if err != nil {
+			return nil, err
+		}
+		return repoContaining(result.DirectoryInfos, req.Path), nil
+	})
+}
+
+// serveWebhook answers rescan webhooks on listener until ctx is cancelled
+func serveWebhook(ctx context.Context, listener net.Listener, secret string) {
+	server := &http.Server{
+		Handler:           webhook.Handler(secret, rescanRepositories),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		daemonLog.Warn("webhook endpoint stopped", "error", err)
+	}
+}
+
+// gitWatchDebounce is how long a repository must be quiet after a change
+// before it is rescanned, so that a commit is rescanned once
+const gitWatchDebounce = 2 * time.Second
+
+// gitWatchSync is how often the watched repositories are matched against the
+// cached scan, to pick up repositories that scheduled scans found or lost
+const gitWatchSync = time.Minute
+
+// watchRepositories rescans the repositories of the daemon's workspace as
+// their index or HEAD change, watching at most limit of them, until ctx is
+// cancelled
+func watchRepositories(ctx context.Context, limit int) {
+	watcher, err := gitwatch.New(limit, gitWatchDebounce)
+	if err != nil {
+		daemonLog.Warn("not watching repositories for changes", "error", err)
+		return
+	}
+	defer watcher.Close()
+	cacheInstance, err := cache.New()
+	if err != nil {
+		daemonLog.Warn("not watching repositories for changes", "error", err)
+		return
+	}
+
+	wsPath := getWorkspacePath()
+	var synced time.Time
+	resync := func() {
+		info, err := os.Stat(cacheInstance.GetCacheFilePath(wsPath))
+		if err != nil || info.ModTime().Equal(synced) {
+			return
+		}
+		result, err := cacheInstance.LoadScanResult(wsPath)
+		if err != nil {
+			return
+		}
+		synced = info.ModTime()
+		repos := make(map[string]time.Time)
+		for _, info := range result.DirectoryInfos {
+			if git := info.GitMetadata; git != nil && git.IsGitRepo {
+				repos[info.Path] = git.LastCommitAt
+			}
+		}
+		watched := watcher.Sync(repos)
+		daemonLog.Debug("watching repositories for changes", "watched", watched, "repositories", len(repos))
+	}
+	resync()
+
+	go watcher.Run(ctx, func(repo string) {
+		if _, err := rescanRepositories(webhook.Target{Path: repo}); err != nil {
+			daemonLog.Warn("failed to rescan changed repository", "path", repo, "error", err)
+		}
+	})
+	ticker := time.NewTicker(gitWatchSync)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resync()
+		}
+	}
+}
+
+// rescanMu serializes webhook and watch rescans, so that they don't rescan
+// the same repositories at once; the cache file's lock orders their saves
+// with those of other processes
+var rescanMu sync.Mutex
+
+// rescanRepositories rescans the repositories of the cached scan matching
+// target, by path or remote, and saves them to the cache. Results scanning
+// doesn't recompute, such as CI status, are kept from the previous scan.
+func rescanRepositories(target webhook.Target) ([]string, error) {
+	rescanMu.Lock()
+	defer rescanMu.Unlock()
+
+	wsPath := getWorkspacePath()
+	cacheInstance, err := cache.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cache: %w", err)
+	}
+	result, err := cacheInstance.LoadScanResult(wsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan of %s: %w", wsPath, err)
+	}
+
+	remotes := make(map[string]bool)
+	for _, url := range target.Remotes {
+		remotes[gitremote.Normalize(url)] = true
+	}
+	matched := make(map[string]bool)
+	if target.Path != "" {
+		path := target.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wsPath, path)
+		}
+		if repo := repoContaining(result.DirectoryInfos, filepath.Clean(path)); repo != nil {
+			matched[repo.Path] = true
+		}
+	}
+	var dirs []string
+	var previous []scanner.DirectoryInfo
+	for _, info := range result.DirectoryInfos {
+		if !matched[info.Path] && (info.GitMetadata == nil || info.GitMetadata.RemoteURL == "" || !remotes[gitremote.Normalize(info.GitMetadata.RemoteURL)]) {
+			continue
+		}
+		dirs = append(dirs, info.Path)
+		previous = append(previous, info)
+	}
+	if len(dirs) == 0 {
+		names := target.Remotes
+		if target.Path != "" {
+			names = append(names, target.Path)
+		}
+		return nil, fmt.Errorf("%w %s", webhook.ErrNoMatch, strings.Join(names, ", "))
+	}
+
+	scannerCfg := getScannerConfig(wsPath)
+	fresh := scanner.CollectDirectoryInfos(dirs, scannerCfg.Concurrency, scannerCfg.DirTimeoutDuration())
+	carryOverResults(fresh, previous)
+	for i := range fresh {
+		fresh[i].Enrichment, fresh[i].Ticket = previous[i].Enrichment, previous[i].Ticket
+		if old := previous[i].Extras; old != nil && old.Code != nil && fresh[i].Extras != nil {
+			fresh[i].Extras.Code = old.Code
+		}
+	}
+	search.Index(fresh, len(fresh))
+
+	byPath := make(map[string]scanner.DirectoryInfo, len(fresh))
+	for _, info := range fresh {
+		byPath[info.Path] = info
+	}
+	// Merge into the cache as it is now: a scheduled scan, which runs in
+	// another process, may have saved a newer result during the rescan
+	err = cacheInstance.Update(wsPath, func(latest *cache.ScanResult) error {
+		for i, info := range latest.DirectoryInfos {
+			if updated, ok := byPath[info.Path]; ok {
+				latest.DirectoryInfos[i] = updated
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save rescan: %w", err)
+	}
+	daemonLog.Info("rescanned repositories", "paths", dirs)
+	return dirs, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// daemonLogsLines is the number of messages to print
+	daemonLogsLines int
+
+	// daemonLogsComponent limits the messages to one component
+	daemonLogsComponent string
+)
+
+// daemonLogsCmd represents: `thandie daemon logs`
+var daemonLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the running daemon's recent log messages",
+	Long: `Print the messages the running daemon logged most recently, from
+memory, whether or not logging.to_file is set. The daemon keeps its last 500
+messages at the levels it logs (logging.level, or logging.components for a
+component).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var entries []logger.Entry
+		err := rpc.Call(rpc.Request{Method: "logs"}, &entries)
+		switch {
+		case errors.Is(err, rpc.ErrNotRunning):
+			fmt.Fprintln(os.Stderr, "Daemon: not running (start it with 'thandie daemon')")
+			exit(exitError)
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		var shown []logger.Entry
+		for _, e := range entries {
+			if daemonLogsComponent == "" || e.Component == daemonLogsComponent {
+				shown = append(shown, e)
+			}
+		}
+		if daemonLogsLines > 0 && len(shown) > daemonLogsLines {
+			shown = shown[len(shown)-daemonLogsLines:]
+		}
+		for _, e := range shown {
+			printLogEntry(e)
+		}
+	},
+}
+
+func init() {
+	// Attach the `logs` command to daemon: thandie daemon logs
+	daemonLogsCmd.Flags().IntVarP(&daemonLogsLines, "lines", "n", 50, "Number of messages to print (0 for all)")
+	daemonLogsCmd.Flags().StringVar(&daemonLogsComponent, "component", "", "Only print messages of this component, e.g. scanner")
+	daemonCmd.AddCommand(daemonLogsCmd)
+}
+
+// printLogEntry prints a log message on one line, colored by level
+func printLogEntry(e logger.Entry) {
+	level := fmt.Sprintf("%-5s", e.Level)
+	switch {
+	case e.Level >= slog.LevelError:
+		level = colorize(level, colorRed)
+	case e.Level >= slog.LevelWarn:
+		level = colorize(level, colorYellow)
+	case e.Level < slog.LevelInfo:
+		level = colorize(level, colorGray)
+	}
+	line := e.Time.Format("15:04:05") + " " + level
+	if e.Component != "" {
+		line += " [" + e.Component + "]"
+	}
+	line += " " + e.Message
+	if e.Attrs != "" {
+		line += " " + colorize(e.Attrs, colorGray)
+	}
+	fmt.Println(line)
+}
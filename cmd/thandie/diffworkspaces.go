@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// diffWorkspacesFormat is the output format: text or json
+	diffWorkspacesFormat string
+)
+
+// workspaceDiff is the comparison of two cached scans
+type workspaceDiff struct {
+	A         diffSide   `json:"a"`
+	B         diffSide   `json:"b"`
+	OnlyA     []string   `json:"only_a"` // Repositories relative to A's workspace
+	OnlyB     []string   `json:"only_b"`
+	Different []repoDiff `json:"different"`
+	Same      int        `json:"same"`
+}
+
+// diffSide is one of the compared scans
+type diffSide struct {
+	Source    string    `json:"source"` // Workspace path or cache file given on the command line
+	Workspace string    `json:"workspace"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// repoDiff is a repository present in both scans whose state differs
+type repoDiff struct {
+	Repo    string   `json:"repo"`             // Relative to A's workspace
+	RepoB   string   `json:"repo_b,omitempty"` // Relative to B's workspace, when matched by remote at another path
+	Changes []string `json:"changes"`
+}
+
+// diffWorkspacesCmd represents: `thandie diff-workspaces`
+var diffWorkspacesCmd = &cobra.Command{
+	Use:   "diff-workspaces <a> <b>",
+	Short: "Compare the cached scans of two workspaces",
+	Long: `Compare the last scans of two workspaces, e.g. a laptop's and a desktop's,
+listing the repositories only one of them has and those whose branch, HEAD
+commit, uncommitted changes or unpushed commits differ.
+
+Each argument is a workspace path or profile name with a cached scan, or a
+cache file (scan_*.json in the thandie cache directory) copied from another
+machine. Repositories are matched by their path relative to the workspace,
+then by remote URL, so a clone at a different path is still recognized.
+
+Exits with 10 if the workspaces differ, for use in scripts.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+
+		if diffWorkspacesFormat != "text" && diffWorkspacesFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected text or json)\n", diffWorkspacesFormat)
+			exit(exitError)
+		}
+		a, resultA := loadComparedScan(args[0])
+		b, resultB := loadComparedScan(args[1])
+		diff := diffWorkspaces(a, resultA, b, resultB)
+
+		if diffWorkspacesFormat == "json" {
+			if err := printJSON(diff); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printWorkspaceDiff(diff)
+		}
+		if len(diff.OnlyA)+len(diff.OnlyB)+len(diff.Different) > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `diff-workspaces` command to the root command
+	rootCmd.AddCommand(diffWorkspacesCmd)
+	diffWorkspacesCmd.Flags().StringVar(&diffWorkspacesFormat, "format", "text", "Output format: text or json")
+	addJSONFlags(diffWorkspacesCmd)
+}
+
+// loadComparedScan loads the scan an argument of diff-workspaces names: a
+// cache file, a profile or a workspace path. Exits with exitScanFailure if
+// there is no such scan.
+func loadComparedScan(arg string) (diffSide, *cache.ScanResult) {
+	side := diffSide{Source: arg}
+	var result *cache.ScanResult
+	var err error
+	if info, statErr := os.Stat(arg); statErr == nil && !info.IsDir() {
+		result, err = cache.LoadFile(arg)
+	} else {
+		wsPath := arg
+		if profile := cfg.FindProfile(arg); profile != nil && statErr != nil {
+			wsPath = profile.Path
+		} else if wsPath, err = config.ExpandString(arg); err == nil {
+			wsPath, err = filepath.Abs(wsPath)
+		}
+		if err == nil {
+			var cacheInstance *cache.Cache
+			if cacheInstance, err = cache.New(); err == nil {
+				result, err = cacheInstance.LoadScanResult(wsPath)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", arg, err)
+		exit(exitScanFailure)
+	}
+	side.Workspace, side.ScannedAt = result.WorkspacePath, result.ScannedAt
+	return side, result
+}
+
+// diffWorkspaces compares the git repositories of two scans
+func diffWorkspaces(a diffSide, resultA *cache.ScanResult, b diffSide, resultB *cache.ScanResult) workspaceDiff {
+	diff := workspaceDiff{A: a, B: b, OnlyA: []string{}, OnlyB: []string{}, Different: []repoDiff{}}
+	reposA := comparedRepos(resultA)
+	reposB := comparedRepos(resultB)
+	namesA := slices.Sorted(maps.Keys(reposA))
+	namesB := slices.Sorted(maps.Keys(reposB))
+
+	// Match by relative path first, then by remote among the rest
+	matched := make(map[string]string) // A's name -> B's name
+	for name := range reposA {
+		if _, ok := reposB[name]; ok {
+			matched[name] = name
+		}
+	}
+	byRemote := make(map[string][]string) // B's unmatched names by remote
+	for _, name := range namesB {
+		if remote := reposB[name].GitMetadata.RemoteURL; remote != "" {
+			if _, ok := reposA[name]; !ok {
+				key := gitremote.Normalize(remote)
+				byRemote[key] = append(byRemote[key], name)
+			}
+		}
+	}
+	for _, name := range namesA {
+		remote := reposA[name].GitMetadata.RemoteURL
+		if _, ok := matched[name]; ok || remote == "" {
+			continue
+		}
+		key := gitremote.Normalize(remote)
+		if candidates := byRemote[key]; len(candidates) > 0 {
+			matched[name] = candidates[0]
+			byRemote[key] = candidates[1:]
+		}
+	}
+
+	matchedB := make(map[string]bool)
+	for _, name := range namesA {
+		info := reposA[name]
+		nameB, ok := matched[name]
+		if !ok {
+			diff.OnlyA = append(diff.OnlyA, name)
+			continue
+		}
+		matchedB[nameB] = true
+		changes := repoChanges(info.GitMetadata, reposB[nameB].GitMetadata)
+		if len(changes) == 0 && nameB == name {
+			diff.Same++
+			continue
+		}
+		d := repoDiff{Repo: name, Changes: changes}
+		if nameB != name {
+			d.RepoB = nameB
+		}
+		diff.Different = append(diff.Different, d)
+	}
+	for _, name := range namesB {
+		if !matchedB[name] {
+			diff.OnlyB = append(diff.OnlyB, name)
+		}
+	}
+
+	return diff
+}
+
+// comparedRepos returns the git repositories of a scan by their path
+// relative to its workspace
+func comparedRepos(result *cache.ScanResult) map[string]scanner.DirectoryInfo {
+	repos := make(map[string]scanner.DirectoryInfo)
+	for _, info := range result.DirectoryInfos {
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+			continue
+		}
+		name, err := filepath.Rel(result.WorkspacePath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+		repos[filepath.ToSlash(name)] = info
+	}
+	return repos
+}
+
+// repoChanges describes how the state of a repository differs between A and B
+func repoChanges(a, b *scanner.GitMetadata) []string {
+	var changes []string
+	if a.CurrentBranch != b.CurrentBranch {
+		changes = append(changes, fmt.Sprintf("branch %s ≠ %s", orNone(a.CurrentBranch, "(detached)"), orNone(b.CurrentBranch, "(detached)")))
+	}
+	if a.Head != b.Head {
+		changes = append(changes, fmt.Sprintf("HEAD %s ≠ %s", orNone(shortHash(a.Head), "(none)"), orNone(shortHash(b.Head), "(none)")))
+	}
+	switch {
+	case a.HasUncommitted && !b.HasUncommitted:
+		changes = append(changes, "uncommitted changes only in A")
+	case b.HasUncommitted && !a.HasUncommitted:
+		changes = append(changes, "uncommitted changes only in B")
+	}
+	if a.Ahead != b.Ahead {
+		changes = append(changes, fmt.Sprintf("unpushed ↑%d ≠ ↑%d", a.Ahead, b.Ahead))
+	}
+	return changes
+}
+
+// shortHash abbreviates a commit hash to 7 characters
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// orNone returns s, or fallback if s is empty
+func orNone(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+// printWorkspaceDiff prints the comparison grouped by kind of difference
+func printWorkspaceDiff(diff workspaceDiff) {
+	fmt.Printf("A: %s (scanned %s)\n", diff.A.Workspace, formatAge(diff.A.ScannedAt))
+	fmt.Printf("B: %s (scanned %s)\n", diff.B.Workspace, formatAge(diff.B.ScannedAt))
+
+	if len(diff.OnlyA) > 0 {
+		fmt.Printf("\nOnly in A (%d):\n", len(diff.OnlyA))
+		for _, name := range diff.OnlyA {
+			fmt.Printf("  %s\n", colorize(name, colorYellow))
+		}
+	}
+	if len(diff.OnlyB) > 0 {
+		fmt.Printf("\nOnly in B (%d):\n", len(diff.OnlyB))
+		for _, name := range diff.OnlyB {
+			fmt.Printf("  %s\n", colorize(name, colorYellow))
+		}
+	}
+	if len(diff.Different) > 0 {
+		fmt.Printf("\nDifferent (%d):\n", len(diff.Different))
+		for _, d := range diff.Different {
+			name := d.Repo
+			if d.RepoB != "" {
+				name += " (B: " + d.RepoB + ")"
+			}
+			fmt.Printf("  %s\n", name)
+			for _, change := range d.Changes {
+				fmt.Printf("      %s\n", colorize(change, colorGray))
+			}
+		}
+	}
+
+	fmt.Printf("\n%d repositories match", diff.Same)
+	if n := len(diff.OnlyA) + len(diff.OnlyB) + len(diff.Different); n > 0 {
+		fmt.Printf(", %d differ", n)
+	}
+	fmt.Println(".")
+}
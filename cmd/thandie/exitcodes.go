@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
+)
+
+// Exit codes returned by thandie subcommands. These are part of the CLI
+// contract so that scripts can branch on the class of failure:
+//
+//	0  success
+//	1  unclassified error (bad flags, unexpected failures)
+//	2  configuration error (unreadable or invalid config)
+//	3  workspace missing (path does not exist or is not a directory)
+//	4  scan failure
+//	5  sync failure
+//	10 check failed (the command ran, but a check it performed did not pass)
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitConfigError      = 2
+	exitWorkspaceMissing = 3
+	exitScanFailure      = 4
+	exitSyncFailure      = 5
+	exitCheckFailed      = 10
+)
+
+// exit flushes traces and the log file before terminating with the given code.
+// Deferred calls in main do not run on os.Exit, so commands must use this instead.
+func exit(code int) {
+	tracing.Shutdown()
+	logger.Sync()
+	logger.Close()
+	os.Exit(code)
+}
+
+// requireConfig exits with exitConfigError if the config file could not be loaded.
+// Commands that depend on configuration call this before doing any work.
+func requireConfig() {
+	if configErr != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", configErr)
+		exit(exitConfigError)
+	}
+}
+
+// readOnly reports whether write actions are disabled by --read-only or
+// security.read_only
+func readOnly() bool {
+	return readOnlyFlag || (cfg != nil && cfg.Security.ReadOnly)
+}
+
+// requireWritable exits with exitError if read-only mode is on. Commands
+// that change repositories, the keychain or anything outside thandie's own
+// cache, or that remove or hide what the cache holds, call this before doing
+// so; action names what would have been done.
+func requireWritable(action string) {
+	if readOnly() {
+		fmt.Fprintf(os.Stderr, "Error: %s is disabled in read-only mode (--read-only or security.read_only)\n", action)
+		exit(exitError)
+	}
+}
+
+// requireWorkspace exits with exitWorkspaceMissing if wsPath is empty or
+// does not point to an existing directory.
+func requireWorkspace(wsPath string) {
+	if wsPath == "" {
+		logger.Error("workspace path is empty", "hint", "use --workspace or -w to specify it")
+		exit(exitWorkspaceMissing)
+	}
+	info, err := os.Stat(wsPath)
+	if err != nil {
+		logger.Error("workspace not found", "path", wsPath, "error", err)
+		exit(exitWorkspaceMissing)
+	}
+	if !info.IsDir() {
+		logger.Error("workspace is not a directory", "path", wsPath)
+		exit(exitWorkspaceMissing)
+	}
+}
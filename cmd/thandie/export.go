@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/attest"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// exportFormat is the output format: html or json
+	exportFormat string
+
+	// exportOutput is the file written instead of stdout
+	exportOutput string
+
+	// exportFilter limits the exported directories (see internal/filter)
+	exportFilter string
+)
+
+// exportData is the template data of `thandie export`
+type exportData struct {
+	reportData
+	Title     string
+	Lines     []string
+	ScannedAt time.Time
+	Stats     exportStats
+}
+
+// exportStats are the counts shown at the top of the dashboard
+type exportStats struct {
+	Repositories int
+	Dirty        int
+	Unpushed     int
+	Behind       int
+	CIFailing    int
+}
+
+// exportCmd represents: `thandie export`
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the workspace as a self-contained HTML dashboard",
+	Long: `Write the last scan of the workspace as a single HTML page with no external
+assets: counts of dirty, unpushed and behind repositories, and a table of
+every repository that sorts by any column when its header is clicked, with
+uncommitted work highlighted and the scan time in the header. Drop it on an
+internal web server or attach it to an email.
+
+--format json writes the scanned directories instead. Use --filter to limit
+the repositories exported, -o to write a file, and --template to render the
+page with your own Go template (see "Custom output templates" in the README).
+With security.sign_key set, files written with -o are signed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if exportFormat != "html" && exportFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected html or json)\n", exportFormat)
+			exit(exitError)
+		}
+		dirFilter := parseFilter(wsPath, exportFilter)
+		result := loadScanResult(wsPath)
+		infos := dirFilter.Apply(result.DirectoryInfos)
+
+		var out bytes.Buffer
+		var err error
+		switch exportFormat {
+		case "json":
+			var data []byte
+			if data, err = json.MarshalIndent(infos, "", "  "); err == nil {
+				out.Write(append(data, '\n'))
+			}
+		default:
+			summary := workspaceSummary(wsPath, infos)
+			data := exportData{
+				reportData: newReportData(wsPath, infos),
+				Title:      summary.Title,
+				Lines:      summary.Lines,
+				ScannedAt:  result.ScannedAt,
+			}
+			for _, dir := range data.Directories {
+				git := dir.GitMetadata
+				if git == nil || !git.IsGitRepo {
+					continue
+				}
+				data.Stats.Repositories++
+				if git.HasUncommitted {
+					data.Stats.Dirty++
+				}
+				if git.Ahead > 0 {
+					data.Stats.Unpushed++
+				}
+				if git.Behind > 0 {
+					data.Stats.Behind++
+				}
+				if dir.Enrichment != nil && dir.Enrichment.CI == providers.CIFailing {
+					data.Stats.CIFailing++
+				}
+			}
+			if templatePath != "" {
+				err = renderTemplate(&out, templatePath, data)
+			} else {
+				err = dashboardHTML.Execute(&out, data)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		if exportOutput == "" {
+			os.Stdout.Write(out.Bytes())
+			warnUnsigned()
+			return
+		}
+		if err := os.WriteFile(exportOutput, out.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", exportOutput, err)
+			exit(exitError)
+		}
+		fmt.Printf("Exported %d directories to %s\n", len(infos), exportOutput)
+		signExport(exportOutput)
+	},
+}
+
+// signExport signs an exported file with security.sign_key, if set, so the
+// receiver can verify it comes from this agent. Exits with exitError if
+// signing fails, as an unsigned export would then pass unnoticed.
+func signExport(path string) {
+	if cfg == nil || cfg.Security.SignKey == "" {
+		return
+	}
+	sigPath, err := attest.Sign(cfg.Security.SignKey, path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitError)
+	}
+	fmt.Printf("Signed %s: %s\n", path, sigPath)
+}
+
+// warnUnsigned notes that an export written to stdout isn't signed even
+// though security.sign_key is set
+func warnUnsigned() {
+	if cfg != nil && cfg.Security.SignKey != "" {
+		logger.Warn("export written to stdout is not signed; write it to a file to sign it")
+	}
+}
+
+func init() {
+	// Attach the `export` command to the root command
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportFormat, "format", "html", "Output format: html or json")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write to this file instead of stdout")
+	exportCmd.Flags().StringVar(&exportFilter, "filter", "", "Only export directories matching the filter, e.g. 'group:platform'")
+	addTemplateFlag(exportCmd)
+}
+
+// dashboardHTML is the page written by `thandie export`. Styles and the
+// sorting script are inline so the file works on its own.
+var dashboardHTML = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"age":  formatAge,
+	"unix": func(t time.Time) int64 { return t.Unix() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; color: #24292f; margin: 2rem; }
+h1 { margin-bottom: 0.25rem; }
+.meta { color: #57606a; margin-top: 0; }
+.stats { display: flex; gap: 1rem; margin: 1.5rem 0; flex-wrap: wrap; }
+.stat { border: 1px solid #d0d7de; border-radius: 6px; padding: 0.75rem 1.25rem; min-width: 8rem; }
+.stat b { display: block; font-size: 1.75rem; }
+.stat.warn b { color: #9a6700; }
+.stat.bad b { color: #cf222e; }
+table { border-collapse: collapse; width: 100%; font-size: 14px; }
+th, td { text-align: left; padding: 0.4rem 0.6rem; border-bottom: 1px solid #d8dee4; }
+th { cursor: pointer; user-select: none; background: #f6f8fa; }
+th.asc::after { content: " ▲"; }
+th.desc::after { content: " ▼"; }
+tr.dirty { background: #fff8c5; }
+.passing { color: #1a7f37; }
+.failing { color: #cf222e; }
+.pending { color: #9a6700; }
+.muted { color: #8c959f; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p class="meta">{{ .WorkspaceName }} &middot; scanned {{ .ScannedAt.Format "Mon, 02 Jan 2006 15:04 MST" }} &middot; exported {{ .GeneratedAt.Format "Mon, 02 Jan 2006 15:04 MST" }}</p>
+<div class="stats">
+<div class="stat"><b>{{ .Stats.Repositories }}</b>repositories</div>
+<div class="stat{{ if .Stats.Dirty }} warn{{ end }}"><b>{{ .Stats.Dirty }}</b>uncommitted changes</div>
+<div class="stat{{ if .Stats.Unpushed }} warn{{ end }}"><b>{{ .Stats.Unpushed }}</b>unpushed commits</div>
+<div class="stat"><b>{{ .Stats.Behind }}</b>behind upstream</div>
+<div class="stat{{ if .Stats.CIFailing }} bad{{ end }}"><b>{{ .Stats.CIFailing }}</b>CI failing</div>
+</div>
+<table id="repos">
+<thead><tr><th>Repository</th><th>Branch</th><th>Changes</th><th>Ahead</th><th>Behind</th><th>CI</th><th>Tests</th><th>Languages</th></tr></thead>
+<tbody>
+{{- range .Directories }}{{ if and .GitMetadata .GitMetadata.IsGitRepo }}
+<tr{{ if .GitMetadata.HasUncommitted }} class="dirty"{{ end }}>
+<td>{{ .Name }}</td>
+<td>{{ .GitMetadata.CurrentBranch }}</td>
+{{- if .GitMetadata.HasUncommitted }}
+<td data-sort="{{ unix .GitMetadata.DirtySince }}">dirty{{ if not .GitMetadata.DirtySince.IsZero }} since {{ age .GitMetadata.DirtySince }}{{ end }}</td>
+{{- else }}
+<td data-sort="" class="muted">clean</td>
+{{- end }}
+<td data-sort="{{ .GitMetadata.Ahead }}">{{ if .GitMetadata.Ahead }}↑{{ .GitMetadata.Ahead }}{{ end }}</td>
+<td data-sort="{{ .GitMetadata.Behind }}">{{ if .GitMetadata.Behind }}↓{{ .GitMetadata.Behind }}{{ end }}</td>
+{{- if and .Enrichment .Enrichment.CI }}
+<td class="{{ .Enrichment.CI }}">{{ .Enrichment.CI }}</td>
+{{- else }}
+<td class="muted">-</td>
+{{- end }}
+{{- if .Tests }}
+<td class="{{ if .Tests.Passed }}passing">passed{{ else }}failing">failed{{ end }}</td>
+{{- else }}
+<td class="muted">-</td>
+{{- end }}
+<td>{{ range $i, $lang := .Languages }}{{ if $i }}, {{ end }}{{ $lang }}{{ end }}</td>
+</tr>
+{{- end }}{{ end }}
+</tbody>
+</table>
+<script>
+document.querySelectorAll("#repos th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var asc = !th.classList.contains("asc");
+    document.querySelectorAll("#repos th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var body = document.querySelector("#repos tbody");
+    var key = function (row) {
+      var cell = row.cells[column];
+      return cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent.trim();
+    };
+    Array.from(body.rows).sort(function (a, b) {
+      var x = key(a), y = key(b);
+      var cmp = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y)) ? x - y : x.localeCompare(y, undefined, { numeric: true });
+      return asc ? cmp : -cmp;
+    }).forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/activity"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// findFilter limits the repositories searched (see internal/filter)
+	findFilter string
+
+	// findLimit is the maximum number of matches printed
+	findLimit int
+
+	// findOpen is the 1-based index of the match to open in the editor
+	findOpen int
+)
+
+// findCmd represents: `thandie find`
+var findCmd = &cobra.Command{
+	Use:   "find <query>",
+	Short: "Fuzzy-find tracked files across all repositories",
+	Long: `Fuzzy-match the query against the paths of the files tracked by every
+repository, as <repo>/<path>, and print the best matches. The characters of
+the query must appear in order; matches in file names, at the start of path
+segments and in runs score higher.
+
+File lists are indexed during scans and cached by HEAD commit, so only
+repositories whose HEAD moved are listed again. With --open N the Nth match
+is opened in $VISUAL or $EDITOR.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		indexDir, err := search.IndexDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		dirFilter := parseFilter(wsPath, findFilter)
+		var candidates []search.Candidate
+		for _, info := range dirFilter.Apply(loadScanResult(wsPath).DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			files, err := search.Files(indexDir, info.Path, info.GitMetadata.Head)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				continue
+			}
+			for _, file := range files {
+				candidates = append(candidates, search.Candidate{Repo: name, Path: file})
+			}
+		}
+
+		matches := search.Rank(strings.Join(args, " "), candidates, findLimit)
+		if findOpen > 0 {
+			if findOpen > len(matches) {
+				fmt.Fprintf(os.Stderr, "Error: only %d matches\n", len(matches))
+				exit(exitError)
+			}
+			match := matches[findOpen-1]
+			path := filepath.Join(wsPath, match.Repo, match.Path)
+			if err := openInEditor(path); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", path, err)
+				exit(exitError)
+			}
+			if err := activity.RecordOpened(filepath.Join(wsPath, match.Repo)); err != nil {
+				logger.Warn("failed to record opened repository", "error", err)
+			}
+			return
+		}
+
+		if len(matches) == 0 {
+			fmt.Printf("No files matching %q in %d files.\n", strings.Join(args, " "), len(candidates))
+			return
+		}
+		for i, match := range matches {
+			fmt.Printf("%3d  %s\n", i+1, filepath.Join(match.Repo, match.Path))
+		}
+	},
+}
+
+func init() {
+	// Attach the `find` command to the root: thandie find <query>
+	findCmd.Flags().StringVar(&findFilter, "filter", "", "Only search repositories matching the filter, e.g. 'group:platform'")
+	findCmd.Flags().IntVar(&findLimit, "limit", 20, "Maximum number of matches to print (0 for all)")
+	findCmd.Flags().IntVar(&findOpen, "open", 0, "Open the Nth match in $VISUAL or $EDITOR")
+	rootCmd.AddCommand(findCmd)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ThandieOps/thandie-agent/internal/jsonfields"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// ANSI color codes used for status glyphs
+const (
+	colorRed    = "31"
+	colorGreen  = "32"
+	colorYellow = "33"
+	colorGray   = "90"
+)
+
+// deuteranopiaPalette replaces red and green, which look alike with
+// red-green color blindness, by vermillion and blue (from the Okabe-Ito
+// palette), and yellow by a brighter yellow
+var deuteranopiaPalette = map[string]string{
+	colorRed:    "38;5;166",
+	colorGreen:  "38;5;32",
+	colorYellow: "38;5;220",
+}
+
+// useColor reports whether stdout supports ANSI colors. NO_COLOR disables them.
+func useColor() bool {
+	return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorize wraps s in the given ANSI color when colors are enabled
+func colorize(s, color string) string {
+	if !useColor() {
+		return s
+	}
+	if cfg != nil && cfg.UI.Palette == "deuteranopia" {
+		if replacement, ok := deuteranopiaPalette[color]; ok {
+			color = replacement
+		}
+	}
+	return "\033[" + color + "m" + s + "\033[0m"
+}
+
+// stateSymbol returns ● for a dirty and ✚ for a clean repository when
+// ui.symbols is set, so the state doesn't rest on color alone, and ""
+// otherwise
+func stateSymbol(git *scanner.GitMetadata) string {
+	switch {
+	case cfg == nil || !cfg.UI.Symbols:
+		return ""
+	case git.HasUncommitted:
+		return colorize("●", colorYellow)
+	}
+	return colorize("✚", colorGreen)
+}
+
+// dirtyMarker returns the marker of a repository's worktree state in
+// directory lines: the state symbol, or " *" when dirty without ui.symbols
+func dirtyMarker(git *scanner.GitMetadata) string {
+	if symbol := stateSymbol(git); symbol != "" {
+		return " " + symbol
+	}
+	if git.HasUncommitted {
+		return " *"
+	}
+	return ""
+}
+
+// inProgressText describes the operation a repository is in the middle of,
+// e.g. "merge in progress (2 conflicts)", or returns "" if there is none
+func inProgressText(git *scanner.GitMetadata) string {
+	if git.InProgress == "" {
+		return ""
+	}
+	text := git.InProgress + " in progress"
+	switch {
+	case git.Conflicts == 1:
+		text += " (1 conflict)"
+	case git.Conflicts > 1:
+		text += fmt.Sprintf(" (%d conflicts)", git.Conflicts)
+	}
+	return text
+}
+
+// ciGlyph returns a colored glyph for a CI state
+func ciGlyph(state string) string {
+	switch state {
+	case providers.CIPassing:
+		return colorize("✓", colorGreen)
+	case providers.CIFailing:
+		return colorize("✗", colorRed)
+	case providers.CIPending:
+		return colorize("●", colorYellow)
+	default:
+		return colorize("-", colorGray)
+	}
+}
+
+// runeWidth returns the number of terminal columns r takes: 2 for wide
+// characters such as CJK and most emoji, 0 for combining marks, joiners and
+// control characters, and 1 otherwise
+func runeWidth(r rune) int {
+	if r < 0x20 || r == 0x7f || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// ansiEscape returns the length of the ANSI escape sequence at the start of
+// s, as written by colorize, or 0
+func ansiEscape(s string) int {
+	if !strings.HasPrefix(s, "\033[") {
+		return 0
+	}
+	if end := strings.IndexByte(s, 'm'); end > 0 {
+		return end + 1
+	}
+	return 0
+}
+
+// displayWidth returns the number of terminal columns s takes, ignoring
+// color codes. Unlike len or the width of fmt's %-30s, which count bytes and
+// runes, it lines up columns containing emoji and CJK names.
+func displayWidth(s string) int {
+	w := 0
+	for i := 0; i < len(s); {
+		if n := ansiEscape(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w += runeWidth(r)
+		i += size
+	}
+	return w
+}
+
+// truncateWidth cuts s to at most n terminal columns, ending it with an
+// ellipsis when cut. Color codes are kept, and reset after a cut.
+func truncateWidth(s string, n int) string {
+	if displayWidth(s) <= n {
+		return s
+	}
+	var b strings.Builder
+	w, colored := 0, false
+	for i := 0; i < len(s); {
+		if e := ansiEscape(s[i:]); e > 0 {
+			b.WriteString(s[i : i+e])
+			colored = true
+			i += e
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if w+runeWidth(r) > n-1 {
+			break
+		}
+		b.WriteRune(r)
+		w += runeWidth(r)
+		i += size
+	}
+	if n > 0 {
+		b.WriteString("…")
+	}
+	if colored {
+		b.WriteString("\033[0m")
+	}
+	return b.String()
+}
+
+// padWidth truncates s to n terminal columns and pads it with spaces to
+// exactly n, for a table column
+func padWidth(s string, n int) string {
+	s = truncateWidth(s, n)
+	return s + strings.Repeat(" ", max(n-displayWidth(s), 0))
+}
+
+// formatAge formats the time elapsed since t as a short relative age, e.g. "3h ago"
+func formatAge(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d.Hours()/(24*30)))
+	default:
+		return fmt.Sprintf("%dy ago", int(d.Hours()/(24*365)))
+	}
+}
+
+// formatDuration formats d without zero trailing units, e.g. "24h" rather than "24h0m0s"
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// openInBrowser opens url with the platform's default handler
+func openInBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// openInEditor opens path in $VISUAL or $EDITOR, waiting for the editor to
+// exit, or with the platform's default handler if neither is set
+func openInEditor(path string) error {
+	return openInEditorAt(path, 0)
+}
+
+// openInEditorAt opens path like openInEditor with the cursor on line (from
+// 1; 0 for none). Editors are told the line the way they understand it:
+// VS Code and its forks with -g file:line, Sublime Text and Zed with
+// file:line, and vi, Emacs, nano and most others with +line.
+func openInEditorAt(path string, line int) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return openInBrowser(path)
+	}
+	args := append(fields[1:], path)
+	if line > 0 {
+		at := fmt.Sprintf("%s:%d", path, line)
+		switch strings.TrimSuffix(filepath.Base(fields[0]), ".exe") {
+		case "code", "code-insiders", "codium", "cursor":
+			args = append(fields[1:], "-g", at)
+		case "subl", "zed":
+			args = append(fields[1:], at)
+		default:
+			args = append(fields[1:], fmt.Sprintf("+%d", line), path)
+		}
+	}
+	cmd := exec.Command(fields[0], args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// dockerBadge returns a badge showing that a directory has container
+// definitions, highlighted with a count when containers are running
+func dockerBadge(d *scanner.Docker) string {
+	if len(d.Running) == 0 {
+		return colorize("[docker]", colorGray)
+	}
+	return colorize(fmt.Sprintf("[docker ▶%d]", len(d.Running)), colorGreen)
+}
+
+// testBadge returns a badge with the outcome of the last test run
+func testBadge(run *scanner.TestRun) string {
+	if run.Passed {
+		return colorize("[test ✓]", colorGreen)
+	}
+	return colorize("[test ✗]", colorRed)
+}
+
+// jsonOpts holds the --fields/--flatten options of JSON-emitting commands
+var jsonOpts struct {
+	fields  []string
+	flatten bool
+}
+
+// addJSONFlags registers --fields and --flatten on a command that emits JSON
+func addJSONFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&jsonOpts.fields, "fields", nil, "Only include these JSON fields, e.g. path,git.branch,git.ahead")
+	cmd.Flags().BoolVar(&jsonOpts.flatten, "flatten", false, "Flatten nested JSON objects into dotted keys")
+}
+
+// printJSON prints v as indented JSON, applying --fields and --flatten
+func printJSON(v any) error {
+	doc, err := jsonfields.Apply(v, jsonfields.Options{Fields: jsonOpts.fields, Flatten: jsonOpts.flatten})
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
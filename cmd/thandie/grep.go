@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// grepFilter limits the repositories searched (see internal/filter)
+	grepFilter string
+
+	// grepOpts controls how the pattern is matched
+	grepOpts search.Options
+
+	// grepFormat is the output format: text or json
+	grepFormat string
+)
+
+// grepResult is the matches of one repository
+type grepResult struct {
+	Repo    string         `json:"repo"` // Relative to the workspace
+	Path    string         `json:"path"`
+	Matches []search.Match `json:"matches,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// grepCmd represents: `thandie grep`
+var grepCmd = &cobra.Command{
+	Use:   "grep <pattern>",
+	Short: "Search the tracked files of every repository",
+	Long: `Search the files tracked by each git repository of the last scan for an
+extended regular expression (or a literal string with --fixed-strings), at
+most scanner.concurrency repositories at a time. Untracked, gitignored and
+binary files are skipped.
+
+Matches are grouped by repository and printed as <repo>/<file>:<line>, which
+most editors and terminals can open directly. Use --filter (e.g. 'lang:go'
+or 'group:platform') to limit the repositories searched.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if grepFormat != "text" && grepFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected text or json)\n", grepFormat)
+			exit(exitError)
+		}
+		dirFilter := parseFilter(wsPath, grepFilter)
+		infos := dirFilter.Apply(loadScanResult(wsPath).DirectoryInfos)
+
+		var results []*grepResult
+		sem := make(chan struct{}, max(getScannerConfig(wsPath).Concurrency, 1))
+		var wg sync.WaitGroup
+		for _, info := range infos {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			result := &grepResult{Repo: name, Path: info.Path}
+			results = append(results, result)
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				matches, err := search.Grep(context.Background(), result.Path, args[0], grepOpts)
+				if err != nil {
+					result.Error = err.Error()
+					return
+				}
+				result.Matches = matches
+			}()
+		}
+		wg.Wait()
+
+		failed := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+			}
+		}
+		if grepFormat == "json" {
+			if err := printJSON(results); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printGrepResults(results)
+		}
+		if failed > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+// printGrepResults prints the matches grouped by repository, followed by
+// the total
+func printGrepResults(results []*grepResult) {
+	total, repos := 0, 0
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "  ✗ %s: %s\n", result.Repo, result.Error)
+			continue
+		}
+		if len(result.Matches) == 0 {
+			continue
+		}
+		if repos > 0 {
+			fmt.Println()
+		}
+		repos++
+		total += len(result.Matches)
+		fmt.Println(colorize(fmt.Sprintf("%s (%d)", result.Repo, len(result.Matches)), colorYellow))
+		for _, match := range result.Matches {
+			location := fmt.Sprintf("%s:%d", filepath.Join(result.Repo, match.File), match.Line)
+			fmt.Printf("  %s: %s\n", colorize(location, colorGray), match.Text)
+		}
+	}
+	if total == 0 {
+		fmt.Println("No matches.")
+		return
+	}
+	fmt.Printf("\n%d matches in %d of %d repositories.\n", total, repos, len(results))
+}
+
+func init() {
+	// Attach the `grep` command to the root: thandie grep <pattern>
+	grepCmd.Flags().StringVar(&grepFilter, "filter", "", "Only search repositories matching the filter, e.g. 'lang:go'")
+	grepCmd.Flags().BoolVarP(&grepOpts.IgnoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	grepCmd.Flags().BoolVarP(&grepOpts.Fixed, "fixed-strings", "F", false, "Treat the pattern as a literal string")
+	grepCmd.Flags().StringVar(&grepFormat, "format", "text", "Output format: text or json")
+	addJSONFlags(grepCmd)
+	rootCmd.AddCommand(grepCmd)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/filter"
+	"github.com/ThandieOps/thandie-agent/internal/groups"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// groupName limits a bulk action or report to one group of repositories
+	groupName string
+)
+
+// addGroupFlag adds the --group flag to a command that works on every
+// repository in the workspace
+func addGroupFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&groupName, "group", "", "Only include repositories in this group (see groups in the config)")
+}
+
+// getGroups returns the configured repository groups of wsPath. Exits with
+// exitConfigError if the groups config is invalid.
+func getGroups(wsPath string) *groups.Groups {
+	var configured []config.GroupConfig
+	if cfg != nil {
+		configured = cfg.Groups
+	}
+	g, err := groups.New(configured, wsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitConfigError)
+	}
+	return g
+}
+
+// parseFilter parses a --filter expression, with group:<name> matching the
+// groups of wsPath. Exits with exitError if it is invalid.
+func parseFilter(wsPath, expr string) *filter.Filter {
+	g := getGroups(wsPath)
+	filter.Register("group", func(info scanner.DirectoryInfo, value string) bool {
+		return g.Contains(value, info.Path)
+	})
+	dirFilter, err := filter.Parse(expr)
+	if err == nil {
+		for _, name := range dirFilter.Values("group") {
+			if _, ok := g.Lookup(name); !ok {
+				err = fmt.Errorf("unknown group %q (configured: %s)", name, strings.Join(g.Names(), ", "))
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitError)
+	}
+	return dirFilter
+}
+
+// selectGroup returns the directories of infos in the named group, or all of
+// them if name is empty. Exits with exitError if the group doesn't exist.
+func selectGroup(wsPath, name string, infos []scanner.DirectoryInfo) []scanner.DirectoryInfo {
+	if name == "" {
+		return infos
+	}
+	g := getGroups(wsPath)
+	if _, ok := g.Lookup(name); !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown group %q (configured: %s)\n", name, strings.Join(g.Names(), ", "))
+		exit(exitError)
+	}
+	var selected []scanner.DirectoryInfo
+	for _, info := range infos {
+		if g.Contains(name, info.Path) {
+			selected = append(selected, info)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/guard"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// guardInstallAll installs the hook into every repository in the workspace
+	guardInstallAll bool
+
+	// guardInstallForce replaces existing pre-push hooks (keeping a backup)
+	guardInstallForce bool
+)
+
+// guardCmd represents: `thandie guard`
+var guardCmd = &cobra.Command{
+	Use:   "guard [remote] [url]",
+	Short: "Pre-push check that blocks pushes containing secrets or failing checks",
+	Long: `Run as a git pre-push hook (see 'thandie guard install'). Reads the refs
+being pushed from stdin, scans the outgoing commits for likely secrets and
+runs the checks configured under guard.checks. The push is blocked if any
+secret is found or any check fails.
+
+To bypass the guard for a single push, run 'git push --no-verify' or set
+THANDIE_GUARD_SKIP=1.`,
+	Args: cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+
+		if os.Getenv("THANDIE_GUARD_SKIP") == "1" {
+			fmt.Fprintln(os.Stderr, "thandie guard: skipped (THANDIE_GUARD_SKIP=1)")
+			return
+		}
+
+		repoDir, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		// git passes the remote's URL as the second argument
+		remoteURL := ""
+		if len(args) == 2 {
+			remoteURL = args[1]
+		} else {
+			remoteURL = scanner.RemoteURL(repoDir)
+		}
+		if err := getPolicy(getWorkspacePath()).Check(policy.ActionPush, repoDir, remoteURL); err != nil {
+			fmt.Fprintf(os.Stderr, "thandie guard: push blocked: %v\n", err)
+			exit(exitCheckFailed)
+		}
+
+		refs, err := guard.ParsePushedRefs(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading pushed refs: %v\n", err)
+			exit(exitError)
+		}
+
+		result, err := guard.Run(repoDir, refs, cfg.Guard)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		if !result.Blocked() {
+			logger.Debug("guard passed", "repo", repoDir, "refs", len(refs))
+			return
+		}
+
+		printGuardResult(result)
+		exit(exitCheckFailed)
+	},
+}
+
+// guardInstallCmd represents: `thandie guard install`
+var guardInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install thandie guard as the pre-push hook",
+	Long: `Install 'thandie guard' as the git pre-push hook of the repository in the
+current directory, or of every repository in the workspace with --all.
+Existing hooks are left alone unless --force is given, in which case they
+are backed up to pre-push.bak, or pre-push.bak.<timestamp> if that holds an
+earlier backup.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireWritable("installing the guard hook")
+
+		executable, err := os.Executable()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error locating thandie executable: %v\n", err)
+			exit(exitError)
+		}
+
+		var wsPath string
+		var repos []string
+		if guardInstallAll {
+			requireProfile()
+			wsPath = getWorkspacePath()
+			requireWorkspace(wsPath)
+			for _, info := range loadScanResult(wsPath).DirectoryInfos {
+				if info.GitMetadata != nil && info.GitMetadata.IsGitRepo {
+					repos = append(repos, info.Path)
+				}
+			}
+		} else {
+			cwd, err := os.Getwd()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			repos = []string{cwd}
+			wsPath = getWorkspacePath()
+		}
+
+		repoPolicy := getPolicy(wsPath)
+		failed := 0
+		for _, repo := range repos {
+			if err := repoPolicy.Check(policy.ActionGuard, repo, scanner.RemoteURL(repo)); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", repo, err)
+				continue
+			}
+			hookPath, err := guard.Install(repo, executable, guardInstallForce)
+			auditlog.Write("guard.install", repo, hookPath, err)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", repo, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  ✓ %s\n", hookPath)
+		}
+		if failed > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	// Attach the `guard` command and its subcommands: thandie guard [install]
+	guardInstallCmd.Flags().BoolVar(&guardInstallAll, "all", false, "Install into every git repository in the workspace")
+	guardInstallCmd.Flags().BoolVar(&guardInstallForce, "force", false, "Replace existing pre-push hooks (a .bak copy is kept)")
+	guardCmd.AddCommand(guardInstallCmd)
+	rootCmd.AddCommand(guardCmd)
+}
+
+// printGuardResult explains why a push was blocked and how to bypass the guard
+func printGuardResult(result *guard.Result) {
+	fmt.Fprintln(os.Stderr, "thandie guard: push blocked")
+
+	if len(result.Secrets) > 0 {
+		fmt.Fprintf(os.Stderr, "\nPossible secrets in outgoing commits (%d):\n", len(result.Secrets))
+		for _, finding := range result.Secrets {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n    %s\n", finding.File, finding.Rule, finding.Line)
+		}
+	}
+
+	for _, check := range result.Checks {
+		fmt.Fprintf(os.Stderr, "\nCheck failed: %s\n", check.Name)
+		if check.Output != "" {
+			fmt.Fprintf(os.Stderr, "%s\n", check.Output)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "\nIf this is a false positive, bypass the guard for this push with:")
+	fmt.Fprintln(os.Stderr, "  git push --no-verify")
+	fmt.Fprintln(os.Stderr, "  (or THANDIE_GUARD_SKIP=1 git push)")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/hidden"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// unhideAll unhides every hidden directory of the workspace
+	unhideAll bool
+)
+
+// hideCmd represents: `thandie hide <dir>...`
+var hideCmd = &cobra.Command{
+	Use:   "hide <dir>...",
+	Short: "Hide directories from list and scan output",
+	Long: `Hide directories you never care about from 'thandie list' and 'thandie
+scan' output, without excluding them from scans the way scanner.ignore_dirs
+does: they are still scanned, queried and reported on.
+
+Hidden directories are remembered per workspace. 'thandie list --hidden'
+shows them, and 'thandie unhide' brings them back.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+		requireWritable("hiding directories")
+
+		result := loadScanResult(wsPath)
+		var paths []string
+		for _, arg := range args {
+			info, err := findDirectory(wsPath, result.DirectoryInfos, arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			paths = append(paths, info.Path)
+		}
+		if err := hidden.Hide(wsPath, paths...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		for _, path := range paths {
+			fmt.Printf("Hid %s\n", path)
+		}
+		fmt.Println("Undo with: thandie unhide " + strings.Join(args, " "))
+	},
+}
+
+// unhideCmd represents: `thandie unhide [<dir>...]`
+var unhideCmd = &cobra.Command{
+	Use:   "unhide [<dir>...]",
+	Short: "Show hidden directories in list and scan output again",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if unhideAll != (len(args) == 0) {
+			return fmt.Errorf("give the directories to unhide, or --all")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+		requireWritable("unhiding directories")
+
+		set := hidden.Load(wsPath)
+		if unhideAll {
+			if err := hidden.Unhide(wsPath); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			fmt.Printf("Unhid %d directories\n", len(set))
+			return
+		}
+
+		_, hiddenInfos := set.Split(loadScanResult(wsPath).DirectoryInfos)
+		var paths []string
+		for _, arg := range args {
+			info, err := findDirectory(wsPath, hiddenInfos, arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v among hidden directories\n", err)
+				exit(exitError)
+			}
+			paths = append(paths, info.Path)
+		}
+		if err := hidden.Unhide(wsPath, paths...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		for _, path := range paths {
+			fmt.Printf("Unhid %s\n", path)
+		}
+	},
+}
+
+func init() {
+	// Attach the `hide` and `unhide` commands to the root: thandie hide <dir>..., thandie unhide [<dir>...]
+	unhideCmd.Flags().BoolVar(&unhideAll, "all", false, "Unhide every hidden directory of the workspace")
+	rootCmd.AddCommand(hideCmd)
+	rootCmd.AddCommand(unhideCmd)
+}
+
+// printHiddenCount tells how many directories were left out of the output
+// because they are hidden, and how to see them
+func printHiddenCount(n int) {
+	if n > 0 {
+		fmt.Println(colorize(fmt.Sprintf("Hidden (%d): 'thandie list --hidden' to review, 'thandie unhide <dir>' to restore", n), colorGray))
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/hooks"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// hooksTemplate is the directory holding the hook scripts to install
+	hooksTemplate string
+
+	// hooksDryRun reports what would change without writing any hooks
+	hooksDryRun bool
+)
+
+// hooksCmd represents: `thandie hooks`
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks across the workspace",
+}
+
+// hooksApplyCmd represents: `thandie hooks apply`
+var hooksApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Install or update a standard set of git hooks in every workspace repository",
+	Long: `Install the hook scripts found in --template (files named after git hooks,
+e.g. pre-commit or commit-msg) into every git repository in the workspace.
+
+Hooks that differ from the template are backed up to <hook>.bak before being
+replaced, or to <hook>.bak.<timestamp> if that holds an earlier backup. The
+summary lists the repositories that were missing hooks; use --dry-run to
+only report without changing anything, and --group to only update the
+repositories of one group.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		if !hooksDryRun {
+			requireWritable("installing hooks")
+		}
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		template, err := hooks.LoadTemplate(hooksTemplate)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+
+		result := loadScanResult(wsPath)
+		repoPolicy := getPolicy(wsPath)
+
+		var missing []string
+		failed := 0
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+
+			if err := repoPolicy.Check(policy.ActionHooks, info.Path, info.GitMetadata.RemoteURL); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", name, err)
+				continue
+			}
+
+			results, err := hooks.Apply(info.Path, template, hooksDryRun)
+			if err != nil {
+				if !hooksDryRun {
+					auditlog.Write("hooks.apply", info.Path, "", err)
+				}
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				failed++
+				continue
+			}
+
+			var changes []string
+			for _, r := range results {
+				if r.Status == hooks.StatusUnchanged {
+					continue
+				}
+				changes = append(changes, fmt.Sprintf("%s %s", r.Name, r.Status))
+			}
+			if len(changes) > 0 && !hooksDryRun {
+				auditlog.Write("hooks.apply", info.Path, strings.Join(changes, ", "), nil)
+			}
+			if len(changes) == 0 {
+				fmt.Printf("  ✓ %s\n", name)
+				continue
+			}
+			missing = append(missing, name)
+			fmt.Printf("  • %s: %s\n", name, strings.Join(changes, ", "))
+		}
+
+		fmt.Println()
+		switch {
+		case len(missing) == 0:
+			fmt.Println("All repositories have the template hooks.")
+		case hooksDryRun:
+			fmt.Printf("%d repositories are missing or have outdated hooks:\n", len(missing))
+		default:
+			fmt.Printf("Updated hooks in %d repositories:\n", len(missing))
+		}
+		for _, name := range missing {
+			fmt.Printf("  %s\n", name)
+		}
+
+		if failed > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	// Attach the `hooks` command and its subcommands: thandie hooks apply
+	hooksApplyCmd.Flags().StringVar(&hooksTemplate, "template", "", "Directory containing the hook scripts to install")
+	hooksApplyCmd.Flags().BoolVar(&hooksDryRun, "dry-run", false, "Report which repositories are missing hooks without changing anything")
+	hooksApplyCmd.MarkFlagRequired("template")
+	addGroupFlag(hooksApplyCmd)
+	hooksCmd.AddCommand(hooksApplyCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// inboxOpen is the 1-based index of the inbox entry to open in the browser
+	inboxOpen int
+
+	// inboxAll includes pull requests for repositories outside the workspace
+	inboxAll bool
+)
+
+// inboxCmd represents: `thandie inbox`
+var inboxCmd = &cobra.Command{
+	Use:   "inbox",
+	Short: "List open pull requests you authored or need to review",
+	Long: `List open pull/merge requests authored by you or awaiting your review,
+for repositories cloned in the workspace. Every provider you are logged in to
+(see 'thandie auth login') is queried.
+
+Use --open N to open the Nth entry in the browser.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		client, err := providers.NewClient()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		// Only keep pull requests for repositories cloned in the workspace
+		local := make(map[string]bool)
+		for _, info := range loadScanResult(wsPath).DirectoryInfos {
+			if info.GitMetadata != nil && info.GitMetadata.RemoteURL != "" {
+				local[gitremote.Normalize(info.GitMetadata.RemoteURL)] = true
+			}
+		}
+
+		var pulls []providers.PullRequest
+		queried := 0
+		for _, name := range []string{"github", "gitlab"} {
+			provider, err := providers.New(client, name, cfg)
+			if err != nil {
+				logger.Debug("skipping provider", "provider", name, "reason", err)
+				continue
+			}
+			queried++
+
+			providerPulls, err := provider.Inbox(context.Background())
+			if err != nil {
+				logger.Error("failed to load inbox", "provider", name, "error", err)
+				exit(exitError)
+			}
+			for _, pr := range providerPulls {
+				if inboxAll || local[gitremote.Normalize(pr.RepoURL)] {
+					pulls = append(pulls, pr)
+				}
+			}
+		}
+		if queried == 0 {
+			fmt.Fprintln(os.Stderr, "Error: not logged in to any provider; run 'thandie auth login github|gitlab'")
+			exit(exitConfigError)
+		}
+
+		// Reviews first, then most recently updated
+		sort.SliceStable(pulls, func(i, j int) bool {
+			if pulls[i].Reason != pulls[j].Reason {
+				return pulls[i].Reason == providers.ReasonReviewRequested
+			}
+			return pulls[i].UpdatedAt.After(pulls[j].UpdatedAt)
+		})
+
+		if inboxOpen > 0 {
+			if inboxOpen > len(pulls) {
+				fmt.Fprintf(os.Stderr, "Error: inbox has %d entries\n", len(pulls))
+				exit(exitError)
+			}
+			pr := pulls[inboxOpen-1]
+			if err := openInBrowser(pr.URL); err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", pr.URL, err)
+				exit(exitError)
+			}
+			fmt.Printf("Opened %s\n", pr.URL)
+			return
+		}
+
+		printInbox(pulls)
+	},
+}
+
+func init() {
+	// Attach the `inbox` command to the root: thandie inbox
+	rootCmd.AddCommand(inboxCmd)
+
+	inboxCmd.Flags().IntVar(&inboxOpen, "open", 0, "Open the Nth inbox entry in the browser")
+	inboxCmd.Flags().BoolVar(&inboxAll, "all", false, "Include pull requests for repositories not cloned in the workspace")
+}
+
+// printInbox prints numbered pull requests grouped by the reason they appear
+func printInbox(pulls []providers.PullRequest) {
+	if len(pulls) == 0 {
+		fmt.Println("Inbox is empty")
+		return
+	}
+
+	headings := map[string]string{
+		providers.ReasonReviewRequested: "Awaiting your review",
+		providers.ReasonAuthored:        "Authored by you",
+	}
+
+	lastReason := ""
+	for i, pr := range pulls {
+		if pr.Reason != lastReason {
+			if lastReason != "" {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", headings[pr.Reason])
+			lastReason = pr.Reason
+		}
+
+		draft := ""
+		if pr.Draft {
+			draft = " [draft]"
+		}
+		fmt.Printf("  %2d. %s#%d %s%s (%s, %s)\n", i+1, pr.Repo, pr.Number, pr.Title, draft, pr.Author, formatAge(pr.UpdatedAt))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
 	"github.com/ThandieOps/thandie-agent/internal/config"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -19,9 +20,10 @@ var initCmd = &cobra.Command{
 	Long: `Initialize Thandie by creating a configuration file with your preferences.
 This command will prompt you for configuration values with sensible defaults.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("writing the config file")
 		if err := runInit(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error initializing config: %v\n", err)
-			os.Exit(1)
+			exit(exitConfigError)
 		}
 	},
 }
@@ -38,8 +40,11 @@ func runInit() error {
 		return fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	// Default config file location
-	defaultConfigPath := filepath.Join(homeDir, ".config", "thandie", "config.yml")
+	// Default config file location: that of the agent profile, if one is selected
+	defaultConfigPath, err := configFilePath()
+	if err != nil {
+		return err
+	}
 
 	// Default workspace path
 	defaultWorkspace := filepath.Join(homeDir, "Workspace")
@@ -83,12 +88,37 @@ func runInit() error {
 			IncludeHidden: false,
 			IgnoreDirs:    []string{".git", "node_modules", "vendor"},
 			MaxDepth:      1,
+			Concurrency:   4,
+
+			KeepMissingDays: 7,
 		},
 		Logging: config.LoggingConfig{
 			Level:  "info",
 			ToFile: false,
 			JSON:   false,
 		},
+		Providers: config.ProvidersConfig{
+			GitHub: config.ProviderConfig{
+				URL:    "https://api.github.com",
+				WebURL: "https://github.com",
+				Token:  "keychain:github",
+			},
+			GitLab: config.ProviderConfig{
+				URL:    "https://gitlab.com/api/v4",
+				WebURL: "https://gitlab.com",
+				Token:  "keychain:gitlab",
+			},
+		},
+		Enrichment: config.EnrichmentConfig{
+			Enabled: false,
+			TTL:     "15m",
+		},
+		Compliance: config.ComplianceConfig{
+			Required: []string{"license", "readme"},
+		},
+		Notify: config.NotifyConfig{
+			DirtyDays: 14,
+		},
 	}
 
 	// Create directory if it doesn't exist
@@ -116,7 +146,9 @@ func runInit() error {
 	}
 
 	// Write config file
-	if err := os.WriteFile(configPathInput, yamlData, 0644); err != nil {
+	err = os.WriteFile(configPathInput, yamlData, 0644)
+	auditlog.Write("config.init", configPathInput, "", err)
+	if err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
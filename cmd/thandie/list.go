@@ -0,0 +1,414 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/groups"
+	"github.com/ThandieOps/thandie-agent/internal/hidden"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// listFilter limits the printed directories (see internal/filter)
+	listFilter string
+
+	// listSort is the order directories are printed in
+	listSort string
+
+	// listByGroup prints the directories under the groups they belong to
+	listByGroup bool
+
+	// listColumns prints a table of these columns instead of one line per
+	// directory (also list.columns)
+	listColumns string
+
+	// listDensity overrides ui.density
+	listDensity string
+
+	// listHidden prints only the directories hidden with `thandie hide`
+	listHidden bool
+)
+
+// List densities
+const (
+	densityCompact  = "compact"  // One line per directory
+	densityDetailed = "detailed" // A second line with the remote and last commit
+)
+
+// listColumnNames are the columns of the list table
+var listColumnNames = []string{"name", "branch", "sync", "dirty", "age"}
+
+// maxColumnWidth caps text columns; longer values are cut with an ellipsis
+const maxColumnWidth = 40
+
+// listCmd represents: `thandie list`
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List directories from the last scan without rescanning",
+	Long: `List the directories recorded by the last scan of the workspace.
+If the workspace has not been scanned yet, a scan is run first.
+
+Filters are space-separated terms that must all match:
+  name:<text>      directory name contains text (a bare word works too)
+  git:<bool>       directory is a git repository
+  dirty:<bool>     repository has uncommitted changes
+  conflicted:<bool> repository has files with unresolved merge conflicts
+  sparse:<bool>    repository is a sparse checkout (its status covers only
+                   the checked out files)
+  branch:<name>    current branch
+  ci:<state>       default-branch CI state: passing, failing, pending, none
+  assigned:<bool>  repository has open issues assigned to you
+  docker:<bool>    directory has a Dockerfile or compose file
+  running:<bool>   containers from the directory were running at scan time
+  terraform:<bool> directory has Terraform configurations
+  lang:<name>      project language from its build files: go, rust, node,
+                   python, java, ruby, php, elixir
+  risk:<bool>      directory has uncommitted Terraform state files
+  remote:<state>   last 'thandie remotes' check: ok, gone, moved, archived,
+                   unreachable
+  group:<name>     directory belongs to the named group (see groups in the
+                   config; write spaces in names as -)
+
+--by-group prints the directories under the configured groups, followed by
+those in no group; a directory in several groups is listed under each.
+
+--columns (or list.columns in the config) prints a table instead, with a
+header row and any of these columns, in the order given:
+  name    path relative to the workspace
+  branch  current branch
+  sync    commits ahead (↑) and behind (↓) the upstream, = if in sync
+  dirty   number of files with uncommitted changes
+  age     time since the last commit on the current branch
+
+--sort orders by name, loc, branch, sync (most out of sync first), dirty
+(most changed files first) or age (most recent commit first).
+
+--density detailed (or ui.density in the config) adds a second line to each
+directory with its remote's owner/repo and the subject of its last commit;
+--density compact switches back to one line.
+
+Directories hidden with 'thandie hide' are left out and counted at the end;
+--hidden lists only them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		dirFilter := parseFilter(wsPath, listFilter)
+
+		if _, err := density(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		var columns []string
+		if listColumns != "" {
+			columns = strings.Split(listColumns, ",")
+		} else if cfg != nil {
+			columns = cfg.List.Columns
+		}
+		for i, column := range columns {
+			columns[i] = strings.TrimSpace(column)
+			if !slices.Contains(listColumnNames, columns[i]) {
+				fmt.Fprintf(os.Stderr, "Error: unknown column %q (supported: %s)\n", columns[i], strings.Join(listColumnNames, ", "))
+				exit(exitError)
+			}
+		}
+
+		result := loadScanResult(wsPath)
+		visible, hiddenInfos := hidden.Load(wsPath).Split(dirFilter.Apply(result.DirectoryInfos))
+		infos := visible
+		if listHidden {
+			infos = hiddenInfos
+		}
+		if less := listLess(listSort); less != nil {
+			infos = slices.Clone(infos)
+			sort.SliceStable(infos, func(i, j int) bool { return less(infos[i], infos[j]) })
+		} else if listSort != "" && listSort != "name" {
+			fmt.Fprintf(os.Stderr, "Error: unknown sort key %q (supported: name, loc, branch, sync, dirty, age)\n", listSort)
+			exit(exitError)
+		}
+
+		switch {
+		case len(columns) > 0 && listByGroup:
+			g := getGroups(wsPath)
+			printGroupedDirectories(wsPath, g, infos, func(members []scanner.DirectoryInfo) {
+				printListTable(wsPath, members, columns)
+			})
+		case len(columns) > 0:
+			if len(infos) == 0 {
+				fmt.Printf("No matching directories in %s\n", workspaceName(wsPath))
+			}
+			printListTable(wsPath, infos, columns)
+		case listByGroup:
+			g := getGroups(wsPath)
+			printGroupedDirectories(wsPath, g, infos, func(members []scanner.DirectoryInfo) {
+				for _, info := range members {
+					printDirectoryLine(info, g)
+				}
+			})
+		default:
+			printDirectories(wsPath, infos)
+		}
+		printMissing(matchingMissing(result.Missing, dirFilter))
+		if !listHidden {
+			printHiddenCount(len(hiddenInfos))
+		}
+		printResumeBanner(wsPath, result.DirectoryInfos)
+	},
+}
+
+func init() {
+	// Attach the `list` command to the root: thandie list
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&listFilter, "filter", "", "Only print directories matching the filter, e.g. 'ci:failing dirty:true'")
+	listCmd.Flags().StringVar(&listSort, "sort", "name", "Sort order: name, branch, sync, dirty, age, or loc for lines of code (requires scanner.loc or 'scan --loc')")
+	listCmd.Flags().BoolVar(&listByGroup, "by-group", false, "Group the directories by the groups in the config")
+	listCmd.Flags().StringVar(&listColumns, "columns", "", "Print a table of these comma-separated columns: name, branch, sync, dirty, age")
+	listCmd.Flags().BoolVar(&listHidden, "hidden", false, "Only print the directories hidden with 'thandie hide'")
+	listCmd.Flags().StringVar(&listDensity, "density", "", "compact for one line per directory, detailed to add the remote and last commit (default ui.density)")
+}
+
+// density returns the list density from --density or ui.density
+func density() (string, error) {
+	d := listDensity
+	if d == "" && cfg != nil {
+		d = cfg.UI.Density
+	}
+	switch d {
+	case "", densityCompact:
+		return densityCompact, nil
+	case densityDetailed:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown density %q (expected compact or detailed)", d)
+}
+
+// printDirectoryLine prints a directory's line, followed by its details
+// when the density is detailed
+func printDirectoryLine(info scanner.DirectoryInfo, g *groups.Groups) {
+	fmt.Println(directoryLine(info, g))
+	if d, _ := density(); d == densityDetailed {
+		if detail := detailLine(info); detail != "" {
+			fmt.Println("     " + detail)
+		}
+	}
+}
+
+// detailLine returns the second line of a detailed row: the remote's
+// owner/repo and the subject of the last commit, or "" for a directory that
+// isn't a repository
+func detailLine(info scanner.DirectoryInfo) string {
+	git := info.GitMetadata
+	if git == nil || !git.IsGitRepo {
+		return ""
+	}
+	remote := "no remote"
+	if git.RemoteURL != "" {
+		remote = git.RemoteURL
+		if r, err := gitremote.Parse(git.RemoteURL); err == nil {
+			remote = r.Path
+		}
+	}
+	line := remote
+	if git.LastSubject != "" {
+		line += " · " + truncateWidth(git.LastSubject, 72)
+	}
+	return colorize(line, colorGray)
+}
+
+// listLess returns the order of a --sort key other than name, the order of
+// the scan, or nil if key is unknown
+func listLess(key string) func(a, b scanner.DirectoryInfo) bool {
+	switch key {
+	case "loc":
+		// Largest codebases first; directories without counts sort last
+		return func(a, b scanner.DirectoryInfo) bool {
+			return codeStats(a).TotalCode() > codeStats(b).TotalCode()
+		}
+	case "branch":
+		return func(a, b scanner.DirectoryInfo) bool { return gitOf(a).CurrentBranch < gitOf(b).CurrentBranch }
+	case "sync":
+		return func(a, b scanner.DirectoryInfo) bool {
+			return gitOf(a).Ahead+gitOf(a).Behind > gitOf(b).Ahead+gitOf(b).Behind
+		}
+	case "dirty":
+		return func(a, b scanner.DirectoryInfo) bool { return dirtyFiles(a) > dirtyFiles(b) }
+	case "age":
+		return func(a, b scanner.DirectoryInfo) bool { return gitOf(a).LastCommitAt.After(gitOf(b).LastCommitAt) }
+	}
+	return nil
+}
+
+// gitOf returns the git metadata of a directory, empty if it isn't a repository
+func gitOf(info scanner.DirectoryInfo) *scanner.GitMetadata {
+	if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+		return &scanner.GitMetadata{}
+	}
+	return info.GitMetadata
+}
+
+// dirtyFiles returns the number of changed files of a directory. Scans from
+// before the count was recorded only know whether there are any.
+func dirtyFiles(info scanner.DirectoryInfo) int {
+	git := gitOf(info)
+	if git.HasUncommitted && git.DirtyFiles == 0 {
+		return 1
+	}
+	return git.DirtyFiles
+}
+
+// listCell returns the value of column for a directory
+func listCell(wsPath string, info scanner.DirectoryInfo, column string) string {
+	if column == "name" {
+		if rel, err := filepath.Rel(wsPath, info.Path); err == nil {
+			return rel
+		}
+		return info.Path
+	}
+	git := info.GitMetadata
+	if git == nil || !git.IsGitRepo {
+		return "-"
+	}
+	switch column {
+	case "branch":
+		return git.CurrentBranch
+	case "sync":
+		switch {
+		case git.Upstream == "":
+			return "-"
+		case git.Ahead == 0 && git.Behind == 0:
+			return "="
+		}
+		var parts []string
+		if git.Ahead > 0 {
+			parts = append(parts, fmt.Sprintf("↑%d", git.Ahead))
+		}
+		if git.Behind > 0 {
+			parts = append(parts, fmt.Sprintf("↓%d", git.Behind))
+		}
+		return strings.Join(parts, " ")
+	case "dirty":
+		if n := dirtyFiles(info); n > 0 {
+			return fmt.Sprintf("%d", n)
+		}
+		if git.Sparse != nil {
+			return "sparse" // Clean only as far as the checked out files go
+		}
+		return ""
+	case "age":
+		if git.LastCommitAt.IsZero() {
+			return "-"
+		}
+		return formatAge(git.LastCommitAt)
+	}
+	return ""
+}
+
+// printListTable prints infos as a table of columns with a header row, each
+// column as wide as its widest value up to maxColumnWidth
+func printListTable(wsPath string, infos []scanner.DirectoryInfo, columns []string) {
+	if len(infos) == 0 {
+		return
+	}
+	rows := make([][]string, len(infos))
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = len(column)
+	}
+	for r, info := range infos {
+		rows[r] = make([]string, len(columns))
+		for i, column := range columns {
+			rows[r][i] = listCell(wsPath, info, column)
+			widths[i] = min(max(widths[i], displayWidth(rows[r][i])), maxColumnWidth)
+		}
+	}
+
+	printRow := func(cells []string) {
+		line := ""
+		for i, cell := range cells {
+			if i == len(cells)-1 {
+				line += truncateWidth(cell, maxColumnWidth)
+				break
+			}
+			line += padWidth(cell, widths[i]) + "  "
+		}
+		fmt.Println(line)
+	}
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = strings.ToUpper(column)
+	}
+	printRow(header)
+	detailed := false
+	if d, _ := density(); d == densityDetailed {
+		detailed = true
+	}
+	for r, row := range rows {
+		printRow(row)
+		if detail := detailLine(infos[r]); detailed && detail != "" {
+			fmt.Println("  " + detail)
+		}
+	}
+}
+
+// printGroupedDirectories prints infos under a heading per group, followed by
+// the directories that belong to no group, printing each section's
+// directories with printSection
+func printGroupedDirectories(wsPath string, g *groups.Groups, infos []scanner.DirectoryInfo, printSection func(members []scanner.DirectoryInfo)) {
+	if len(infos) == 0 {
+		fmt.Printf("No matching directories in %s\n", workspaceName(wsPath))
+		return
+	}
+
+	members := make(map[string][]scanner.DirectoryInfo)
+	var ungrouped []scanner.DirectoryInfo
+	for _, info := range infos {
+		names := g.Of(info.Path)
+		if len(names) == 0 {
+			ungrouped = append(ungrouped, info)
+		}
+		for _, name := range names {
+			members[name] = append(members[name], info)
+		}
+	}
+
+	sections := slices.Clone(g.Names())
+	if len(ungrouped) > 0 {
+		sections = append(sections, "")
+		members[""] = ungrouped
+	}
+	first := true
+	for _, name := range sections {
+		if len(members[name]) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Println()
+		}
+		first = false
+		heading := name
+		if heading == "" {
+			heading = "Ungrouped"
+		}
+		fmt.Printf("%s (%d):\n", heading, len(members[name]))
+		printSection(members[name])
+	}
+}
+
+// codeStats returns the line counts recorded for a directory, or nil
+func codeStats(info scanner.DirectoryInfo) *scanner.CodeStats {
+	if info.Extras == nil {
+		return nil
+	}
+	return info.Extras.Code
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// lspPollInterval is how often subscribed workspaces' caches are checked for changes
+	lspPollInterval time.Duration
+)
+
+// lspParams are the params of the thandie/* methods
+type lspParams struct {
+	Workspace string `json:"workspace,omitempty"` // Default: the selected workspace
+	Path      string `json:"path,omitempty"`
+}
+
+// lspChange is the params of the thandie/didChange notification
+type lspChange struct {
+	Workspace string            `json:"workspace"`
+	Result    *cache.ScanResult `json:"result"`
+}
+
+// lspCmd represents: `thandie lsp`
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Serve workspace state to editor extensions over stdio JSON-RPC",
+	Long: `Speak JSON-RPC 2.0 on stdin/stdout, framed with Content-Length headers like
+the Language Server Protocol, so editor extensions (VS Code, Neovim) can
+use their LSP client libraries to show a Thandie sidebar. Logs go to stderr.
+
+Requests:
+  initialize          server info and the methods below
+  thandie/workspace   the cached scan of {"workspace"} (default: the selected one)
+  thandie/repo        the scanned directory containing {"path"}, or null
+  thandie/scan        scan the workspace now and return the result
+  thandie/subscribe   send thandie/didChange notifications for the workspace
+  thandie/unsubscribe stop them
+  shutdown, exit
+
+thandie/didChange carries {"workspace", "result"} whenever the workspace's
+cache is rewritten, whether by thandie/scan, 'thandie scan' or the daemon.
+Nothing is scanned unless requested, so run 'thandie daemon' to keep the
+state fresh.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
+		cacheInstance, err := cache.New()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize cache: %v\n", err)
+			exit(exitError)
+		}
+		conn := lsp.NewConn(os.Stdin, os.Stdout)
+		serveLSP(conn, &scanResults{cache: cacheInstance, entries: make(map[string]scanEntry)})
+		if err := conn.Serve(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	// Attach the `lsp` command to the root command
+	rootCmd.AddCommand(lspCmd)
+	lspCmd.Flags().DurationVar(&lspPollInterval, "poll-interval", 2*time.Second, "How often subscribed workspaces are checked for new scan results")
+}
+
+// serveLSP registers the methods of `thandie lsp` on conn
+func serveLSP(conn *lsp.Conn, results *scanResults) {
+	decode := func(raw json.RawMessage) (lspParams, error) {
+		var params lspParams
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return params, &lsp.Error{Code: lsp.CodeInvalidParams, Message: err.Error()}
+			}
+		}
+		if params.Workspace == "" {
+			params.Workspace = getWorkspacePath()
+		}
+		return params, nil
+	}
+	watcher := &lspWatcher{conn: conn, results: results, stop: make(map[string]chan struct{})}
+
+	conn.Handle("initialize", func(json.RawMessage) (any, error) {
+		return map[string]any{
+			"serverInfo": map[string]string{"name": "thandie"},
+			"capabilities": map[string]any{
+				"methods":       []string{"thandie/workspace", "thandie/repo", "thandie/scan", "thandie/subscribe", "thandie/unsubscribe"},
+				"notifications": []string{"thandie/didChange"},
+			},
+		}, nil
+	})
+	conn.Handle("initialized", func(json.RawMessage) (any, error) { return nil, nil })
+	conn.Handle("shutdown", func(json.RawMessage) (any, error) {
+		watcher.stopAll()
+		return nil, nil
+	})
+	conn.Handle("exit", func(json.RawMessage) (any, error) { return nil, lsp.ErrExit })
+
+	conn.Handle("thandie/workspace", func(raw json.RawMessage) (any, error) {
+		params, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		return results.get(params.Workspace)
+	})
+	conn.Handle("thandie/repo", func(raw json.RawMessage) (any, error) {
+		params, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if params.Path == "" {
+			return nil, &lsp.Error{Code: lsp.CodeInvalidParams, Message: "path is required"}
+		}
+		result, err := results.get(params.Workspace)
+		if err != nil {
+			return nil, err
+		}
+		return repoContaining(result.DirectoryInfos, params.Path), nil
+	})
+	conn.Handle("thandie/scan", func(raw json.RawMessage) (any, error) {
+		params, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(params.Workspace); err != nil {
+			return nil, fmt.Errorf("workspace not found: %s", params.Workspace)
+		}
+		return scanWorkspace(params.Workspace, getScannerConfig(params.Workspace))
+	})
+	conn.Handle("thandie/subscribe", func(raw json.RawMessage) (any, error) {
+		params, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		watcher.subscribe(params.Workspace)
+		return nil, nil
+	})
+	conn.Handle("thandie/unsubscribe", func(raw json.RawMessage) (any, error) {
+		params, err := decode(raw)
+		if err != nil {
+			return nil, err
+		}
+		watcher.unsubscribe(params.Workspace)
+		return nil, nil
+	})
+}
+
+// lspWatcher polls the cache files of subscribed workspaces and notifies
+// the client when they are rewritten
+type lspWatcher struct {
+	conn    *lsp.Conn
+	results *scanResults
+	mu      sync.Mutex
+	stop    map[string]chan struct{}
+}
+
+// subscribe starts watching wsPath; subscribing twice is a no-op
+func (w *lspWatcher) subscribe(wsPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.stop[wsPath]; ok {
+		return
+	}
+	stop := make(chan struct{})
+	w.stop[wsPath] = stop
+	go w.watch(wsPath, stop)
+}
+
+// unsubscribe stops watching wsPath
+func (w *lspWatcher) unsubscribe(wsPath string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stop, ok := w.stop[wsPath]; ok {
+		close(stop)
+		delete(w.stop, wsPath)
+	}
+}
+
+// stopAll stops watching every workspace
+func (w *lspWatcher) stopAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for wsPath, stop := range w.stop {
+		close(stop)
+		delete(w.stop, wsPath)
+	}
+}
+
+// watch notifies the client each time the cache file of wsPath changes,
+// until stop is closed
+func (w *lspWatcher) watch(wsPath string, stop chan struct{}) {
+	path := w.results.cache.GetCacheFilePath(wsPath)
+	var last time.Time
+	if info, err := os.Stat(path); err == nil {
+		last = info.ModTime()
+	}
+
+	ticker := time.NewTicker(lspPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(last) {
+			continue
+		}
+		result, err := w.results.get(wsPath)
+		if err != nil {
+			continue // Caught mid-write; the next tick reads it again
+		}
+		last = info.ModTime()
+		if err := w.conn.Notify("thandie/didChange", lspChange{Workspace: wsPath, Result: result}); err != nil {
+			return
+		}
+	}
+}
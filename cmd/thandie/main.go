@@ -2,9 +2,11 @@ package main
 
 import (
 	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
 )
 
 func main() {
 	defer logger.Close() // Ensure log file is closed on exit
 	Execute()
+	tracing.Shutdown()
 }
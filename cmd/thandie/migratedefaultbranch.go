@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/branch"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// migrateFrom is the old default branch
+	migrateFrom string
+
+	// migrateTo is the new default branch
+	migrateTo string
+
+	// migrateAll migrates every repository instead of a group
+	migrateAll bool
+
+	// migrateDryRun reports what would change without fetching or changing anything
+	migrateDryRun bool
+)
+
+// migrateDefaultBranchCmd represents: `thandie migrate-default-branch`
+var migrateDefaultBranchCmd = &cobra.Command{
+	Use:   "migrate-default-branch",
+	Short: "Follow a default branch rename (e.g. master to main) in local clones",
+	Long: `After the default branch was renamed on the remote, e.g. from master to
+main, update the clones of --group (or of the whole workspace with --all):
+fetch origin, point origin/HEAD at the new branch, and rename the local
+--from branch to --to, tracking origin/<to>.
+
+Local branches are only renamed where that's safe: origin has the --to
+branch and it's origin's default, there is no local --to branch yet, and
+the local --from branch tracks origin/<from> or nothing. Other repositories
+are left alone and listed with the reason, as are local commits that
+aren't on the new branch yet. --dry-run reports what would happen based on
+the last fetch, without fetching or changing anything.
+
+Exits with code 10 if any repository needs attention, and 1 if git failed
+in any.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		if !migrateDryRun {
+			requireWritable("migrating branches")
+		}
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		for _, name := range []string{migrateFrom, migrateTo} {
+			if err := branch.ValidName(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		}
+		if migrateFrom == migrateTo {
+			fmt.Fprintln(os.Stderr, "Error: --from and --to must differ")
+			exit(exitError)
+		}
+		if groupName == "" && !migrateAll {
+			fmt.Fprintln(os.Stderr, "Error: specify the repositories with --group <name> or --all")
+			exit(exitError)
+		}
+
+		result := loadScanResult(wsPath)
+		repoPolicy := getPolicy(wsPath)
+		selected := make(map[string]bool)
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			selected[info.Path] = true
+		}
+
+		migrated, already, attention, failed := 0, 0, 0, 0
+		for i := range result.DirectoryInfos {
+			info := &result.DirectoryInfos[i]
+			if !selected[info.Path] || info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			repoName, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				repoName = info.Path
+			}
+
+			if err := repoPolicy.Check(policy.ActionBranch, info.Path, info.GitMetadata.RemoteURL); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", repoName, err)
+				continue
+			}
+
+			m, err := branch.MigrateDefault(info.Path, migrateFrom, migrateTo, migrateDryRun)
+			if !migrateDryRun {
+				detail := migrateFrom + " -> " + migrateTo
+				if m != nil && m.Status == branch.MigrationManual {
+					detail += ": " + m.Reason
+				}
+				auditlog.Write("branch.migrate", info.Path, detail, err)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", repoName, err)
+				failed++
+				continue
+			}
+
+			switch m.Status {
+			case branch.MigrationManual:
+				fmt.Printf("  %s %s: %s\n", colorize("!", colorYellow), repoName, m.Reason)
+				attention++
+				continue
+			case branch.MigrationAlready:
+				fmt.Printf("  %s %s: already on %s\n", colorize("·", colorGray), repoName, migrateTo)
+				already++
+			default:
+				fmt.Printf("  ✓ %s: %s\n", repoName, strings.Join(m.Actions, "; "))
+				migrated++
+			}
+			for _, note := range m.Notes {
+				fmt.Printf("      %s\n", colorize(note, colorGray))
+			}
+
+			// Keep list and reports accurate until the next scan
+			if git := info.GitMetadata; !migrateDryRun && git.CurrentBranch == migrateFrom && m.Status == branch.MigrationDone {
+				git.CurrentBranch, git.Upstream = migrateTo, "origin/"+migrateTo
+			}
+		}
+
+		if !migrateDryRun && migrated > 0 {
+			if cacheInstance, err := cache.New(); err != nil {
+				logger.Warn("failed to initialize cache", "error", err)
+			} else if err := cacheInstance.Save(result); err != nil {
+				logger.Warn("failed to save branches to cache", "error", err)
+			}
+		}
+
+		done := "migrated"
+		if migrateDryRun {
+			done = "to migrate"
+		}
+		fmt.Printf("\n%s → %s: %d %s, %d already done, %d need attention, %d failed.\n",
+			migrateFrom, migrateTo, migrated, done, already, attention, failed)
+		switch {
+		case failed > 0:
+			exit(exitError)
+		case attention > 0:
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `migrate-default-branch` command to the root command
+	migrateDefaultBranchCmd.Flags().StringVar(&migrateFrom, "from", "master", "Old default branch")
+	migrateDefaultBranchCmd.Flags().StringVar(&migrateTo, "to", "main", "New default branch")
+	migrateDefaultBranchCmd.Flags().BoolVar(&migrateAll, "all", false, "Migrate every repository of the workspace")
+	migrateDefaultBranchCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Report what would change, based on the last fetch, without changing anything")
+	addGroupFlag(migrateDefaultBranchCmd)
+	rootCmd.AddCommand(migrateDefaultBranchCmd)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/notify"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// notifyAlerts sends only alerts, and nothing if there are none
+	notifyAlerts bool
+
+	// notifyMessage sends this text instead of a summary
+	notifyMessage string
+
+	// notifyDryRun prints the message instead of sending it
+	notifyDryRun bool
+)
+
+// notifyCmd represents: `thandie notify`
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Post a workspace summary or alerts to Slack/Teams",
+	Long: `Post a summary of the last scan to the webhooks configured under notify
+(slack_webhook and/or teams_webhook; keychain:<name> references work), followed
+by any alerts, e.g. repositories dirty for more than notify.dirty_days days.
+
+With --alerts only the alerts are posted, and nothing is sent when there are
+none, which suits scheduled runs. --message posts arbitrary text and
+--template renders the message body from a template.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+
+		var msg notify.Message
+		if notifyMessage != "" {
+			msg.Lines = []string{notifyMessage}
+		} else {
+			requireProfile()
+			wsPath := getWorkspacePath()
+			requireWorkspace(wsPath)
+			result := loadScanResult(wsPath)
+			infos := result.DirectoryInfos
+
+			switch {
+			case templatePath != "":
+				var body strings.Builder
+				if err := renderTemplate(&body, templatePath, newReportData(wsPath, infos)); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					exit(exitError)
+				}
+				msg.Lines = []string{strings.TrimSpace(body.String())}
+			case notifyAlerts:
+				msg = workspaceAlerts(wsPath, infos, result.Missing, cfg.Notify.DirtyDays)
+				if len(msg.Lines) == 0 {
+					fmt.Println("No alerts.")
+					return
+				}
+			default:
+				msg = workspaceSummary(wsPath, infos)
+				msg.Lines = append(msg.Lines, workspaceAlerts(wsPath, infos, result.Missing, cfg.Notify.DirtyDays).Lines...)
+			}
+		}
+
+		if notifyDryRun {
+			fmt.Println(notify.SlackText(msg))
+			return
+		}
+
+		requireWritable("sending notifications")
+		notifier, err := notify.New(cfg.Notify)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+		err = notifier.Send(context.Background(), msg)
+		auditlog.Write("notify.send", "webhooks", msg.Title, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to send notification: %v\n", err)
+			exit(exitError)
+		}
+		fmt.Println("Notification sent.")
+	},
+}
+
+func init() {
+	// Attach the `notify` command to the root: thandie notify
+	notifyCmd.Flags().BoolVar(&notifyAlerts, "alerts", false, "Only send alerts, and nothing if there are none")
+	notifyCmd.Flags().StringVar(&notifyMessage, "message", "", "Send this text instead of a workspace summary")
+	notifyCmd.Flags().BoolVar(&notifyDryRun, "dry-run", false, "Print the message instead of sending it")
+	addTemplateFlag(notifyCmd)
+	rootCmd.AddCommand(notifyCmd)
+}
+
+// workspaceSummary describes the state of the repositories in the workspace
+func workspaceSummary(wsPath string, infos []scanner.DirectoryInfo) notify.Message {
+	repos, dirty, ahead, behind, busy := 0, 0, 0, 0, 0
+	var ciFailing, testsFailing []string
+	vulns := 0
+	for _, info := range infos {
+		git := info.GitMetadata
+		if git == nil || !git.IsGitRepo {
+			continue
+		}
+		repos++
+		name := filepath.Base(info.Path)
+		if git.HasUncommitted {
+			dirty++
+		}
+		if git.InProgress != "" {
+			busy++
+		}
+		if git.Ahead > 0 {
+			ahead++
+		}
+		if git.Behind > 0 {
+			behind++
+		}
+		if info.Enrichment != nil && info.Enrichment.CI == "failing" {
+			ciFailing = append(ciFailing, name)
+		}
+		if info.Tests != nil && !info.Tests.Passed {
+			testsFailing = append(testsFailing, name)
+		}
+		vulns += info.Audit.Total()
+	}
+
+	msg := notify.Message{
+		Title: fmt.Sprintf("%s: %d repositories", workspaceTitle(wsPath), repos),
+		Lines: []string{
+			fmt.Sprintf("%d with uncommitted changes", dirty),
+			fmt.Sprintf("%d with unpushed commits, %d behind upstream", ahead, behind),
+		},
+	}
+	if busy > 0 {
+		msg.Lines = append(msg.Lines, fmt.Sprintf("%d in the middle of a merge, rebase, cherry-pick, revert or bisect", busy))
+	}
+	if len(ciFailing) > 0 {
+		msg.Lines = append(msg.Lines, "CI failing: "+strings.Join(ciFailing, ", "))
+	}
+	if len(testsFailing) > 0 {
+		msg.Lines = append(msg.Lines, "Tests failing: "+strings.Join(testsFailing, ", "))
+	}
+	if vulns > 0 {
+		msg.Lines = append(msg.Lines, fmt.Sprintf("%d known vulnerabilities (see 'thandie audit --cached')", vulns))
+	}
+	return msg
+}
+
+// workspaceTitle names the workspace at wsPath in message titles: its alias,
+// or else the last element of its path
+func workspaceTitle(wsPath string) string {
+	if name := workspaceName(wsPath); name != wsPath {
+		return name
+	}
+	return filepath.Base(wsPath)
+}
+
+// workspaceAlerts lists conditions that need attention: repositories dirty
+// for at least dirtyDays days, uncommitted Terraform state and directories
+// that have disappeared since earlier scans
+func workspaceAlerts(wsPath string, infos []scanner.DirectoryInfo, missing []cache.MissingDir, dirtyDays int) notify.Message {
+	msg := notify.Message{Title: fmt.Sprintf("%s alerts", workspaceTitle(wsPath))}
+	for _, dir := range missing {
+		msg.Lines = append(msg.Lines, fmt.Sprintf("%s missing since %s", filepath.Base(dir.Info.Path), dir.Since.Format("2006-01-02 15:04")))
+	}
+	for _, info := range infos {
+		git := info.GitMetadata
+		if git == nil || !git.IsGitRepo {
+			continue
+		}
+		name := filepath.Base(info.Path)
+		if dirtyDays > 0 && git.HasUncommitted && !git.DirtySince.IsZero() {
+			if days := int(time.Since(git.DirtySince).Hours() / 24); days >= dirtyDays {
+				msg.Lines = append(msg.Lines, fmt.Sprintf("%s dirty for %d days", name, days))
+			}
+		}
+		if text := inProgressText(git); text != "" {
+			msg.Lines = append(msg.Lines, fmt.Sprintf("%s: %s", name, text))
+		}
+		if info.Extras != nil && info.Extras.Terraform.HighRisk() {
+			msg.Lines = append(msg.Lines, fmt.Sprintf("%s has uncommitted Terraform state", name))
+		}
+	}
+	return msg
+}
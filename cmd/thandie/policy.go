@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/policypack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// policyRefresh downloads the policy pack even if the cached one is recent
+	policyRefresh bool
+)
+
+// policyCmd represents: `thandie policy`
+var policyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Team policy packs",
+}
+
+// policyStatusCmd represents: `thandie policy status`
+var policyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List repositories violating the team's policy pack",
+	Long: `Download the policy pack at policy_pack.url, verify its signature
+(policy_pack.url with .sig appended) against policy_pack.allowed_signers, and
+check every git repository against it:
+
+  name: acme-baseline
+  version: "3"
+  hooks: [pre-commit, commit-msg]     # must be installed
+  branch: main                        # expected default branch
+  compliance: [license, codeowners]   # required files
+  signing: {required: true}           # as signing in the config
+  git_config: ["pull.rebase=true"]    # as git_config on groups
+
+Sign a pack with 'ssh-keygen -Y sign -n thandie-policy -f <key> pack.yml'.
+The pack is cached for policy_pack.ttl (default 24h) and reused when the
+download fails; --refresh downloads it regardless. A pack whose signature
+doesn't verify is never used.
+
+Exits with code 10 if any repository violates the pack.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if cfg.PolicyPack.URL == "" {
+			fmt.Println("No policy pack: set policy_pack.url and policy_pack.allowed_signers in the config.")
+			return
+		}
+		loaded, err := policypack.Load(cfg.PolicyPack, policyRefresh)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		pack := loaded.Pack
+		name := pack.Name
+		if pack.Version != "" {
+			name += " " + pack.Version
+		}
+		fmt.Printf("Policy pack %s, signed by %s, fetched %s\n", name, loaded.Signer, formatAge(loaded.FetchedAt))
+		if loaded.Stale != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", colorize(fmt.Sprintf("Using the cached pack: %v", loaded.Stale), colorYellow))
+		}
+		fmt.Println()
+
+		result := loadScanResult(wsPath)
+		checked, violating, failed := 0, 0, 0
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			repoName, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				repoName = info.Path
+			}
+			violations, err := pack.Check(info)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", repoName, err)
+				failed++
+				continue
+			}
+			checked++
+			if len(violations) == 0 {
+				continue
+			}
+			violating++
+			fmt.Printf("  %s %s\n", padWidth(repoName, 30), strings.Join(violations, ", "))
+		}
+
+		if violating == 0 && failed == 0 {
+			fmt.Printf("All %d repositories follow the policy pack.\n", checked)
+			return
+		}
+		if violating > 0 {
+			fmt.Printf("\n%d of %d repositories violate the policy pack.\n", violating, checked)
+		}
+		switch {
+		case failed > 0:
+			exit(exitError)
+		case violating > 0:
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `policy` command and its subcommand: thandie policy status
+	policyStatusCmd.Flags().BoolVar(&policyRefresh, "refresh", false, "Download the policy pack even if the cached one is recent")
+	addGroupFlag(policyStatusCmd)
+	policyCmd.AddCommand(policyStatusCmd)
+	rootCmd.AddCommand(policyCmd)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/rpc"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// promptCwd is the directory whose repository is described
+	promptCwd string
+)
+
+// promptDaemonTimeout is how long the prompt waits for the daemon before
+// reading the cache instead, so that a busy daemon doesn't slow the shell
+const promptDaemonTimeout = 50 * time.Millisecond
+
+// promptCmd represents: `thandie prompt`
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a compact status of the current repository for shell prompts",
+	Long: `Print the branch of the repository containing --cwd (default: the current
+directory), followed by * if it has uncommitted changes and ↑/↓ counts of
+commits ahead of and behind upstream, e.g. "main* ↑2".
+
+The status comes from the running daemon, or else from the last scan's
+cache, so it prints in a few milliseconds; keep the cache fresh with
+'thandie daemon'. Nothing is printed
+outside a scanned repository or if no scan is cached, and the exit code is
+always 0, so it is safe to embed in a prompt:
+
+  # starship.toml
+  [custom.thandie]
+  command = "thandie prompt"
+  when = true`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cwd := promptCwd
+		if cwd == "" {
+			var err error
+			if cwd, err = os.Getwd(); err != nil {
+				return
+			}
+		}
+		cwd, err := filepath.Abs(cwd)
+		if err != nil {
+			return
+		}
+
+		// Ask the daemon first, falling back to the cache file if it isn't
+		// running, is busy or can't answer
+		wsPath := promptWorkspace(cwd)
+		var repo *scanner.DirectoryInfo
+		err = rpc.CallWithin(rpc.Request{Method: "repo", Workspace: wsPath, Path: cwd}, &repo, promptDaemonTimeout)
+		if err != nil {
+			repo = nil
+			cacheInstance, err := cache.New()
+			if err != nil {
+				return
+			}
+			result, err := cacheInstance.LoadScanSummary(wsPath)
+			if err != nil {
+				return
+			}
+			repo = repoContaining(result.DirectoryInfos, cwd)
+		}
+		if repo == nil || repo.GitMetadata == nil || !repo.GitMetadata.IsGitRepo {
+			return
+		}
+		fmt.Println(promptSegment(repo.GitMetadata))
+	},
+}
+
+// promptWorkspace returns the workspace containing cwd: --workspace if
+// given, else the deepest profile or default workspace containing it, else
+// the usual workspace
+func promptWorkspace(cwd string) string {
+	if workspacePath != "" || workspaceProfile != "" || cfg == nil {
+		return getWorkspacePath()
+	}
+	best := ""
+	candidates := []string{getWorkspacePath()}
+	for _, profile := range cfg.Workspace.Profiles {
+		candidates = append(candidates, profile.Path)
+	}
+	for _, path := range candidates {
+		if path != "" && within(cwd, path) && len(path) > len(best) {
+			best = path
+		}
+	}
+	if best == "" {
+		return getWorkspacePath()
+	}
+	return best
+}
+
+// repoContaining returns the deepest directory of infos containing path, or nil
+func repoContaining(infos []scanner.DirectoryInfo, path string) *scanner.DirectoryInfo {
+	var repo *scanner.DirectoryInfo
+	for i, info := range infos {
+		if within(path, info.Path) && (repo == nil || len(info.Path) > len(repo.Path)) {
+			repo = &infos[i]
+		}
+	}
+	return repo
+}
+
+// within reports whether path is dir or inside it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// promptSegment formats the branch and state of a repository compactly
+func promptSegment(git *scanner.GitMetadata) string {
+	segment := git.CurrentBranch
+	if segment == "" {
+		segment = "(detached)"
+		if len(git.Head) >= 7 {
+			segment = git.Head[:7]
+		}
+	}
+	if git.HasUncommitted {
+		segment += "*"
+	}
+	if git.InProgress != "" {
+		segment += "|" + strings.ToUpper(git.InProgress)
+	}
+	if git.Ahead > 0 {
+		segment += fmt.Sprintf(" ↑%d", git.Ahead)
+	}
+	if git.Behind > 0 {
+		segment += fmt.Sprintf(" ↓%d", git.Behind)
+	}
+	return segment
+}
+
+func init() {
+	// Attach the `prompt` command to the root: thandie prompt
+	promptCmd.Flags().StringVar(&promptCwd, "cwd", "", "Directory to describe (default: the current directory)")
+	rootCmd.AddCommand(promptCmd)
+}
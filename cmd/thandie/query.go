@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ThandieOps/thandie-agent/internal/query"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// queryFormat selects the output format: table, paths or json
+	queryFormat string
+)
+
+// queryCmd represents: `thandie query '<expr>'`
+var queryCmd = &cobra.Command{
+	Use:   "query <expr>",
+	Short: "Select directories from the last scan with an expression",
+	Long: `Evaluate an expression against every directory from the last scan and
+print the ones it is true for, e.g.
+
+  thandie query 'dirty && behind > 0 && host == "github.com"'
+  thandie query '"go" in lang && tests == "failed"' --format paths | xargs -n1 code
+
+Operators: || && ! == != < <= > >= =~ (regular expression) in (list or
+substring), with parentheses for grouping. Text is quoted with " or '. A
+field on its own is true when it is non-zero, non-empty or true.
+
+Fields:
+` + query.FieldHelp(),
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if queryFormat != "table" && queryFormat != "paths" && queryFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown format %q (supported: table, paths, json)\n", queryFormat)
+			exit(exitError)
+		}
+
+		q, err := query.Parse(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid query: %v\n", err)
+			exit(exitError)
+		}
+
+		result := loadScanResult(wsPath)
+		matched, err := q.Apply(result.DirectoryInfos)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		if templatePath != "" {
+			if err := renderTemplate(os.Stdout, templatePath, newReportData(wsPath, matched)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			return
+		}
+
+		switch queryFormat {
+		case "paths":
+			for _, info := range matched {
+				fmt.Println(info.Path)
+			}
+		case "json":
+			if matched == nil {
+				matched = []scanner.DirectoryInfo{}
+			}
+			if err := printJSON(matched); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		default:
+			printQueryTable(wsPath, matched)
+		}
+	},
+}
+
+func init() {
+	// Attach the `query` command to the root: thandie query
+	queryCmd.Flags().StringVar(&queryFormat, "format", "table", "Output format: table, paths or json")
+	addJSONFlags(queryCmd)
+	addTemplateFlag(queryCmd)
+	rootCmd.AddCommand(queryCmd)
+}
+
+// printQueryTable prints one row per directory with its main git state
+func printQueryTable(wsPath string, infos []scanner.DirectoryInfo) {
+	if len(infos) == 0 {
+		return
+	}
+	fmt.Printf("%-30s %-24s %6s %6s  %s\n", "NAME", "BRANCH", "AHEAD", "BEHIND", "STATE")
+	for _, info := range infos {
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+		git := info.GitMetadata
+		if git == nil || !git.IsGitRepo {
+			fmt.Printf("%s %-24s %6s %6s  %s\n", padWidth(name, 30), "-", "-", "-", "not a repository")
+			continue
+		}
+		state := "clean"
+		if git.HasUncommitted {
+			state = "dirty"
+		}
+		if git.Sparse != nil {
+			state += " (sparse)"
+		}
+		if git.InProgress != "" {
+			state += ", " + git.InProgress + " in progress"
+		}
+		if symbol := stateSymbol(git); symbol != "" {
+			state = symbol + " " + state
+		}
+		if info.Enrichment != nil && info.Enrichment.CI != "" {
+			state += " ci:" + info.Enrichment.CI
+		}
+		fmt.Printf("%s %s %6d %6d  %s\n", padWidth(name, 30), padWidth(git.CurrentBranch, 24), git.Ahead, git.Behind, state)
+	}
+}
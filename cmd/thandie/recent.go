@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/activity"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// recentSince is how far back the feed goes
+	recentSince time.Duration
+
+	// recentFilter limits the repositories included (see internal/filter)
+	recentFilter string
+
+	// recentLimit is the maximum number of files shown
+	recentLimit int
+
+	// recentEveryone includes commits by other authors
+	recentEveryone bool
+
+	// recentFormat is the output format: text or json
+	recentFormat string
+)
+
+// recentEntry is a recently modified file of a repository
+type recentEntry struct {
+	Repo string `json:"repo"` // Relative to the workspace
+	activity.Entry
+}
+
+// recentCmd represents: `thandie recent`
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "Feed of files recently modified across all repositories",
+	Long: `List the files modified in the last --since (default 24h) across the
+repositories of the last scan, newest first: uncommitted changes, dated by
+their modification time, and files changed by your recent commits (those
+authored by the repository's user.email; --everyone includes all authors).
+
+Use it to get back to what you were doing yesterday.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if recentFormat != "text" && recentFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected text or json)\n", recentFormat)
+			exit(exitError)
+		}
+		since := time.Now().Add(-recentSince)
+		infos := parseFilter(wsPath, recentFilter).Apply(loadScanResult(wsPath).DirectoryInfos)
+
+		entries, failures := collectRecent(wsPath, infos, since, recentEveryone)
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "  ✗ %s\n", failure)
+		}
+		if recentLimit > 0 && len(entries) > recentLimit {
+			entries = entries[:recentLimit]
+		}
+
+		if recentFormat == "json" {
+			if err := printJSON(entries); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printRecent(entries)
+		}
+		if len(failures) > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+// collectRecent returns the files of the git repositories among infos
+// modified since since, newest first, and "<repo>: <error>" for each
+// repository whose activity couldn't be read
+func collectRecent(wsPath string, infos []scanner.DirectoryInfo, since time.Time, everyone bool) ([]recentEntry, []string) {
+	var mu sync.Mutex // Guards entries and failures
+	var entries []recentEntry
+	var failures []string
+	sem := make(chan struct{}, max(getScannerConfig(wsPath).Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, info := range infos {
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+			continue
+		}
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			repoEntries, err := activity.Collect(info.Path, since, everyone)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+				return
+			}
+			for _, e := range repoEntries {
+				entries = append(entries, recentEntry{Repo: name, Entry: e})
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.After(entries[j].Time)
+		}
+		if entries[i].Repo != entries[j].Repo {
+			return entries[i].Repo < entries[j].Repo
+		}
+		return entries[i].File < entries[j].File
+	})
+	sort.Strings(failures)
+	return entries, failures
+}
+
+// printRecent prints the feed, one file per line
+func printRecent(entries []recentEntry) {
+	if len(entries) == 0 {
+		fmt.Printf("No files modified in the last %s.\n", formatDuration(recentSince))
+		return
+	}
+	for _, e := range entries {
+		fmt.Println(recentLine(e))
+	}
+}
+
+// recentLine formats a recently modified file with its age and origin
+func recentLine(e recentEntry) string {
+	// Pad before coloring so escape codes don't break the alignment
+	line := fmt.Sprintf("%s  %s", colorize(fmt.Sprintf("%-8s", formatAge(e.Time)), colorGray), filepath.Join(e.Repo, e.File))
+	if e.Kind == activity.KindModified {
+		return line + " " + colorize("(uncommitted)", colorYellow)
+	}
+	return line + " " + colorize(fmt.Sprintf("(%s %s)", e.Commit, e.Subject), colorGray)
+}
+
+func init() {
+	// Attach the `recent` command to the root: thandie recent
+	recentCmd.Flags().DurationVar(&recentSince, "since", 24*time.Hour, "How far back to look, e.g. 4h or 72h")
+	recentCmd.Flags().StringVar(&recentFilter, "filter", "", "Only include repositories matching the filter, e.g. 'dirty:true'")
+	recentCmd.Flags().IntVar(&recentLimit, "limit", 50, "Maximum number of files to show (0 for all)")
+	recentCmd.Flags().BoolVar(&recentEveryone, "everyone", false, "Include files changed by other authors' commits")
+	recentCmd.Flags().StringVar(&recentFormat, "format", "text", "Output format: text or json")
+	addJSONFlags(recentCmd)
+	rootCmd.AddCommand(recentCmd)
+}
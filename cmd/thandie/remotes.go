@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/ThandieOps/thandie-agent/internal/remotecheck"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	// remotesCached prints the results of the last check instead of checking again
+	remotesCached bool
+
+	// remotesFrom is the URL prefix `remotes rewrite` replaces
+	remotesFrom string
+
+	// remotesTo is the URL prefix `remotes rewrite` replaces it with
+	remotesTo string
+
+	// remotesApply applies the rewrites without prompting
+	remotesApply bool
+
+	// remotesFilter limits the repositories rewritten (see internal/filter)
+	remotesFilter string
+)
+
+// remotesCmd represents: `thandie remotes`
+var remotesCmd = &cobra.Command{
+	Use:   "remotes [directory]",
+	Short: "Check that the remotes of the workspace's repositories still exist",
+	Long: `Check the origin remote of every git repository, or only of the given
+directory, and flag those that were deleted, renamed or archived. Remotes on
+a provider you are logged in to (see 'thandie auth login') are looked up
+through its API, which also finds the new location of a renamed or
+transferred repository; other remotes are checked with 'git ls-remote',
+which never prompts for credentials.
+
+Results are stored with the scan metadata, and flagged repositories are
+marked in 'thandie list'. Schedule the check with the daemon to run it
+periodically, e.g. a weekly "remotes" job.
+
+Exits with code 10 if any remote is gone, moved or archived.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		result := loadScanResult(wsPath)
+
+		infos := result.DirectoryInfos
+		if len(args) == 1 {
+			info, err := findDirectory(wsPath, result.DirectoryInfos, args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			// Slice of the cached result so the check is stored in place
+			for i := range result.DirectoryInfos {
+				if result.DirectoryInfos[i].Path == info.Path {
+					infos = result.DirectoryInfos[i : i+1]
+					break
+				}
+			}
+		}
+
+		if !remotesCached {
+			client, err := providers.NewClient()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			remotecheck.New(providers.ByHost(client, cfg)).Run(context.Background(), infos, getScannerConfig(wsPath).Concurrency)
+			if cacheInstance, err := cache.New(); err != nil {
+				logger.Warn("failed to initialize cache", "error", err)
+			} else if err := cacheInstance.Save(result); err != nil {
+				logger.Warn("failed to save remote checks to cache", "error", err)
+			}
+		}
+
+		if printRemotesReport(wsPath, infos) > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+// remotesRewriteCmd represents: `thandie remotes rewrite`
+var remotesRewriteCmd = &cobra.Command{
+	Use:   "rewrite --from <prefix> --to <prefix>",
+	Short: "Rewrite remote URLs across the workspace, e.g. for a move to another host",
+	Long: `Replace the --from prefix of remote URLs with --to in every git repository
+of the workspace, e.g. when an organization moves between hosts:
+
+  thandie remotes rewrite --from git@old-host: --to git@new-host:
+
+Every remote's fetch and push URLs are considered, not only origin's. The
+rewrites are listed first and applied after confirmation, or right away with
+--apply; without a terminal to confirm on, only the preview is printed.
+Limit the repositories with --filter or --group. The policy action remote
+can forbid rewrites in some repositories.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if remotesFrom == "" || remotesFrom == remotesTo {
+			fmt.Fprintln(os.Stderr, "Error: --from is required and must differ from --to")
+			exit(exitError)
+		}
+		dirFilter := parseFilter(wsPath, remotesFilter)
+		result := loadScanResult(wsPath)
+		repoPolicy := getPolicy(wsPath)
+
+		type pending struct {
+			info     *scanner.DirectoryInfo
+			name     string
+			rewrites []gitremote.Rewrite
+		}
+		var planned []pending
+		total, failed := 0, 0
+		for _, info := range dirFilter.Apply(selectGroup(wsPath, groupName, result.DirectoryInfos)) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			rewrites, err := gitremote.FindRewrites(info.Path, remotesFrom, remotesTo)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			if len(rewrites) == 0 {
+				continue
+			}
+			if err := repoPolicy.Check(policy.ActionRemote, info.Path, info.GitMetadata.RemoteURL); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", name, err)
+				continue
+			}
+			fmt.Println(name)
+			for _, r := range rewrites {
+				kind := "url"
+				if r.Push {
+					kind = "pushurl"
+				}
+				fmt.Printf("    %s %s: %s\n", r.Remote, kind, colorize(r.Old, colorGray))
+				fmt.Printf("    %s %s  %s\n", strings.Repeat(" ", len(r.Remote)), strings.Repeat(" ", len(kind)), colorize("→ "+r.New, colorGreen))
+			}
+			planned = append(planned, pending{info: findInfo(result.DirectoryInfos, info.Path), name: name, rewrites: rewrites})
+			total += len(rewrites)
+		}
+
+		if len(planned) == 0 {
+			fmt.Printf("No remote URLs start with %s.\n", remotesFrom)
+			if failed > 0 {
+				exit(exitError)
+			}
+			return
+		}
+		fmt.Printf("\n%d remote URLs in %d repositories to rewrite.\n", total, len(planned))
+
+		if !remotesApply {
+			if !term.IsTerminal(int(os.Stdin.Fd())) {
+				return
+			}
+			fmt.Print("Apply these rewrites? (y/N): ")
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				return
+			}
+		}
+		requireWritable("rewriting remotes")
+
+		for _, p := range planned {
+			for _, r := range p.rewrites {
+				err := gitremote.ApplyRewrite(p.info.Path, r)
+				auditlog.Write("remotes.rewrite", p.info.Path, r.Old+" -> "+r.New, err)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", p.name, err)
+					failed++
+					continue
+				}
+				// Keep the cached remote current until the next scan
+				if git := p.info.GitMetadata; !r.Push && git.RemoteURL == r.Old {
+					git.RemoteURL = r.New
+				}
+			}
+		}
+		if cacheInstance, err := cache.New(); err != nil {
+			logger.Warn("failed to initialize cache", "error", err)
+		} else if err := cacheInstance.Save(result); err != nil {
+			logger.Warn("failed to save rewritten remotes to cache", "error", err)
+		}
+
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "%d rewrites failed\n", failed)
+			exit(exitError)
+		}
+		fmt.Printf("Rewrote %d remote URLs.\n", total)
+	},
+}
+
+func init() {
+	// Attach the `remotes` command and its subcommand: thandie remotes [rewrite]
+	remotesCmd.Flags().BoolVar(&remotesCached, "cached", false, "Show the results of the last check without checking again")
+	remotesRewriteCmd.Flags().StringVar(&remotesFrom, "from", "", "URL prefix to replace, e.g. git@old-host:")
+	remotesRewriteCmd.Flags().StringVar(&remotesTo, "to", "", "URL prefix to replace it with, e.g. git@new-host:")
+	remotesRewriteCmd.Flags().BoolVar(&remotesApply, "apply", false, "Apply the rewrites without prompting")
+	remotesRewriteCmd.Flags().StringVar(&remotesFilter, "filter", "", "Only rewrite repositories matching the filter, e.g. 'name:api'")
+	addGroupFlag(remotesRewriteCmd)
+	remotesCmd.AddCommand(remotesRewriteCmd)
+	rootCmd.AddCommand(remotesCmd)
+}
+
+// findInfo returns the entry of infos at path, so changes to it are saved
+// with the scan result
+func findInfo(infos []scanner.DirectoryInfo, path string) *scanner.DirectoryInfo {
+	for i := range infos {
+		if infos[i].Path == path {
+			return &infos[i]
+		}
+	}
+	return nil
+}
+
+// printRemotesReport prints the repositories whose remote needs attention,
+// with the command that fixes a moved one, and returns how many there are
+func printRemotesReport(wsPath string, infos []scanner.DirectoryInfo) int {
+	checked, flagged := 0, 0
+	var unreachable []string
+	for _, info := range infos {
+		check := info.Remote
+		if check == nil {
+			continue
+		}
+		checked++
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+		switch check.State {
+		case scanner.RemoteGone:
+			flagged++
+			fmt.Printf("  %s %s  %s\n", padWidth(name, 30), colorize("gone    ", colorRed), check.URL)
+			fmt.Printf("  %-30s %s\n", "", colorize("deleted, or no longer accessible with your credentials; remove or repoint the remote", colorGray))
+		case scanner.RemoteMoved:
+			flagged++
+			fmt.Printf("  %s %s  %s → %s\n", padWidth(name, 30), colorize("moved   ", colorYellow), check.URL, check.NewURL)
+			fmt.Printf("  %-30s %s\n", "", colorize(fmt.Sprintf("git -C %s remote set-url origin %s", info.Path, check.NewURL), colorGray))
+		case scanner.RemoteArchived:
+			flagged++
+			fmt.Printf("  %s %s  %s\n", padWidth(name, 30), colorize("archived", colorYellow), check.URL)
+		case scanner.RemoteUnreachable:
+			unreachable = append(unreachable, fmt.Sprintf("%s: %s", name, check.Error))
+		}
+	}
+
+	if checked == 0 {
+		fmt.Println("No remote checks. Run 'thandie remotes' without --cached.")
+		return 0
+	}
+	if len(unreachable) > 0 {
+		if flagged > 0 {
+			fmt.Println()
+		}
+		fmt.Println("Could not check:")
+		for _, line := range unreachable {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	if flagged == 0 {
+		fmt.Printf("\nAll %d checked remotes exist.\n", checked-len(unreachable))
+		return 0
+	}
+	fmt.Printf("\n%d of %d remotes are gone, moved or archived.\n", flagged, checked)
+	return flagged
+}
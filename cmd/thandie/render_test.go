@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// update rewrites the golden files with the current output instead of
+// comparing against them: go test ./cmd/thandie -run Render -update
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// renderWorkspace is the workspace path of the fixtures
+const renderWorkspace = "/ws"
+
+// renderFixtures returns directories covering the states the list and
+// details views draw differently. Times are relative to now so relative ages
+// render the same on every run.
+func renderFixtures() []scanner.DirectoryInfo {
+	threeDaysAgo := time.Now().Add(-(3*24 + 1) * time.Hour)
+	return []scanner.DirectoryInfo{
+		{Path: "/ws/api", GitMetadata: &scanner.GitMetadata{
+			IsGitRepo: true, CurrentBranch: "main", RemoteURL: "git@github.com:example/api.git",
+			StatusSummary: "clean", Upstream: "origin/main", LastCommitAt: threeDaysAgo, LastSubject: "Add rate limits",
+		}},
+		{Path: "/ws/web", GitMetadata: &scanner.GitMetadata{
+			IsGitRepo: true, CurrentBranch: "feature/a-rather-long-branch-name-that-needs-cutting", RemoteURL: "https://github.com/example/web.git",
+			HasUncommitted: true, StatusSummary: " M index.html; ?? notes.txt", DirtyFiles: 2,
+			Upstream: "origin/feature/a-rather-long-branch-name-that-needs-cutting", Ahead: 2, Behind: 1, LastCommitAt: threeDaysAgo, LastSubject: "Redesign the landing page",
+			UnpushedCommits: []scanner.CommitInfo{{Hash: "1a2b3c4", Subject: "Redesign the landing page"}, {Hash: "5d6e7f8", Subject: "Fix typo"}},
+		}},
+		{Path: "/ws/文档", GitMetadata: &scanner.GitMetadata{
+			IsGitRepo: true, CurrentBranch: "HEAD", StatusSummary: "clean", LastCommitAt: threeDaysAgo, LastSubject: "初始提交",
+		}},
+		{Path: "/ws/notes"},
+		{Path: "/ws/slow", ScanError: "timed out after 2m0s"},
+		{Path: "/ws/locked", AccessDenied: true},
+	}
+}
+
+// render returns what output writes to stdout. Colors are off, as stdout is
+// not a terminal while rendering.
+func render(t *testing.T, output func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	output()
+	w.Close()
+	return string(<-done)
+}
+
+// golden compares got with testdata/<name>.golden, or rewrites the file
+// with -update
+func golden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to accept it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestRenderList(t *testing.T) {
+	golden(t, "list", render(t, func() {
+		printDirectories(renderWorkspace, renderFixtures())
+	}))
+}
+
+func TestRenderListTable(t *testing.T) {
+	golden(t, "list_table", render(t, func() {
+		printListTable(renderWorkspace, renderFixtures(), listColumnNames)
+	}))
+}
+
+func TestRenderListDetailed(t *testing.T) {
+	listDensity = densityDetailed
+	defer func() { listDensity = "" }()
+	golden(t, "list_detailed", render(t, func() {
+		printDirectories(renderWorkspace, renderFixtures())
+	}))
+}
+
+func TestRenderDetails(t *testing.T) {
+	for _, info := range renderFixtures() {
+		name := "show_" + filepath.Base(info.Path)
+		if filepath.Base(info.Path) == "文档" {
+			name = "show_cjk"
+		}
+		t.Run(name, func(t *testing.T) {
+			golden(t, name, render(t, func() { printDetails(info) }))
+		})
+	}
+}
+
+func TestRenderScanSummary(t *testing.T) {
+	record := cache.ScanRecord{
+		Status: cache.ScanFull, Duration: 1234 * time.Millisecond, Directories: 6,
+		Dirty: 1, Missing: 2, Errored: 1, AccessDenied: 1,
+	}
+	golden(t, "scan_summary", render(t, func() {
+		fmt.Println(scanSummary(record))
+		fmt.Println(scanSummary(cache.ScanRecord{Status: cache.ScanUnchanged, Directories: 6}))
+		printScanErrors(renderFixtures())
+	}))
+}
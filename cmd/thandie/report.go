@@ -0,0 +1,632 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitconfig"
+	"github.com/ThandieOps/thandie-agent/internal/mail"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+// reportCmd represents: `thandie report`
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Workspace-wide reports",
+}
+
+// reportComplianceCmd represents: `thandie report compliance`
+var reportComplianceCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "List repositories missing required files (LICENSE, README, CODEOWNERS)",
+	Long: `List git repositories in the workspace that are missing any of the files
+required by compliance.required in the config. Valid entries are license,
+readme and codeowners; the default policy requires license and readme.
+
+Exits with code 10 if any repository is missing a required file.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		required := cfg.Compliance.Required
+		for _, name := range required {
+			if !scanner.IsKnownFile(name) {
+				fmt.Fprintf(os.Stderr, "Error: unknown compliance.required entry %q (expected license, readme or codeowners)\n", name)
+				exit(exitConfigError)
+			}
+		}
+		if len(required) == 0 {
+			fmt.Println("No files are required by the compliance policy.")
+			return
+		}
+
+		result := loadScanResult(wsPath)
+
+		var repos []scanner.DirectoryInfo
+		var failing []complianceRow
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			repos = append(repos, info)
+
+			files := info.Files
+			if files == nil {
+				// Cached by a version that didn't record files
+				files = scanner.CollectRepoFiles(info.Path)
+			}
+
+			var missing []string
+			for _, name := range required {
+				if !files.Has(name) {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) == 0 {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			failing = append(failing, complianceRow{Name: name, Path: info.Path, Missing: missing})
+		}
+
+		data := complianceData{reportData: newReportData(wsPath, repos), Required: required, Failing: failing}
+
+		if templatePath != "" {
+			if err := renderTemplate(os.Stdout, templatePath, data); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printCompliance(data)
+		}
+		if len(data.Failing) > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+// complianceRow is a repository missing required files
+type complianceRow struct {
+	Name    string
+	Path    string
+	Missing []string
+}
+
+// complianceData is the template data of `thandie report compliance`
+type complianceData struct {
+	reportData
+	Required []string
+	Failing  []complianceRow
+}
+
+// printCompliance prints the repositories missing required files
+func printCompliance(data complianceData) {
+	if len(data.Failing) == 0 {
+		fmt.Printf("All %d repositories have the required files (%s).\n", len(data.Directories), strings.Join(data.Required, ", "))
+		return
+	}
+	for _, row := range data.Failing {
+		fmt.Printf("  %s missing %s\n", padWidth(row.Name, 30), strings.Join(row.Missing, ", "))
+	}
+	fmt.Printf("\n%d of %d repositories are missing required files.\n", len(data.Failing), len(data.Directories))
+}
+
+var (
+	// reportEmail sends the summary as an HTML email instead of printing it
+	reportEmail bool
+)
+
+// reportSummaryCmd represents: `thandie report summary`
+var reportSummaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "Print or email a digest of the workspace",
+	Long: `Print a digest of the last scan: uncommitted and unpushed work, failing CI
+and tests, known vulnerabilities and the alerts of 'thandie notify --alerts'.
+
+With --email the digest is sent as an HTML email using the smtp settings in the
+config (host, port, username, password, from, to; the password may be a
+keychain:<name> reference). Schedule it weekly, e.g. from cron, for a weekly
+digest. With --template the template renders the printed digest or, with
+--email, the HTML body.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+		result := loadScanResult(wsPath)
+		infos := selectGroup(wsPath, groupName, result.DirectoryInfos)
+		missing := result.Missing
+		if groupName != "" {
+			g := getGroups(wsPath)
+			missing = nil
+			for _, dir := range result.Missing {
+				if g.Contains(groupName, dir.Info.Path) {
+					missing = append(missing, dir)
+				}
+			}
+		}
+
+		summary := workspaceSummary(wsPath, infos)
+		data := summaryData{
+			reportData: newReportData(wsPath, infos),
+			Title:      summary.Title,
+			Lines:      summary.Lines,
+			Alerts:     workspaceAlerts(wsPath, infos, missing, cfg.Notify.DirtyDays).Lines,
+		}
+
+		if !reportEmail {
+			if templatePath != "" {
+				if err := renderTemplate(os.Stdout, templatePath, data); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					exit(exitError)
+				}
+				return
+			}
+			fmt.Print(summaryText(data))
+			return
+		}
+
+		requireWritable("sending email")
+		var html bytes.Buffer
+		var err error
+		if templatePath != "" {
+			err = renderTemplate(&html, templatePath, data)
+		} else {
+			err = summaryHTML.Execute(&html, data)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		msg := mail.Message{
+			Subject: fmt.Sprintf("Thandie digest: %s (%s)", data.Title, data.GeneratedAt.Format("2006-01-02")),
+			HTML:    html.String(),
+			Text:    summaryText(data),
+		}
+		err = mail.Send(cfg.SMTP, msg)
+		auditlog.Write("report.email", strings.Join(cfg.SMTP.To, ", "), msg.Subject, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if errors.Is(err, mail.ErrNotConfigured) {
+				exit(exitConfigError)
+			}
+			exit(exitError)
+		}
+		fmt.Printf("Digest sent to %s.\n", strings.Join(cfg.SMTP.To, ", "))
+	},
+}
+
+// summaryData is the template data of `thandie report summary`
+type summaryData struct {
+	reportData
+	Title  string
+	Lines  []string
+	Alerts []string
+}
+
+// summaryText renders the digest as plain text
+func summaryText(data summaryData) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", data.Title)
+	for _, line := range data.Lines {
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+	if len(data.Alerts) > 0 {
+		b.WriteString("\nAlerts:\n")
+		for _, line := range data.Alerts {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// summaryHTML is the HTML body of the emailed digest
+var summaryHTML = template.Must(template.New("summary").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Helvetica, Arial, sans-serif; color: #24292f;">
+<h2>{{ .Title }}</h2>
+<p style="color: #57606a;">{{ .WorkspaceName }} &middot; {{ .GeneratedAt.Format "Mon, 02 Jan 2006 15:04" }}</p>
+<ul>
+{{- range .Lines }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- if .Alerts }}
+<h3 style="color: #cf222e;">Alerts</h3>
+<ul>
+{{- range .Alerts }}
+<li>{{ . }}</li>
+{{- end }}
+</ul>
+{{- end }}
+<table cellpadding="4" style="border-collapse: collapse; font-size: 14px;">
+<tr style="text-align: left; border-bottom: 1px solid #d0d7de;"><th>Repository</th><th>Branch</th><th>Status</th></tr>
+{{- range .Directories }}{{ if and .GitMetadata .GitMetadata.IsGitRepo }}
+<tr><td>{{ .Name }}</td><td>{{ .GitMetadata.CurrentBranch }}</td><td>
+{{- if .GitMetadata.HasUncommitted }}dirty {{ end }}
+{{- if .GitMetadata.Ahead }}↑{{ .GitMetadata.Ahead }} {{ end }}
+{{- if .GitMetadata.Behind }}↓{{ .GitMetadata.Behind }}{{ end }}</td></tr>
+{{- end }}{{ end }}
+</table>
+</body>
+</html>
+`))
+
+var (
+	// reportExpect is the expected branch of repositories whose groups don't set one
+	reportExpect string
+)
+
+// reportBranchesCmd represents: `thandie report branches`
+var reportBranchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "List repositories not on their expected branch",
+	Long: `List git repositories whose current branch, or default branch (from
+origin/HEAD or the provider), differs from the expected branch.
+
+The expected branch is the branch of the first group containing the
+repository (see groups in the config), or --expect for repositories whose
+groups don't set one. Repositories with no expected branch are not checked.
+
+Exits with code 10 if any repository doesn't match.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		g := getGroups(wsPath)
+		result := loadScanResult(wsPath)
+
+		var repos []scanner.DirectoryInfo
+		var mismatched []branchRow
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			expected := g.Branch(info.Path)
+			if expected == "" {
+				expected = reportExpect
+			}
+			if expected == "" {
+				continue
+			}
+			repos = append(repos, info)
+
+			defaultBranch := scanner.DefaultBranch(info.Path)
+			if defaultBranch == "" && info.Enrichment != nil {
+				defaultBranch = info.Enrichment.DefaultBranch
+			}
+			current := info.GitMetadata.CurrentBranch
+			if current == expected && (defaultBranch == "" || defaultBranch == expected) {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			mismatched = append(mismatched, branchRow{
+				Name:     name,
+				Path:     info.Path,
+				Expected: expected,
+				Current:  current,
+				Default:  defaultBranch,
+			})
+		}
+		if len(repos) == 0 {
+			fmt.Println("No expected branch: pass --expect or set branch on a group in the config.")
+			return
+		}
+
+		data := branchesData{reportData: newReportData(wsPath, repos), Mismatched: mismatched}
+
+		if templatePath != "" {
+			if err := renderTemplate(os.Stdout, templatePath, data); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printBranches(data)
+		}
+		if len(data.Mismatched) > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+// branchRow is a repository that isn't on its expected branch
+type branchRow struct {
+	Name     string
+	Path     string
+	Expected string
+	Current  string // Checked-out branch, empty when HEAD is detached
+	Default  string // Default branch, empty when unknown
+}
+
+// branchesData is the template data of `thandie report branches`
+type branchesData struct {
+	reportData
+	Mismatched []branchRow
+}
+
+// printBranches prints the repositories that aren't on their expected branch
+func printBranches(data branchesData) {
+	if len(data.Mismatched) == 0 {
+		fmt.Printf("All %d repositories are on their expected branch.\n", len(data.Directories))
+		return
+	}
+	for _, row := range data.Mismatched {
+		var problems []string
+		if row.Current != row.Expected {
+			current := row.Current
+			if current == "" {
+				current = "detached HEAD"
+			}
+			problems = append(problems, "on "+current)
+		}
+		if row.Default != "" && row.Default != row.Expected {
+			problems = append(problems, "default "+row.Default)
+		}
+		fmt.Printf("  %s expected %s, %s\n", padWidth(row.Name, 30), row.Expected, strings.Join(problems, ", "))
+	}
+	fmt.Printf("\n%d of %d repositories don't match their expected branch.\n", len(data.Mismatched), len(data.Directories))
+}
+
+// reportSigningCmd represents: `thandie report signing`
+var reportSigningCmd = &cobra.Command{
+	Use:   "signing",
+	Short: "List repositories violating the commit signing policy",
+	Long: `Check the commit signing setup of every git repository against signing
+in the config:
+
+  required  commit.gpgsign must be enabled and user.signingkey set
+  format    gpg.format must be this (openpgp, ssh or x509)
+  commits   the last this many commits on HEAD by user.email must be signed
+
+Settings are read as git sees them from the repository, so global config
+counts. Signatures are checked for presence, not verified.
+
+Exits with code 10 if any repository violates the policy.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		policy := cfg.Signing
+		if policy.Format != "" && !signing.ValidFormat(policy.Format) {
+			fmt.Fprintf(os.Stderr, "Error: unknown signing.format %q (expected %s)\n", policy.Format, strings.Join(signing.Formats, ", "))
+			exit(exitConfigError)
+		}
+		if !policy.Required && policy.Format == "" && policy.Commits <= 0 {
+			fmt.Println("No signing policy: set signing.required, signing.format or signing.commits in the config.")
+			return
+		}
+
+		result := loadScanResult(wsPath)
+
+		var repos []scanner.DirectoryInfo
+		var violating []signingRow
+		failed := 0
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			status, err := signing.Inspect(info.Path, policy.Commits)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			repos = append(repos, info)
+			if problems := status.Violations(policy); len(problems) > 0 {
+				violating = append(violating, signingRow{Name: name, Path: info.Path, Status: *status, Problems: problems})
+			}
+		}
+
+		data := signingData{reportData: newReportData(wsPath, repos), Policy: policy, Violating: violating}
+
+		if templatePath != "" {
+			if err := renderTemplate(os.Stdout, templatePath, data); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printSigning(data)
+		}
+		switch {
+		case failed > 0:
+			exit(exitError)
+		case len(data.Violating) > 0:
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+// signingRow is a repository violating the signing policy
+type signingRow struct {
+	Name     string
+	Path     string
+	Status   signing.Status
+	Problems []string
+}
+
+// signingData is the template data of `thandie report signing`
+type signingData struct {
+	reportData
+	Policy    config.SigningConfig
+	Violating []signingRow
+}
+
+// printSigning prints the repositories violating the signing policy
+func printSigning(data signingData) {
+	if len(data.Violating) == 0 {
+		fmt.Printf("All %d repositories follow the signing policy.\n", len(data.Directories))
+		return
+	}
+	for _, row := range data.Violating {
+		fmt.Printf("  %s %s\n", padWidth(row.Name, 30), strings.Join(row.Problems, ", "))
+	}
+	fmt.Printf("\n%d of %d repositories violate the signing policy.\n", len(data.Violating), len(data.Directories))
+}
+
+// reportGitConfigCmd represents: `thandie report git-config`
+var reportGitConfigCmd = &cobra.Command{
+	Use:   "git-config",
+	Short: "List repositories whose git config differs from their group's",
+	Long: `Compare the git config of every repository with the git_config its groups
+expect, e.g. to catch commits with a personal email in work repositories:
+
+  groups:
+    - name: Work
+      paths: [acme/*]
+      git_config: ["user.email=*@acme.com", "pull.rebase=true", "core.autocrlf="]
+
+Values are globs; an empty value means the key must not be set. Each key is
+taken from the first group containing the repository that sets it. Settings
+are read as git sees them from the repository, so global config counts.
+
+Exits with code 10 if any repository doesn't match.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		g := getGroups(wsPath)
+		result := loadScanResult(wsPath)
+
+		var repos []scanner.DirectoryInfo
+		var mismatched []gitConfigRow
+		failed := 0
+		for _, info := range selectGroup(wsPath, groupName, result.DirectoryInfos) {
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+				continue
+			}
+			expected := g.GitConfig(info.Path)
+			if len(expected) == 0 {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			mismatches, err := gitconfig.Check(info.Path, expected)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				failed++
+				continue
+			}
+			repos = append(repos, info)
+			if len(mismatches) > 0 {
+				mismatched = append(mismatched, gitConfigRow{Name: name, Path: info.Path, Mismatches: mismatches})
+			}
+		}
+		if len(repos) == 0 && failed == 0 {
+			fmt.Println("No expected git config: set git_config on a group in the config.")
+			return
+		}
+
+		data := gitConfigData{reportData: newReportData(wsPath, repos), Mismatched: mismatched}
+
+		if templatePath != "" {
+			if err := renderTemplate(os.Stdout, templatePath, data); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printGitConfig(data)
+		}
+		switch {
+		case failed > 0:
+			exit(exitError)
+		case len(data.Mismatched) > 0:
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+// gitConfigRow is a repository whose git config differs from the expected one
+type gitConfigRow struct {
+	Name       string
+	Path       string
+	Mismatches []gitconfig.Mismatch
+}
+
+// gitConfigData is the template data of `thandie report git-config`
+type gitConfigData struct {
+	reportData
+	Mismatched []gitConfigRow
+}
+
+// printGitConfig prints the repositories whose git config differs from the
+// expected one
+func printGitConfig(data gitConfigData) {
+	if len(data.Mismatched) == 0 {
+		fmt.Printf("All %d repositories have the expected git config.\n", len(data.Directories))
+		return
+	}
+	for _, row := range data.Mismatched {
+		for i, m := range row.Mismatches {
+			name := row.Name
+			if i > 0 {
+				name = ""
+			}
+			actual, expected := m.Actual, m.Expected
+			if !m.Set {
+				actual = "unset"
+			}
+			if expected == "" {
+				expected = "unset"
+			}
+			fmt.Printf("  %s %s is %s, expected %s\n", padWidth(name, 30), m.Key, colorize(actual, colorYellow), expected)
+		}
+	}
+	fmt.Printf("\n%d of %d repositories don't have the expected git config.\n", len(data.Mismatched), len(data.Directories))
+}
+
+func init() {
+	// Attach the `report` command and its subcommands: thandie report compliance|summary|branches|signing|git-config
+	addTemplateFlag(reportComplianceCmd)
+	addGroupFlag(reportComplianceCmd)
+	reportCmd.AddCommand(reportComplianceCmd)
+	reportSummaryCmd.Flags().BoolVar(&reportEmail, "email", false, "Send the digest as an HTML email to smtp.to")
+	addTemplateFlag(reportSummaryCmd)
+	addGroupFlag(reportSummaryCmd)
+	reportCmd.AddCommand(reportSummaryCmd)
+	reportBranchesCmd.Flags().StringVar(&reportExpect, "expect", "", "Expected branch of repositories whose groups don't set one")
+	addTemplateFlag(reportBranchesCmd)
+	addGroupFlag(reportBranchesCmd)
+	reportCmd.AddCommand(reportBranchesCmd)
+	addTemplateFlag(reportSigningCmd)
+	addGroupFlag(reportSigningCmd)
+	reportCmd.AddCommand(reportSigningCmd)
+	addTemplateFlag(reportGitConfigCmd)
+	addGroupFlag(reportGitConfigCmd)
+	reportCmd.AddCommand(reportGitConfigCmd)
+	rootCmd.AddCommand(reportCmd)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ThandieOps/thandie-agent/internal/activity"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	// resumeOpen opens the suggested repository in the editor
+	resumeOpen bool
+
+	// resumePath prints only the suggested repository's path
+	resumePath bool
+
+	// resumeDismiss hides the suggestion from `thandie list`
+	resumeDismiss bool
+)
+
+// resumeCmd represents: `thandie resume`
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Suggest the repository to get back to",
+	Long: `Suggest the repository you were most likely working in: the dirty
+repository whose uncommitted files changed last, or the repository last
+opened with thandie if that was more recent.
+
+'thandie list' shows the suggestion too, when run in a terminal, until it is
+dismissed with --dismiss; it comes back when you work somewhere else.
+
+--open opens the repository in $VISUAL or $EDITOR, and --path prints just
+its path, e.g. for cd "$(thandie resume --path)".`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		suggestion := activity.Suggest(loadScanResult(wsPath).DirectoryInfos)
+		if suggestion == nil {
+			fmt.Fprintln(os.Stderr, "Nothing to resume: no repository has uncommitted changes.")
+			exit(exitError)
+		}
+
+		switch {
+		case resumePath:
+			fmt.Println(suggestion.Path)
+		case resumeDismiss:
+			if err := activity.Dismiss(suggestion); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			fmt.Println("Suggestion dismissed until you work somewhere else.")
+		case resumeOpen:
+			openRepo(suggestion.Path)
+		default:
+			fmt.Println(resumeText(wsPath, suggestion))
+		}
+	},
+}
+
+// resumeText describes a suggestion in one line
+func resumeText(wsPath string, s *activity.Suggestion) string {
+	name, err := filepath.Rel(wsPath, s.Path)
+	if err != nil {
+		name = s.Path
+	}
+	if s.Opened {
+		return fmt.Sprintf("Resume work in %s: last opened %s", name, formatAge(s.Time))
+	}
+	return fmt.Sprintf("Resume work in %s: %d uncommitted files, last edited %s (%s)", name, s.Files, formatAge(s.Time), s.File)
+}
+
+// printResumeBanner prints the resume suggestion after interactive output,
+// unless it was dismissed
+func printResumeBanner(wsPath string, infos []scanner.DirectoryInfo) {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return
+	}
+	suggestion := activity.Suggest(infos)
+	if suggestion == nil || activity.Dismissed(suggestion) {
+		return
+	}
+	fmt.Printf("\n%s\n", colorize("↪ "+resumeText(wsPath, suggestion), colorYellow))
+	fmt.Println(colorize("  thandie resume --open to open it, --dismiss to hide this", colorGray))
+}
+
+// openRepo opens a repository in the editor and remembers it as the last
+// one opened
+func openRepo(path string) {
+	if err := openInEditor(path); err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", path, err)
+		exit(exitError)
+	}
+	if err := activity.RecordOpened(path); err != nil {
+		logger.Warn("failed to record opened repository", "error", err)
+	}
+}
+
+func init() {
+	// Attach the `resume` command to the root: thandie resume
+	resumeCmd.Flags().BoolVar(&resumeOpen, "open", false, "Open the repository in $VISUAL or $EDITOR")
+	resumeCmd.Flags().BoolVar(&resumePath, "path", false, "Print only the repository's path")
+	resumeCmd.Flags().BoolVar(&resumeDismiss, "dismiss", false, "Hide the suggestion from 'thandie list' until the context changes")
+	rootCmd.AddCommand(resumeCmd)
+}
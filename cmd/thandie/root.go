@@ -1,22 +1,44 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/ThandieOps/thandie-agent/internal/cache"
 	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
 	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
+	"github.com/ThandieOps/thandie-agent/internal/workspace"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
 	// Global flags (available to all subcommands)
-	workspacePath string
+	workspacePath    string
+	workspaceProfile string
+	traceTarget      string
+	readOnlyFlag     bool
+
+	// agentProfile is the agent profile selected with --agent-profile or
+	// THANDIE_PROFILE, whose config file replaces config.yml; "" for none
+	agentProfile string
 
 	// Global config instance
 	cfg *config.Config
+
+	// configErr records a config file that exists but could not be read or parsed
+	configErr error
 )
 
 // rootCmd represents the base command: `thandie`
@@ -24,7 +46,25 @@ var rootCmd = &cobra.Command{
 	Use:   "thandie",
 	Short: "Thandie monitors local workspaces and syncs their state",
 	Long: `Thandie is a CLI tool for monitoring your local development workspaces
-and syncing their state with a remote service.`,
+and syncing their state with a remote service.
+
+Exit codes:
+  0   success
+  1   unclassified error
+  2   configuration error
+  3   workspace missing
+  4   scan failure
+  5   sync failure
+  10  check failed`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		target := traceTarget
+		if target == "" && cfg != nil {
+			target = cfg.Tracing.Export
+		}
+		if err := tracing.Init(context.Background(), target, cmd.CommandPath()); err != nil {
+			logger.Warn("tracing disabled", "error", err)
+		}
+	},
 	// If you want `thandie` to do something when called with no subcommand,
 	// add a Run: func(cmd, args) {...} here. For now, we'll leave it empty.
 }
@@ -45,7 +85,7 @@ func Execute() {
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		exit(exitError)
 	}
 }
 
@@ -62,6 +102,36 @@ func init() {
 		"Path to the workspace directory (overrides THANDIE_WORKSPACE env var and config file)",
 	)
 
+	rootCmd.PersistentFlags().StringVarP(
+		&workspaceProfile,
+		"profile",
+		"p",
+		"",
+		"Name of a workspace profile from the config file (workspace.profiles)",
+	)
+
+	// Read by initConfig from the command line before flags are parsed;
+	// registered so that it is accepted and documented
+	rootCmd.PersistentFlags().String(
+		"agent-profile",
+		"",
+		"Name of an agent profile: use ~/.config/thandie/profiles/<name>.yml instead of config.yml (overrides THANDIE_PROFILE)",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&traceTarget,
+		"trace",
+		"",
+		"Export OpenTelemetry spans to an OTLP endpoint (http(s)://...), \"otlp\" (OTEL_EXPORTER_OTLP_* env vars) or a file",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&readOnlyFlag,
+		"read-only",
+		false,
+		"Refuse write actions: installing hooks, storing secrets, sending notifications, running tests (also security.read_only)",
+	)
+
 	// Bind the flag to Viper (this allows Viper to read the flag value)
 	if err := viper.BindPFlag("workspace", rootCmd.PersistentFlags().Lookup("workspace")); err != nil {
 		fmt.Fprintf(os.Stderr, "Error binding workspace flag: %v\n", err)
@@ -87,6 +157,26 @@ func initConfig() {
 	configDir := filepath.Join(homeDir, ".config", "thandie")
 	viper.AddConfigPath(configDir)
 
+	// An agent profile replaces config.yml with its own config file
+	agentProfile = agentProfileFromArgs(os.Args[1:])
+	if agentProfile == "" {
+		agentProfile = os.Getenv("THANDIE_PROFILE")
+	}
+	if agentProfile != "" {
+		path, err := configFilePath()
+		if err == nil {
+			_, err = os.Stat(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = fmt.Errorf("agent profile %q not found: create %s (e.g. with 'thandie init --agent-profile %s')", agentProfile, path, agentProfile)
+			}
+		}
+		if err != nil {
+			configErr = err
+		} else {
+			viper.SetConfigFile(path)
+		}
+	}
+
 	// Set environment variable prefix
 	viper.SetEnvPrefix("THANDIE")
 	viper.AutomaticEnv() // Automatically read environment variables with THANDIE_ prefix
@@ -99,16 +189,43 @@ func initConfig() {
 	viper.SetDefault("scanner.include_hidden", false)
 	viper.SetDefault("scanner.ignore_dirs", []string{".git", "node_modules", "vendor"})
 	viper.SetDefault("scanner.max_depth", 1)
+	viper.SetDefault("scanner.concurrency", 4)
+	viper.SetDefault("scanner.loc", false)
+	viper.SetDefault("scanner.keep_missing_days", 7)
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.to_file", false)
 	viper.SetDefault("logging.json", false)
+	viper.SetDefault("providers.github.url", "https://api.github.com")
+	viper.SetDefault("providers.github.web_url", "https://github.com")
+	viper.SetDefault("providers.github.token", "keychain:github")
+	viper.SetDefault("providers.gitlab.url", "https://gitlab.com/api/v4")
+	viper.SetDefault("providers.gitlab.web_url", "https://gitlab.com")
+	viper.SetDefault("providers.gitlab.token", "keychain:gitlab")
+	viper.SetDefault("enrichment.enabled", false)
+	viper.SetDefault("enrichment.ttl", "15m")
+	viper.SetDefault("commits.lint", false)
+	viper.SetDefault("compliance.required", []string{"license", "readme"})
+	viper.SetDefault("notify.dirty_days", 14)
+	viper.SetDefault("security.read_only", false)
+	viper.SetDefault("security.encrypt_cache", false)
+	viper.SetDefault("cache.max_age_days", 90)
+	viper.SetDefault("cache.auto_gc", true)
+	viper.SetDefault("git_watch.enabled", true)
+	viper.SetDefault("git_watch.max_repos", 256)
+	viper.SetDefault("power.scan_on_battery", false)
+	viper.SetDefault("power.scan_on_metered", false)
 
-	// Read config file (if it exists)
-	if err := viper.ReadInConfig(); err != nil {
-		// Config file not found is okay - we'll use defaults/env/flags
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			// Other errors (like parse errors) are more serious, but we'll continue
-			// The user can still use flags and env vars
+	// Read config file (if it exists). A missing agent profile config was
+	// recorded above rather than falling back to config.yml.
+	if configErr == nil {
+		if err := viper.ReadInConfig(); err != nil {
+			// Config file not found is okay - we'll use defaults/env/flags
+			if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+				// Other errors (like parse errors) are more serious. Record them so
+				// commands that depend on config can fail with exitConfigError, while
+				// commands like `init` can still run and fix the file.
+				configErr = err
+			}
 		}
 	}
 
@@ -130,16 +247,107 @@ func initConfig() {
 				IncludeHidden: viper.GetBool("scanner.include_hidden"),
 				IgnoreDirs:    viper.GetStringSlice("scanner.ignore_dirs"),
 				MaxDepth:      viper.GetInt("scanner.max_depth"),
+				Concurrency:   viper.GetInt("scanner.concurrency"),
+				LOC:           viper.GetBool("scanner.loc"),
+
+				FullScanInterval: viper.GetString("scanner.full_scan_interval"),
+				KeepMissingDays:  viper.GetInt("scanner.keep_missing_days"),
+				GitBackend:       viper.GetString("scanner.git_backend"),
 			},
 			Logging: config.LoggingConfig{
 				Level:  viper.GetString("logging.level"),
 				ToFile: viper.GetBool("logging.to_file"),
 				JSON:   viper.GetBool("logging.json"),
+
+				Components: viper.GetStringMapString("logging.components"),
+			},
+			Providers: config.ProvidersConfig{
+				GitHub: config.ProviderConfig{
+					URL:      viper.GetString("providers.github.url"),
+					WebURL:   viper.GetString("providers.github.web_url"),
+					Token:    viper.GetString("providers.github.token"),
+					ClientID: viper.GetString("providers.github.client_id"),
+				},
+				GitLab: config.ProviderConfig{
+					URL:    viper.GetString("providers.gitlab.url"),
+					WebURL: viper.GetString("providers.gitlab.web_url"),
+					Token:  viper.GetString("providers.gitlab.token"),
+				},
+			},
+			Enrichment: config.EnrichmentConfig{
+				Enabled: viper.GetBool("enrichment.enabled"),
+				TTL:     viper.GetString("enrichment.ttl"),
+			},
+			Commits: config.CommitsConfig{
+				Lint:        viper.GetBool("commits.lint"),
+				LintPattern: viper.GetString("commits.lint_pattern"),
+			},
+			Compliance: config.ComplianceConfig{
+				Required: viper.GetStringSlice("compliance.required"),
+			},
+			Notify: config.NotifyConfig{
+				SlackWebhook: viper.GetString("notify.slack_webhook"),
+				TeamsWebhook: viper.GetString("notify.teams_webhook"),
+				DirtyDays:    viper.GetInt("notify.dirty_days"),
+			},
+			SMTP: config.SMTPConfig{
+				Host:     viper.GetString("smtp.host"),
+				Port:     viper.GetInt("smtp.port"),
+				Username: viper.GetString("smtp.username"),
+				Password: viper.GetString("smtp.password"),
+				From:     viper.GetString("smtp.from"),
+				To:       viper.GetStringSlice("smtp.to"),
+			},
+			Power: config.PowerConfig{
+				ScanOnBattery: viper.GetBool("power.scan_on_battery"),
+				ScanOnMetered: viper.GetBool("power.scan_on_metered"),
+				ActiveHours:   viper.GetString("power.active_hours"),
+				IdleMinutes:   viper.GetInt("power.idle_minutes"),
+				MaxDefer:      viper.GetString("power.max_defer"),
+			},
+			Security: config.SecurityConfig{
+				ReadOnly:     viper.GetBool("security.read_only"),
+				EncryptCache: viper.GetBool("security.encrypt_cache"),
+			},
+			Tracing: config.TracingConfig{
+				Export: viper.GetString("tracing.export"),
+			},
+			Cache: config.CacheConfig{
+				Compression: viper.GetString("cache.compression"),
+				MaxAgeDays:  viper.GetInt("cache.max_age_days"),
+				AutoGC:      viper.GetBool("cache.auto_gc"),
 			},
 		}
 		fmt.Fprintf(os.Stderr, "Config loaded from Viper directly - Logging.ToFile=%v\n", cfg.Logging.ToFile)
 	}
 
+	// Expand ~, ${VAR} and ${env:VAR} in paths and patterns
+	if err := cfg.Expand(); err != nil && configErr == nil {
+		configErr = err
+	}
+
+	// Encrypt cache files from here on if asked to
+	if cfg.Security.EncryptCache {
+		seal.Enable()
+	}
+
+	// Compress scan caches from here on if asked to
+	if err := cache.SetCompression(cfg.Cache.Compression); err != nil && configErr == nil {
+		configErr = fmt.Errorf("invalid cache.compression: %w", err)
+	}
+
+	// Read repositories with the configured git backend
+	if provider, err := gitprovider.New(cfg.Scanner.GitBackend); err != nil {
+		if configErr == nil {
+			configErr = fmt.Errorf("invalid scanner.git_backend: %w", err)
+		}
+	} else {
+		scanner.SetGitProvider(provider)
+	}
+
+	// Record user.email during scans only if a group checks it
+	scanner.SetCollectEmail(slices.ContainsFunc(cfg.Groups, func(g config.GroupConfig) bool { return len(g.EmailDomains) > 0 }))
+
 	// Debug: Print config values to stderr before logger init (for debugging)
 	// This helps verify config is being read correctly
 	if cfg != nil {
@@ -150,7 +358,7 @@ func initConfig() {
 
 	// Initialize logger from config
 	if cfg != nil {
-		if err := logger.Init(cfg.Logging.Level, cfg.Logging.JSON, cfg.Logging.ToFile); err != nil {
+		if err := logger.Init(cfg.Logging.Level, cfg.Logging.JSON, cfg.Logging.ToFile, cfg.Logging.Components); err != nil {
 			// Log error but don't fail - continue with stderr logging
 			logPath, pathErr := logger.GetLogFilePath()
 			if pathErr == nil {
@@ -158,7 +366,7 @@ func initConfig() {
 			} else {
 				fmt.Fprintf(os.Stderr, "ERROR: failed to initialize file logging: %v\n", err)
 			}
-			logger.Init(cfg.Logging.Level, cfg.Logging.JSON, false) // Fallback to stderr only
+			logger.Init(cfg.Logging.Level, cfg.Logging.JSON, false, cfg.Logging.Components) // Fallback to stderr only
 		} else if cfg.Logging.ToFile {
 			// Log successful file logging initialization (only if enabled)
 			logPath, err := logger.GetLogFilePath()
@@ -171,35 +379,173 @@ func initConfig() {
 			fmt.Fprintf(os.Stderr, "DEBUG: File logging is disabled (to_file=false)\n")
 		}
 	} else {
-		logger.Init("info", false, false) // default: info level, text format, no file
+		logger.Init("info", false, false, nil) // default: info level, text format, no file
 	}
 }
 
+// agentProfileFromArgs returns the value of --agent-profile in args, which
+// initConfig needs before cobra parses the flags; "" if it isn't given
+func agentProfileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--agent-profile="); ok {
+			return value
+		}
+		if arg == "--agent-profile" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// agentProfilesDir returns the directory holding the agent profiles' config
+// files
+func agentProfilesDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "thandie", "profiles"), nil
+}
+
+// configFilePath returns the config file of the selected agent profile, or
+// config.yml without one
+func configFilePath() (string, error) {
+	if agentProfile == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(homeDir, ".config", "thandie", "config.yml"), nil
+	}
+	if agentProfile != filepath.Base(agentProfile) || strings.HasPrefix(agentProfile, ".") {
+		return "", fmt.Errorf("invalid agent profile name %q", agentProfile)
+	}
+	dir, err := agentProfilesDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, agentProfile+".yml"), nil
+}
+
 // getWorkspacePath returns the workspace path following the precedence order:
 // 1. CLI flag (--workspace)
-// 2. Environment variable (THANDIE_WORKSPACE)
-// 3. Config file (workspace.default)
-// 4. Default ($HOME/Workspace)
+// 2. Workspace profile (--profile)
+// 3. Environment variable (THANDIE_WORKSPACE)
+// 4. Config file (workspace.default)
+// 5. Default ($HOME/Workspace)
 func getWorkspacePath() string {
 	// 1. Check CLI flag (highest precedence)
 	if workspacePath != "" {
 		return workspacePath
 	}
 
-	// 2. Check environment variable directly (explicit precedence)
+	// 2. Check the selected profile
+	if profile := getWorkspaceProfile(""); profile != nil && profile.Path != "" {
+		return profile.Path
+	}
+
+	// 3. Check environment variable directly (explicit precedence)
 	if envPath := os.Getenv("THANDIE_WORKSPACE"); envPath != "" {
 		return envPath
 	}
 
-	// 3. Check config file
+	// 4. Check config file
 	if cfg != nil && cfg.Workspace.Default != "" {
 		return cfg.Workspace.Default
 	}
 
-	// 4. Default fallback
+	// 5. Default fallback
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "." // Last resort: current directory
 	}
 	return filepath.Join(homeDir, "Workspace")
 }
+
+// getWorkspaceProfile returns the profile selected with --profile, or else the
+// profile whose path matches wsPath. Returns nil if neither applies.
+func getWorkspaceProfile(wsPath string) *config.WorkspaceProfile {
+	if cfg == nil {
+		return nil
+	}
+	if workspaceProfile != "" {
+		return cfg.FindProfile(workspaceProfile)
+	}
+	if wsPath != "" {
+		return cfg.ProfileForPath(wsPath)
+	}
+	return nil
+}
+
+// workspaceName returns what to call the workspace at wsPath in headers and
+// titles: the alias of its profile, or else its path
+func workspaceName(wsPath string) string {
+	if profile := getWorkspaceProfile(wsPath); profile != nil && profile.Alias != "" {
+		return profile.Alias
+	}
+	return wsPath
+}
+
+// requireProfile exits with exitConfigError if --profile names a profile that
+// is not defined in the config file
+func requireProfile() {
+	if workspaceProfile == "" {
+		return
+	}
+	if cfg == nil || cfg.FindProfile(workspaceProfile) == nil {
+		fmt.Fprintf(os.Stderr, "Error: workspace profile %q not found in config\n", workspaceProfile)
+		exit(exitConfigError)
+	}
+}
+
+// getScannerConfig returns the effective scanner config for wsPath, with the
+// overrides of the matching workspace profile applied
+func getScannerConfig(wsPath string) config.ScannerConfig {
+	if cfg == nil {
+		return config.ScannerConfig{
+			IgnoreDirs:  []string{".git", "node_modules", "vendor"},
+			MaxDepth:    1,
+			Concurrency: 4,
+
+			KeepMissingDays: 7,
+		}
+	}
+	return cfg.EffectiveScanner(getWorkspaceProfile(wsPath))
+}
+
+// getWorkspaceProvider returns what enumerates the directories of the
+// workspace at wsPath: the manifest of its profile if it has one, or else the
+// directories under wsPath
+func getWorkspaceProvider(wsPath string, scannerCfg config.ScannerConfig) workspace.Provider {
+	if profile := getWorkspaceProfile(wsPath); profile != nil && profile.Manifest != "" {
+		manifest := profile.Manifest
+		if !filepath.IsAbs(manifest) {
+			manifest = filepath.Join(wsPath, manifest)
+		}
+		return workspace.Manifest{Path: manifest}
+	}
+	return workspace.Local{
+		Root:          wsPath,
+		IgnoreDirs:    scannerCfg.IgnoreDirs,
+		IncludeHidden: scannerCfg.IncludeHidden,
+		MaxDepth:      scannerCfg.MaxDepth,
+	}
+}
+
+// getPolicy returns the per-repository action policy for wsPath. Exits with
+// exitConfigError if the policy config is invalid.
+func getPolicy(wsPath string) *policy.Policy {
+	var rules []config.PolicyRule
+	if cfg != nil {
+		rules = cfg.Policy
+	}
+	p, err := policy.New(rules, wsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitConfigError)
+	}
+	return p
+}
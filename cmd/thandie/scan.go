@@ -1,13 +1,79 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/docker"
+	"github.com/ThandieOps/thandie-agent/internal/enrich"
+	"github.com/ThandieOps/thandie-agent/internal/filter"
+	"github.com/ThandieOps/thandie-agent/internal/groups"
+	"github.com/ThandieOps/thandie-agent/internal/hidden"
+	"github.com/ThandieOps/thandie-agent/internal/loc"
 	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/mounts"
+	"github.com/ThandieOps/thandie-agent/internal/progress"
+	"github.com/ThandieOps/thandie-agent/internal/query"
+	"github.com/ThandieOps/thandie-agent/internal/scanhooks"
 	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/search"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// scanLog logs scans under the scanner component, so that
+// logging.components can turn them up or down on their own
+var scanLog = logger.For("scanner")
+
+var (
+	// scanPlan prints the scan plan instead of scanning
+	scanPlan bool
+
+	// scanShowSkipped lists skipped directories after the scan results
+	scanShowSkipped bool
+
+	// scanEnrich fetches provider data even if enrichment is disabled in config
+	scanEnrich bool
+
+	// scanFilter limits the printed directories (see internal/filter)
+	scanFilter string
+
+	// scanLOC counts lines of code even if scanner.loc is false
+	scanLOC bool
+
+	// scanIfChanged skips metadata collection when the workspace looks unchanged
+	scanIfChanged bool
+
+	// scanHistory prints the scan history instead of scanning
+	scanHistory bool
+
+	// scanSummaryOnly prints the summary of the last scan instead of scanning
+	scanSummaryOnly bool
+
+	// scanOnly rescans only the cached directories matching this query
+	scanOnly string
+
+	// scanSkip lists directories to leave unscanned this time, keeping their last results
+	scanSkip []string
+
+	// scanFirst lists directories to scan before the others
+	scanFirst []string
+
+	// scanProgressFormat is the format of the progress stream: "" for none or jsonl
+	scanProgressFormat string
+
+	// scanProgressFile is where the progress stream goes; stderr if empty
+	scanProgressFile string
+
+	// scanProgress receives scan progress events; nil when not requested
+	scanProgress *progress.Reporter
 )
 
 // scanCmd represents: `thandie scan`
@@ -17,83 +83,133 @@ var scanCmd = &cobra.Command{
 	Long: `Scan the configured workspace directory and display the
 top-level project folders found there.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
 		// Log logging configuration status
 		if cfg != nil {
 			logPath, pathErr := logger.GetLogFilePath()
 			if pathErr == nil {
-				logger.Info("logging configuration",
+				scanLog.Info("logging configuration",
 					"level", cfg.Logging.Level,
 					"to_file", cfg.Logging.ToFile,
 					"json", cfg.Logging.JSON,
 					"log_path", logPath)
 			} else {
-				logger.Info("logging configuration",
+				scanLog.Info("logging configuration",
 					"level", cfg.Logging.Level,
 					"to_file", cfg.Logging.ToFile,
 					"json", cfg.Logging.JSON)
 			}
 		}
 
-		// Resolve workspace path using precedence: flag > env > config > default
+		// Resolve workspace path using precedence: flag > profile > env > config > default
 		wsPath := getWorkspacePath()
-		if wsPath == "" {
-			logger.Error("workspace path is empty", "hint", "use --workspace or -w to specify it")
-			os.Exit(1)
+		requireWorkspace(wsPath)
+
+		if scanHistory {
+			printScanHistory(wsPath)
+			return
+		}
+		if scanSummaryOnly {
+			printLastScan(wsPath)
+			return
 		}
 
-		logger.Info("scanning workspace", "path", wsPath)
-		logger.Debug("scanning workspace", "path", wsPath)
+		scanLog.Info("scanning workspace", "path", wsPath)
+		scanLog.Debug("scanning workspace", "path", wsPath)
 
-		// Get scanner config from global config
-		ignoreDirs := []string{".git", "node_modules", "vendor"} // default
-		includeHidden := false                                   // default
-		if cfg != nil {
-			ignoreDirs = cfg.Scanner.IgnoreDirs
-			includeHidden = cfg.Scanner.IncludeHidden
+		// Get scanner config from global config and the workspace profile
+		scannerCfg := getScannerConfig(wsPath)
+		scanSkip = resolveScanDirs(wsPath, "--skip", scanSkip)
+		scanFirst = resolveScanDirs(wsPath, "--first", scanFirst)
+
+		if scanPlan {
+			source := getWorkspaceProvider(wsPath, scannerCfg)
+			plan, err := source.Plan()
+			if err != nil {
+				scanLog.Error("failed to scan workspace", "error", err, "path", wsPath)
+				exit(exitScanFailure)
+			}
+			scanner.Skip(plan, scanSkip)
+			printScanPlan(source.Describe(), plan)
+			return
 		}
 
-		logger.Info("scanner configuration",
-			"ignore_dirs", ignoreDirs,
-			"include_hidden", includeHidden)
+		if scanLOC {
+			scannerCfg.LOC = true
+		}
 
-		// Scan directories with metadata collection
-		dirInfos, err := scanner.ScanDirectoriesWithMetadata(wsPath, ignoreDirs, includeHidden)
-		if err != nil {
-			logger.Error("failed to scan workspace", "error", err, "path", wsPath)
-			os.Exit(1)
+		switch scanProgressFormat {
+		case "":
+		case "jsonl":
+			out := os.Stderr
+			if scanProgressFile != "" {
+				f, err := os.Create(scanProgressFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: failed to create progress file: %v\n", err)
+					exit(exitError)
+				}
+				defer f.Close()
+				out = f
+			}
+			scanProgress = progress.New(out)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown --progress-format %q (expected jsonl)\n", scanProgressFormat)
+			exit(exitError)
 		}
 
-		logger.Info("scan completed", "directories_found", len(dirInfos))
+		if scanOnly != "" {
+			q, err := query.Parse(scanOnly)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --only query: %v\n", err)
+				exit(exitError)
+			}
+			_, refreshed, err := rescanMatching(wsPath, scannerCfg, q)
+			if err != nil {
+				scanProgress.Error(err)
+				scanLog.Error("failed to scan workspace", "error", err, "path", wsPath)
+				exit(exitScanFailure)
+			}
+			if len(refreshed) == 0 {
+				fmt.Printf("No cached directories match %s\n", scanOnly)
+				return
+			}
+			fmt.Printf("Rescanned %d directories in %s:\n", len(refreshed), workspaceName(wsPath))
+			g := getGroups(wsPath)
+			for _, info := range refreshed {
+				fmt.Println(directoryLine(info, g))
+			}
+			printScanErrors(refreshed)
+			return
+		}
 
-		// Save scan results with metadata to cache
-		cacheInstance, err := cache.New()
+		result, err := scanWorkspace(wsPath, scannerCfg)
 		if err != nil {
-			logger.Warn("failed to initialize cache", "error", err)
-		} else {
-			if err := cacheInstance.SaveScanResultWithMetadata(wsPath, dirInfos); err != nil {
-				logger.Warn("failed to save scan results to cache", "error", err)
-			} else {
-				logger.Info("scan results cached", "count", len(dirInfos), "cache_dir", cacheInstance.GetCacheDir())
-				logger.Debug("scan results cached", "count", len(dirInfos), "cache_dir", cacheInstance.GetCacheDir())
-			}
+			scanProgress.Error(err)
+			scanLog.Error("failed to scan workspace", "error", err, "path", wsPath)
+			exit(exitScanFailure)
 		}
+		dirInfos, skipped := result.DirectoryInfos, result.Skipped
 
 		if len(dirInfos) == 0 {
-			fmt.Printf("No top-level directories found in %s\n", wsPath)
+			fmt.Printf("No top-level directories found in %s\n", workspaceName(wsPath))
+			printMissing(result.Missing)
+			if scanShowSkipped {
+				printSkipped(skipped)
+			}
 			return
 		}
 
-		fmt.Printf("Top-level directories in %s:\n", wsPath)
-		for _, info := range dirInfos {
-			output := " - " + info.Path
-			if info.GitMetadata != nil && info.GitMetadata.IsGitRepo {
-				output += " [git: " + info.GitMetadata.CurrentBranch
-				if info.GitMetadata.HasUncommitted {
-					output += " *"
-				}
-				output += "]"
-			}
-			fmt.Println(output)
+		dirFilter := parseFilter(wsPath, scanFilter)
+		visible, hiddenInfos := hidden.Load(wsPath).Split(dirFilter.Apply(dirInfos))
+		printDirectories(wsPath, visible)
+		printMissing(matchingMissing(result.Missing, dirFilter))
+		printHiddenCount(len(hiddenInfos))
+		printScanErrors(dirInfos)
+
+		if scanShowSkipped {
+			printSkipped(skipped)
 		}
 	},
 }
@@ -104,4 +220,713 @@ func init() {
 
 	// If you want flags specific to scan, add them here:
 	// scanCmd.Flags().Bool("json", false, "Output results as JSON")
+	scanCmd.Flags().BoolVar(&scanPlan, "plan", false, "Print which directories would be scanned or skipped (and why) without scanning")
+	scanCmd.Flags().BoolVar(&scanShowSkipped, "show-skipped", false, "List directories excluded from the scan with the rule that excluded each")
+	scanCmd.Flags().BoolVar(&scanEnrich, "enrich", false, "Fetch repository data (CI status) from providers, even if enrichment.enabled is false")
+	scanCmd.Flags().StringVar(&scanFilter, "filter", "", "Only print directories matching the filter, e.g. 'ci:failing dirty:true'")
+	scanCmd.Flags().BoolVar(&scanLOC, "loc", false, "Count lines of code per language, even if scanner.loc is false")
+	scanCmd.Flags().BoolVar(&scanIfChanged, "if-changed", false, "Reuse the last scan if directory and git index mtimes are unchanged (full scan at least every scanner.full_scan_interval)")
+	scanCmd.Flags().BoolVar(&scanHistory, "history", false, "Print recent scans of the workspace, including skipped ones, instead of scanning")
+	scanCmd.Flags().BoolVar(&scanSummaryOnly, "summary-only", false, "Print the counts, duration and errors of the last scan instead of scanning")
+	scanCmd.Flags().StringVar(&scanOnly, "only", "", "Rescan only the cached directories matching a query, e.g. 'dirty || behind > 0' (see thandie query), keeping the rest of the cache")
+	scanCmd.Flags().StringArrayVar(&scanSkip, "skip", nil, "Leave this directory (relative to the workspace) and those below it unscanned, keeping their last results; repeatable")
+	scanCmd.Flags().StringArrayVar(&scanFirst, "first", nil, "Scan this directory (relative to the workspace) and those below it before the others; repeatable")
+	scanCmd.Flags().StringVar(&scanProgressFormat, "progress-format", "", "Write progress events in this format (jsonl: one JSON object per line) to stderr or --progress-file")
+	scanCmd.Flags().StringVar(&scanProgressFile, "progress-file", "", "Write the progress stream to this file instead of stderr")
+}
+
+// scanWorkspace scans wsPath with the given scanner config and saves the
+// result to the cache. Cache failures are logged but don't fail the scan.
+// With --if-changed, metadata collection is skipped when the workspace
+// fingerprint matches the previous full scan.
+func scanWorkspace(wsPath string, scannerCfg config.ScannerConfig) (*cache.ScanResult, error) {
+	scanLog.Info("scanner configuration",
+		"ignore_dirs", scannerCfg.IgnoreDirs,
+		"include_hidden", scannerCfg.IncludeHidden,
+		"max_depth", scannerCfg.MaxDepth,
+		"concurrency", scannerCfg.Concurrency,
+		"loc", scannerCfg.LOC)
+
+	runScanHook(scanhooks.Payload{Event: scanhooks.PreScan, Workspace: wsPath, Time: time.Now()})
+
+	ctx, span := tracing.Start(tracing.Context(), "scan", attribute.String("workspace", wsPath))
+	defer span.End()
+
+	start := time.Now()
+	_, planSpan := tracing.Start(ctx, "scan.plan")
+	plan, err := getWorkspaceProvider(wsPath, scannerCfg).Plan()
+	planSpan.End()
+	if err != nil {
+		return nil, err
+	}
+	scanner.Skip(plan, scanSkip)
+	logNetworkDirs(plan)
+	cacheInstance, err := cache.New()
+	if err != nil {
+		scanLog.Warn("failed to initialize cache", "error", err)
+	}
+	var prev *cache.ScanResult
+	if cacheInstance != nil {
+		_, loadSpan := tracing.Start(ctx, "cache.load")
+		prev, _ = cacheInstance.LoadScanResult(wsPath)
+		loadSpan.End()
+	}
+	var previous []scanner.DirectoryInfo
+	if prev != nil {
+		previous = prev.DirectoryInfos
+	}
+
+	fingerprint := scanner.Fingerprint(wsPath, plan)
+	settings := scannerCfg.SettingsHash()
+	sameSettings := prev == nil || prev.Settings == "" || prev.Settings == settings
+	if !sameSettings {
+		scanLog.Warn("cache built with different scanner settings, replacing it with a full scan", "path", wsPath)
+	}
+	var result *cache.ScanResult
+	status := cache.ScanFull
+	if scanIfChanged && prev != nil && sameSettings && prev.Fingerprint == fingerprint && time.Since(prev.FullScanAt) < scannerCfg.FullScanIntervalDuration() {
+		status = cache.ScanUnchanged
+		scanProgress.Start(wsPath, nil, 0)
+		result = prev
+		result.ScannedAt = time.Now()
+		scanLog.Info("workspace unchanged since last full scan, skipping metadata collection", "last_full_scan", prev.FullScanAt)
+	} else {
+		// Scan directories with metadata collection
+		queue := scanner.Queue(plan, previous, scanFirst)
+		var done func(string)
+		if scanProgress != nil {
+			scanProgress.Start(wsPath, queue, len(queue))
+			scanProgress.Phase("directories")
+			done = scanProgress.Directory
+		}
+		result = &cache.ScanResult{
+			WorkspacePath:  wsPath,
+			DirectoryInfos: scanner.ScanPlanned(ctx, plan, queue, scannerCfg.Concurrency, scannerCfg.DirTimeoutDuration(), previous, done),
+			Skipped:        scanner.Skipped(plan),
+			Fingerprint:    fingerprint,
+			Settings:       settings,
+			FullScanAt:     time.Now(),
+		}
+		if prev != nil {
+			result.History = prev.History
+		}
+		scanLog.Info("scan completed", "directories_found", len(result.DirectoryInfos), "directories_skipped", len(result.Skipped))
+		warnScanErrors(result.DirectoryInfos)
+		carryOverResults(result.DirectoryInfos, previous)
+	}
+
+	scanProgress.Phase("containers")
+	_, containerSpan := tracing.Start(ctx, "scan.containers")
+	correlateContainers(result.DirectoryInfos)
+	containerSpan.End()
+
+	scanProgress.Phase("files")
+	_, filesSpan := tracing.Start(ctx, "scan.files")
+	search.Index(result.DirectoryInfos, scannerCfg.Concurrency)
+	filesSpan.End()
+
+	if scannerCfg.LOC {
+		scanProgress.Phase("loc")
+		_, locSpan := tracing.Start(ctx, "scan.loc")
+		loc.Collect(result.DirectoryInfos, previous, scannerCfg.Concurrency)
+		locSpan.End()
+	}
+
+	if scanEnrich || (cfg != nil && cfg.Enrichment.Enabled) {
+		scanProgress.Phase("enrich")
+		enrichCtx, enrichSpan := tracing.Start(ctx, "scan.enrich")
+		enrichScanResult(enrichCtx, result.DirectoryInfos, previous, scannerCfg.Concurrency)
+		enrichSpan.End()
+	}
+	keepMissing := time.Duration(scannerCfg.KeepMissingDays) * 24 * time.Hour
+	for _, dir := range result.TrackMissing(prev, keepMissing, time.Now()) {
+		scanLog.Warn("previously scanned directory no longer exists", "path", dir.Info.Path)
+	}
+	span.SetAttributes(attribute.String("status", status), attribute.Int("directories", len(result.DirectoryInfos)))
+
+	if dirty := scanhooks.NewlyDirty(result.DirectoryInfos, previous); len(dirty) > 0 && status == cache.ScanFull {
+		runScanHook(scanhooks.Payload{Event: scanhooks.DirtyFound, Workspace: wsPath, Time: time.Now(), Directories: dirty})
+	}
+
+	counts := scanCounts(result)
+	result.Record(cache.ScanRecord{
+		At:           start,
+		Status:       status,
+		Duration:     time.Since(start),
+		Directories:  len(result.DirectoryInfos),
+		Dirty:        counts.Dirty,
+		Missing:      counts.Missing,
+		Errored:      counts.Errored,
+		AccessDenied: counts.AccessDenied,
+	})
+
+	// Save scan results with metadata to cache
+	if cacheInstance != nil {
+		scanProgress.Phase("save")
+		_, saveSpan := tracing.Start(ctx, "cache.save")
+		err = cacheInstance.Save(result)
+		saveSpan.End()
+		if err != nil {
+			scanLog.Warn("failed to save scan results to cache", "error", err)
+		} else {
+			scanLog.Info("scan results cached", "count", len(result.DirectoryInfos), "cache_dir", cacheInstance.GetCacheDir())
+			scanLog.Debug("scan results cached", "count", len(result.DirectoryInfos), "cache_dir", cacheInstance.GetCacheDir())
+			autoCollectCacheGarbage(cacheInstance)
+		}
+	}
+
+	scanProgress.Done(status, len(result.DirectoryInfos))
+	runScanHook(scanhooks.Payload{
+		Event:      scanhooks.PostScan,
+		Workspace:  wsPath,
+		Time:       time.Now(),
+		Status:     status,
+		DurationMS: time.Since(start).Milliseconds(),
+		Counts:     counts,
+	})
+
+	return result, nil
+}
+
+// rescanMatching refreshes the cached directories of wsPath that q matches
+// and saves them back into the cache, leaving the other directories as they
+// were. q is evaluated against the cached state, so it can't pick up a
+// repository that changed since; a full scan does. Without a cached scan the
+// whole workspace is scanned. Returns the updated result and the refreshed
+// directories.
+func rescanMatching(wsPath string, scannerCfg config.ScannerConfig, q *query.Query) (*cache.ScanResult, []scanner.DirectoryInfo, error) {
+	cacheInstance, err := cache.New()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize cache: %w", err)
+	}
+	result, err := cacheInstance.LoadScanResult(wsPath)
+	if err != nil {
+		scanLog.Info("no cached scan result, scanning workspace", "path", wsPath)
+		result, err := scanWorkspace(wsPath, scannerCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result, result.DirectoryInfos, nil
+	}
+	matched, err := q.Apply(result.DirectoryInfos)
+	if err != nil {
+		return nil, nil, err
+	}
+	var plan []scanner.PlanEntry
+	table, _ := mounts.Load()
+	for _, info := range matched {
+		if _, err := os.Stat(info.Path); err != nil {
+			scanLog.Warn("directory no longer exists, run a full scan to track it", "path", info.Path)
+			continue
+		}
+		if slices.ContainsFunc(scanSkip, func(dir string) bool { return within(info.Path, dir) }) {
+			continue // Left as cached
+		}
+		plan = append(plan, scanner.PlanEntry{Path: info.Path, Scan: true, Network: table.Network(info.Path)})
+	}
+	if len(plan) == 0 {
+		return result, nil, nil
+	}
+
+	runScanHook(scanhooks.Payload{Event: scanhooks.PreScan, Workspace: wsPath, Time: time.Now()})
+	ctx, span := tracing.Start(tracing.Context(), "scan", attribute.String("workspace", wsPath), attribute.String("only", scanOnly))
+	defer span.End()
+	start := time.Now()
+	previous := result.DirectoryInfos
+	queue := scanner.Queue(plan, previous, scanFirst)
+	var done func(string)
+	if scanProgress != nil {
+		scanProgress.Start(wsPath, queue, len(queue))
+		scanProgress.Phase("directories")
+		done = scanProgress.Directory
+	}
+	refreshed := scanner.ScanPlanned(ctx, plan, queue, scannerCfg.Concurrency, scannerCfg.DirTimeoutDuration(), previous, done)
+	warnScanErrors(refreshed)
+	carryOverResults(refreshed, previous)
+
+	scanProgress.Phase("containers")
+	correlateContainers(refreshed)
+	scanProgress.Phase("files")
+	search.Index(refreshed, scannerCfg.Concurrency)
+	if scannerCfg.LOC {
+		scanProgress.Phase("loc")
+		loc.Collect(refreshed, previous, scannerCfg.Concurrency)
+	}
+	if scanEnrich || (cfg != nil && cfg.Enrichment.Enabled) {
+		scanProgress.Phase("enrich")
+		enrichScanResult(ctx, refreshed, previous, scannerCfg.Concurrency)
+	}
+
+	if dirty := scanhooks.NewlyDirty(refreshed, previous); len(dirty) > 0 {
+		runScanHook(scanhooks.Payload{Event: scanhooks.DirtyFound, Workspace: wsPath, Time: time.Now(), Directories: dirty})
+	}
+
+	// Merge into a copy so previous still holds the old state for the checks above
+	byPath := make(map[string]scanner.DirectoryInfo, len(refreshed))
+	for _, info := range refreshed {
+		byPath[info.Path] = info
+	}
+	infos := make([]scanner.DirectoryInfo, len(previous))
+	for i, info := range previous {
+		if updated, ok := byPath[info.Path]; ok {
+			info = updated
+		}
+		infos[i] = info
+	}
+	result.DirectoryInfos = infos
+	result.ScannedAt = time.Now()
+	counts := scanCounts(result)
+	result.Record(cache.ScanRecord{
+		At:           start,
+		Status:       cache.ScanPartial,
+		Duration:     time.Since(start),
+		Directories:  len(refreshed),
+		Dirty:        counts.Dirty,
+		Missing:      counts.Missing,
+		Errored:      counts.Errored,
+		AccessDenied: counts.AccessDenied,
+	})
+
+	scanProgress.Phase("save")
+	if err := cacheInstance.Save(result); err != nil {
+		scanLog.Warn("failed to save scan results to cache", "error", err)
+	}
+	scanProgress.Done(cache.ScanPartial, len(refreshed))
+	runScanHook(scanhooks.Payload{
+		Event:      scanhooks.PostScan,
+		Workspace:  wsPath,
+		Time:       time.Now(),
+		Status:     cache.ScanPartial,
+		DurationMS: time.Since(start).Milliseconds(),
+		Counts:     counts,
+	})
+	return result, refreshed, nil
+}
+
+// scanCounts summarizes a scan result for post_scan hooks
+func scanCounts(result *cache.ScanResult) *scanhooks.Counts {
+	counts := &scanhooks.Counts{Directories: len(result.DirectoryInfos), Missing: len(result.Missing)}
+	for _, info := range result.DirectoryInfos {
+		if info.GitMetadata != nil && info.GitMetadata.HasUncommitted {
+			counts.Dirty++
+		}
+		if info.ScanError != "" {
+			counts.Errored++
+		}
+		if info.AccessDenied {
+			counts.AccessDenied++
+		}
+	}
+	return counts
+}
+
+// runScanHook runs the configured hook for a scan event, logging failures.
+// Hooks are skipped in read-only mode, as they may change anything.
+func runScanHook(payload scanhooks.Payload) {
+	if cfg == nil || scanhooks.Command(cfg.Hooks, payload.Event) == "" {
+		return
+	}
+	if readOnly() {
+		scanLog.Debug("skipping scan hook in read-only mode", "event", payload.Event)
+		return
+	}
+	if name := workspaceName(payload.Workspace); name != payload.Workspace {
+		payload.Alias = name
+	}
+	if err := scanhooks.Run(cfg.Hooks, payload); err != nil {
+		scanLog.Warn("scan hook failed", "event", payload.Event, "error", err)
+	}
+}
+
+// logNetworkDirs notes the directories of plan on network filesystems, which
+// are scanned without git status
+func logNetworkDirs(plan []scanner.PlanEntry) {
+	count := 0
+	for _, entry := range plan {
+		if entry.Network != "" {
+			count++
+		}
+	}
+	if count > 0 {
+		scanLog.Info("scanning directories on network filesystems without git status", "count", count)
+	}
+}
+
+// warnScanErrors logs each directory whose metadata collection was abandoned
+func warnScanErrors(infos []scanner.DirectoryInfo) {
+	for _, info := range infos {
+		if info.ScanError != "" {
+			scanLog.Warn("directory scan abandoned", "path", info.Path, "reason", info.ScanError)
+		}
+	}
+}
+
+// carryOverResults copies results that scanning doesn't recompute (audits,
+// test runs, remote checks of unchanged remotes, Terraform drift checks and
+// how long a repository has been dirty) from a previous scan onto the matching directories of a new one
+func carryOverResults(infos, previous []scanner.DirectoryInfo) {
+	prior := make(map[string]scanner.DirectoryInfo, len(previous))
+	for _, info := range previous {
+		prior[info.Path] = info
+	}
+
+	for i := range infos {
+		info := &infos[i]
+		old, ok := prior[info.Path]
+		if !ok {
+			continue
+		}
+		info.Audit = old.Audit
+		info.Tests = old.Tests
+		if git := info.GitMetadata; git != nil && old.Remote != nil && old.Remote.URL == git.RemoteURL {
+			info.Remote = old.Remote
+		}
+
+		// Keep the time a repository first became dirty while it stays dirty
+		if git := info.GitMetadata; git != nil && git.HasUncommitted && old.GitMetadata != nil && old.GitMetadata.HasUncommitted && !old.GitMetadata.DirtySince.IsZero() {
+			git.DirtySince = old.GitMetadata.DirtySince
+		}
+
+		if info.Extras == nil || info.Extras.Terraform == nil || old.Extras == nil || old.Extras.Terraform == nil {
+			continue
+		}
+		checks := make(map[string]scanner.TerraformModule)
+		for _, module := range old.Extras.Terraform.Modules {
+			checks[module.Path] = module
+		}
+		for j := range info.Extras.Terraform.Modules {
+			module := &info.Extras.Terraform.Modules[j]
+			if check, ok := checks[module.Path]; ok {
+				module.DriftCheckedAt = check.DriftCheckedAt
+				module.Drift = check.Drift
+				module.DriftError = check.DriftError
+			}
+		}
+	}
+}
+
+// printScanHistory prints the recorded scans of wsPath, newest first
+func printScanHistory(wsPath string) {
+	cacheInstance, err := cache.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitError)
+	}
+	result, err := cacheInstance.LoadScanResult(wsPath)
+	if err != nil || len(result.History) == 0 {
+		fmt.Printf("No scan history for %s\n", workspaceName(wsPath))
+		return
+	}
+	for i := len(result.History) - 1; i >= 0; i-- {
+		record := result.History[i]
+		fmt.Printf("  %s  %-20s %4d dirs  %s%s\n",
+			record.At.Local().Format("2006-01-02 15:04:05"), record.Status, record.Directories, record.Duration.Round(time.Millisecond), scanProblems(record))
+	}
+}
+
+// printLastScan prints the summary of the last recorded scan of wsPath
+func printLastScan(wsPath string) {
+	cacheInstance, err := cache.New()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(exitError)
+	}
+	result, err := cacheInstance.LoadScanResult(wsPath)
+	if err != nil {
+		fmt.Printf("%s has not been scanned yet (run 'thandie scan').\n", workspaceName(wsPath))
+		return
+	}
+	record, ok := result.LastScan()
+	if !ok {
+		fmt.Printf("No scan summary for %s; the next scan records one.\n", workspaceName(wsPath))
+		return
+	}
+	fmt.Printf("Last scan of %s: %s\n", workspaceName(wsPath), formatAge(record.At))
+	fmt.Printf("  %s\n", scanSummary(record))
+}
+
+// scanSummary describes a recorded scan in one line, e.g. "full scan of 12
+// directories in 1.2s, 3 dirty, 1 could not be scanned"
+func scanSummary(record cache.ScanRecord) string {
+	return fmt.Sprintf("%s scan of %d directories in %s, %d dirty%s",
+		record.Status, record.Directories, record.Duration.Round(time.Millisecond), record.Dirty, scanProblems(record))
+}
+
+// scanProblems lists the missing, errored and unreadable directories of a
+// recorded scan, each preceded by a comma, or "" if there were none
+func scanProblems(record cache.ScanRecord) string {
+	var problems string
+	if record.Missing > 0 {
+		problems += ", " + colorize(fmt.Sprintf("%d missing", record.Missing), colorYellow)
+	}
+	if record.Errored > 0 {
+		problems += ", " + colorize(fmt.Sprintf("%d could not be scanned", record.Errored), colorRed)
+	}
+	if record.AccessDenied > 0 {
+		problems += ", " + colorize(fmt.Sprintf("%d permission denied", record.AccessDenied), colorYellow)
+	}
+	return problems
+}
+
+// correlateContainers marks directories with running containers, when a
+// Docker socket is available
+func correlateContainers(infos []scanner.DirectoryInfo) {
+	socketPath := docker.SocketPath()
+	if socketPath == "" {
+		return
+	}
+	containers, err := docker.RunningContainers(context.Background(), socketPath)
+	if err != nil {
+		scanLog.Debug("docker unavailable, skipping container correlation", "socket", socketPath, "error", err)
+		return
+	}
+	docker.Correlate(infos, containers)
+}
+
+// enrichScanResult fetches provider data for the scanned repositories,
+// reusing enrichment from the previous cached scan that is still within the TTL
+func enrichScanResult(ctx context.Context, infos, previous []scanner.DirectoryInfo, concurrency int) {
+	if cfg == nil {
+		return
+	}
+
+	enricher, err := enrich.New(cfg, concurrency)
+	if err != nil {
+		scanLog.Warn("failed to initialize enrichment", "error", err)
+		return
+	}
+
+	enricher.Enrich(ctx, infos, previous)
+}
+
+// loadScanResult returns the cached scan result for wsPath, scanning the
+// workspace first if nothing is cached yet. Exits with exitScanFailure if
+// neither works.
+func loadScanResult(wsPath string) *cache.ScanResult {
+	cacheInstance, err := cache.New()
+	if err == nil {
+		_, span := tracing.Start(tracing.Context(), "cache.load", attribute.String("workspace", wsPath))
+		result, err := cacheInstance.LoadScanResult(wsPath)
+		span.End()
+		if err == nil {
+			warnCacheSettings(wsPath, result)
+			return result
+		}
+	}
+
+	scanLog.Info("no cached scan result, scanning workspace", "path", wsPath)
+	result, err := scanWorkspace(wsPath, getScannerConfig(wsPath))
+	if err != nil {
+		scanLog.Error("failed to scan workspace", "error", err, "path", wsPath)
+		exit(exitScanFailure)
+	}
+	return result
+}
+
+// warnCacheSettings tells on stderr when the cached result of wsPath was
+// built with other scanner settings, see cacheSettingsChanged
+func warnCacheSettings(wsPath string, result *cache.ScanResult) {
+	if cacheSettingsChanged(wsPath, result) {
+		fmt.Fprintln(os.Stderr, colorize("Note: cache built with different settings (ignore_dirs, include_hidden or max_depth); run 'thandie scan' to rebuild it", colorYellow))
+	}
+}
+
+// cacheSettingsChanged reports whether the cached result of wsPath was built
+// with scanner settings other than the current ones, so it may list
+// directories they'd ignore or miss ones they'd find. Caches written before
+// the settings were recorded count as unchanged.
+func cacheSettingsChanged(wsPath string, result *cache.ScanResult) bool {
+	return result.Settings != "" && result.Settings != getScannerConfig(wsPath).SettingsHash()
+}
+
+// printDirectories prints one line per directory with its git state and, when
+// enriched, the CI status of its default branch
+func printDirectories(wsPath string, infos []scanner.DirectoryInfo) {
+	if len(infos) == 0 {
+		fmt.Printf("No matching directories in %s\n", workspaceName(wsPath))
+		return
+	}
+
+	g := getGroups(wsPath)
+	fmt.Printf("Top-level directories in %s:\n", workspaceName(wsPath))
+	for _, info := range infos {
+		printDirectoryLine(info, g)
+	}
+}
+
+// printScanErrors counts the directories that couldn't be scanned or read
+// and tells how to fix them
+func printScanErrors(infos []scanner.DirectoryInfo) {
+	errored, denied := 0, 0
+	for _, info := range infos {
+		switch {
+		case info.ScanError != "":
+			errored++
+		case info.AccessDenied:
+			denied++
+		}
+	}
+	if errored > 0 {
+		fmt.Printf("\n%s\n", colorize(fmt.Sprintf("%d of %d directories could not be scanned; see 'thandie show <dir>' for why.", errored, len(infos)), colorRed))
+		fmt.Println("Retry them with: thandie scan --only 'scan_error != \"\"'")
+	}
+	if denied > 0 {
+		fmt.Printf("\n%s\n", colorize(fmt.Sprintf("%d of %d directories could not be read (permission denied).", denied, len(infos)), colorYellow))
+		fmt.Println("Grant yourself access (e.g. chmod u+rx), or add them to scanner.ignore_dirs; list them with: thandie query access_denied")
+	}
+}
+
+// printMissing lists the previously scanned directories that have
+// disappeared, so an accidental deletion doesn't go unnoticed
+func printMissing(missing []cache.MissingDir) {
+	if len(missing) == 0 {
+		return
+	}
+	fmt.Printf("\nMissing since earlier scans (%d):\n", len(missing))
+	for _, dir := range missing {
+		fmt.Println(missingLine(dir))
+	}
+}
+
+// missingLine formats a missing directory with its last known state
+func missingLine(dir cache.MissingDir) string {
+	return directoryLine(dir.Info, nil) + " " + colorize("missing since "+formatAge(dir.Since), colorRed)
+}
+
+// matchingMissing returns the missing directories whose last known state
+// matches dirFilter
+func matchingMissing(missing []cache.MissingDir, dirFilter *filter.Filter) []cache.MissingDir {
+	var matched []cache.MissingDir
+	for _, dir := range missing {
+		if dirFilter.Match(dir.Info) {
+			matched = append(matched, dir)
+		}
+	}
+	return matched
+}
+
+// directoryLine formats a directory with its git state and badges
+func directoryLine(info scanner.DirectoryInfo, g *groups.Groups) string {
+	output := " - " + info.Path
+	if info.ScanError != "" {
+		output += " " + colorize("✗ scan "+info.ScanError, colorRed)
+	}
+	if info.AccessDenied {
+		output += " " + colorize("✗ access denied", colorYellow)
+	}
+	if info.Unscanned {
+		output += " " + colorize("not scanned (--skip)", colorGray)
+	}
+	if info.GitMetadata != nil && info.GitMetadata.IsGitRepo {
+		output += " [git: " + info.GitMetadata.CurrentBranch
+		if info.Network == "" {
+			output += dirtyMarker(info.GitMetadata)
+		} else {
+			output += " " + colorize("status?", colorYellow)
+		}
+		if info.GitMetadata.Sparse != nil {
+			output += " " + colorize("sparse", colorGray)
+		}
+		if info.GitMetadata.Ahead > 0 {
+			output += fmt.Sprintf(" ↑%d", info.GitMetadata.Ahead)
+		}
+		if info.GitMetadata.Behind > 0 {
+			output += fmt.Sprintf(" ↓%d", info.GitMetadata.Behind)
+		}
+		output += "]"
+		if text := inProgressText(info.GitMetadata); text != "" {
+			output += " " + colorize("⚠ "+text, colorRed)
+		}
+	}
+	if info.Enrichment != nil && info.Enrichment.CI != "" {
+		output += " " + ciGlyph(info.Enrichment.CI)
+	}
+	if info.Tests != nil {
+		output += " " + testBadge(info.Tests)
+	}
+	if info.Docker != nil {
+		output += " " + dockerBadge(info.Docker)
+	}
+	if info.Network != "" {
+		output += " " + colorize("~"+info.Network, colorGray)
+	}
+	if info.Extras != nil && info.Extras.Terraform.HighRisk() {
+		output += " " + colorize("⚠ tfstate", colorRed)
+	}
+	if info.Remote.NeedsAttention() {
+		output += " " + colorize("remote "+info.Remote.State, colorRed)
+	}
+	if badge := emailBadge(info, g); badge != "" {
+		output += " " + badge
+	}
+	return output
+}
+
+// emailBadge flags a repository whose user.email is outside the email
+// domains its groups expect, or "" if it matches or g is nil
+func emailBadge(info scanner.DirectoryInfo, g *groups.Groups) string {
+	if g == nil || info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+		return ""
+	}
+	domains := g.EmailDomains(info.Path)
+	if len(domains) == 0 || groups.EmailMatches(info.GitMetadata.UserEmail, domains) {
+		return ""
+	}
+	email := info.GitMetadata.UserEmail
+	if email == "" {
+		email = "unset"
+	}
+	return colorize("⚠ user.email "+email, colorYellow)
+}
+
+// resolveScanDirs resolves the directories given to flag, relative to the
+// workspace or absolute, exiting if one isn't a directory
+func resolveScanDirs(wsPath, flag string, dirs []string) []string {
+	resolved := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(wsPath, dir)
+		}
+		if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: %s %s is not a directory\n", flag, dir)
+			exit(exitError)
+		}
+		resolved = append(resolved, filepath.Clean(dir))
+	}
+	return resolved
+}
+
+// printScanPlan prints the scan plan, one directory per line, followed by totals
+func printScanPlan(source string, plan []scanner.PlanEntry) {
+	fmt.Printf("Scan plan for %s:\n", source)
+
+	scanned, skipped := 0, 0
+	for _, entry := range plan {
+		if entry.Scan {
+			scanned++
+			if entry.Network != "" {
+				fmt.Printf("  light %s (%s: no git status)\n", entry.Path, entry.Network)
+				continue
+			}
+			fmt.Printf("  scan  %s\n", entry.Path)
+			continue
+		}
+		skipped++
+		fmt.Printf("  skip  %s (%s: %s)\n", entry.Path, entry.Reason, entry.Rule)
+	}
+
+	fmt.Printf("\n%d to scan, %d skipped\n", scanned, skipped)
+}
+
+// printSkipped prints the directories excluded from a scan and the rule that excluded each
+func printSkipped(skipped []scanner.PlanEntry) {
+	if len(skipped) == 0 {
+		fmt.Println("\nNo directories were skipped")
+		return
+	}
+
+	fmt.Printf("\nSkipped (%d):\n", len(skipped))
+	for _, entry := range skipped {
+		fmt.Printf(" - %s (%s: %s)\n", entry.Path, entry.Reason, entry.Rule)
+	}
 }
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// secretsCmd represents: `thandie secrets`
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage tokens stored in the OS keychain",
+	Long: `Manage sync and provider tokens stored in the OS keychain.
+
+Secrets are stored under an alias and referenced from the config file
+instead of writing the token in plaintext, e.g.:
+
+  token: keychain:github`,
+}
+
+// secretsSetCmd represents: `thandie secrets set <alias>`
+var secretsSetCmd = &cobra.Command{
+	Use:   "set <alias>",
+	Short: "Store a secret in the OS keychain",
+	Long: `Store a secret in the OS keychain under the given alias.
+The value is read from the terminal without echo, or from stdin when piped.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("storing secrets")
+		alias := args[0]
+
+		value, err := readSecret(fmt.Sprintf("Value for %s: ", alias))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading secret: %v\n", err)
+			exit(exitError)
+		}
+		if value == "" {
+			fmt.Fprintln(os.Stderr, "Error: secret value must not be empty")
+			exit(exitError)
+		}
+
+		err = secrets.Set(alias, value)
+		auditlog.Write("secrets.set", alias, "", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		fmt.Printf("✓ Secret stored. Reference it from config as: %s\n", secrets.Ref(alias))
+	},
+}
+
+// secretsGetCmd represents: `thandie secrets get <alias>`
+var secretsGetCmd = &cobra.Command{
+	Use:   "get <alias>",
+	Short: "Print a secret stored in the OS keychain",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := secrets.Get(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if errors.Is(err, secrets.ErrNotFound) {
+				exit(exitConfigError)
+			}
+			exit(exitError)
+		}
+		fmt.Println(value)
+	},
+}
+
+// secretsRmCmd represents: `thandie secrets rm <alias>`
+var secretsRmCmd = &cobra.Command{
+	Use:   "rm <alias>",
+	Short: "Remove a secret from the OS keychain",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("removing secrets")
+		err := secrets.Delete(args[0])
+		auditlog.Write("secrets.delete", args[0], "", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if errors.Is(err, secrets.ErrNotFound) {
+				exit(exitConfigError)
+			}
+			exit(exitError)
+		}
+		fmt.Printf("✓ Secret %s removed\n", args[0])
+	},
+}
+
+func init() {
+	// Attach the `secrets` command and its subcommands: thandie secrets set|get|rm
+	secretsCmd.AddCommand(secretsSetCmd, secretsGetCmd, secretsRmCmd)
+	rootCmd.AddCommand(secretsCmd)
+}
+
+// readSecret reads a secret from the terminal without echo, or a single line
+// from stdin when it is not a terminal
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		value, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
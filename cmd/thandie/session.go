@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/groups"
+	"github.com/ThandieOps/thandie-agent/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// sessionDryRun prints the multiplexer commands instead of running them
+	sessionDryRun bool
+)
+
+// sessionCmd represents: `thandie session`
+var sessionCmd = &cobra.Command{
+	Use:   "session <repo>",
+	Short: "Open a tmux or zellij session for a repository",
+	Long: `Attach to the tmux (or zellij, with session.launcher: zellij) session of a
+repository, named after its directory, creating it first if needed with the
+windows of the matching template under session.templates:
+
+  session:
+    templates:
+      - name: backend
+        groups: [Platform]       # and/or paths: [api, "services/*"]
+        windows:
+          - name: editor
+            command: $EDITOR .
+          - name: shell
+          - name: logs
+            command: docker compose logs -f
+
+The first template whose paths or groups match the repository is used, then
+the template named "default"; without one the session has a single shell.
+<repo> is a directory name or a path relative to the workspace.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		dir := findRepo(wsPath, args[0])
+		template, err := sessionTemplate(wsPath, dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+		var windows []config.SessionWindow
+		if template != nil {
+			windows = template.Windows
+		}
+		s, err := session.New(cfg.Session.Launcher, dir, windows)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitConfigError)
+		}
+		if err := s.Open(os.Stdout, sessionDryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+	},
+}
+
+// findRepo returns the path of the directory of the last scan named name,
+// either by its path relative to the workspace or by its directory name.
+// Exits with exitError if there is no such directory or several.
+func findRepo(wsPath, name string) string {
+	var byBase []string
+	for _, info := range loadScanResult(wsPath).DirectoryInfos {
+		if rel, err := filepath.Rel(wsPath, info.Path); err == nil && rel == filepath.Clean(name) {
+			return info.Path
+		}
+		if filepath.Base(info.Path) == name {
+			byBase = append(byBase, info.Path)
+		}
+	}
+	switch len(byBase) {
+	case 0:
+		fmt.Fprintf(os.Stderr, "Error: no directory named %q in the last scan of %s\n", name, wsPath)
+	case 1:
+		return byBase[0]
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %q is ambiguous, use the path relative to the workspace: %v\n", name, byBase)
+	}
+	exit(exitError)
+	return ""
+}
+
+// sessionTemplate returns the session template for the repository at dir,
+// or nil if none applies
+func sessionTemplate(wsPath, dir string) (*config.SessionTemplate, error) {
+	g := getGroups(wsPath)
+	var fallback *config.SessionTemplate
+	for i := range cfg.Session.Templates {
+		template := &cfg.Session.Templates[i]
+		paths, err := groups.New([]config.GroupConfig{{Name: template.Name, Paths: template.Paths}}, wsPath)
+		if err != nil {
+			return nil, fmt.Errorf("session.templates[%d]: %w", i, err)
+		}
+		if paths.Contains(template.Name, dir) {
+			return template, nil
+		}
+		for _, group := range template.Groups {
+			if _, ok := g.Lookup(group); !ok {
+				return nil, fmt.Errorf("session.templates[%d]: unknown group %q", i, group)
+			}
+			if g.Contains(group, dir) {
+				return template, nil
+			}
+		}
+		if template.Name == "default" && fallback == nil {
+			fallback = template
+		}
+	}
+	return fallback, nil
+}
+
+func init() {
+	// Attach the `session` command to the root: thandie session <repo>
+	sessionCmd.Flags().BoolVar(&sessionDryRun, "dry-run", false, "Print the tmux or zellij commands instead of running them")
+	rootCmd.AddCommand(sessionCmd)
+}
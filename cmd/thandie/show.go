@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+// showCmd represents: `thandie show <dir>`
+var showCmd = &cobra.Command{
+	Use:   "show <dir>",
+	Short: "Show details for a directory from the last scan",
+	Long: `Show everything Thandie knows about a single directory from the last
+scan: git state, provider enrichment (CI status) and the issue triage summary.
+
+The directory can be given as an absolute path, a path relative to the
+workspace, just its name, or the start of its name if only one directory
+starts with it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		result := loadScanResult(wsPath)
+		info, err := findDirectory(wsPath, result.DirectoryInfos, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		printDetails(*info)
+	},
+}
+
+func init() {
+	// Attach the `show` command to the root: thandie show <dir>
+	rootCmd.AddCommand(showCmd)
+}
+
+// findDirectory returns the scanned directory matching arg as an absolute
+// path, a path relative to the workspace, a unique directory name or, failing
+// those, the start of a unique directory name (case-insensitive), which is
+// noted on stderr so a surprising match doesn't go unnoticed
+func findDirectory(wsPath string, infos []scanner.DirectoryInfo, arg string) (*scanner.DirectoryInfo, error) {
+	candidates := []string{filepath.Clean(arg), filepath.Join(wsPath, arg)}
+	if abs, err := filepath.Abs(arg); err == nil {
+		candidates = append(candidates, abs)
+	}
+	for i := range infos {
+		for _, candidate := range candidates {
+			if infos[i].Path == candidate {
+				return &infos[i], nil
+			}
+		}
+	}
+
+	var matches []*scanner.DirectoryInfo
+	for i := range infos {
+		if filepath.Base(infos[i].Path) == arg {
+			matches = append(matches, &infos[i])
+		}
+	}
+	prefix := false
+	if len(matches) == 0 {
+		for i := range infos {
+			if strings.HasPrefix(strings.ToLower(filepath.Base(infos[i].Path)), strings.ToLower(arg)) {
+				matches = append(matches, &infos[i])
+			}
+		}
+		prefix = true
+	}
+	if prefix && len(matches) == 1 {
+		fmt.Fprintln(os.Stderr, colorize(fmt.Sprintf("→ %s (matched %q)", filepath.Base(matches[0].Path), arg), colorGray))
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no scanned directory matches %q", arg)
+	case 1:
+		return matches[0], nil
+	default:
+		var paths []string
+		for _, m := range matches {
+			paths = append(paths, m.Path)
+		}
+		return nil, fmt.Errorf("%q is ambiguous: %s", arg, strings.Join(paths, ", "))
+	}
+}
+
+// printDetails prints the details of a directory in labelled sections
+func printDetails(info scanner.DirectoryInfo) {
+	fmt.Println(info.Path)
+
+	if info.ScanError != "" {
+		fmt.Println("\nScan:")
+		printField("Error", colorize(info.ScanError, colorRed))
+		printField("Retry", fmt.Sprintf("thandie scan --only 'path == %q'", info.Path))
+		return
+	}
+	if info.AccessDenied {
+		fmt.Println("\nAccess:")
+		printField("Denied", colorize("the directory or its .git can't be read by you", colorYellow))
+		printField("Fix", fmt.Sprintf("chmod u+rx %q, or add it to scanner.ignore_dirs", info.Path))
+		return
+	}
+
+	git := info.GitMetadata
+	if git == nil || !git.IsGitRepo {
+		fmt.Println("\nNot a git repository")
+		return
+	}
+
+	fmt.Println("\nGit:")
+	printField("Branch", git.CurrentBranch)
+	if info.Network != "" {
+		printField("Filesystem", colorize(info.Network+" (network mount: git status not collected)", colorYellow))
+	}
+	if ticket := info.Ticket; ticket != nil {
+		if ticket.Error != "" {
+			printField("Ticket", fmt.Sprintf("%s (lookup failed: %s)", ticket.Key, ticket.Error))
+		} else {
+			printField("Ticket", fmt.Sprintf("%s %s [%s]", ticket.Key, ticket.Title, ticket.Status))
+			printField("", ticket.URL)
+		}
+	}
+	printField("Remote", git.RemoteURL)
+	if symbol := stateSymbol(git); symbol != "" && git.StatusSummary != "" {
+		printField("Status", symbol+" "+git.StatusSummary)
+	} else {
+		printField("Status", git.StatusSummary)
+	}
+	if text := inProgressText(git); text != "" {
+		printField("In progress", colorize(text, colorRed))
+	}
+	if sparse := git.Sparse; sparse != nil {
+		mode := "patterns"
+		if sparse.Cone {
+			mode = "cone"
+		}
+		printField("Sparse", fmt.Sprintf("%s: %s", mode, strings.Join(sparse.Patterns, ", ")))
+		printField("", colorize("status covers only these; files outside the sparse checkout aren't checked", colorYellow))
+	}
+	if git.Upstream != "" {
+		printField("Upstream", fmt.Sprintf("%s (%d ahead, %d behind)", git.Upstream, git.Ahead, git.Behind))
+	}
+
+	if len(git.UnpushedCommits) > 0 {
+		printUnpushedCommits(git)
+	}
+
+	if git.Conflicts > 0 {
+		fmt.Printf("\nConflicts (%d):\n", git.Conflicts)
+		for _, file := range git.ConflictFiles {
+			fmt.Printf("  %s %s\n", colorize("✗", colorRed), file)
+		}
+		if hidden := git.Conflicts - len(git.ConflictFiles); hidden > 0 {
+			fmt.Printf("  ... and %d more\n", hidden)
+		}
+		fmt.Printf("  Resolve with 'thandie conflicts %s --open' (or --mergetool)\n", filepath.Base(info.Path))
+	}
+
+	if d := info.Docker; d != nil {
+		fmt.Println("\nDocker:")
+		if len(d.Dockerfiles) > 0 {
+			printField("Dockerfiles", strings.Join(d.Dockerfiles, ", "))
+		}
+		if len(d.ComposeFiles) > 0 {
+			printField("Compose", strings.Join(d.ComposeFiles, ", "))
+		}
+		if len(d.Running) > 0 {
+			printField("Running", strings.Join(d.Running, ", "))
+		}
+	}
+
+	if info.Extras != nil {
+		printExtras(info.Extras)
+	}
+
+	enrichment := info.Enrichment
+	if enrichment == nil {
+		return
+	}
+
+	fmt.Println("\nProvider:")
+	printField("Repository", enrichment.Provider+":"+enrichment.Repo)
+	printField("Fetched", formatAge(enrichment.FetchedAt))
+	if enrichment.Error != "" {
+		printField("Error", enrichment.Error)
+		return
+	}
+	printField("Default branch", enrichment.DefaultBranch)
+	printField("CI", ciGlyph(enrichment.CI)+" "+enrichment.CI)
+
+	fmt.Println("\nIssues:")
+	printField("Open", fmt.Sprintf("%d", enrichment.OpenIssues))
+	printField("Assigned to you", fmt.Sprintf("%d", len(enrichment.AssignedIssues)))
+	for _, issue := range enrichment.AssignedIssues {
+		fmt.Printf("    #%d %s\n      %s\n", issue.Number, issue.Title, issue.URL)
+	}
+}
+
+// printUnpushedCommits lists unpushed commit subjects, flagging those that
+// don't match commits.lint_pattern when linting is enabled. Merge commits are
+// not linted since their subjects are generated by git.
+func printUnpushedCommits(git *scanner.GitMetadata) {
+	var lint *regexp.Regexp
+	if cfg != nil {
+		re, err := cfg.Commits.LintRegexp()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		lint = re
+	}
+
+	fmt.Printf("\nUnpushed commits (%d):\n", git.Ahead)
+	nonConforming := 0
+	for _, commit := range git.UnpushedCommits {
+		marker := " "
+		if lint != nil && !commit.Merge && !lint.MatchString(commit.Subject) {
+			marker = colorize("✗", colorRed)
+			nonConforming++
+		}
+		fmt.Printf("  %s %s %s\n", marker, commit.Hash, commit.Subject)
+	}
+	if hidden := git.Ahead - len(git.UnpushedCommits); hidden > 0 {
+		fmt.Printf("    ... and %d more\n", hidden)
+	}
+	if nonConforming > 0 {
+		fmt.Printf("  %d commit(s) don't match the commit message convention\n", nonConforming)
+	}
+}
+
+// printField prints a labelled value in the details view, skipping empty
+// values. An empty label continues the previous field on a new line.
+func printField(label, value string) {
+	if value == "" {
+		return
+	}
+	if label != "" {
+		label += ":"
+	}
+	fmt.Printf("  %-16s %s\n", label, value)
+}
+
+// printExtras prints the tool-specific metadata of a directory
+func printExtras(extras *scanner.Extras) {
+	if extras.Code != nil {
+		printCodeStats(extras.Code)
+	}
+	if extras.Terraform == nil {
+		return
+	}
+
+	fmt.Println("\nExtras:")
+	if tf := extras.Terraform; tf != nil {
+		for _, module := range tf.Modules {
+			label := "Terraform"
+			if module.Path != "." {
+				label += " " + module.Path
+			}
+			state := "no local state"
+			if module.LocalState {
+				state = "local state"
+			}
+			if module.Backend != "" {
+				state = module.Backend + " backend"
+			}
+			workspace := "workspace " + module.Workspace
+			if len(module.Workspaces) > 0 {
+				workspace += fmt.Sprintf(" (local: %s)", strings.Join(module.Workspaces, ", "))
+			}
+			printField(label, workspace+", "+state)
+
+			drift := "drift not checked"
+			if !module.DriftCheckedAt.IsZero() {
+				switch module.Drift {
+				case scanner.DriftNone:
+					drift = "no drift"
+				case scanner.DriftDrifted:
+					drift = "drifted"
+				default:
+					drift = "drift check failed: " + module.DriftError
+				}
+				drift += ", checked " + formatAge(module.DriftCheckedAt)
+			}
+			if !module.Initialized {
+				drift = "not initialized"
+			}
+			printField("", drift)
+		}
+		if tf.HighRisk() {
+			printField("High risk", colorize("uncommitted state files: "+strings.Join(tf.UncommittedState, ", "), colorRed))
+		}
+	}
+}
+
+// printCodeStats prints lines of code per language, largest first
+func printCodeStats(stats *scanner.CodeStats) {
+	names := make([]string, 0, len(stats.Languages))
+	for name := range stats.Languages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return stats.Languages[names[i]].Code > stats.Languages[names[j]].Code
+	})
+
+	fmt.Println("\nCode:")
+	fmt.Printf("  %-18s %7s %9s %9s %9s\n", "Language", "Files", "Code", "Comments", "Blanks")
+	for _, name := range names {
+		lang := stats.Languages[name]
+		fmt.Printf("  %-18s %7d %9d %9d %9d\n", name, lang.Files, lang.Code, lang.Comments, lang.Blanks)
+	}
+	fmt.Printf("  %-18s %7s %9d\n", "Total", "", stats.TotalCode())
+	if stats.Truncated {
+		fmt.Println("  (file limit reached; counts are partial)")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/auditlog"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	// stateForce lets `state import` replace an existing config file
+	stateForce bool
+)
+
+// stateCmd represents: `thandie state`
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Export or import everything thandie keeps on this machine",
+	Long: `Bundle thandie's state into a tar.gz archive, or restore one, to move to
+another machine or hand over for debugging: the thandie cache directory (scan
+caches with their history, file indexes, job and test logs, the audit log and
+resume state) and the config file.
+
+Secrets in the exported config (provider and tracker tokens, notify
+webhook URLs, smtp.password and webhook.secret) are replaced with REDACTED;
+keychain:<alias> references are kept, but the keychain itself is not
+exported, so store the secrets again on the new machine with 'thandie
+secrets set'. Workspace paths are kept as they are. Cache files encrypted
+with security.encrypt_cache are exported encrypted, and export warns about
+them: copy the cache-key secret too, or the new machine can't read them.`,
+}
+
+// stateExportCmd represents: `thandie state export <file>`
+var stateExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Write the cache and redacted config to a tar.gz archive",
+	Long: `Write the thandie cache directory and the config file, with secrets
+redacted, to a tar.gz archive. Use - to write the archive to stdout.
+With security.sign_key set, the archive is signed.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheDir, err := cache.Dir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to locate cache directory: %v\n", err)
+			exit(exitError)
+		}
+
+		var out io.Writer = os.Stdout
+		if args[0] != "-" {
+			file, err := os.OpenFile(args[0], os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to create %s: %v\n", args[0], err)
+				exit(exitError)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		manifest, err := state.Export(out, cacheDir, stateConfigPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if args[0] != "-" {
+				os.Remove(args[0])
+			}
+			exit(exitError)
+		}
+		if len(manifest.Sealed) > 0 {
+			logger.Warn("the export holds encrypted cache files that only this machine's keychain key opens; copy the cache-key secret to the new machine with 'thandie secrets get' and 'thandie secrets set'",
+				"files", len(manifest.Sealed))
+		}
+		if args[0] == "-" {
+			warnUnsigned()
+			return
+		}
+		fmt.Printf("Exported %d cache files to %s\n", len(manifest.Files), args[0])
+		switch {
+		case !manifest.Config:
+			fmt.Println("No config file found; the archive has none.")
+		case len(manifest.Redacted) > 0:
+			fmt.Printf("Redacted in the config: %s\n", strings.Join(manifest.Redacted, ", "))
+		}
+		signExport(args[0])
+	},
+}
+
+// stateImportCmd represents: `thandie state import <file>`
+var stateImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Restore the cache and config from an archive",
+	Long: `Restore a 'thandie state export' archive: cache files replace local ones,
+except logs that already exist here (the audit log is append-only). The
+config is restored if there is none yet; otherwise it is written next to
+the current one as config.yml.imported, unless --force replaces it.
+
+Re-enter the secrets the export redacted before using the config.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireWritable("importing state")
+		cacheDir, err := cache.Dir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to locate cache directory: %v\n", err)
+			exit(exitError)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		defer file.Close()
+		imported, err := state.Import(file, cacheDir)
+		auditlog.Write("state.import", args[0], "", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+		from := imported.Manifest.Hostname
+		if from == "" {
+			from = "unknown host"
+		}
+		fmt.Printf("Imported %d cache files from %s (exported %s)\n", len(imported.Files), from, imported.Manifest.CreatedAt.Format("2006-01-02 15:04"))
+		if len(imported.Kept) > 0 {
+			fmt.Printf("Kept the local copies of %s\n", strings.Join(imported.Kept, ", "))
+		}
+		if imported.Config == nil {
+			return
+		}
+
+		configPath := stateConfigPath()
+		target := configPath
+		if _, err := os.Stat(configPath); err == nil && !stateForce {
+			target = configPath + ".imported"
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create config directory: %v\n", err)
+			exit(exitError)
+		}
+		err = os.WriteFile(target, imported.Config, 0600)
+		auditlog.Write("config.import", target, "", err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write config: %v\n", err)
+			exit(exitError)
+		}
+		if target != configPath {
+			fmt.Printf("Config written to %s; the existing %s was left alone (use --force to replace it)\n", target, configPath)
+		} else {
+			fmt.Printf("Config restored to %s\n", target)
+		}
+		if len(imported.Manifest.Redacted) > 0 {
+			fmt.Printf("Replace the redacted values before use: %s\n", strings.Join(imported.Manifest.Redacted, ", "))
+		}
+	},
+}
+
+func init() {
+	// Attach the `state` command and its subcommands: thandie state export|import
+	stateImportCmd.Flags().BoolVar(&stateForce, "force", false, "Replace the existing config file with the imported one")
+	stateCmd.AddCommand(stateExportCmd, stateImportCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+// stateConfigPath returns the config file in use, or where it would be
+func stateConfigPath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	path, _ := configFilePath()
+	return path
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/rpc"
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents: `thandie status`
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the daemon's jobs and a summary of the workspace",
+	Long: `Show whether 'thandie daemon' is running, when its jobs run next and how
+their last runs went, followed by a summary of the last scan of the
+workspace. The summary comes from the daemon's memory when it is running and
+from the cache otherwise; status never scans.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+
+		var state daemonState
+		err := rpc.Call(rpc.Request{Method: "status"}, &state)
+		switch {
+		case errors.Is(err, rpc.ErrNotRunning):
+			fmt.Println("Daemon: not running (start it with 'thandie daemon')")
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		default:
+			printDaemonState(state)
+		}
+		fmt.Println()
+
+		var result *cache.ScanResult
+		if err == nil {
+			err = rpc.Call(rpc.Request{Method: "scan_result", Workspace: wsPath}, &result)
+		}
+		if err != nil {
+			if cacheInstance, cacheErr := cache.New(); cacheErr == nil {
+				result, err = cacheInstance.LoadScanSummary(wsPath)
+			}
+		}
+		if err != nil || result == nil {
+			fmt.Printf("%s has not been scanned yet (run 'thandie scan').\n", workspaceName(wsPath))
+			return
+		}
+
+		summary := workspaceSummary(wsPath, result.DirectoryInfos)
+		fmt.Printf("%s, scanned %s\n", summary.Title, formatAge(result.ScannedAt))
+		for _, line := range summary.Lines {
+			fmt.Printf("  %s\n", line)
+		}
+		if record, ok := result.LastScan(); ok {
+			fmt.Printf("  Last scan: %s\n", scanSummary(record))
+		}
+		if cacheSettingsChanged(wsPath, result) {
+			fmt.Printf("  %s\n", colorize("Cache built with different settings; run 'thandie scan' to rebuild it", colorYellow))
+		}
+	},
+}
+
+// printDaemonState prints the daemon's uptime and jobs
+func printDaemonState(state daemonState) {
+	fmt.Printf("Daemon: running (pid %d, up %s)\n", state.PID, time.Since(state.Started).Round(time.Second))
+	for _, job := range state.Jobs {
+		line := fmt.Sprintf("  %-20s", job.Name)
+		switch {
+		case job.Running:
+			line += " running"
+		case job.Next.IsZero():
+			line += " not scheduled"
+		default:
+			line += fmt.Sprintf(" next in %s", time.Until(job.Next).Round(time.Second))
+		}
+		if !job.LastRun.IsZero() && !job.Running {
+			outcome := colorize("ok", colorGreen)
+			if job.LastExit != exitOK {
+				outcome = colorize(fmt.Sprintf("exit %d", job.LastExit), colorRed)
+			}
+			line += fmt.Sprintf(", last run %s (%s)", formatAge(job.LastRun), outcome)
+		}
+		fmt.Println(line)
+	}
+}
+
+func init() {
+	// Attach the `status` command to the root: thandie status
+	rootCmd.AddCommand(statusCmd)
+}
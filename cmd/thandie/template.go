@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// templatePath is a Go text/template used instead of the built-in output
+	templatePath string
+)
+
+// reportData is the data passed to --template templates. Commands embed it
+// in their own data with report-specific fields.
+type reportData struct {
+	Workspace     string
+	WorkspaceName string // Alias of the workspace profile, or else Workspace
+	GeneratedAt   time.Time
+	Directories   []reportDir
+}
+
+// reportDir is a scanned directory with its name relative to the workspace
+type reportDir struct {
+	scanner.DirectoryInfo
+	Name string
+}
+
+// newReportData builds template data for the given directories
+func newReportData(wsPath string, infos []scanner.DirectoryInfo) reportData {
+	data := reportData{Workspace: wsPath, WorkspaceName: workspaceName(wsPath), GeneratedAt: time.Now()}
+	for _, info := range infos {
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+		data.Directories = append(data.Directories, reportDir{DirectoryInfo: info, Name: name})
+	}
+	return data
+}
+
+// templateFuncs are the helpers available to --template templates
+var templateFuncs = template.FuncMap{
+	"age":   formatAge,
+	"date":  func(layout string, t time.Time) string { return t.Format(layout) },
+	"join":  func(sep string, items []string) string { return strings.Join(items, sep) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"default": func(fallback string, value any) any {
+		if value == nil || value == "" || value == 0 {
+			return fallback
+		}
+		return value
+	},
+	"truncate": func(n int, s string) string { return truncateWidth(s, n) },
+	"pad": func(n int, s string) string {
+		return s + strings.Repeat(" ", max(n-displayWidth(s), 0))
+	},
+	"plural": func(n int, singular, plural string) string {
+		if n == 1 {
+			return singular
+		}
+		return plural
+	},
+	"add": func(a, b int) int { return a + b },
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// jsonString quotes s for embedding in a JSON document, e.g. a Slack block
+	"jsonString": func(s string) string {
+		data, _ := json.Marshal(s)
+		return string(data)
+	},
+}
+
+// addTemplateFlag registers --template on a command that supports custom output
+func addTemplateFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&templatePath, "template", "", "Render output with this Go text/template file instead of the built-in format")
+}
+
+// renderTemplate renders the template at path with data to w
+func renderTemplate(w io.Writer, path string, data any) error {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/terraform"
+	"github.com/spf13/cobra"
+)
+
+// terraformCmd represents: `thandie terraform`
+var terraformCmd = &cobra.Command{
+	Use:   "terraform",
+	Short: "Terraform configurations in the workspace",
+}
+
+// terraformDriftCmd represents: `thandie terraform drift`
+var terraformDriftCmd = &cobra.Command{
+	Use:   "drift [directory]",
+	Short: "Check initialized Terraform configurations for drift",
+	Long: `Run 'terraform plan -detailed-exitcode' (without locking state) in every
+initialized Terraform configuration in the workspace, or only in the given
+directory, and record when each was checked and whether it has drifted.
+Configurations that haven't been through 'terraform init' are skipped.
+
+Exits with code 10 if any configuration has drifted.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if _, err := exec.LookPath("terraform"); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: terraform is not installed")
+			exit(exitError)
+		}
+
+		result := loadScanResult(wsPath)
+		infos := result.DirectoryInfos
+		if len(args) == 1 {
+			info, err := findDirectory(wsPath, result.DirectoryInfos, args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+			infos = []scanner.DirectoryInfo{*info}
+		}
+
+		sem := make(chan struct{}, max(getScannerConfig(wsPath).Concurrency, 1))
+		var wg sync.WaitGroup
+		for _, info := range infos {
+			if info.Extras == nil || info.Extras.Terraform == nil {
+				continue
+			}
+			// Modules are shared with result through the slice, so results are cached below
+			modules := info.Extras.Terraform.Modules
+			for i := range modules {
+				module := &modules[i]
+				if !module.Initialized {
+					continue
+				}
+				wg.Add(1)
+				sem <- struct{}{}
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					terraform.CheckDrift(context.Background(), filepath.Join(info.Path, module.Path), module)
+				}()
+			}
+		}
+		wg.Wait()
+
+		if cacheInstance, err := cache.New(); err != nil {
+			logger.Warn("failed to initialize cache", "error", err)
+		} else if err := cacheInstance.Save(result); err != nil {
+			logger.Warn("failed to save drift results to cache", "error", err)
+		}
+
+		drifted := 0
+		for _, info := range infos {
+			if info.Extras == nil || info.Extras.Terraform == nil {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+			for _, module := range info.Extras.Terraform.Modules {
+				fmt.Printf("  %s  %s\n", driftLabel(module), filepath.Join(name, module.Path))
+				if module.DriftError != "" {
+					fmt.Printf("  %-15s  %s\n", "", module.DriftError)
+				}
+				if module.Drift == scanner.DriftDrifted {
+					drifted++
+				}
+			}
+		}
+		if drifted > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `terraform` command and its subcommands: thandie terraform drift
+	terraformCmd.AddCommand(terraformDriftCmd)
+	rootCmd.AddCommand(terraformCmd)
+}
+
+// driftLabel returns a colored description of a module's last drift check
+func driftLabel(module scanner.TerraformModule) string {
+	switch {
+	case !module.Initialized:
+		return colorize(fmt.Sprintf("%-15s", "not initialized"), colorGray)
+	case module.Drift == scanner.DriftNone:
+		return colorize(fmt.Sprintf("%-15s", "no drift"), colorGreen)
+	case module.Drift == scanner.DriftDrifted:
+		return colorize(fmt.Sprintf("%-15s", "drifted"), colorRed)
+	case module.Drift == scanner.DriftError:
+		return colorize(fmt.Sprintf("%-15s", "check failed"), colorYellow)
+	}
+	return colorize(fmt.Sprintf("%-15s", "not checked"), colorGray)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/policy"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/testrun"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// testFilter limits the repositories tested (see internal/filter)
+	testFilter string
+
+	// testForce reruns tests even when a cached result for HEAD exists
+	testForce bool
+
+	// testVerbose streams every repository's test output to the terminal
+	testVerbose bool
+)
+
+// testCmd represents: `thandie test`
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run each repository's tests in parallel and cache the results",
+	Long: `Run the detected test command of every git repository in the workspace
+(or those matching --filter, e.g. 'lang:go'), at most scanner.concurrency at
+a time. A Makefile 'test' target is preferred; otherwise the language's
+usual command is used (go test ./..., cargo test, npm test, pytest, ...).
+
+Output goes to a log file per repository. Results are cached by HEAD commit,
+so repositories whose HEAD hasn't moved and whose worktree is clean are not
+retested unless --force is given.
+
+Exits with code 10 if any repository's tests fail.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireWritable("running tests")
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		dirFilter := parseFilter(wsPath, testFilter)
+		logDir, err := testrun.LogDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(exitError)
+		}
+
+		result := loadScanResult(wsPath)
+		repoPolicy := getPolicy(wsPath)
+
+		var mu sync.Mutex // Guards failed and serializes terminal output
+		sem := make(chan struct{}, max(getScannerConfig(wsPath).Concurrency, 1))
+		var wg sync.WaitGroup
+		tested, failed := 0, 0
+		for i := range result.DirectoryInfos {
+			info := &result.DirectoryInfos[i]
+			if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo || !dirFilter.Match(*info) {
+				continue
+			}
+			name, err := filepath.Rel(wsPath, info.Path)
+			if err != nil {
+				name = info.Path
+			}
+
+			if err := repoPolicy.Check(policy.ActionTest, info.Path, info.GitMetadata.RemoteURL); err != nil {
+				fmt.Printf("  ⊘ %s: %v\n", name, err)
+				continue
+			}
+
+			command := testrun.DetectCommand(info.Path, info.Languages)
+			if command == nil {
+				logger.Debug("no test command detected", "path", info.Path)
+				continue
+			}
+			tested++
+
+			commit, dirty, err := testrun.State(info.Path)
+			if err != nil {
+				mu.Lock()
+				fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+				failed++
+				mu.Unlock()
+				continue
+			}
+			if !testForce && testrun.Reusable(info.Tests, commit, dirty) {
+				mu.Lock()
+				printTestRun(name, info.Tests, true)
+				if !info.Tests.Passed {
+					failed++
+				}
+				mu.Unlock()
+				continue
+			}
+
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				var onLine func(string)
+				if testVerbose {
+					onLine = func(line string) {
+						mu.Lock()
+						defer mu.Unlock()
+						fmt.Printf("[%s] %s\n", name, line)
+					}
+				}
+				run, err := testrun.Run(context.Background(), info.Path, command, logDir, onLine)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "  ✗ %s: %v\n", name, err)
+					failed++
+					return
+				}
+				info.Tests = run
+				printTestRun(name, run, false)
+				if !run.Passed {
+					failed++
+				}
+			}()
+		}
+		wg.Wait()
+
+		if cacheInstance, err := cache.New(); err != nil {
+			logger.Warn("failed to initialize cache", "error", err)
+		} else if err := cacheInstance.Save(result); err != nil {
+			logger.Warn("failed to save test results to cache", "error", err)
+		}
+
+		if tested == 0 {
+			fmt.Println("No repositories with a detectable test command.")
+			return
+		}
+		fmt.Printf("\n%d of %d repositories passed.\n", tested-failed, tested)
+		if failed > 0 {
+			exit(exitCheckFailed)
+		}
+	},
+}
+
+func init() {
+	// Attach the `test` command to the root: thandie test
+	testCmd.Flags().StringVar(&testFilter, "filter", "", "Only test repositories matching the filter, e.g. 'lang:go'")
+	testCmd.Flags().BoolVar(&testForce, "force", false, "Rerun tests even if a cached result for the current commit exists")
+	testCmd.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "Stream test output, prefixed with the repository name")
+	rootCmd.AddCommand(testCmd)
+}
+
+// printTestRun prints one repository's test outcome
+func printTestRun(name string, run *scanner.TestRun, cached bool) {
+	note := run.Duration.String()
+	if cached {
+		note = "cached, " + formatAge(run.FinishedAt)
+	}
+	if run.Passed {
+		fmt.Printf("  %s %s (%s)\n", colorize("✓", colorGreen), name, note)
+		return
+	}
+	fmt.Printf("  %s %s (%s)\n    %s\n    log: %s\n", colorize("✗", colorRed), name, note, run.Command, run.Log)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/activity"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// todaySince is how far back the recap goes
+	todaySince time.Duration
+
+	// todayFilter limits the repositories included (see internal/filter)
+	todayFilter string
+
+	// todaySummary prints only the counts of each repository
+	todaySummary bool
+
+	// todayFormat is the output format: text or json
+	todayFormat string
+)
+
+// todayRepo is the reflog activity of a repository
+type todayRepo struct {
+	Repo    string                 `json:"repo"` // Relative to the workspace
+	Counts  map[string]int         `json:"counts"`
+	Entries []activity.ReflogEntry `json:"entries"`
+}
+
+// todayReport is the recap of the workspace
+type todayReport struct {
+	Since  time.Time      `json:"since"`
+	Counts map[string]int `json:"counts"` // Across all repositories
+	Repos  []todayRepo    `json:"repos"`
+}
+
+// todayCmd represents: `thandie today`
+var todayCmd = &cobra.Command{
+	Use:   "today",
+	Short: "Recap of the commits, checkouts and resets made today",
+	Long: `Summarize what you did in the last --since (default 24h) from the HEAD
+reflogs of the repositories of the last scan: the commits, checkouts, resets,
+merges, rebases and pulls of each repository, newest first, with the totals
+across the workspace.
+
+Unlike 'thandie recent' it doesn't look at files: the reflog records the
+branches you switched between and the work you threw away too, and only
+what was done in this clone, so commits by others that you pulled don't
+count. --summary prints the counts without the entries.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if todayFormat != "text" && todayFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected text or json)\n", todayFormat)
+			exit(exitError)
+		}
+		since := time.Now().Add(-todaySince)
+		infos := parseFilter(wsPath, todayFilter).Apply(loadScanResult(wsPath).DirectoryInfos)
+
+		report, failures := collectToday(wsPath, infos, since)
+		for _, failure := range failures {
+			fmt.Fprintf(os.Stderr, "  ✗ %s\n", failure)
+		}
+
+		if todayFormat == "json" {
+			if err := printJSON(report); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				exit(exitError)
+			}
+		} else {
+			printToday(report)
+		}
+		if len(failures) > 0 {
+			exit(exitError)
+		}
+	},
+}
+
+// collectToday reads the reflogs of the git repositories among infos,
+// keeping the repositories with entries since since, most recently active
+// first, and returns "<repo>: <error>" for each reflog that couldn't be read
+func collectToday(wsPath string, infos []scanner.DirectoryInfo, since time.Time) (*todayReport, []string) {
+	report := &todayReport{Since: since, Counts: make(map[string]int)}
+	var mu sync.Mutex // Guards report and failures
+	var failures []string
+	sem := make(chan struct{}, max(getScannerConfig(wsPath).Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, info := range infos {
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+			continue
+		}
+		name, err := filepath.Rel(wsPath, info.Path)
+		if err != nil {
+			name = info.Path
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var entries []activity.ReflogEntry
+			repo, err := scanner.GitProvider().OpenRepo(info.Path)
+			if err == nil {
+				entries, err = activity.Reflog(repo, since)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+				return
+			}
+			if len(entries) == 0 {
+				return
+			}
+			summary := todayRepo{Repo: name, Counts: make(map[string]int), Entries: entries}
+			for _, e := range entries {
+				summary.Counts[e.Action]++
+				report.Counts[e.Action]++
+			}
+			report.Repos = append(report.Repos, summary)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(report.Repos, func(i, j int) bool {
+		a, b := report.Repos[i].Entries[0].Time, report.Repos[j].Entries[0].Time
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return report.Repos[i].Repo < report.Repos[j].Repo
+	})
+	sort.Strings(failures)
+	return report, failures
+}
+
+// printToday prints each repository's counts and entries, then the totals
+func printToday(report *todayReport) {
+	if len(report.Repos) == 0 {
+		fmt.Printf("Nothing recorded in the reflogs in the last %s.\n", formatDuration(todaySince))
+		return
+	}
+	for i, repo := range report.Repos {
+		if i > 0 && !todaySummary {
+			fmt.Println()
+		}
+		fmt.Printf("%s  %s\n", repo.Repo, colorize(formatActionCounts(repo.Counts), colorGray))
+		if todaySummary {
+			continue
+		}
+		for _, e := range repo.Entries {
+			// Pad before coloring so escape codes don't break the alignment
+			fmt.Printf("  %s  %-11s %s %s\n", colorize(fmt.Sprintf("%-8s", formatAge(e.Time)), colorGray),
+				e.Action, colorize(e.Commit, colorYellow), e.Detail)
+		}
+	}
+	fmt.Printf("\n%d repositories in the last %s: %s\n",
+		len(report.Repos), formatDuration(todaySince), formatActionCounts(report.Counts))
+}
+
+// formatActionCounts formats reflog action counts, e.g. "3 commits,
+// 1 checkout": commits, checkouts and resets first, then the other actions
+// alphabetically
+func formatActionCounts(counts map[string]int) string {
+	order := []string{activity.ActionCommit, activity.ActionCheckout, activity.ActionReset}
+	var others []string
+	for action := range counts {
+		if action != activity.ActionCommit && action != activity.ActionCheckout && action != activity.ActionReset {
+			others = append(others, action)
+		}
+	}
+	sort.Strings(others)
+
+	var parts []string
+	for _, action := range append(order, others...) {
+		n := counts[action]
+		if n == 0 {
+			continue
+		}
+		if n == 1 {
+			parts = append(parts, fmt.Sprintf("1 %s", action))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, action))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	// Attach the `today` command to the root: thandie today
+	todayCmd.Flags().DurationVar(&todaySince, "since", 24*time.Hour, "How far back to look, e.g. 8h or 72h")
+	todayCmd.Flags().StringVar(&todayFilter, "filter", "", "Only include repositories matching the filter, e.g. 'group:work'")
+	todayCmd.Flags().BoolVar(&todaySummary, "summary", false, "Print only the counts of each repository")
+	todayCmd.Flags().StringVar(&todayFormat, "format", "text", "Output format: text or json")
+	addJSONFlags(todayCmd)
+	rootCmd.AddCommand(todayCmd)
+}
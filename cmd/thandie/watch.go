@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/filter"
+	"github.com/ThandieOps/thandie-agent/internal/rpc"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	// watchInterval is how often the dashboard is redrawn without new scans
+	watchInterval time.Duration
+
+	// watchSince is how far back the latest changes go
+	watchSince time.Duration
+
+	// watchFilter limits the repositories shown (see internal/filter)
+	watchFilter string
+
+	// watchWrap wraps lines wider than the terminal instead of cutting them
+	watchWrap bool
+)
+
+// watchPoll is how often the cache is checked for a new scan
+const watchPoll = time.Second
+
+// watchCmd represents: `thandie watch`
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Full-screen dashboard of the workspace that refreshes itself",
+	Long: `Show a dashboard of the workspace that fills the terminal and redraws
+itself, for leaving on a second monitor: repository counts, failing CI and
+tests, the repositories with uncommitted changes (longest dirty first) or
+unpushed commits, and the files changed in the last --since.
+
+The dashboard is redrawn every --interval and as soon as a new scan lands in
+the cache, e.g. from 'thandie daemon'. watch never scans itself; run the
+daemon alongside it. It takes no input; press Ctrl-C to quit.
+
+Lines wider than the terminal, e.g. long paths, are cut with an ellipsis so
+the dashboard keeps fitting the screen; --wrap wraps them instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requireConfig()
+		requireProfile()
+		wsPath := getWorkspacePath()
+		requireWorkspace(wsPath)
+
+		if watchInterval < time.Second {
+			fmt.Fprintln(os.Stderr, "Error: --interval must be at least 1s")
+			exit(exitError)
+		}
+		dirFilter := parseFilter(wsPath, watchFilter)
+		cacheInstance, err := cache.New()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to initialize cache: %v\n", err)
+			exit(exitError)
+		}
+		cachePath := cacheInstance.GetCacheFilePath(wsPath)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		tty := term.IsTerminal(int(os.Stdout.Fd()))
+		if tty {
+			// Draw on the alternate screen so the shell is restored on exit
+			fmt.Print("\033[?1049h\033[?25l")
+			defer fmt.Print("\033[?25h\033[?1049l")
+		}
+
+		var lastScan time.Time
+		var lastDraw time.Time
+		ticker := time.NewTicker(watchPoll)
+		defer ticker.Stop()
+		for {
+			modTime := time.Time{}
+			if info, err := os.Stat(cachePath); err == nil {
+				modTime = info.ModTime()
+			}
+			if !modTime.Equal(lastScan) || time.Since(lastDraw) >= watchInterval {
+				lastScan, lastDraw = modTime, time.Now()
+				frame := watchFrame(wsPath, cacheInstance, dirFilter)
+				if tty {
+					frame = fitScreen(frame)
+					fmt.Print("\033[H\033[2J")
+				} else {
+					fmt.Println()
+				}
+				fmt.Print(strings.Join(frame, "\n"))
+				if !tty {
+					fmt.Println()
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+func init() {
+	// Attach the `watch` command to the root command
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", 10*time.Second, "How often to redraw when no new scan arrives")
+	watchCmd.Flags().DurationVar(&watchSince, "since", 24*time.Hour, "How far back the latest changes go")
+	watchCmd.Flags().StringVar(&watchFilter, "filter", "", "Only show repositories matching the filter, e.g. 'group:platform'")
+	watchCmd.Flags().BoolVar(&watchWrap, "wrap", false, "Wrap lines wider than the terminal instead of cutting them")
+}
+
+// watchFrame returns the lines of the dashboard. The scan comes from the
+// daemon when it is running and from the cache otherwise.
+func watchFrame(wsPath string, cacheInstance *cache.Cache, dirFilter *filter.Filter) []string {
+	var result *cache.ScanResult
+	daemon := "daemon running"
+	err := rpc.Call(rpc.Request{Method: "scan_result", Workspace: wsPath}, &result)
+	if errors.Is(err, rpc.ErrNotRunning) {
+		daemon = "daemon not running"
+	}
+	if err != nil {
+		result, err = cacheInstance.LoadScanResult(wsPath)
+	}
+	header := colorize(time.Now().Format("15:04:05"), colorGray) + "  "
+	if err != nil || result == nil {
+		return []string{header + fmt.Sprintf("%s has not been scanned yet (run 'thandie scan' or 'thandie daemon').", workspaceName(wsPath))}
+	}
+	infos := dirFilter.Apply(result.DirectoryInfos)
+
+	summary := workspaceSummary(wsPath, infos)
+	lines := []string{header + fmt.Sprintf("%s · scanned %s · %s", summary.Title, formatAge(result.ScannedAt), daemon), ""}
+	for _, line := range summary.Lines {
+		lines = append(lines, "  "+line)
+	}
+
+	var dirty, unpushed []scanner.DirectoryInfo
+	for _, info := range infos {
+		if git := info.GitMetadata; git != nil && git.IsGitRepo {
+			if git.HasUncommitted {
+				dirty = append(dirty, info)
+			}
+			if git.Ahead > 0 {
+				unpushed = append(unpushed, info)
+			}
+		}
+	}
+	// Longest dirty first; repositories without a recorded time last
+	sort.SliceStable(dirty, func(i, j int) bool {
+		a, b := dirty[i].GitMetadata.DirtySince, dirty[j].GitMetadata.DirtySince
+		return !a.IsZero() && (b.IsZero() || a.Before(b))
+	})
+	sort.SliceStable(unpushed, func(i, j int) bool { return unpushed[i].GitMetadata.Ahead > unpushed[j].GitMetadata.Ahead })
+
+	name := func(info scanner.DirectoryInfo) string {
+		if rel, err := filepath.Rel(wsPath, info.Path); err == nil {
+			return rel
+		}
+		return info.Path
+	}
+	if len(dirty) > 0 {
+		lines = append(lines, "", colorize(fmt.Sprintf("Uncommitted changes (%d)", len(dirty)), colorYellow))
+		for _, info := range dirty {
+			line := fmt.Sprintf("  %s %s", padWidth(name(info), 30), info.GitMetadata.CurrentBranch)
+			if since := info.GitMetadata.DirtySince; !since.IsZero() {
+				line += colorize(" dirty since "+formatAge(since), colorGray)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(unpushed) > 0 {
+		lines = append(lines, "", colorize(fmt.Sprintf("Unpushed commits (%d)", len(unpushed)), colorYellow))
+		for _, info := range unpushed {
+			lines = append(lines, fmt.Sprintf("  %s %s ↑%d", padWidth(name(info), 30), info.GitMetadata.CurrentBranch, info.GitMetadata.Ahead))
+		}
+	}
+
+	if missing := matchingMissing(result.Missing, dirFilter); len(missing) > 0 {
+		lines = append(lines, "", colorize(fmt.Sprintf("Missing since earlier scans (%d)", len(missing)), colorRed))
+		for _, dir := range missing {
+			lines = append(lines, fmt.Sprintf("  %s %s", padWidth(name(dir.Info), 30), colorize("gone since "+formatAge(dir.Since), colorGray)))
+		}
+	}
+
+	entries, _ := collectRecent(wsPath, infos, time.Now().Add(-watchSince), false)
+	lines = append(lines, "", fmt.Sprintf("Latest changes (last %s)", formatDuration(watchSince)))
+	if len(entries) == 0 {
+		lines = append(lines, colorize("  none", colorGray))
+	}
+	for _, e := range entries {
+		lines = append(lines, "  "+recentLine(e))
+	}
+	return lines
+}
+
+// fitScreen cuts frame to the terminal's height, leaving the last line for
+// a footer, and cuts lines to its width unless --wrap is set
+func fitScreen(frame []string) []string {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 3 {
+		return frame
+	}
+	if !watchWrap {
+		for i, line := range frame {
+			frame[i] = truncateWidth(line, width)
+		}
+	}
+	footer := colorize(fmt.Sprintf("Refreshing every %s · Ctrl-C to quit", formatDuration(watchInterval)), colorGray)
+	if len(frame) > height-1 {
+		frame = frame[:height-1]
+	}
+	return append(frame, footer)
+}
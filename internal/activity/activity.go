@@ -0,0 +1,161 @@
+// Package activity collects the files recently modified in repositories,
+// from uncommitted changes and recent commits.
+package activity
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of activity
+const (
+	KindModified  = "modified"  // Uncommitted change in the worktree
+	KindCommitted = "committed" // Changed by a recent commit
+)
+
+// Entry is a file that was recently modified
+type Entry struct {
+	File    string    `json:"file"` // Relative to the repository root
+	Time    time.Time `json:"time"`
+	Kind    string    `json:"kind"`
+	Commit  string    `json:"commit,omitempty"` // Abbreviated hash, for committed files
+	Subject string    `json:"subject,omitempty"`
+}
+
+// Collect returns the files of the repository at dir modified since since,
+// newest first, each listed once with its latest activity. Uncommitted files
+// are dated by their modification time. Commits are limited to those
+// authored by the repository's user.email, if set, unless everyone is true.
+func Collect(dir string, since time.Time, everyone bool) ([]Entry, error) {
+	latest := make(map[string]Entry)
+	add := func(e Entry) {
+		if prev, ok := latest[e.File]; !ok || e.Time.After(prev.Time) {
+			latest[e.File] = e
+		}
+	}
+
+	modified, err := Uncommitted(dir, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range modified {
+		add(e)
+	}
+
+	committed, err := commits(dir, since, everyone)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range committed {
+		add(e)
+	}
+
+	entries := make([]Entry, 0, len(latest))
+	for _, e := range latest {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Time.Equal(entries[j].Time) {
+			return entries[i].Time.After(entries[j].Time)
+		}
+		return entries[i].File < entries[j].File
+	})
+	return entries, nil
+}
+
+// Uncommitted returns the changed files in the worktree modified since
+// since, in git status order
+func Uncommitted(dir string, since time.Time) ([]Entry, error) {
+	out, err := git(dir, "status", "--porcelain", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 4 {
+			continue
+		}
+		status, file := field[:2], field[3:]
+		if status[0] == 'R' || status[0] == 'C' {
+			i++ // The original path of a rename or copy follows
+		}
+		info, err := os.Stat(filepath.Join(dir, file))
+		if err != nil || info.IsDir() || info.ModTime().Before(since) {
+			continue // Deleted files have no modification time
+		}
+		entries = append(entries, Entry{File: file, Time: info.ModTime(), Kind: KindModified})
+	}
+	return entries, nil
+}
+
+// commits returns the files changed by commits made since since
+func commits(dir string, since time.Time, everyone bool) ([]Entry, error) {
+	args := []string{"log", "--no-merges", "--name-only", "--format=%x1e%h%x1f%ct%x1f%s",
+		"--since=" + since.Format(time.RFC3339)}
+	if !everyone {
+		if email, err := git(dir, "config", "user.email"); err == nil && strings.TrimSpace(email) != "" {
+			args = append(args, "--author="+strings.TrimSpace(email))
+		}
+	}
+	out, err := git(dir, args...)
+	if err != nil {
+		// A repository without commits has no log
+		if _, headErr := git(dir, "rev-parse", "--verify", "--quiet", "HEAD"); headErr != nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, record := range strings.Split(out, "\x1e") {
+		header, files, _ := strings.Cut(record, "\n")
+		parts := strings.SplitN(header, "\x1f", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		unix, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		for _, file := range strings.Split(files, "\n") {
+			if file = strings.TrimSpace(file); file == "" {
+				continue
+			}
+			entries = append(entries, Entry{
+				File:    file,
+				Time:    time.Unix(unix, 0),
+				Kind:    KindCommitted,
+				Commit:  parts[0],
+				Subject: parts[2],
+			})
+		}
+	}
+	return entries, nil
+}
+
+// git runs a git command in dir, returning its output or an error carrying
+// git's message
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
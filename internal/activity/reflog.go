@@ -0,0 +1,53 @@
+package activity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+)
+
+// Actions of reflog entries that summaries count separately; others, such
+// as merge, rebase, pull or cherry-pick, are named by the command that made
+// them
+const (
+	ActionCommit   = "commit"
+	ActionCheckout = "checkout"
+	ActionReset    = "reset"
+)
+
+// ReflogEntry is a move of HEAD recorded in a repository's reflog
+type ReflogEntry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`           // Command that moved HEAD, e.g. commit or checkout
+	Detail  string    `json:"detail,omitempty"` // Rest of the message, e.g. the commit subject
+	Commit  string    `json:"commit"`           // Abbreviated hash HEAD moved to
+	Message string    `json:"message"`
+}
+
+// Reflog returns the entries of the HEAD reflog of repo made since since,
+// newest first. The reflog is local, so every entry is the user's own work.
+func Reflog(repo gitprovider.Repo, since time.Time) ([]ReflogEntry, error) {
+	moves, err := repo.Reflog()
+	if err != nil {
+		return nil, err
+	}
+	var entries []ReflogEntry
+	for _, move := range moves {
+		if move.Time.Before(since) {
+			continue
+		}
+		e := ReflogEntry{Time: move.Time, Commit: move.New, Message: move.Message}
+		if len(e.Commit) > 7 {
+			e.Commit = e.Commit[:7]
+		}
+		// "commit (amend): subject", "checkout: moving from a to b",
+		// "rebase (pick): subject", "pull --rebase origin main (finish): ..."
+		prefix, detail, _ := strings.Cut(move.Message, ": ")
+		e.Action, _, _ = strings.Cut(prefix, " ")
+		e.Action = strings.TrimSuffix(e.Action, ":")
+		e.Detail = strings.TrimSpace(detail)
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
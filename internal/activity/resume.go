@@ -0,0 +1,134 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+)
+
+// Suggestion is the repository the user most likely wants to get back to
+type Suggestion struct {
+	Path   string    // Repository path
+	File   string    // Most recently modified uncommitted file, relative to Path, if any
+	Files  int       // Number of uncommitted files
+	Time   time.Time // When the repository was last worked on
+	Opened bool      // Chosen because it was the last repository opened
+}
+
+// Key identifies the working context of a suggestion; a dismissed
+// suggestion is shown again once its key changes
+func (s *Suggestion) Key() string {
+	return fmt.Sprintf("%s@%d", s.Path, s.Time.Unix())
+}
+
+// Suggest picks the repository with the most recent work: the dirty
+// repository whose uncommitted files were modified last, or the repository
+// last opened with thandie if that happened later. Returns nil if there is
+// neither.
+func Suggest(infos []scanner.DirectoryInfo) *Suggestion {
+	var best *Suggestion
+	for _, info := range infos {
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo || !info.GitMetadata.HasUncommitted {
+			continue
+		}
+		entries, err := Uncommitted(info.Path, time.Time{})
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		newest := entries[0]
+		for _, e := range entries[1:] {
+			if e.Time.After(newest.Time) {
+				newest = e
+			}
+		}
+		if best == nil || newest.Time.After(best.Time) {
+			best = &Suggestion{Path: info.Path, File: newest.File, Files: len(entries), Time: newest.Time}
+		}
+	}
+
+	state := loadState()
+	if state.LastOpened != "" && (best == nil || state.LastOpenedAt.After(best.Time)) {
+		if _, err := os.Stat(state.LastOpened); err == nil {
+			best = &Suggestion{Path: state.LastOpened, Time: state.LastOpenedAt, Opened: true}
+		}
+	}
+	return best
+}
+
+// state is what thandie remembers about the user's working context
+type state struct {
+	LastOpened   string    `json:"last_opened,omitempty"` // Repository last opened with thandie
+	LastOpenedAt time.Time `json:"last_opened_at,omitzero"`
+	Dismissed    string    `json:"dismissed,omitempty"` // Key of the dismissed suggestion
+}
+
+// RecordOpened remembers that the repository at path was opened in the editor
+func RecordOpened(path string) error {
+	s := loadState()
+	s.LastOpened, s.LastOpenedAt = path, time.Now()
+	return saveState(s)
+}
+
+// Dismiss hides the suggestion until the working context changes
+func Dismiss(s *Suggestion) error {
+	st := loadState()
+	st.Dismissed = s.Key()
+	return saveState(st)
+}
+
+// Dismissed reports whether the suggestion was dismissed
+func Dismissed(s *Suggestion) bool {
+	return loadState().Dismissed == s.Key()
+}
+
+// statePath returns the file holding the working context state
+func statePath() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "resume.json"), nil
+}
+
+// loadState reads the state, returning an empty state if there is none
+func loadState() state {
+	var s state
+	path, err := statePath()
+	if err != nil {
+		return s
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if data, err = seal.Open(data); err == nil {
+			_ = json.Unmarshal(data, &s)
+		}
+	}
+	return s
+}
+
+// saveState writes the state
+func saveState(s state) error {
+	path, err := statePath()
+	if err != nil {
+		return fmt.Errorf("failed to locate state file: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if data, err = seal.Seal(data); err != nil {
+		return fmt.Errorf("failed to encrypt state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package attest signs files thandie exports with a local key, so whoever
+// receives them can verify they come from this agent and weren't altered,
+// and verifies files thandie receives, such as policy packs.
+package attest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Namespace is the ssh-keygen -Y namespace of signatures, which verifiers
+// must pass to `ssh-keygen -Y verify -n`
+const Namespace = "thandie-export"
+
+// PolicyNamespace is the ssh-keygen -Y namespace policy packs are signed in
+const PolicyNamespace = "thandie-policy"
+
+// Key formats
+const (
+	FormatSSH      = "ssh"      // An OpenSSH private key, signed with `ssh-keygen -Y sign`
+	FormatMinisign = "minisign" // A minisign secret key, signed with `minisign -S`
+)
+
+// Format returns the format of the key file at keyPath. minisign secret keys
+// start with an "untrusted comment:" line; anything else is handed to
+// ssh-keygen, which also accepts a public key whose private half is in the
+// SSH agent.
+func Format(keyPath string) (string, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read signing key: %w", err)
+	}
+	if bytes.HasPrefix(data, []byte("untrusted comment:")) {
+		return FormatMinisign, nil
+	}
+	return FormatSSH, nil
+}
+
+// Sign signs the file at path with the key at keyPath and returns the path
+// of the detached signature written next to it: path.sig for SSH keys and
+// path.minisig for minisign keys.
+func Sign(keyPath, path string) (string, error) {
+	format, err := Format(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	var cmd *exec.Cmd
+	var sigPath string
+	switch format {
+	case FormatMinisign:
+		sigPath = path + ".minisig"
+		cmd = exec.Command("minisign", "-S", "-s", keyPath, "-m", path, "-x", sigPath)
+		// minisign prompts for the key's password, if it has one
+		cmd.Stdin = os.Stdin
+	default:
+		sigPath = path + ".sig"
+		// ssh-keygen refuses to overwrite an existing signature
+		if err := os.Remove(sigPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to replace %s: %w", sigPath, err)
+		}
+		cmd = exec.Command("ssh-keygen", "-Y", "sign", "-q", "-f", keyPath, "-n", Namespace, path)
+		cmd.Stdin = os.Stdin
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("failed to sign %s with %s: %s", path, cmd.Args[0], strings.ReplaceAll(msg, "\n", "; "))
+		}
+		return "", fmt.Errorf("failed to sign %s with %s: %w", path, cmd.Args[0], err)
+	}
+	return sigPath, nil
+}
+
+// Verify checks that sig is an SSH signature of data in namespace by one of
+// the keys in the allowed_signers file at allowedSigners (see ssh-keygen(1))
+// and returns the principal that signed it
+func Verify(allowedSigners, namespace string, data, sig []byte) (string, error) {
+	dir, err := os.MkdirTemp("", "thandie-verify-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	sigPath := filepath.Join(dir, "data.sig")
+	if err := os.WriteFile(sigPath, sig, 0600); err != nil {
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	out, err := sshKeygen(nil, "-Y", "find-principals", "-f", allowedSigners, "-s", sigPath)
+	if err != nil {
+		return "", fmt.Errorf("signature is not by an allowed signer: %w", err)
+	}
+	principal, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if _, err := sshKeygen(data, "-Y", "verify", "-f", allowedSigners, "-I", principal, "-n", namespace, "-s", sigPath); err != nil {
+		return "", fmt.Errorf("bad signature: %w", err)
+	}
+	return principal, nil
+}
+
+// sshKeygen runs ssh-keygen with stdin on its standard input and returns its
+// output, or an error carrying its message
+func sshKeygen(stdin []byte, args ...string) (string, error) {
+	cmd := exec.Command("ssh-keygen", args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(strings.ReplaceAll(msg, "\n", "; "))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,280 @@
+// Package audit runs dependency vulnerability scanners over repositories.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// tool is a vulnerability scanner for one ecosystem
+type tool struct {
+	name    string
+	applies func(dir string) bool
+	run     func(ctx context.Context, dir string) ([]scanner.Vulnerability, error)
+}
+
+var tools = []tool{
+	{name: "govulncheck", applies: hasAny("go.mod"), run: runGovulncheck},
+	{name: "npm", applies: hasAny("package-lock.json", "npm-shrinkwrap.json"), run: runNpmAudit},
+	{name: "pip-audit", applies: hasAny("requirements.txt", "pyproject.toml"), run: runPipAudit},
+}
+
+// Run audits every git repository in infos, setting its Audit. At most
+// concurrency repositories are audited at once.
+func Run(ctx context.Context, infos []scanner.DirectoryInfo, concurrency int) {
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range infos {
+		info := &infos[i]
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info.Audit = Repo(ctx, info.Path)
+		}()
+	}
+	wg.Wait()
+}
+
+// Repo runs every applicable, installed tool over the repository at dir
+func Repo(ctx context.Context, dir string) *scanner.Audit {
+	result := &scanner.Audit{FetchedAt: time.Now(), Counts: make(map[string]int)}
+
+	for _, t := range tools {
+		if !t.applies(dir) {
+			continue
+		}
+		if _, err := exec.LookPath(t.name); err != nil {
+			result.Missing = append(result.Missing, t.name)
+			continue
+		}
+
+		vulns, err := t.run(ctx, dir)
+		if err != nil {
+			logger.Warn("audit tool failed", "tool", t.name, "path", dir, "error", err)
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", t.name, err))
+			continue
+		}
+		result.Tools = append(result.Tools, t.name)
+		for _, v := range vulns {
+			result.Counts[v.Severity]++
+		}
+		result.Vulnerabilities = append(result.Vulnerabilities, vulns...)
+	}
+
+	sort.SliceStable(result.Vulnerabilities, func(i, j int) bool {
+		return scanner.SeverityRank(result.Vulnerabilities[i].Severity) < scanner.SeverityRank(result.Vulnerabilities[j].Severity)
+	})
+	return result
+}
+
+// hasAny returns a predicate reporting whether dir contains any of the files
+func hasAny(files ...string) func(dir string) bool {
+	return func(dir string) bool {
+		for _, f := range files {
+			if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// output runs a tool and returns its stdout. Audit tools exit non-zero when
+// they find vulnerabilities, so a failing exit status is only an error if
+// nothing was written to stdout.
+func output(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s", lastLine(msg))
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func lastLine(s string) string {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// runGovulncheck reports vulnerabilities whose vulnerable code is reachable.
+// The Go vulnerability database has no severities, so they are all unknown.
+func runGovulncheck(ctx context.Context, dir string) ([]scanner.Vulnerability, error) {
+	out, err := output(ctx, dir, "govulncheck", "-json", "./...")
+	if err != nil {
+		return nil, err
+	}
+
+	type message struct {
+		OSV *struct {
+			ID      string `json:"id"`
+			Summary string `json:"summary"`
+		} `json:"osv"`
+		Finding *struct {
+			OSV   string `json:"osv"`
+			Trace []struct {
+				Module   string `json:"module"`
+				Function string `json:"function"`
+			} `json:"trace"`
+		} `json:"finding"`
+	}
+
+	summaries := make(map[string]string)
+	found := make(map[string]string) // OSV ID -> module
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for dec.More() {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return nil, fmt.Errorf("failed to parse govulncheck output: %w", err)
+		}
+		if msg.OSV != nil {
+			summaries[msg.OSV.ID] = msg.OSV.Summary
+		}
+		// Only symbol-level findings (with a function) are actually called
+		if f := msg.Finding; f != nil && len(f.Trace) > 0 && f.Trace[0].Function != "" {
+			found[f.OSV] = f.Trace[0].Module
+		}
+	}
+
+	var vulns []scanner.Vulnerability
+	for id, module := range found {
+		vulns = append(vulns, scanner.Vulnerability{
+			ID:       id,
+			Package:  module,
+			Severity: scanner.SeverityUnknown,
+			Summary:  summaries[id],
+			Tool:     "govulncheck",
+		})
+	}
+	sort.Slice(vulns, func(i, j int) bool { return vulns[i].ID < vulns[j].ID })
+	return vulns, nil
+}
+
+// runNpmAudit parses the npm 7+ `npm audit --json` report
+func runNpmAudit(ctx context.Context, dir string) ([]scanner.Vulnerability, error) {
+	out, err := output(ctx, dir, "npm", "audit", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	var report struct {
+		Vulnerabilities map[string]struct {
+			Severity string            `json:"severity"`
+			Via      []json.RawMessage `json:"via"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse npm audit output: %w", err)
+	}
+
+	var vulns []scanner.Vulnerability
+	for name, entry := range report.Vulnerabilities {
+		for _, raw := range entry.Via {
+			// via holds advisories, or names of vulnerable dependencies that are reported separately
+			var advisory struct {
+				Title    string `json:"title"`
+				URL      string `json:"url"`
+				Severity string `json:"severity"`
+			}
+			if json.Unmarshal(raw, &advisory) != nil || advisory.URL == "" {
+				continue
+			}
+			vulns = append(vulns, scanner.Vulnerability{
+				ID:       advisory.URL[strings.LastIndex(advisory.URL, "/")+1:],
+				Package:  name,
+				Severity: npmSeverity(advisory.Severity),
+				Summary:  advisory.Title,
+				Tool:     "npm",
+			})
+		}
+	}
+	sort.Slice(vulns, func(i, j int) bool { return vulns[i].Package < vulns[j].Package })
+	return vulns, nil
+}
+
+func npmSeverity(s string) string {
+	switch s {
+	case scanner.SeverityCritical, scanner.SeverityHigh, scanner.SeverityModerate, scanner.SeverityLow:
+		return s
+	case "info":
+		return scanner.SeverityLow
+	}
+	return scanner.SeverityUnknown
+}
+
+// runPipAudit parses `pip-audit -f json`. PyPI advisories carry no
+// severity, so they are all unknown.
+func runPipAudit(ctx context.Context, dir string) ([]scanner.Vulnerability, error) {
+	args := []string{"-f", "json", "--progress-spinner", "off"}
+	if _, err := os.Stat(filepath.Join(dir, "requirements.txt")); err == nil {
+		args = append(args, "-r", "requirements.txt")
+	} else {
+		args = append(args, ".")
+	}
+	out, err := output(ctx, dir, "pip-audit", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	type dependency struct {
+		Name  string `json:"name"`
+		Vulns []struct {
+			ID          string `json:"id"`
+			Description string `json:"description"`
+		} `json:"vulns"`
+	}
+	// Newer versions wrap the list in {"dependencies": [...]}
+	var report struct {
+		Dependencies []dependency `json:"dependencies"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		if err := json.Unmarshal(out, &report.Dependencies); err != nil {
+			return nil, fmt.Errorf("failed to parse pip-audit output: %w", err)
+		}
+	}
+
+	var vulns []scanner.Vulnerability
+	for _, dep := range report.Dependencies {
+		for _, v := range dep.Vulns {
+			vulns = append(vulns, scanner.Vulnerability{
+				ID:       v.ID,
+				Package:  dep.Name,
+				Severity: scanner.SeverityUnknown,
+				Summary:  firstLine(v.Description),
+				Tool:     "pip-audit",
+			})
+		}
+	}
+	return vulns, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+// Package auditlog appends a structured record of every write action thandie
+// performs (hooks installed, secrets stored, messages sent, ...) to an
+// append-only JSON lines file.
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+)
+
+// Results recorded for an action
+const (
+	ResultOK    = "ok"
+	ResultError = "error"
+)
+
+// Record is one write action
+type Record struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Host   string    `json:"host,omitempty"`
+	Action string    `json:"action"`           // e.g. hooks.apply, secrets.set
+	Target string    `json:"target,omitempty"` // What was written, e.g. a repository or secret alias
+	Detail string    `json:"detail,omitempty"`
+	Result string    `json:"result"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Path returns the audit log file
+func Path() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs", "audit.log"), nil
+}
+
+// Write records the outcome of an action on target: ResultOK if err is nil,
+// ResultError otherwise. Failures to write the log are logged, not returned,
+// so they never fail the action itself.
+func Write(action, target, detail string, err error) {
+	record := Record{Action: action, Target: target, Detail: detail, Result: ResultOK}
+	if err != nil {
+		record.Result = ResultError
+		record.Error = err.Error()
+	}
+	Append(record)
+}
+
+// Append fills in the time, user and host of record and appends it to the log
+func Append(record Record) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.User == "" {
+		record.User = currentUser()
+	}
+	if record.Host == "" {
+		record.Host, _ = os.Hostname()
+	}
+
+	if err := appendRecord(record); err != nil {
+		logger.Warn("failed to write audit log", "action", record.Action, "error", err)
+	}
+}
+
+// appendRecord writes record as one JSON line
+func appendRecord(record Record) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// Read returns every record in the log, oldest first. A missing log has no
+// records; malformed lines are skipped.
+func Read() ([]Record, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// currentUser returns the name of the user running thandie
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	for _, env := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(env); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
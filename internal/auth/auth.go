@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RequiredScopes lists the token scopes each provider needs for enrichment
+// (pull requests, CI status, issues and organization repositories)
+var RequiredScopes = map[string][]string{
+	"github": {"repo", "read:org"},
+	"gitlab": {"read_api"},
+}
+
+// impliedScopes lists scopes that grant a superset of another scope
+var impliedScopes = map[string][]string{
+	"read:org": {"admin:org", "write:org"},
+	"read_api": {"api"},
+}
+
+// TokenInfo describes a validated provider token
+type TokenInfo struct {
+	Login         string   // Account the token belongs to
+	Scopes        []string // Scopes granted to the token
+	ScopesKnown   bool     // False when the provider doesn't report scopes (e.g. GitHub fine-grained tokens)
+	MissingScopes []string // Required scopes the token lacks
+}
+
+// httpClient is used for all provider requests made during authentication
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// Validate checks a token against the provider API and reports the account it
+// belongs to and any scopes required for enrichment that it lacks
+func Validate(ctx context.Context, provider, apiURL, token string) (*TokenInfo, error) {
+	var info *TokenInfo
+	var err error
+	switch provider {
+	case "github":
+		info, err = validateGitHub(ctx, apiURL, token)
+	case "gitlab":
+		info, err = validateGitLab(ctx, apiURL, token)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.ScopesKnown {
+		for _, required := range RequiredScopes[provider] {
+			if !hasScope(info.Scopes, required) {
+				info.MissingScopes = append(info.MissingScopes, required)
+			}
+		}
+	}
+	return info, nil
+}
+
+// hasScope reports whether scopes grants required, directly or via a broader scope
+func hasScope(scopes []string, required string) bool {
+	if slices.Contains(scopes, required) {
+		return true
+	}
+	for _, broader := range impliedScopes[required] {
+		if slices.Contains(scopes, broader) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateGitHub validates a GitHub token using the authenticated user endpoint.
+// Classic tokens report their scopes in the X-OAuth-Scopes header.
+func validateGitHub(ctx context.Context, apiURL, token string) (*TokenInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/user", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	var user struct {
+		Login string `json:"login"`
+	}
+	resp, err := doJSON(req, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TokenInfo{Login: user.Login}
+	if header, ok := resp.Header["X-Oauth-Scopes"]; ok {
+		info.ScopesKnown = true
+		for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				info.Scopes = append(info.Scopes, scope)
+			}
+		}
+	}
+	return info, nil
+}
+
+// validateGitLab validates a GitLab token using the token self-inspection endpoint
+func validateGitLab(ctx context.Context, apiURL, token string) (*TokenInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(apiURL, "/")+"/personal_access_tokens/self", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", token)
+
+	var self struct {
+		Scopes []string `json:"scopes"`
+		UserID int      `json:"user_id"`
+	}
+	if _, err := doJSON(req, &self); err != nil {
+		return nil, err
+	}
+
+	return &TokenInfo{
+		Login:       fmt.Sprintf("user #%d", self.UserID),
+		Scopes:      self.Scopes,
+		ScopesKnown: true,
+	}, nil
+}
+
+// doJSON performs req and decodes a successful JSON response into out
+func doJSON(req *http.Request, out any) (*http.Response, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("token rejected by %s (HTTP %d)", req.URL.Host, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from %s: HTTP %d", req.URL.Host, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %s: %w", req.URL.Host, err)
+	}
+	return resp, nil
+}
+
+// DeviceCode is the code a user enters to authorize a device-flow login
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// StartGitHubDeviceFlow requests a device code for the OAuth app with the given client ID
+func StartGitHubDeviceFlow(ctx context.Context, webURL, clientID string) (*DeviceCode, error) {
+	form := url.Values{
+		"client_id": {clientID},
+		"scope":     {strings.Join(RequiredScopes["github"], " ")},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(webURL, "/")+"/login/device/code", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var code DeviceCode
+	if _, err := doJSON(req, &code); err != nil {
+		return nil, err
+	}
+	if code.DeviceCode == "" {
+		return nil, fmt.Errorf("device flow was not started; check that device flow is enabled for client %s", clientID)
+	}
+	return &code, nil
+}
+
+// PollGitHubDeviceFlow waits for the user to authorize the device code and returns the access token
+func PollGitHubDeviceFlow(ctx context.Context, webURL, clientID string, code *DeviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	form := url.Values{
+		"client_id":   {clientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(interval):
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(webURL, "/")+"/login/oauth/access_token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/json")
+
+		var result struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+			Interval    int    `json:"interval"`
+		}
+		if _, err := doJSON(req, &result); err != nil {
+			return "", err
+		}
+
+		switch result.Error {
+		case "":
+			return result.AccessToken, nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			if result.Interval > 0 {
+				interval = time.Duration(result.Interval) * time.Second
+			}
+		case "expired_token":
+			return "", fmt.Errorf("device code expired before authorization")
+		case "access_denied":
+			return "", fmt.Errorf("authorization was denied")
+		default:
+			return "", fmt.Errorf("device flow failed: %s", result.Error)
+		}
+	}
+	return "", fmt.Errorf("device code expired before authorization")
+}
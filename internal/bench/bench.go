@@ -0,0 +1,187 @@
+// Package bench generates synthetic workspaces and measures scan performance
+// against stored baselines.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Generate creates n directories in dir: mostly git repositories with one
+// commit, every fifth of them with an uncommitted change, and every tenth
+// directory a plain non-git directory
+func Generate(dir string, n, concurrency int) error {
+	sem := make(chan struct{}, max(concurrency, 1))
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := generateRepo(filepath.Join(dir, fmt.Sprintf("repo-%04d", i)), i); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// generateRepo creates the i-th synthetic directory at path
+func generateRepo(path string, i int) error {
+	files := map[string]string{
+		"README.md":     fmt.Sprintf("# repo %d\n", i),
+		"go.mod":        fmt.Sprintf("module example.com/repo%d\n\ngo 1.22\n", i),
+		"main.go":       "package main\n\nfunc main() {}\n",
+		"internal/a.go": "package internal\n\n// A is synthetic\nconst A = 1\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(path, name)), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(path, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	if i%10 == 9 {
+		return nil
+	}
+
+	repo, err := git.PlainInit(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to init %s: %w", path, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+	signature := &object.Signature{Name: "thandie bench", Email: "bench@example.com", When: time.Now()}
+	if _, err := worktree.Commit("Initial commit", &git.CommitOptions{Author: signature}); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", path, err)
+	}
+	if i%5 == 0 {
+		return os.WriteFile(filepath.Join(path, "main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0644)
+	}
+	return nil
+}
+
+// Result holds the measurements of one benchmark run
+type Result struct {
+	Directories int           `json:"directories"`
+	ColdScan    time.Duration `json:"cold_scan"`
+	WarmScan    time.Duration `json:"warm_scan"` // Rescan reusing the cold scan's git status
+	CacheWrite  time.Duration `json:"cache_write"`
+	AllocBytes  uint64        `json:"alloc_bytes"` // Allocated during both scans
+	HeapBytes   uint64        `json:"heap_bytes"`  // Live heap after the scans
+	RecordedAt  time.Time     `json:"recorded_at"`
+}
+
+// Throughput returns cold-scanned directories per second
+func (r Result) Throughput() float64 {
+	if r.ColdScan <= 0 {
+		return 0
+	}
+	return float64(r.Directories) / r.ColdScan.Seconds()
+}
+
+// Run scans the workspace at dir twice and writes the result to a cache in
+// cacheDir, measuring each step
+func Run(dir, cacheDir string, concurrency int) (Result, error) {
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	plan, err := scanner.PlanScan(dir, nil, false, 1)
+	if err != nil {
+		return Result{}, err
+	}
+	infos := scanner.ScanPlanned(context.Background(), plan, nil, concurrency, 0, nil, nil)
+	result := Result{Directories: len(infos), ColdScan: time.Since(start), RecordedAt: time.Now()}
+
+	start = time.Now()
+	infos = scanner.ScanPlanned(context.Background(), plan, nil, concurrency, 0, infos, nil)
+	result.WarmScan = time.Since(start)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	result.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	result.HeapBytes = after.HeapAlloc
+
+	c, err := cache.NewAt(cacheDir)
+	if err != nil {
+		return Result{}, err
+	}
+	start = time.Now()
+	if err := c.Save(&cache.ScanResult{WorkspacePath: dir, DirectoryInfos: infos}); err != nil {
+		return Result{}, err
+	}
+	result.CacheWrite = time.Since(start)
+	return result, nil
+}
+
+// Baselines are stored results keyed by the number of synthetic directories
+type Baselines map[string]Result
+
+// BaselinePath returns the file holding the stored baselines
+func BaselinePath() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "bench", "baselines.json"), nil
+}
+
+// LoadBaselines reads the stored baselines; a missing file yields none
+func LoadBaselines(path string) (Baselines, error) {
+	baselines := Baselines{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return baselines, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baselines: %w", err)
+	}
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return nil, fmt.Errorf("failed to parse baselines: %w", err)
+	}
+	return baselines, nil
+}
+
+// Save writes the baselines to path
+func (b Baselines) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create baseline directory: %w", err)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal baselines: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write baselines: %w", err)
+	}
+	return nil
+}
+
+// Key returns the baseline key for a run over n synthetic directories
+func Key(n int) string {
+	return strconv.Itoa(n)
+}
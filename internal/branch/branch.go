@@ -0,0 +1,63 @@
+// Package branch creates and checks out branches across repositories.
+package branch
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Outcomes of Create
+const (
+	StatusCreated  = "created"
+	StatusSwitched = "switched" // The branch already existed and was checked out
+)
+
+// ValidName reports an error if name isn't a valid branch name
+func ValidName(name string) error {
+	if _, err := git("", "check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+// Create creates branch name in the repository at repoDir, starting at from
+// (HEAD if empty), and checks it out. An existing branch is checked out as it
+// is. Uncommitted changes are carried over as with git checkout; if they
+// conflict, git refuses and the error says why.
+func Create(repoDir, name, from string) (string, error) {
+	if _, err := git(repoDir, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+		if _, err := git(repoDir, "checkout", name); err != nil {
+			return "", err
+		}
+		return StatusSwitched, nil
+	}
+
+	args := []string{"checkout", "-b", name}
+	if from != "" {
+		args = append(args, from)
+	}
+	if _, err := git(repoDir, args...); err != nil {
+		return "", err
+	}
+	return StatusCreated, nil
+}
+
+// git runs a git command in dir, returning its output or an error carrying
+// git's message
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			msg = strings.ReplaceAll(msg, "\n", "; ")
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,130 @@
+package branch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Outcomes of MigrateDefault
+const (
+	MigrationDone    = "migrated"
+	MigrationAlready = "already migrated"
+	MigrationManual  = "needs attention" // Local branches were left alone; Reason says why
+)
+
+// Migration is the outcome of MigrateDefault for one repository
+type Migration struct {
+	Status  string
+	Actions []string // What was done, or with dryRun what would be done
+	Reason  string   // Why the repository needs attention
+	Notes   []string // Things worth knowing that didn't stop the migration
+}
+
+// MigrateDefault moves the repository at repoDir from default branch from to
+// to, following a rename on the origin remote: it fetches origin, points
+// origin/HEAD at origin/<to>, and renames the local from branch to to,
+// tracking origin/<to>. The local branch is only renamed when origin has a
+// to branch, origin's default is to, there is no local to branch yet and
+// from tracks origin/<from> (or nothing); otherwise the repository is left
+// alone and reported. With dryRun nothing is fetched or changed, and the
+// decision is based on the last fetch.
+func MigrateDefault(repoDir, from, to string, dryRun bool) (*Migration, error) {
+	m := &Migration{}
+	if _, err := git(repoDir, "remote", "get-url", "origin"); err != nil {
+		return manual(m, "no origin remote"), nil
+	}
+
+	if !dryRun {
+		if _, err := git(repoDir, "fetch", "--prune", "--quiet", "origin"); err != nil {
+			return nil, err
+		}
+	}
+	if !refExists(repoDir, "refs/remotes/origin/"+to) {
+		return manual(m, fmt.Sprintf("origin has no %s branch", to)), nil
+	}
+
+	// origin/HEAD follows the remote's default branch
+	before := originHead(repoDir)
+	if !dryRun {
+		if _, err := git(repoDir, "remote", "set-head", "origin", "--auto"); err != nil {
+			return nil, err
+		}
+	}
+	switch head := originHead(repoDir); {
+	case head == to && before != to:
+		m.Actions = append(m.Actions, "origin/HEAD → origin/"+to)
+	case head != to && dryRun:
+		m.Actions = append(m.Actions, "update origin/HEAD from the remote")
+	case head != to:
+		return manual(m, fmt.Sprintf("origin's default branch is %s, not %s", head, to)), nil
+	}
+
+	hasFrom := refExists(repoDir, "refs/heads/"+from)
+	hasTo := refExists(repoDir, "refs/heads/"+to)
+	switch {
+	case hasFrom && hasTo:
+		return manual(m, fmt.Sprintf("local %s and %s both exist; merge or delete one", from, to)), nil
+	case !hasFrom && !hasTo:
+		return finish(m), nil
+	case hasTo:
+		upstream, _ := git(repoDir, "rev-parse", "--abbrev-ref", to+"@{upstream}")
+		if strings.TrimSpace(upstream) == "origin/"+to {
+			return finish(m), nil
+		}
+		if !dryRun {
+			if _, err := git(repoDir, "branch", "--set-upstream-to=origin/"+to, to); err != nil {
+				return nil, err
+			}
+		}
+		m.Actions = append(m.Actions, fmt.Sprintf("%s now tracks origin/%s", to, to))
+		return finish(m), nil
+	}
+
+	upstream, err := git(repoDir, "rev-parse", "--abbrev-ref", from+"@{upstream}")
+	if upstream = strings.TrimSpace(upstream); err == nil && upstream != "origin/"+from && upstream != "origin/"+to {
+		return manual(m, fmt.Sprintf("local %s tracks %s, not origin/%s", from, upstream, from)), nil
+	}
+	if out, err := git(repoDir, "rev-list", "--count", "origin/"+to+".."+from); err == nil {
+		if n, _ := strconv.Atoi(strings.TrimSpace(out)); n > 0 {
+			m.Notes = append(m.Notes, fmt.Sprintf("%d local commits not on origin/%s", n, to))
+		}
+	}
+	if !dryRun {
+		if _, err := git(repoDir, "branch", "--move", from, to); err != nil {
+			return nil, err
+		}
+		if _, err := git(repoDir, "branch", "--set-upstream-to=origin/"+to, to); err != nil {
+			return nil, err
+		}
+	}
+	m.Actions = append(m.Actions, fmt.Sprintf("renamed %s to %s, tracking origin/%s", from, to, to))
+	return finish(m), nil
+}
+
+// finish marks a migration as done, or as already done if it took no actions
+func finish(m *Migration) *Migration {
+	m.Status = MigrationDone
+	if len(m.Actions) == 0 {
+		m.Status = MigrationAlready
+	}
+	return m
+}
+
+// manual marks a migration as needing attention
+func manual(m *Migration, reason string) *Migration {
+	m.Status, m.Reason = MigrationManual, reason
+	return m
+}
+
+// originHead returns the branch origin/HEAD points at, or "" if it isn't set
+func originHead(repoDir string) string {
+	head, _ := git(repoDir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	return strings.TrimPrefix(strings.TrimSpace(head), "origin/")
+}
+
+// refExists reports whether ref exists in the repository at repoDir
+func refExists(repoDir, ref string) bool {
+	_, err := git(repoDir, "rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
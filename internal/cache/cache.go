@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
 )
 
 // ScanResult represents the cached results of a workspace scan
@@ -19,6 +20,95 @@ type ScanResult struct {
 	Directories    []string                `json:"directories"` // Deprecated: use DirectoryInfos instead
 	Count          int                     `json:"count"`
 	DirectoryInfos []scanner.DirectoryInfo `json:"directory_infos"`
+	Skipped        []scanner.PlanEntry     `json:"skipped,omitempty"`     // Directories excluded by the scanner config, with the rule responsible
+	Fingerprint    string                  `json:"fingerprint,omitempty"` // scanner.Fingerprint of the workspace before the last full scan
+	Settings       string                  `json:"settings,omitempty"`    // config.ScannerConfig.SettingsHash of the last full scan
+	FullScanAt     time.Time               `json:"full_scan_at,omitzero"` // Last scan that collected metadata; ScannedAt also counts unchanged checks
+	History        []ScanRecord            `json:"history,omitempty"`     // Oldest first, capped at MaxHistory
+	Missing        []MissingDir            `json:"missing,omitempty"`     // Scanned directories that have since disappeared, see TrackMissing
+}
+
+// MissingDir is a directory that an earlier scan found and that no longer
+// exists, with its last known state
+type MissingDir struct {
+	Info  scanner.DirectoryInfo `json:"info"`
+	Since time.Time             `json:"since"` // First scan that didn't find it
+}
+
+// Scan statuses recorded in the history
+const (
+	ScanFull      = "full"
+	ScanUnchanged = "skipped (unchanged)"
+	ScanPartial   = "partial" // Only the directories matching `scan --only` were rescanned
+)
+
+// MaxHistory is the number of scans kept in ScanResult.History
+const MaxHistory = 100
+
+// ScanRecord is one entry of the scan history. The counts after Directories
+// are of the whole workspace once the scan finished, also for partial scans.
+type ScanRecord struct {
+	At           time.Time     `json:"at"`
+	Status       string        `json:"status"`
+	Duration     time.Duration `json:"duration"`
+	Directories  int           `json:"directories"`
+	Dirty        int           `json:"dirty,omitempty"`
+	Missing      int           `json:"missing,omitempty"`
+	Errored      int           `json:"errored,omitempty"`       // Directories whose metadata collection was abandoned
+	AccessDenied int           `json:"access_denied,omitempty"` // Directories that couldn't be read
+}
+
+// LastScan returns the most recent entry of the history, if any
+func (r *ScanResult) LastScan() (ScanRecord, bool) {
+	if len(r.History) == 0 {
+		return ScanRecord{}, false
+	}
+	return r.History[len(r.History)-1], true
+}
+
+// Record appends an entry to the history, dropping the oldest beyond MaxHistory
+func (r *ScanResult) Record(record ScanRecord) {
+	r.History = append(r.History, record)
+	if len(r.History) > MaxHistory {
+		r.History = r.History[len(r.History)-MaxHistory:]
+	}
+}
+
+// TrackMissing records the directories of previous that no longer exist as
+// missing, keeping those already missing for up to keep. Directories that
+// exist again, or were merely excluded from the scan, are not missing.
+// previous may be r itself when a scan reused the previous result. Returns
+// the directories that went missing since previous.
+func (r *ScanResult) TrackMissing(previous *ScanResult, keep time.Duration, now time.Time) []MissingDir {
+	var candidates []MissingDir
+	if previous != nil {
+		candidates = append(candidates, previous.Missing...)
+		for _, info := range previous.DirectoryInfos {
+			candidates = append(candidates, MissingDir{Info: info, Since: now})
+		}
+	}
+
+	found := make(map[string]bool, len(r.DirectoryInfos))
+	for _, info := range r.DirectoryInfos {
+		found[info.Path] = true
+	}
+	var missing, gone []MissingDir
+	for _, dir := range candidates {
+		path := dir.Info.Path
+		if found[path] || now.Sub(dir.Since) >= keep {
+			continue
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		found[path] = true
+		missing = append(missing, dir)
+		if dir.Since.Equal(now) {
+			gone = append(gone, dir)
+		}
+	}
+	r.Missing = missing
+	return gone
 }
 
 // Cache manages scan result caching
@@ -43,8 +133,19 @@ func New() (*Cache, error) {
 	}, nil
 }
 
-// getCacheDir returns the platform-appropriate cache directory
-func getCacheDir() (string, error) {
+// NewAt creates a cache instance storing results in cacheDir instead of the
+// user cache directory
+func NewAt(cacheDir string) (*Cache, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{cacheDir: cacheDir}, nil
+}
+
+// Dir returns thandie's own directory under the platform's cache directory,
+// or under ~/.cache if there is none. It holds the scan caches and every
+// other file thandie keeps, such as logs, indexes and the daemon's socket.
+func Dir() (string, error) {
 	cacheDir, err := os.UserCacheDir()
 	if err != nil {
 		// Fallback to home directory if cache dir unavailable
@@ -54,8 +155,16 @@ func getCacheDir() (string, error) {
 		}
 		cacheDir = filepath.Join(homeDir, ".cache")
 	}
+	return filepath.Join(cacheDir, "thandie"), nil
+}
 
-	return filepath.Join(cacheDir, "thandie", "cache"), nil
+// getCacheDir returns the directory holding the scan caches
+func getCacheDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache"), nil
 }
 
 // SaveScanResult saves scan results to the cache
@@ -71,31 +180,75 @@ func (c *Cache) SaveScanResult(workspacePath string, directories []string) error
 
 // SaveScanResultWithMetadata saves scan results with metadata to the cache
 func (c *Cache) SaveScanResultWithMetadata(workspacePath string, directoryInfos []scanner.DirectoryInfo) error {
-	// Extract directory paths for backward compatibility
-	directories := make([]string, len(directoryInfos))
-	for i, info := range directoryInfos {
-		directories[i] = info.Path
-	}
-
-	result := ScanResult{
+	return c.Save(&ScanResult{
 		WorkspacePath:  workspacePath,
-		ScannedAt:      time.Now(),
-		Directories:    directories, // Keep for backward compatibility
-		Count:          len(directoryInfos),
 		DirectoryInfos: directoryInfos,
+	})
+}
+
+// Save writes a scan result to the cache. ScannedAt defaults to now, and the
+// deprecated Directories and Count fields are derived from DirectoryInfos.
+// The file is replaced atomically under the lock Update also takes.
+func (c *Cache) Save(result *ScanResult) error {
+	unlock, err := lockFile(c.getCacheFilePath(result.WorkspacePath))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return c.save(result)
+}
+
+// Update loads the cached scan result of a workspace, lets update change it
+// and saves it, holding the cache file's lock throughout so that a scan
+// saved by another process in between isn't overwritten. Nothing is saved if
+// update returns an error.
+func (c *Cache) Update(workspacePath string, update func(*ScanResult) error) error {
+	unlock, err := lockFile(c.getCacheFilePath(workspacePath))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	result, err := c.LoadScanResult(workspacePath)
+	if err != nil {
+		return err
 	}
+	if err := update(result); err != nil {
+		return err
+	}
+	return c.save(result)
+}
+
+// save writes a scan result to the cache; the caller holds the lock
+func (c *Cache) save(result *ScanResult) error {
+	if result.ScannedAt.IsZero() {
+		result.ScannedAt = time.Now()
+	}
+
+	// Extract directory paths for backward compatibility
+	result.Directories = make([]string, len(result.DirectoryInfos))
+	for i, info := range result.DirectoryInfos {
+		result.Directories[i] = info.Path
+	}
+	result.Count = len(result.DirectoryInfos)
 
 	// Create a safe filename from workspace path (hash or sanitize)
-	cacheFile := c.getCacheFilePath(workspacePath)
+	cacheFile := c.getCacheFilePath(result.WorkspacePath)
 
 	// Marshal to JSON
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal scan result: %w", err)
 	}
+	// Compressed before sealing, as encrypted data doesn't compress
+	if data, err = compress(data); err != nil {
+		return fmt.Errorf("failed to compress scan result: %w", err)
+	}
+	if data, err = seal.Seal(data); err != nil {
+		return fmt.Errorf("failed to encrypt scan result: %w", err)
+	}
 
-	// Write to file
-	if err := os.WriteFile(cacheFile, data, 0644); err != nil {
+	if err := writeAtomic(cacheFile, data); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
@@ -111,19 +264,17 @@ func (c *Cache) LoadScanResult(workspacePath string) (*ScanResult, error) {
 		return nil, fmt.Errorf("no cached scan result found for workspace: %s", workspacePath)
 	}
 
-	// Read cache file
-	data, err := os.ReadFile(cacheFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read cache file: %w", err)
-	}
+	return LoadFile(cacheFile)
+}
 
-	// Unmarshal JSON
-	var result ScanResult
-	if err := json.Unmarshal(data, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal cache file: %w", err)
+// LoadScanSummary loads the most recent scan result for a workspace like
+// LoadScanResult, without the fields LoadFileSummary leaves out
+func (c *Cache) LoadScanSummary(workspacePath string) (*ScanResult, error) {
+	cacheFile := c.getCacheFilePath(workspacePath)
+	if _, err := os.Stat(cacheFile); os.IsNotExist(err) {
+		return nil, fmt.Errorf("no cached scan result found for workspace: %s", workspacePath)
 	}
-
-	return &result, nil
+	return LoadFileSummary(cacheFile)
 }
 
 // HasCachedResult checks if a cached scan result exists for a workspace
@@ -0,0 +1,269 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/pkg/scannertest"
+)
+
+func TestSaveAndLoadScan(t *testing.T) {
+	w := scannertest.New(t, scannertest.Mixed(7)...)
+	infos, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	c, err := cache.NewAt(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewAt: %v", err)
+	}
+	if err := c.Save(&cache.ScanResult{WorkspacePath: w.Root, DirectoryInfos: infos}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := c.LoadScanResult(w.Root)
+	if err != nil {
+		t.Fatalf("LoadScanResult: %v", err)
+	}
+	if loaded.Count != len(infos) || len(loaded.Directories) != len(infos) {
+		t.Errorf("Count, Directories = %d, %d, want %d", loaded.Count, len(loaded.Directories), len(infos))
+	}
+	for i, info := range infos {
+		got, want := loaded.DirectoryInfos[i].GitMetadata, info.GitMetadata
+		if got == nil || want == nil {
+			if got != want {
+				t.Errorf("%s: loaded git metadata %+v, want %+v", info.Path, got, want)
+			}
+			continue
+		}
+		if got.Head != want.Head || got.CurrentBranch != want.CurrentBranch || got.RemoteURL != want.RemoteURL ||
+			got.HasUncommitted != want.HasUncommitted || got.StatusSummary != want.StatusSummary || !got.LastCommitAt.Equal(want.LastCommitAt) {
+			t.Errorf("%s: loaded git metadata %+v, want %+v", info.Path, got, want)
+		}
+	}
+	if _, err := c.LoadScanResult(t.TempDir()); err == nil {
+		t.Error("LoadScanResult of an unscanned workspace succeeded")
+	}
+}
+
+func TestTrackMissing(t *testing.T) {
+	w := scannertest.New(t, scannertest.Mixed(3)...)
+	infos, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	previous := &cache.ScanResult{WorkspacePath: w.Root, DirectoryInfos: infos}
+
+	if err := os.RemoveAll(w.Dir("repo-0001")); err != nil {
+		t.Fatal(err)
+	}
+	infos, err = w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	now := time.Now()
+	result := &cache.ScanResult{WorkspacePath: w.Root, DirectoryInfos: infos}
+	gone := result.TrackMissing(previous, time.Hour, now)
+	if len(gone) != 1 || gone[0].Info.Path != w.Dir("repo-0001") {
+		t.Fatalf("TrackMissing = %+v, want repo-0001 gone", gone)
+	}
+
+	// Still missing on the next scan, but no longer newly gone
+	next := &cache.ScanResult{WorkspacePath: w.Root, DirectoryInfos: infos}
+	if gone := next.TrackMissing(result, time.Hour, now.Add(time.Minute)); len(gone) != 0 || len(next.Missing) != 1 {
+		t.Errorf("second TrackMissing = %+v with %d missing, want none gone and 1 missing", gone, len(next.Missing))
+	}
+	// Forgotten once kept for longer than keep
+	last := &cache.ScanResult{WorkspacePath: w.Root, DirectoryInfos: infos}
+	if last.TrackMissing(next, time.Hour, now.Add(2*time.Hour)); len(last.Missing) != 0 {
+		t.Errorf("Missing after keep = %+v, want none", last.Missing)
+	}
+}
+
+func TestLoadFileTolerance(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewAt: %v", err)
+	}
+	result := &cache.ScanResult{
+		WorkspacePath: "/ws",
+		DirectoryInfos: []scanner.DirectoryInfo{
+			{Path: "/ws/infra", Extras: &scanner.Extras{Code: &scanner.CodeStats{}}},
+			{Path: "/ws/notes"},
+		},
+		Skipped: []scanner.PlanEntry{{Path: "/ws/.cache", Reason: scanner.SkipHidden}},
+		History: []cache.ScanRecord{{Status: cache.ScanFull, Directories: 2}},
+	}
+	if err := c.Save(result); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	path := c.GetCacheFilePath("/ws")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An interrupted write of a longer file left data after the document
+	if err := os.WriteFile(path, append(data, "\n  }\n]}garbage"...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := cache.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile with trailing data: %v", err)
+	}
+	if len(loaded.DirectoryInfos) != 2 || loaded.DirectoryInfos[0].Extras == nil || len(loaded.Skipped) != 1 || len(loaded.History) != 1 {
+		t.Errorf("LoadFile = %+v, want the saved result", loaded)
+	}
+
+	summary, err := cache.LoadFileSummary(path)
+	if err != nil {
+		t.Fatalf("LoadFileSummary: %v", err)
+	}
+	if len(summary.DirectoryInfos) != 2 || summary.DirectoryInfos[0].Path != "/ws/infra" || summary.DirectoryInfos[0].Extras != nil || summary.Skipped != nil {
+		t.Errorf("LoadFileSummary = %+v, want directories without extras or skipped", summary)
+	}
+
+	// Truncated inside the document
+	for _, corrupt := range [][]byte{data[:len(data)/2], []byte(`{"directory_infos": {"path": 1}}`), []byte(`[]`)} {
+		if err := os.WriteFile(path, corrupt, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cache.LoadFile(path); err == nil {
+			t.Errorf("LoadFile of %.40q succeeded", corrupt)
+		}
+	}
+}
+
+func TestCompressedCache(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewAt: %v", err)
+	}
+	if err := cache.SetCompression("zstd"); err == nil {
+		t.Error("SetCompression(zstd) succeeded")
+	}
+	if err := cache.SetCompression(cache.CompressionGzip); err != nil {
+		t.Fatalf("SetCompression: %v", err)
+	}
+	defer cache.SetCompression(cache.CompressionNone)
+
+	result := &cache.ScanResult{WorkspacePath: "/ws", DirectoryInfos: []scanner.DirectoryInfo{{Path: "/ws/api"}}}
+	if err := c.Save(result); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(c.GetCacheFilePath("/ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		t.Errorf("cache file starts with %q, want gzip magic", data[:min(len(data), 8)])
+	}
+
+	// Readable with compression off again
+	cache.SetCompression(cache.CompressionNone)
+	loaded, err := c.LoadScanResult("/ws")
+	if err != nil {
+		t.Fatalf("LoadScanResult: %v", err)
+	}
+	if len(loaded.DirectoryInfos) != 1 || loaded.DirectoryInfos[0].Path != "/ws/api" {
+		t.Errorf("loaded %+v, want /ws/api", loaded.DirectoryInfos)
+	}
+}
+
+func TestGC(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewAt: %v", err)
+	}
+	now := time.Now()
+	live, stale := t.TempDir(), t.TempDir()
+	gone := live + "-deleted"
+	for ws, age := range map[string]time.Duration{live: time.Hour, stale: 100 * 24 * time.Hour, gone: 2 * time.Hour} {
+		if err := c.Save(&cache.ScanResult{WorkspacePath: ws, ScannedAt: now.Add(-age)}); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	// Within the grace period a deleted workspace is kept
+	report, err := c.GC(cache.GCOptions{MaxAge: 90 * 24 * time.Hour, MissingGrace: 7 * 24 * time.Hour, DryRun: true}, now)
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Workspace != stale || report.Removed[0].Reason != cache.GCStale {
+		t.Errorf("dry run removed %+v, want %s as stale", report.Removed, stale)
+	}
+	if _, err := c.LoadScanResult(stale); err != nil {
+		t.Errorf("dry run removed the cache: %v", err)
+	}
+
+	report, err = c.GC(cache.GCOptions{MaxAge: 90 * 24 * time.Hour}, now)
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, e := range report.Removed {
+		reasons[e.Workspace] = e.Reason
+		if e.Size == 0 {
+			t.Errorf("%s: removed file size 0", e.Workspace)
+		}
+	}
+	if len(reasons) != 2 || reasons[stale] != cache.GCStale || reasons[gone] != cache.GCWorkspaceMissing {
+		t.Errorf("removed %v, want %s stale and %s missing", reasons, stale, gone)
+	}
+	if len(report.Kept) != 1 || report.Kept[0].WorkspacePath != live {
+		t.Errorf("kept %d caches, want only %s", len(report.Kept), live)
+	}
+	if _, err := c.LoadScanResult(gone); err == nil {
+		t.Error("cache of the deleted workspace is still there")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.NewAt(dir)
+	if err != nil {
+		t.Fatalf("NewAt: %v", err)
+	}
+	ws := t.TempDir()
+	if err := c.Save(&cache.ScanResult{WorkspacePath: ws, DirectoryInfos: []scanner.DirectoryInfo{{Path: "a"}, {Path: "b"}}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// A save waits for an update in progress instead of being overwritten
+	saved := make(chan error, 1)
+	err = c.Update(ws, func(result *cache.ScanResult) error {
+		go func() {
+			saved <- c.Save(&cache.ScanResult{WorkspacePath: ws, DirectoryInfos: []scanner.DirectoryInfo{{Path: "a"}, {Path: "b"}, {Path: "c"}}})
+		}()
+		time.Sleep(200 * time.Millisecond)
+		result.DirectoryInfos[1].ScanError = "rescanned"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := <-saved; err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := c.LoadScanResult(ws)
+	if err != nil {
+		t.Fatalf("LoadScanResult: %v", err)
+	}
+	if len(loaded.DirectoryInfos) != 3 {
+		t.Errorf("loaded %d directories, want the 3 of the save that waited", len(loaded.DirectoryInfos))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) != ".json" {
+			t.Errorf("left %s behind", e.Name())
+		}
+	}
+}
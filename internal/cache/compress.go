@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Compression formats of cache files, set with SetCompression
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+// compression is the format Save writes
+var compression = CompressionNone
+
+// Magic bytes starting compressed files
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// SetCompression sets how Save compresses scan results, which keeps long
+// histories small on disk: none (or "") or gzip. Files are read whatever
+// their compression, so changing it needs no migration.
+func SetCompression(format string) error {
+	switch format {
+	case "", CompressionNone:
+		compression = CompressionNone
+	case CompressionGzip:
+		compression = CompressionGzip
+	case "zstd":
+		return errors.New("zstd compression is not supported, use gzip")
+	default:
+		return fmt.Errorf("unknown compression %q (expected %s or %s)", format, CompressionNone, CompressionGzip)
+	}
+	return nil
+}
+
+// compress returns data in the format set with SetCompression
+func compress(data []byte) ([]byte, error) {
+	if compression != CompressionGzip {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress returns a reader of the data r holds, decompressing it if it
+// starts with the magic bytes of a supported format
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+		return zr, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		return nil, errors.New("file is zstd-compressed, which is not supported")
+	}
+	return br, nil
+}
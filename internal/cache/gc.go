@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GCOptions selects the scan caches GC removes
+type GCOptions struct {
+	MaxAge       time.Duration // Remove caches not scanned for longer; 0 keeps them however old
+	MissingGrace time.Duration // Remove caches of deleted workspaces once not scanned for this long; 0 at once
+	DryRun       bool          // Report what would be removed without removing it
+}
+
+// Reasons a cache file is removed
+const (
+	GCWorkspaceMissing = "workspace no longer exists"
+	GCStale            = "not scanned recently"
+)
+
+// GCEntry is a cache file removed by GC
+type GCEntry struct {
+	Path      string
+	Workspace string
+	ScannedAt time.Time
+	Reason    string
+	Size      int64
+}
+
+// GCReport is the result of GC
+type GCReport struct {
+	Removed []GCEntry
+	Kept    []*ScanResult // Summaries of the caches kept, see LoadFileSummary
+	Skipped []string      // Cache files that couldn't be read and were left alone
+}
+
+// Reclaimed returns the bytes the removed files took
+func (r *GCReport) Reclaimed() int64 {
+	var total int64
+	for _, e := range r.Removed {
+		total += e.Size
+	}
+	return total
+}
+
+// GC removes the scan caches of workspaces that no longer exist or weren't
+// scanned within opts.MaxAge. Files it can't read, e.g. sealed with a key
+// that is no longer in the keychain, are left alone.
+func (c *Cache) GC(opts GCOptions, now time.Time) (*GCReport, error) {
+	entries, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	report := &GCReport{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "scan_") || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(c.cacheDir, entry.Name())
+		result, err := LoadFileSummary(path)
+		if err != nil {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+
+		age := now.Sub(result.ScannedAt)
+		reason := ""
+		if _, err := os.Stat(result.WorkspacePath); errors.Is(err, fs.ErrNotExist) && age >= opts.MissingGrace {
+			reason = GCWorkspaceMissing
+		} else if opts.MaxAge > 0 && age > opts.MaxAge {
+			reason = GCStale
+		}
+		if reason == "" {
+			report.Kept = append(report.Kept, result)
+			continue
+		}
+
+		removed := GCEntry{Path: path, Workspace: result.WorkspacePath, ScannedAt: result.ScannedAt, Reason: reason}
+		if fi, err := entry.Info(); err == nil {
+			removed.Size = fi.Size()
+		}
+		if !opts.DryRun {
+			if err := os.Remove(path); err != nil {
+				return report, err
+			}
+		}
+		report.Removed = append(report.Removed, removed)
+	}
+	return report, nil
+}
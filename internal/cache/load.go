@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+)
+
+// MaxFileSize is the largest cache file LoadFile reads, so that a runaway
+// or foreign file can't exhaust memory
+const MaxFileSize = 512 << 20
+
+// LoadFile loads a scan result from a cache file, e.g. one copied from
+// another machine, whether or not it is compressed. The file is decoded as a stream, one directory at a time,
+// so memory use stays close to the size of the result. Data after the end of
+// the JSON document, e.g. left by an interrupted write, is ignored.
+func LoadFile(path string) (*ScanResult, error) {
+	return loadFile(path, false)
+}
+
+// LoadFileSummary loads a scan result like LoadFile, leaving out the fields
+// that only detailed views need and that make up much of a large cache: each
+// directory's Extras (Terraform resources, line counts) and Docker
+// definitions, and the Skipped directories. For status lines and prompts.
+func LoadFileSummary(path string) (*ScanResult, error) {
+	return loadFile(path, true)
+}
+
+// loadFile loads a cache file, leaving out the summary fields if summary is set
+func loadFile(path string, summary bool) (*ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size() > MaxFileSize {
+		return nil, fmt.Errorf("cache file %s is %d MB, over the %d MB limit (delete it and rescan)", path, fi.Size()>>20, MaxFileSize>>20)
+	}
+
+	// Sealed files are authenticated as a whole, so they are decrypted in memory
+	var r io.Reader = bufio.NewReader(f)
+	if head, _ := r.(*bufio.Reader).Peek(64); seal.IsSealed(head) {
+		data, err := io.ReadAll(io.LimitReader(r, MaxFileSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cache file: %w", err)
+		}
+		if data, err = seal.Open(data); err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	if r, err = decompress(r); err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	// The limit also applies after decompression
+	result, err := decode(io.LimitReader(r, MaxFileSize), summary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cache file: %w", err)
+	}
+	return result, nil
+}
+
+// skip decodes any JSON value to nothing
+type skip struct{}
+
+func (*skip) UnmarshalJSON([]byte) error { return nil }
+
+// summaryInfo decodes a DirectoryInfo without the fields summaries leave out
+type summaryInfo struct {
+	scanner.DirectoryInfo
+	Extras skip `json:"extras"`
+	Docker skip `json:"docker"`
+}
+
+// decode reads a scan result from r token by token, decoding the directories
+// one at a time rather than reading the whole document first. Malformed
+// input is an error, never a panic.
+func decode(r io.Reader, summary bool) (result *ScanResult, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("malformed scan result: %v", p)
+		}
+	}()
+
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	result = &ScanResult{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %v, want a field name", token)
+		}
+
+		switch {
+		case key == "directory_infos":
+			if result.DirectoryInfos, err = decodeInfos(dec, summary); err != nil {
+				return nil, fmt.Errorf("directory_infos: %w", err)
+			}
+		case key == "skipped" && summary:
+			if err := dec.Decode(&skip{}); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		default:
+			// Other fields are small; decode each into the result by name
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			field, err := json.Marshal(map[string]json.RawMessage{key: value})
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(field, result); err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		logger.Warn("ignoring data after the end of a scan result", "workspace", result.WorkspacePath)
+	}
+	return result, nil
+}
+
+// decodeInfos decodes an array of directories, or null, one element at a time
+func decodeInfos(dec *json.Decoder, summary bool) ([]scanner.DirectoryInfo, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if token != json.Delim('[') {
+		return nil, fmt.Errorf("unexpected %v, want [", token)
+	}
+	infos := []scanner.DirectoryInfo{}
+	for dec.More() {
+		if summary {
+			var info summaryInfo
+			if err := dec.Decode(&info); err != nil {
+				return nil, err
+			}
+			infos = append(infos, info.DirectoryInfo)
+			continue
+		}
+		var info scanner.DirectoryInfo
+		if err := dec.Decode(&info); err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, expectDelim(dec, ']')
+}
+
+// expectDelim reads the next token, failing unless it is delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected %v, want %v", token, delim)
+	}
+	return nil
+}
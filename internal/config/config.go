@@ -1,11 +1,44 @@
 package config
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
 // Config represents the application configuration structure
 type Config struct {
-	Version   int             `mapstructure:"version" yaml:"version"`
-	Workspace WorkspaceConfig `mapstructure:"workspace" yaml:"workspace"`
-	Scanner   ScannerConfig   `mapstructure:"scanner" yaml:"scanner"`
-	Logging   LoggingConfig   `mapstructure:"logging" yaml:"logging"`
+	Version    int              `mapstructure:"version" yaml:"version"`
+	Workspace  WorkspaceConfig  `mapstructure:"workspace" yaml:"workspace"`
+	Scanner    ScannerConfig    `mapstructure:"scanner" yaml:"scanner"`
+	Logging    LoggingConfig    `mapstructure:"logging" yaml:"logging"`
+	Providers  ProvidersConfig  `mapstructure:"providers" yaml:"providers"`
+	Enrichment EnrichmentConfig `mapstructure:"enrichment" yaml:"enrichment"`
+	Trackers   []TrackerConfig  `mapstructure:"trackers" yaml:"trackers,omitempty"`
+	Commits    CommitsConfig    `mapstructure:"commits" yaml:"commits"`
+	Guard      GuardConfig      `mapstructure:"guard" yaml:"guard"`
+	Compliance ComplianceConfig `mapstructure:"compliance" yaml:"compliance"`
+	Signing    SigningConfig    `mapstructure:"signing" yaml:"signing,omitempty"` // Commit signing policy, see `thandie report signing`
+	Notify     NotifyConfig     `mapstructure:"notify" yaml:"notify"`
+	SMTP       SMTPConfig       `mapstructure:"smtp" yaml:"smtp,omitempty"`
+	Schedule   []ScheduleEntry  `mapstructure:"schedule" yaml:"schedule,omitempty"` // Jobs run by `thandie daemon`
+	Power      PowerConfig      `mapstructure:"power" yaml:"power"`
+	Tracing    TracingConfig    `mapstructure:"tracing" yaml:"tracing,omitempty"`
+	Security   SecurityConfig   `mapstructure:"security" yaml:"security"`
+	Cache      CacheConfig      `mapstructure:"cache" yaml:"cache,omitempty"`
+	Policy     []PolicyRule     `mapstructure:"policy" yaml:"policy,omitempty"`           // Per-repository allowed actions
+	PolicyPack PolicyPackConfig `mapstructure:"policy_pack" yaml:"policy_pack,omitempty"` // Team policy checked by `thandie policy status`
+	Groups     []GroupConfig    `mapstructure:"groups" yaml:"groups,omitempty"`           // Named sets of repositories
+	Session    SessionConfig    `mapstructure:"session" yaml:"session,omitempty"`
+	Webhook    WebhookConfig    `mapstructure:"webhook" yaml:"webhook,omitempty"` // Rescan endpoint served by `thandie daemon`
+	GitWatch   GitWatchConfig   `mapstructure:"git_watch" yaml:"git_watch"`       // Rescans by `thandie daemon` as repositories change
+	Hooks      HooksConfig      `mapstructure:"hooks" yaml:"hooks,omitempty"`     // Shell commands run around scans
+	List       ListConfig       `mapstructure:"list" yaml:"list,omitempty"`       // Output of `thandie list`
+	UI         UIConfig         `mapstructure:"ui" yaml:"ui,omitempty"`
 }
 
 // WorkspaceConfig holds workspace-related settings
@@ -14,11 +47,14 @@ type WorkspaceConfig struct {
 	Profiles []WorkspaceProfile `mapstructure:"profiles" yaml:"profiles"`
 }
 
-// WorkspaceProfile represents a named workspace profile (for future use)
+// WorkspaceProfile represents a named workspace profile
 type WorkspaceProfile struct {
-	Name string   `mapstructure:"name" yaml:"name"`
-	Path string   `mapstructure:"path" yaml:"path"`
-	Tags []string `mapstructure:"tags" yaml:"tags,omitempty"`
+	Name     string            `mapstructure:"name" yaml:"name"`
+	Alias    string            `mapstructure:"alias" yaml:"alias,omitempty"` // Display name used instead of the path, e.g. "Work"
+	Path     string            `mapstructure:"path" yaml:"path"`
+	Manifest string            `mapstructure:"manifest" yaml:"manifest,omitempty"` // File listing the directories of the workspace, one per line, instead of those under Path
+	Tags     []string          `mapstructure:"tags" yaml:"tags,omitempty"`
+	Scanner  *ScannerOverrides `mapstructure:"scanner" yaml:"scanner,omitempty"` // Takes precedence over the global scanner config
 }
 
 // ScannerConfig holds scanner-related settings
@@ -26,6 +62,23 @@ type ScannerConfig struct {
 	IncludeHidden bool     `mapstructure:"include_hidden" yaml:"include_hidden"`
 	IgnoreDirs    []string `mapstructure:"ignore_dirs" yaml:"ignore_dirs"`
 	MaxDepth      int      `mapstructure:"max_depth" yaml:"max_depth"`
+	Concurrency   int      `mapstructure:"concurrency" yaml:"concurrency"`
+	LOC           bool     `mapstructure:"loc" yaml:"loc"` // Count lines of code per language
+
+	FullScanInterval string `mapstructure:"full_scan_interval" yaml:"full_scan_interval,omitempty"` // Longest time `scan --if-changed` reuses an unchanged scan
+	KeepMissingDays  int    `mapstructure:"keep_missing_days" yaml:"keep_missing_days"`             // Days a deleted directory stays listed as missing; 0 forgets it at once
+	DirTimeout       string `mapstructure:"dir_timeout" yaml:"dir_timeout,omitempty"`               // Longest time spent collecting one directory's metadata, e.g. 2m
+	GitBackend       string `mapstructure:"git_backend" yaml:"git_backend,omitempty"`               // How repositories are read: go-git (default) or cli (the git command)
+}
+
+// ScannerOverrides holds per-profile scanner settings. Unset fields fall back
+// to the global scanner config.
+type ScannerOverrides struct {
+	IncludeHidden *bool    `mapstructure:"include_hidden" yaml:"include_hidden,omitempty"`
+	IgnoreDirs    []string `mapstructure:"ignore_dirs" yaml:"ignore_dirs,omitempty"`
+	MaxDepth      *int     `mapstructure:"max_depth" yaml:"max_depth,omitempty"`
+	Concurrency   *int     `mapstructure:"concurrency" yaml:"concurrency,omitempty"`
+	LOC           *bool    `mapstructure:"loc" yaml:"loc,omitempty"`
 }
 
 // LoggingConfig holds logging-related settings
@@ -33,4 +86,333 @@ type LoggingConfig struct {
 	Level  string `mapstructure:"level" yaml:"level"`
 	ToFile bool   `mapstructure:"to_file" yaml:"to_file"`
 	JSON   bool   `mapstructure:"json" yaml:"json"`
+
+	Components map[string]string `mapstructure:"components" yaml:"components,omitempty"` // Level per component, e.g. scanner: debug, overriding Level
+}
+
+// ProvidersConfig holds settings for git hosting provider APIs
+type ProvidersConfig struct {
+	GitHub ProviderConfig `mapstructure:"github" yaml:"github"`
+	GitLab ProviderConfig `mapstructure:"gitlab" yaml:"gitlab"`
+}
+
+// ProviderConfig holds settings for a single provider API
+type ProviderConfig struct {
+	URL      string `mapstructure:"url" yaml:"url"`                       // API base URL (change for self-hosted instances)
+	WebURL   string `mapstructure:"web_url" yaml:"web_url"`               // Web base URL, used for device-flow login
+	Token    string `mapstructure:"token" yaml:"token"`                   // Plaintext token or keychain:<alias>
+	ClientID string `mapstructure:"client_id" yaml:"client_id,omitempty"` // OAuth app client ID enabling device-flow login
+}
+
+// EnrichmentConfig holds settings for fetching repository data from providers
+type EnrichmentConfig struct {
+	Enabled bool   `mapstructure:"enabled" yaml:"enabled"`
+	TTL     string `mapstructure:"ttl" yaml:"ttl"` // How long fetched data is reused, e.g. "15m"
+}
+
+// TrackerConfig configures an issue tracker used to link tickets named in branch names
+type TrackerConfig struct {
+	Name     string   `mapstructure:"name" yaml:"name"`
+	Type     string   `mapstructure:"type" yaml:"type"`             // jira or linear
+	URL      string   `mapstructure:"url" yaml:"url,omitempty"`     // Base URL (Jira only)
+	Email    string   `mapstructure:"email" yaml:"email,omitempty"` // Account email for Jira Cloud basic auth
+	Token    string   `mapstructure:"token" yaml:"token"`           // Plaintext token or keychain:<alias>
+	Projects []string `mapstructure:"projects" yaml:"projects"`     // Ticket key prefixes owned by this tracker, e.g. PROJ
+}
+
+// CommitsConfig holds settings for checking unpushed commits
+type CommitsConfig struct {
+	Lint        bool   `mapstructure:"lint" yaml:"lint"`                           // Validate unpushed commit subjects
+	LintPattern string `mapstructure:"lint_pattern" yaml:"lint_pattern,omitempty"` // Defaults to ConventionalCommitPattern
+}
+
+// ConventionalCommitPattern matches Conventional Commits subjects, e.g. "feat(api): add login"
+const ConventionalCommitPattern = `^(build|chore|ci|docs|feat|fix|perf|refactor|revert|style|test)(\([^)]+\))?!?: .+`
+
+// LintRegexp returns the compiled lint pattern, or nil if linting is disabled
+func (c CommitsConfig) LintRegexp() (*regexp.Regexp, error) {
+	if !c.Lint {
+		return nil, nil
+	}
+	pattern := c.LintPattern
+	if pattern == "" {
+		pattern = ConventionalCommitPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("commits.lint_pattern: %w", err)
+	}
+	return re, nil
+}
+
+// ComplianceConfig holds the policy for `thandie report compliance`
+type ComplianceConfig struct {
+	Required []string `mapstructure:"required" yaml:"required"` // Files every repository must have: license, readme, codeowners
+}
+
+// SigningConfig is the commit signing policy checked by `thandie report signing`
+type SigningConfig struct {
+	Required bool   `mapstructure:"required" yaml:"required"`         // Repositories must have commit.gpgsign and user.signingkey set
+	Format   string `mapstructure:"format" yaml:"format,omitempty"`   // Required gpg.format: openpgp, ssh or x509
+	Commits  int    `mapstructure:"commits" yaml:"commits,omitempty"` // Recent own commits on HEAD that must be signed; 0 skips the check
+}
+
+// NotifyConfig holds chat webhooks for `thandie notify`. Webhook URLs may be
+// keychain references (keychain:<name>) since they grant posting access.
+type NotifyConfig struct {
+	SlackWebhook string `mapstructure:"slack_webhook" yaml:"slack_webhook,omitempty"`
+	TeamsWebhook string `mapstructure:"teams_webhook" yaml:"teams_webhook,omitempty"`
+	DirtyDays    int    `mapstructure:"dirty_days" yaml:"dirty_days"` // Alert on repositories dirty for this many days
+}
+
+// SMTPConfig holds the mail server used for emailed reports. Password may
+// be a keychain reference (keychain:<name>).
+type SMTPConfig struct {
+	Host     string   `mapstructure:"host" yaml:"host,omitempty"`
+	Port     int      `mapstructure:"port" yaml:"port,omitempty"` // Defaults to 587; 465 uses implicit TLS
+	Username string   `mapstructure:"username" yaml:"username,omitempty"`
+	Password string   `mapstructure:"password" yaml:"password,omitempty"`
+	From     string   `mapstructure:"from" yaml:"from,omitempty"`
+	To       []string `mapstructure:"to" yaml:"to,omitempty"`
+}
+
+// ScheduleEntry is a job run by `thandie daemon` on a cron schedule
+type ScheduleEntry struct {
+	Cron string `mapstructure:"cron" yaml:"cron"` // Five-field cron expression, e.g. "0 9 * * 1-5"
+	Job  string `mapstructure:"job" yaml:"job"`   // scan, report, notify or any thandie command line
+}
+
+// PowerConfig controls when `thandie daemon` runs heavy jobs (scan, audit,
+// test, terraform). Jobs that can't run are deferred, not skipped.
+type PowerConfig struct {
+	ScanOnBattery bool   `mapstructure:"scan_on_battery" yaml:"scan_on_battery"`
+	ScanOnMetered bool   `mapstructure:"scan_on_metered" yaml:"scan_on_metered"`
+	ActiveHours   string `mapstructure:"active_hours" yaml:"active_hours,omitempty"` // Defer during these hours, e.g. 09:00-18:00
+	IdleMinutes   int    `mapstructure:"idle_minutes" yaml:"idle_minutes,omitempty"` // Defer until the user has been idle this long
+	MaxDefer      string `mapstructure:"max_defer" yaml:"max_defer,omitempty"`       // Run anyway after deferring this long (default 6h, 0 waits indefinitely)
+}
+
+// SecurityConfig holds safety settings for shared environments
+type SecurityConfig struct {
+	ReadOnly     bool   `mapstructure:"read_only" yaml:"read_only"`         // Refuse write actions, as with --read-only
+	EncryptCache bool   `mapstructure:"encrypt_cache" yaml:"encrypt_cache"` // Encrypt scan caches, file indexes and resume state with a keychain key
+	SignKey      string `mapstructure:"sign_key" yaml:"sign_key,omitempty"` // SSH or minisign key that signs exports written to files
+}
+
+// CacheConfig holds settings for the scan cache files
+type CacheConfig struct {
+	Compression string `mapstructure:"compression" yaml:"compression,omitempty"` // none (default) or gzip
+	MaxAgeDays  int    `mapstructure:"max_age_days" yaml:"max_age_days"`         // Remove caches of workspaces not scanned for this many days; 0 keeps them
+	AutoGC      bool   `mapstructure:"auto_gc" yaml:"auto_gc"`                   // Collect garbage after a scan, at most once a day
+}
+
+// WebhookConfig enables the daemon's /hooks/rescan endpoint
+type WebhookConfig struct {
+	Listen string `mapstructure:"listen" yaml:"listen,omitempty"` // Address to listen on, e.g. 127.0.0.1:8787; empty disables the endpoint
+	Secret string `mapstructure:"secret" yaml:"secret,omitempty"` // Shared secret, or keychain:<alias>
+}
+
+// GitWatchConfig controls how `thandie daemon` watches the index and HEAD of
+// repositories to rescan them as soon as they are committed to or switch
+// branches
+type GitWatchConfig struct {
+	Enabled  bool `mapstructure:"enabled" yaml:"enabled"`
+	MaxRepos int  `mapstructure:"max_repos" yaml:"max_repos"` // Watch only this many, the most recently active
+}
+
+// PolicyPackConfig locates a signed team policy pack (see internal/policypack)
+type PolicyPackConfig struct {
+	URL            string `mapstructure:"url" yaml:"url,omitempty"`                         // URL or path of the pack; its signature is at URL.sig
+	AllowedSigners string `mapstructure:"allowed_signers" yaml:"allowed_signers,omitempty"` // ssh-keygen allowed_signers file the signature must match
+	TTL            string `mapstructure:"ttl" yaml:"ttl,omitempty"`                         // How long a downloaded pack is reused, e.g. 24h (the default)
+}
+
+// HooksConfig holds shell commands run around every scan with a JSON
+// description of the event on stdin (see internal/scanhooks)
+type HooksConfig struct {
+	PreScan      string `mapstructure:"pre_scan" yaml:"pre_scan,omitempty"`             // Before the workspace is scanned
+	PostScan     string `mapstructure:"post_scan" yaml:"post_scan,omitempty"`           // After the scan result is saved
+	OnDirtyFound string `mapstructure:"on_dirty_found" yaml:"on_dirty_found,omitempty"` // When repositories that were clean have uncommitted changes
+}
+
+// GroupConfig is a named set of repositories, e.g. "Platform"
+type GroupConfig struct {
+	Name         string   `mapstructure:"name" yaml:"name"`
+	Paths        []string `mapstructure:"paths" yaml:"paths"`                           // Globs on the path relative to the workspace, or absolute paths
+	Branch       string   `mapstructure:"branch" yaml:"branch,omitempty"`               // Expected branch, checked by `thandie report branches`
+	GitConfig    []string `mapstructure:"git_config" yaml:"git_config,omitempty"`       // Expected git config as key=value (value a glob), checked by `thandie report git-config`
+	EmailDomains []string `mapstructure:"email_domains" yaml:"email_domains,omitempty"` // Domains user.email must belong to; others are flagged in `thandie list`
+}
+
+// ListConfig holds the defaults of `thandie list`
+type ListConfig struct {
+	Columns []string `mapstructure:"columns" yaml:"columns,omitempty"` // Print a table of these columns: name, branch, sync, dirty, age
+}
+
+// UIConfig holds display preferences shared by the commands listing directories
+type UIConfig struct {
+	Density string `mapstructure:"density" yaml:"density,omitempty"` // compact (default): one line per directory; detailed: adds the remote and last commit
+	Symbols bool   `mapstructure:"symbols" yaml:"symbols,omitempty"` // Mark dirty (●) and clean (✚) repositories with symbols, not just color
+	Palette string `mapstructure:"palette" yaml:"palette,omitempty"` // default, or deuteranopia for colors told apart with red-green color blindness
+}
+
+// SessionConfig holds the terminal multiplexer sessions opened by
+// `thandie session`
+type SessionConfig struct {
+	Launcher  string            `mapstructure:"launcher" yaml:"launcher,omitempty"` // tmux (default) or zellij
+	Templates []SessionTemplate `mapstructure:"templates" yaml:"templates,omitempty"`
+}
+
+// SessionTemplate lists the windows of the sessions of matching repositories.
+// A template named "default" applies to repositories no other template matches.
+type SessionTemplate struct {
+	Name    string          `mapstructure:"name" yaml:"name"`
+	Paths   []string        `mapstructure:"paths" yaml:"paths,omitempty"`   // Globs on the path relative to the workspace, as for groups
+	Groups  []string        `mapstructure:"groups" yaml:"groups,omitempty"` // Groups whose repositories use this template
+	Windows []SessionWindow `mapstructure:"windows" yaml:"windows"`
+}
+
+// SessionWindow is a window (a tab in zellij) started in the repository
+type SessionWindow struct {
+	Name    string `mapstructure:"name" yaml:"name"`
+	Command string `mapstructure:"command" yaml:"command,omitempty"` // Run in the window's shell; empty for a plain shell
+}
+
+// PolicyRule restricts the actions allowed in repositories matching Path or
+// Remote. A repository matching several rules must satisfy all of them.
+type PolicyRule struct {
+	Path   string   `mapstructure:"path" yaml:"path,omitempty"`     // Glob on the path relative to the workspace, or an absolute path
+	Remote string   `mapstructure:"remote" yaml:"remote,omitempty"` // Glob on the normalized remote, e.g. github.com/prod-org/*
+	Allow  []string `mapstructure:"allow" yaml:"allow,omitempty"`   // Only these actions are allowed (empty allows all but Deny)
+	Deny   []string `mapstructure:"deny" yaml:"deny,omitempty"`     // These actions are refused
+}
+
+// TracingConfig holds OpenTelemetry export settings
+type TracingConfig struct {
+	Export string `mapstructure:"export" yaml:"export,omitempty"` // OTLP endpoint URL, "otlp" or a trace file; overridden by --trace
+}
+
+// GuardConfig holds settings for the pre-push guard
+type GuardConfig struct {
+	Secrets *bool        `mapstructure:"secrets" yaml:"secrets,omitempty"` // Block pushes that add likely secrets (default true)
+	Checks  []GuardCheck `mapstructure:"checks" yaml:"checks,omitempty"`
+}
+
+// GuardCheck is a shell command that must succeed before a push is allowed
+type GuardCheck struct {
+	Name string `mapstructure:"name" yaml:"name"`
+	Run  string `mapstructure:"run" yaml:"run"`
+}
+
+// SecretsEnabled reports whether the guard scans outgoing commits for secrets
+func (g GuardConfig) SecretsEnabled() bool {
+	return g.Secrets == nil || *g.Secrets
+}
+
+// DefaultFullScanInterval is used when scanner.full_scan_interval is unset or invalid
+const DefaultFullScanInterval = time.Hour
+
+// FullScanIntervalDuration returns the full scan interval, falling back to
+// DefaultFullScanInterval
+func (s ScannerConfig) FullScanIntervalDuration() time.Duration {
+	d, err := time.ParseDuration(s.FullScanInterval)
+	if err != nil || d <= 0 {
+		return DefaultFullScanInterval
+	}
+	return d
+}
+
+// DefaultDirTimeout is used when scanner.dir_timeout is unset or invalid
+const DefaultDirTimeout = 2 * time.Minute
+
+// DirTimeoutDuration returns the per-directory scan timeout, falling back to
+// DefaultDirTimeout
+func (s ScannerConfig) DirTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(s.DirTimeout)
+	if err != nil || d <= 0 {
+		return DefaultDirTimeout
+	}
+	return d
+}
+
+// SettingsHash identifies the settings that decide which directories a scan
+// finds: ignore_dirs (in any order), include_hidden and max_depth. Scans with
+// the same hash list the same directories of an unchanged workspace.
+func (s ScannerConfig) SettingsHash() string {
+	ignore := slices.Clone(s.IgnoreDirs)
+	slices.Sort(ignore)
+	sum := sha256.Sum256(fmt.Appendf(nil, "%q %t %d", ignore, s.IncludeHidden, s.MaxDepth))
+	return hex.EncodeToString(sum[:8])
+}
+
+// DefaultEnrichmentTTL is used when enrichment.ttl is unset or invalid
+const DefaultEnrichmentTTL = 15 * time.Minute
+
+// TTLDuration returns the enrichment TTL, falling back to DefaultEnrichmentTTL
+func (e EnrichmentConfig) TTLDuration() time.Duration {
+	ttl, err := time.ParseDuration(e.TTL)
+	if err != nil || ttl <= 0 {
+		return DefaultEnrichmentTTL
+	}
+	return ttl
+}
+
+// Provider returns the config for the named provider (github or gitlab), or nil if unknown
+func (c *Config) Provider(name string) *ProviderConfig {
+	switch name {
+	case "github":
+		return &c.Providers.GitHub
+	case "gitlab":
+		return &c.Providers.GitLab
+	}
+	return nil
+}
+
+// FindProfile returns the workspace profile with the given name, or nil if none matches
+func (c *Config) FindProfile(name string) *WorkspaceProfile {
+	for i := range c.Workspace.Profiles {
+		if c.Workspace.Profiles[i].Name == name {
+			return &c.Workspace.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// ProfileForPath returns the workspace profile whose path matches wsPath, or nil if none matches
+func (c *Config) ProfileForPath(wsPath string) *WorkspaceProfile {
+	cleanPath := filepath.Clean(wsPath)
+	for i := range c.Workspace.Profiles {
+		profile := &c.Workspace.Profiles[i]
+		if profile.Path != "" && filepath.Clean(profile.Path) == cleanPath {
+			return profile
+		}
+	}
+	return nil
+}
+
+// EffectiveScanner returns the global scanner config with any overrides from
+// the given profile applied. A nil profile returns the global config unchanged.
+func (c *Config) EffectiveScanner(profile *WorkspaceProfile) ScannerConfig {
+	effective := c.Scanner
+	if profile == nil || profile.Scanner == nil {
+		return effective
+	}
+
+	overrides := profile.Scanner
+	if overrides.IncludeHidden != nil {
+		effective.IncludeHidden = *overrides.IncludeHidden
+	}
+	if overrides.IgnoreDirs != nil {
+		effective.IgnoreDirs = overrides.IgnoreDirs
+	}
+	if overrides.MaxDepth != nil {
+		effective.MaxDepth = *overrides.MaxDepth
+	}
+	if overrides.Concurrency != nil {
+		effective.Concurrency = *overrides.Concurrency
+	}
+	if overrides.LOC != nil {
+		effective.LOC = *overrides.LOC
+	}
+	return effective
 }
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExpandString expands a leading ~ to the home directory and replaces ${VAR}
+// and ${env:VAR} references with the value of the environment variable VAR.
+// Referencing a variable that is not set is an error.
+func ExpandString(value string) (string, error) {
+	if value == "~" || strings.HasPrefix(value, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand ~: %w", err)
+		}
+		value = filepath.Join(homeDir, value[1:])
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			b.WriteString(value)
+			break
+		}
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", value)
+		}
+		end += start
+
+		name := strings.TrimPrefix(value[start+2:end], "env:")
+		if name == "" {
+			return "", fmt.Errorf("empty variable reference in %q", value)
+		}
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("undefined variable ${%s}", name)
+		}
+
+		b.WriteString(value[:start])
+		b.WriteString(envValue)
+		value = value[end+1:]
+	}
+
+	return b.String(), nil
+}
+
+// Expand applies ExpandString to every config value that holds a path or
+// pattern: workspace paths and ignore patterns, globally and per profile.
+// Errors name the config key that failed to expand.
+func (c *Config) Expand() error {
+	var err error
+	if c.Workspace.Default, err = expandField("workspace.default", c.Workspace.Default); err != nil {
+		return err
+	}
+	if err := expandSlice("scanner.ignore_dirs", c.Scanner.IgnoreDirs); err != nil {
+		return err
+	}
+	if c.Tracing.Export, err = expandField("tracing.export", c.Tracing.Export); err != nil {
+		return err
+	}
+	if c.Security.SignKey, err = expandField("security.sign_key", c.Security.SignKey); err != nil {
+		return err
+	}
+	if c.PolicyPack.URL, err = expandField("policy_pack.url", c.PolicyPack.URL); err != nil {
+		return err
+	}
+	if c.PolicyPack.AllowedSigners, err = expandField("policy_pack.allowed_signers", c.PolicyPack.AllowedSigners); err != nil {
+		return err
+	}
+
+	for i := range c.Workspace.Profiles {
+		profile := &c.Workspace.Profiles[i]
+		key := fmt.Sprintf("workspace.profiles[%d]", i)
+		if profile.Path, err = expandField(key+".path", profile.Path); err != nil {
+			return err
+		}
+		if profile.Manifest, err = expandField(key+".manifest", profile.Manifest); err != nil {
+			return err
+		}
+		if profile.Scanner != nil {
+			if err := expandSlice(key+".scanner.ignore_dirs", profile.Scanner.IgnoreDirs); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range c.Groups {
+		if err := expandSlice(fmt.Sprintf("groups[%d].paths", i), c.Groups[i].Paths); err != nil {
+			return err
+		}
+	}
+
+	for i := range c.Policy {
+		if c.Policy[i].Path, err = expandField(fmt.Sprintf("policy[%d].path", i), c.Policy[i].Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expandField expands a single config value, naming key in any error
+func expandField(key, value string) (string, error) {
+	expanded, err := ExpandString(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return expanded, nil
+}
+
+// expandSlice expands each element of values in place, naming key in any error
+func expandSlice(key string, values []string) error {
+	for i, value := range values {
+		expanded, err := expandField(fmt.Sprintf("%s[%d]", key, i), value)
+		if err != nil {
+			return err
+		}
+		values[i] = expanded
+	}
+	return nil
+}
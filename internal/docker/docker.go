@@ -0,0 +1,133 @@
+// Package docker correlates running containers with workspace repositories
+// using the Docker Engine API.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// composeWorkingDirLabel is set by Docker Compose on the containers it starts
+const composeWorkingDirLabel = "com.docker.compose.project.working_dir"
+
+// Container is a running container as reported by the Docker Engine API
+type Container struct {
+	Name   string
+	Image  string
+	Labels map[string]string
+}
+
+// SocketPath returns the Docker socket to use: DOCKER_HOST if it is a unix
+// socket, otherwise the first of the usual locations that exists. It
+// returns "" if no socket is available.
+func SocketPath() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if path, ok := strings.CutPrefix(host, "unix://"); ok {
+			return path
+		}
+		return ""
+	}
+
+	candidates := []string{"/var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// RunningContainers lists the running containers via the socket at socketPath
+func RunningContainers(ctx context.Context, socketPath string) ([]Container, error) {
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query docker: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker returned %s", resp.Status)
+	}
+
+	var raw []struct {
+		Names  []string          `json:"Names"`
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode docker response: %w", err)
+	}
+
+	containers := make([]Container, 0, len(raw))
+	for _, c := range raw {
+		name := ""
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		containers = append(containers, Container{Name: name, Image: c.Image, Labels: c.Labels})
+	}
+	return containers, nil
+}
+
+// Correlate sets Docker.Running on every directory with container
+// definitions that has containers running from it. A container belongs to a
+// directory if Compose started it from inside the directory, or if its image
+// is named after the directory (e.g. "api", "api:latest", "org/api-web").
+func Correlate(infos []scanner.DirectoryInfo, containers []Container) {
+	for i := range infos {
+		info := &infos[i]
+		if info.Docker == nil {
+			continue
+		}
+		info.Docker.Running = nil
+		for _, c := range containers {
+			if belongsTo(c, info.Path) {
+				info.Docker.Running = append(info.Docker.Running, c.Name)
+			}
+		}
+		sort.Strings(info.Docker.Running)
+	}
+}
+
+func belongsTo(c Container, dir string) bool {
+	if workingDir := c.Labels[composeWorkingDirLabel]; workingDir != "" {
+		rel, err := filepath.Rel(dir, workingDir)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+
+	image := c.Image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	if i := strings.IndexAny(image, ":@"); i >= 0 {
+		image = image[:i]
+	}
+	name := strings.ToLower(filepath.Base(dir))
+	return image == name || strings.HasPrefix(image, name+"-") || strings.HasPrefix(image, name+"_")
+}
@@ -0,0 +1,159 @@
+package enrich
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
+	"github.com/ThandieOps/thandie-agent/internal/trackers"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// log shares the providers component with the API clients
+var log = logger.For("providers")
+
+// Enricher fetches provider data for scanned repositories
+type Enricher struct {
+	providers   map[string]*providers.Provider // Keyed by web host, e.g. github.com
+	trackers    *trackers.Set
+	ttl         time.Duration
+	concurrency int
+}
+
+// New creates an enricher for every provider the user is logged in to.
+// Providers and trackers without a usable token are skipped, the trackers
+// with a warning.
+func New(cfg *config.Config, concurrency int) (*Enricher, error) {
+	client, err := providers.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Enricher{
+		providers:   providers.ByHost(client, cfg),
+		ttl:         cfg.Enrichment.TTLDuration(),
+		concurrency: max(concurrency, 1),
+	}
+
+	if e.trackers, err = trackers.NewSet(cfg.Trackers, client); err != nil {
+		log.Warn("skipping misconfigured trackers", "error", err)
+	}
+	return e, nil
+}
+
+// Enrich sets the Enrichment of every repository hosted on a known provider,
+// and the Ticket of every repository whose branch names a tracked ticket.
+// Data from previous that is younger than the TTL is reused instead of
+// refetched. Fetch failures are recorded on the results, not returned.
+func (e *Enricher) Enrich(ctx context.Context, infos []scanner.DirectoryInfo, previous []scanner.DirectoryInfo) {
+	prior := make(map[string]scanner.DirectoryInfo, len(previous))
+	for _, info := range previous {
+		prior[info.Path] = info
+	}
+
+	sem := make(chan struct{}, e.concurrency)
+	var wg sync.WaitGroup
+	run := func(job func()) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			job()
+		}()
+	}
+
+	for i := range infos {
+		info := &infos[i]
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo {
+			continue
+		}
+		old := prior[info.Path]
+
+		if key, trackerName := e.trackers.FindKey(info.GitMetadata.CurrentBranch); key != "" {
+			if old.Ticket.Fresh(e.ttl) && old.Ticket.Key == key {
+				info.Ticket = old.Ticket
+			} else {
+				run(func() { info.Ticket = e.fetchTicket(ctx, trackerName, key) })
+			}
+		}
+
+		remote, err := gitremote.Parse(info.GitMetadata.RemoteURL)
+		if err != nil {
+			continue
+		}
+		provider, ok := e.providers[remote.Host]
+		if !ok {
+			continue
+		}
+		if old.Enrichment.Fresh(e.ttl) && old.Enrichment.Repo == remote.Path {
+			info.Enrichment = old.Enrichment
+			continue
+		}
+		run(func() { info.Enrichment = e.fetch(ctx, provider, remote.Path) })
+	}
+	wg.Wait()
+}
+
+// fetchTicket looks up a ticket in the named tracker
+func (e *Enricher) fetchTicket(ctx context.Context, trackerName, key string) *scanner.Ticket {
+	ticket := &scanner.Ticket{
+		Key:       key,
+		Tracker:   trackerName,
+		FetchedAt: time.Now(),
+	}
+
+	resolved, err := e.trackers.Resolve(ctx, trackerName, key)
+	if err != nil {
+		log.Warn("failed to resolve ticket", "tracker", trackerName, "key", key, "error", err)
+		ticket.Error = err.Error()
+		return ticket
+	}
+	ticket.Title = resolved.Title
+	ticket.Status = resolved.Status
+	ticket.URL = resolved.URL
+	return ticket
+}
+
+// fetch retrieves fresh enrichment data for a single repository
+func (e *Enricher) fetch(ctx context.Context, provider *providers.Provider, repoPath string) *scanner.Enrichment {
+	enrichment := &scanner.Enrichment{
+		Provider:  provider.Name,
+		Repo:      repoPath,
+		FetchedAt: time.Now(),
+	}
+
+	ctx, span := tracing.Start(ctx, "enrich.repo", attribute.String("provider", provider.Name), attribute.String("repo", repoPath))
+	defer span.End()
+
+	status, err := provider.RepoStatus(ctx, repoPath)
+	if err != nil {
+		log.Warn("failed to enrich repository", "provider", provider.Name, "repo", repoPath, "error", err)
+		enrichment.Error = err.Error()
+		return enrichment
+	}
+	enrichment.DefaultBranch = status.DefaultBranch
+	enrichment.CI = status.CI
+
+	issues, err := provider.Issues(ctx, repoPath)
+	if err != nil {
+		log.Warn("failed to fetch repository issues", "provider", provider.Name, "repo", repoPath, "error", err)
+		enrichment.Error = err.Error()
+		return enrichment
+	}
+	enrichment.OpenIssues = issues.Open
+	for _, issue := range issues.Assigned {
+		enrichment.AssignedIssues = append(enrichment.AssignedIssues, scanner.Issue{
+			Number: issue.Number,
+			Title:  issue.Title,
+			URL:    issue.URL,
+		})
+	}
+	return enrichment
+}
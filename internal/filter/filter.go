@@ -0,0 +1,159 @@
+package filter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// Filter matches directories against a list of space-separated terms. A term
+// is either key:value, matched by the matcher registered for key, or a bare
+// word matched against the directory name. All terms must match.
+type Filter struct {
+	terms []term
+}
+
+// term is a single parsed filter term
+type term struct {
+	key   string
+	value string
+}
+
+// Matcher reports whether a directory matches the value of a key:value term
+type Matcher func(info scanner.DirectoryInfo, value string) bool
+
+// matchers holds the supported filter keys
+var matchers = map[string]Matcher{
+	"name": func(info scanner.DirectoryInfo, value string) bool {
+		return strings.Contains(strings.ToLower(filepath.Base(info.Path)), strings.ToLower(value))
+	},
+	"git": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.GitMetadata != nil && info.GitMetadata.IsGitRepo, value)
+	},
+	"dirty": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.GitMetadata != nil && info.GitMetadata.HasUncommitted, value)
+	},
+	"conflicted": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.GitMetadata != nil && info.GitMetadata.Conflicts > 0, value)
+	},
+	"sparse": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.GitMetadata != nil && info.GitMetadata.Sparse != nil, value)
+	},
+	"branch": func(info scanner.DirectoryInfo, value string) bool {
+		return info.GitMetadata != nil && strings.EqualFold(info.GitMetadata.CurrentBranch, value)
+	},
+	"ci": func(info scanner.DirectoryInfo, value string) bool {
+		return info.Enrichment != nil && strings.EqualFold(info.Enrichment.CI, value)
+	},
+	"assigned": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.Enrichment != nil && len(info.Enrichment.AssignedIssues) > 0, value)
+	},
+	"docker": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.Docker != nil, value)
+	},
+	"running": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.Docker != nil && len(info.Docker.Running) > 0, value)
+	},
+	"terraform": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.Extras != nil && info.Extras.Terraform != nil, value)
+	},
+	"lang": func(info scanner.DirectoryInfo, value string) bool {
+		for _, language := range info.Languages {
+			if strings.EqualFold(language, value) {
+				return true
+			}
+		}
+		return false
+	},
+	"risk": func(info scanner.DirectoryInfo, value string) bool {
+		return matchBool(info.Extras != nil && info.Extras.Terraform.HighRisk(), value)
+	},
+	"remote": func(info scanner.DirectoryInfo, value string) bool {
+		return info.Remote != nil && strings.EqualFold(info.Remote.State, value)
+	},
+}
+
+// Register adds or replaces the matcher for key, for keys that depend on
+// state outside the scan result such as the configured groups
+func Register(key string, m Matcher) {
+	matchers[strings.ToLower(key)] = m
+}
+
+// Keys returns the supported filter keys, sorted
+func Keys() []string {
+	keys := make([]string, 0, len(matchers))
+	for key := range matchers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parse parses a filter expression such as "ci:failing dirty:true api".
+// An empty expression matches everything.
+func Parse(expr string) (*Filter, error) {
+	f := &Filter{}
+	for _, field := range strings.Fields(expr) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			f.terms = append(f.terms, term{key: "name", value: field})
+			continue
+		}
+		key = strings.ToLower(key)
+		if _, known := matchers[key]; !known {
+			return nil, fmt.Errorf("unknown filter key %q (supported: %s)", key, strings.Join(Keys(), ", "))
+		}
+		f.terms = append(f.terms, term{key: key, value: value})
+	}
+	return f, nil
+}
+
+// Match reports whether a directory matches every term of the filter
+func (f *Filter) Match(info scanner.DirectoryInfo) bool {
+	for _, t := range f.terms {
+		if !matchers[t.key](info, t.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Values returns the values of the filter's terms with the given key
+func (f *Filter) Values(key string) []string {
+	var values []string
+	for _, t := range f.terms {
+		if t.key == key {
+			values = append(values, t.value)
+		}
+	}
+	return values
+}
+
+// Apply returns the directories that match the filter, in their original order
+func (f *Filter) Apply(infos []scanner.DirectoryInfo) []scanner.DirectoryInfo {
+	if len(f.terms) == 0 {
+		return infos
+	}
+	var matched []scanner.DirectoryInfo
+	for _, info := range infos {
+		if f.Match(info) {
+			matched = append(matched, info)
+		}
+	}
+	return matched
+}
+
+// matchBool matches a boolean attribute against a term value. An empty value
+// (e.g. "dirty:") means true.
+func matchBool(actual bool, value string) bool {
+	switch strings.ToLower(value) {
+	case "", "true", "yes", "1":
+		return actual
+	case "false", "no", "0":
+		return !actual
+	}
+	return false
+}
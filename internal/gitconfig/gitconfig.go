@@ -0,0 +1,81 @@
+// Package gitconfig compares the effective git config of repositories with
+// expected values.
+package gitconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Mismatch is a config key whose value differs from the expected one
+type Mismatch struct {
+	Key      string
+	Expected string // Glob the value must match; "" means the key must be unset
+	Actual   string // Effective value, "" when unset
+	Set      bool   // Whether the key is set at all
+}
+
+// Parse splits a "key=value" expectation as written in the config
+func Parse(entry string) (key, value string, err error) {
+	key, value, ok := strings.Cut(entry, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || !strings.Contains(key, ".") {
+		return "", "", fmt.Errorf("invalid git config entry %q (expected key=value, e.g. pull.rebase=true)", entry)
+	}
+	if _, err := path.Match(value, ""); err != nil {
+		return "", "", fmt.Errorf("invalid git config value pattern %q: %w", value, err)
+	}
+	return key, value, nil
+}
+
+// Check compares the config git sees in the repository at dir (repository,
+// global and system config combined) with expected, a map of keys to globs
+// their values must match, and returns the mismatches sorted by key
+func Check(dir string, expected map[string]string) ([]Mismatch, error) {
+	keys := make([]string, 0, len(expected))
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var mismatches []Mismatch
+	for _, key := range keys {
+		actual, set, err := get(dir, key)
+		if err != nil {
+			return nil, err
+		}
+		want := expected[key]
+		if want == "" && !set {
+			continue
+		}
+		if ok, _ := path.Match(want, actual); ok && set {
+			continue
+		}
+		mismatches = append(mismatches, Mismatch{Key: key, Expected: want, Actual: actual, Set: set})
+	}
+	return mismatches, nil
+}
+
+// get returns the effective value of key in dir. `git config --get` exits
+// with 1 when the key is unset, which is not an error.
+func get(dir, key string) (string, bool, error) {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "", false, nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", false, fmt.Errorf("git config %s: %s", key, strings.ReplaceAll(msg, "\n", "; "))
+		}
+		return "", false, fmt.Errorf("git config %s: %w", key, err)
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
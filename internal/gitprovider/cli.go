@@ -0,0 +1,178 @@
+package gitprovider
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CLI reads repositories by running the git command, which understands
+// everything git does (e.g. newer index and object formats, partial clones)
+// at the cost of a process per query
+type CLI struct{}
+
+// OpenRepo opens the repository at dir
+func (CLI) OpenRepo(dir string) (Repo, error) {
+	// Like go-git, only open dir itself rather than a repository it is in
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, dir)
+	}
+	r := &cliRepo{dir: dir}
+	if _, err := r.git("rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotRepository, dir, err)
+	}
+	return r, nil
+}
+
+// cliRepo is a repository opened by CLI
+type cliRepo struct {
+	dir string
+}
+
+// git runs git in the repository and returns its output. Optional locks are
+// off so that reading never rewrites the index.
+func (r *cliRepo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0", "LC_ALL=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// logFormat prints a commit as NUL-separated hash, committer time, parents
+// and subject, one commit per line
+const logFormat = "--format=%H%x00%ct%x00%P%x00%s"
+
+func (r *cliRepo) Head() (Head, error) {
+	out, err := r.git("log", "-1", logFormat, "HEAD")
+	if err != nil {
+		return Head{}, err
+	}
+	commits := parseLog(out)
+	if len(commits) == 0 {
+		return Head{}, fmt.Errorf("HEAD has no commits")
+	}
+	head := Head{Branch: "HEAD", Hash: commits[0].Hash, Detached: true, Commit: commits[0]}
+	if ref, err := r.git("symbolic-ref", "-q", "HEAD"); err == nil {
+		head.Branch, head.Detached = strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/"), false
+	}
+	if head.Detached {
+		return head, nil
+	}
+	if upstream, err := r.git("rev-parse", "--abbrev-ref", head.Branch+"@{upstream}"); err == nil {
+		head.Upstream = strings.TrimSpace(upstream)
+	} else if _, err := r.git("rev-parse", "--verify", "-q", "refs/remotes/origin/"+head.Branch); err == nil {
+		head.Upstream = "origin/" + head.Branch
+	}
+	return head, nil
+}
+
+func (r *cliRepo) Remotes() ([]Remote, error) {
+	// Exits with 1 when there are no remotes
+	urls, _ := r.git("config", "--get-regexp", `^remote\..*\.url$`)
+	byName := make(map[string]*Remote)
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(urls), "\n") {
+		key, url, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		remote, ok := byName[name]
+		if !ok {
+			remote = &Remote{Name: name}
+			byName[name] = remote
+			names = append(names, name)
+		}
+		remote.URLs = append(remote.URLs, url)
+	}
+	sort.Strings(names)
+	var remotes []Remote
+	for _, name := range names {
+		remote := byName[name]
+		if ref, err := r.git("symbolic-ref", "-q", "refs/remotes/"+name+"/HEAD"); err == nil {
+			remote.Head = strings.TrimPrefix(strings.TrimSpace(ref), "refs/remotes/"+name+"/")
+		}
+		remotes = append(remotes, *remote)
+	}
+	return remotes, nil
+}
+
+func (r *cliRepo) Status() ([]FileStatus, error) {
+	out, err := r.git("status", "--porcelain=v1", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	var files []FileStatus
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		files = append(files, FileStatus{Path: entry[3:], Staging: entry[0], Worktree: entry[1]})
+		if entry[0] == 'R' || entry[0] == 'C' {
+			i++ // The source path of a rename or copy follows
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (r *cliRepo) Log(rev, exclude string) ([]Commit, error) {
+	args := []string{"log", logFormat, rev}
+	if exclude != "" {
+		args = append(args, "^"+exclude)
+	}
+	out, err := r.git(append(args, "--")...)
+	if err != nil {
+		return nil, err
+	}
+	commits := parseLog(out)
+	sortNewestFirst(commits)
+	return commits, nil
+}
+
+// Reflog asks git where the reflog is, as it may be outside .git
+func (r *cliRepo) Reflog() ([]ReflogEntry, error) {
+	out, err := r.git("rev-parse", "--git-path", "logs/HEAD")
+	if err != nil {
+		return nil, err
+	}
+	path := strings.TrimSpace(out)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	return readReflog(path)
+}
+
+// parseLog parses git log output in logFormat
+func parseLog(out string) []Commit {
+	var commits []Commit
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		c := Commit{Hash: fields[0], Subject: fields[3]}
+		if parents := strings.Fields(fields[2]); len(parents) > 0 {
+			c.Parents = parents
+		}
+		if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			c.Time = time.Unix(seconds, 0)
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
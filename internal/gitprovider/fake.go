@@ -0,0 +1,111 @@
+package gitprovider
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Fake is an in-memory provider for tests: it serves the repositories in
+// Repos, keyed by directory, and reports any other directory as not a
+// repository
+type Fake struct {
+	Repos map[string]*FakeRepo
+}
+
+// OpenRepo returns the fake repository at dir
+func (f Fake) OpenRepo(dir string) (Repo, error) {
+	if r, ok := f.Repos[filepath.Clean(dir)]; ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotRepository, dir)
+}
+
+// FakeRepo is a repository held in memory. Head.Hash, Refs and the Parents
+// of Commits must refer to hashes in Commits; an empty Head.Hash is an
+// unborn branch.
+type FakeRepo struct {
+	HeadState Head
+	RemoteSet []Remote
+	Files     []FileStatus
+	Commits   []Commit
+	Refs      map[string]string // Hash each ref name (e.g. "origin/main") points to
+	Moves     []ReflogEntry     // The reflog, newest first
+}
+
+func (r *FakeRepo) Head() (Head, error) {
+	if r.HeadState.Hash == "" {
+		return Head{}, fmt.Errorf("reference not found")
+	}
+	head := r.HeadState
+	if c, ok := r.commit(head.Hash); ok {
+		head.Commit = c
+	}
+	return head, nil
+}
+
+func (r *FakeRepo) Remotes() ([]Remote, error) {
+	return r.RemoteSet, nil
+}
+
+func (r *FakeRepo) Status() ([]FileStatus, error) {
+	return r.Files, nil
+}
+
+func (r *FakeRepo) Log(rev, exclude string) ([]Commit, error) {
+	reachable, err := r.reachable(rev)
+	if err != nil {
+		return nil, err
+	}
+	hidden := map[string]bool{}
+	if exclude != "" {
+		if hidden, err = r.reachable(exclude); err != nil {
+			return nil, err
+		}
+	}
+	var commits []Commit
+	for _, c := range r.Commits {
+		if reachable[c.Hash] && !hidden[c.Hash] {
+			commits = append(commits, c)
+		}
+	}
+	sortNewestFirst(commits)
+	return commits, nil
+}
+
+func (r *FakeRepo) Reflog() ([]ReflogEntry, error) {
+	return r.Moves, nil
+}
+
+// reachable returns the hashes of the commits reachable from rev
+func (r *FakeRepo) reachable(rev string) (map[string]bool, error) {
+	hash := rev
+	if h, ok := r.Refs[rev]; ok {
+		hash = h
+	}
+	if _, ok := r.commit(hash); !ok {
+		return nil, fmt.Errorf("unknown revision %q", rev)
+	}
+	seen := make(map[string]bool)
+	pending := []string{hash}
+	for len(pending) > 0 {
+		hash, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		if c, ok := r.commit(hash); ok {
+			pending = append(pending, c.Parents...)
+		}
+	}
+	return seen, nil
+}
+
+// commit returns the commit with the given hash
+func (r *FakeRepo) commit(hash string) (Commit, bool) {
+	for _, c := range r.Commits {
+		if c.Hash == hash {
+			return c, true
+		}
+	}
+	return Commit{}, false
+}
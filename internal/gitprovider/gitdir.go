@@ -0,0 +1,50 @@
+package gitprovider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitDir returns the git directory of the repository whose worktree is
+// repo: repo/.git, or the directory a .git file points to in linked
+// worktrees and submodules. HEAD, the index, the reflog and the state of a
+// merge or rebase in progress are all kept there.
+func GitDir(repo string) (string, error) {
+	path := filepath.Join(repo, ".git")
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return path, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", fmt.Errorf("%s is neither a directory nor a gitdir file", path)
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(repo, gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
+
+// CommonDir returns the directory holding the refs, objects and config that
+// the worktrees of a repository share: the main git directory named by the
+// commondir file of a linked worktree's gitDir, or gitDir itself
+func CommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
+}
@@ -0,0 +1,102 @@
+// Package gitprovider reads git repositories for the scanner through an
+// interface, so the scanner can use go-git, the git command or an in-memory
+// fake in tests, and other backends can be added without changing it.
+package gitprovider
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotRepository is returned by OpenRepo for a directory that isn't the
+// top of a git repository
+var ErrNotRepository = errors.New("not a git repository")
+
+// Provider opens repositories
+type Provider interface {
+	// OpenRepo opens the repository whose worktree is dir, returning an
+	// error wrapping ErrNotRepository if dir has no .git
+	OpenRepo(dir string) (Repo, error)
+}
+
+// Repo reads one repository
+type Repo interface {
+	// Head returns what HEAD points to. It fails on an unborn branch.
+	Head() (Head, error)
+	// Remotes returns the configured remotes, sorted by name
+	Remotes() ([]Remote, error)
+	// Status returns the files with uncommitted changes, untracked files
+	// included, sorted by path
+	Status() ([]FileStatus, error)
+	// Log returns the commits reachable from rev but not from exclude,
+	// newest first; with an empty exclude, all commits reachable from rev.
+	// Revisions are commit hashes or ref names such as "origin/main".
+	Log(rev, exclude string) ([]Commit, error)
+	// Reflog returns the moves of HEAD recorded in its reflog, newest
+	// first; none if the repository keeps no reflog
+	Reflog() ([]ReflogEntry, error)
+}
+
+// Head is the state of HEAD
+type Head struct {
+	Branch   string // Branch checked out, or "HEAD" when detached
+	Hash     string // Commit HEAD points to
+	Detached bool
+	Upstream string // Remote-tracking branch the branch tracks, e.g. origin/main, or origin/<branch> if untracked but present; "" if none
+	Commit   Commit // The commit HEAD points to
+}
+
+// Commit describes a commit
+type Commit struct {
+	Hash    string
+	Subject string    // First line of the message
+	Time    time.Time // Committer time
+	Parents []string  // Parent hashes
+}
+
+// Merge reports whether c is a merge commit
+func (c Commit) Merge() bool {
+	return len(c.Parents) > 1
+}
+
+// ReflogEntry is a move of HEAD recorded in the reflog
+type ReflogEntry struct {
+	Old     string // Hash HEAD moved from, all zeros for the first entry
+	New     string // Hash HEAD moved to
+	Time    time.Time
+	Message string // What moved it, e.g. "checkout: moving from main to fix"
+}
+
+// Remote is a configured remote
+type Remote struct {
+	Name string
+	URLs []string
+	Head string // Branch refs/remotes/<name>/HEAD points to (set by clone or 'git remote set-head'), if any
+}
+
+// FileStatus is the status of a changed file, with the codes of
+// 'git status --porcelain': ' ' unmodified, M modified, A added, D deleted,
+// R renamed, C copied, U unmerged, ? untracked
+type FileStatus struct {
+	Path     string
+	Staging  byte // Status in the index
+	Worktree byte // Status in the worktree
+}
+
+// Backends that New accepts
+const (
+	BackendGoGit = "go-git" // Pure Go, the default
+	BackendCLI   = "cli"    // The git command, for repositories go-git can't read
+)
+
+// New returns the provider of the named backend; "" is go-git
+func New(backend string) (Provider, error) {
+	switch backend {
+	case "", BackendGoGit:
+		return GoGit{}, nil
+	case BackendCLI:
+		return CLI{}, nil
+	}
+	return nil, fmt.Errorf("unknown git backend %q (expected %s or %s)", backend, BackendGoGit, BackendCLI)
+}
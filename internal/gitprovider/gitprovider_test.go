@@ -0,0 +1,140 @@
+package gitprovider_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+	"github.com/ThandieOps/thandie-agent/pkg/scannertest"
+)
+
+// TestBackendsAgree checks that go-git and the git command describe the same
+// repositories the same way
+func TestBackendsAgree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	w := scannertest.New(t, scannertest.Mixed(7)...)
+
+	// Give the repository with several commits an upstream two commits behind
+	ahead := w.Dir("repo-0006")
+	if out, err := exec.Command("git", "-C", ahead, "update-ref", "refs/remotes/origin/main", "HEAD~2").CombinedOutput(); err != nil {
+		t.Fatalf("git update-ref: %v: %s", err, out)
+	}
+
+	backends := []gitprovider.Provider{gitprovider.GoGit{}, gitprovider.CLI{}}
+	for _, r := range w.Repos {
+		t.Run(r.Name, func(t *testing.T) {
+			var repos []gitprovider.Repo
+			for _, backend := range backends {
+				repo, err := backend.OpenRepo(w.Dir(r.Name))
+				if r.Plain {
+					if !errors.Is(err, gitprovider.ErrNotRepository) {
+						t.Errorf("%T: OpenRepo of a plain directory = %v, want ErrNotRepository", backend, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%T: OpenRepo: %v", backend, err)
+				}
+				repos = append(repos, repo)
+			}
+			if r.Plain {
+				return
+			}
+			goGit, cli := repos[0], repos[1]
+
+			compare(t, "Head", goGit.Head, cli.Head)
+			compare(t, "Remotes", goGit.Remotes, cli.Remotes)
+			compare(t, "Status", goGit.Status, cli.Status)
+			compare(t, "Reflog", goGit.Reflog, cli.Reflog)
+			compare(t, "Log", func() ([]gitprovider.Commit, error) { return goGit.Log("HEAD", "") },
+				func() ([]gitprovider.Commit, error) { return cli.Log("HEAD", "") })
+
+			head, err := cli.Head()
+			if err != nil || head.Upstream == "" {
+				return
+			}
+			for _, side := range [][2]string{{head.Hash, head.Upstream}, {head.Upstream, head.Hash}} {
+				compare(t, "Log "+side[0]+" ^"+side[1],
+					func() ([]gitprovider.Commit, error) { return goGit.Log(side[0], side[1]) },
+					func() ([]gitprovider.Commit, error) { return cli.Log(side[0], side[1]) })
+			}
+			if got, _ := cli.Log(head.Hash, head.Upstream); r.Name == "repo-0006" && len(got) != 2 {
+				t.Errorf("ahead of upstream by %d commits, want 2", len(got))
+			}
+		})
+	}
+}
+
+// compare fails the test if the go-git and git command results differ
+func compare[T any](t *testing.T, name string, goGit, cli func() (T, error)) {
+	t.Helper()
+	a, errA := goGit()
+	b, errB := cli()
+	if (errA == nil) != (errB == nil) {
+		t.Errorf("%s: go-git error %v, git error %v", name, errA, errB)
+		return
+	}
+	if !reflect.DeepEqual(normalize(a), normalize(b)) {
+		t.Errorf("%s differs:\ngo-git: %+v\ngit:    %+v", name, a, b)
+	}
+}
+
+// normalize makes commit times comparable with DeepEqual, which would tell
+// apart the same instant in different locations
+func normalize(v any) any {
+	switch v := v.(type) {
+	case gitprovider.Head:
+		v.Commit.Time = v.Commit.Time.UTC()
+		return v
+	case []gitprovider.Commit:
+		commits := make([]gitprovider.Commit, len(v))
+		for i, c := range v {
+			c.Time = c.Time.UTC()
+			commits[i] = c
+		}
+		return commits
+	}
+	return v
+}
+
+func TestGitDir(t *testing.T) {
+	root := t.TempDir()
+	main := filepath.Join(root, "main")
+	linked := filepath.Join(root, "linked")
+	for _, dir := range []string{filepath.Join(main, ".git", "worktrees", "linked"), linked} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(linked, ".git"), []byte("gitdir: ../main/.git/worktrees/linked\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for repo, want := range map[string]string{
+		main:   filepath.Join(main, ".git"),
+		linked: filepath.Join(main, ".git", "worktrees", "linked"),
+	} {
+		got, err := gitprovider.GitDir(repo)
+		if err != nil || got != want {
+			t.Errorf("GitDir(%s) = %q, %v, want %q", filepath.Base(repo), got, err, want)
+		}
+	}
+	if got := gitprovider.CommonDir(filepath.Join(main, ".git", "worktrees", "linked")); got != filepath.Join(main, ".git", "worktrees", "linked") {
+		t.Errorf("CommonDir without a commondir file = %q, want the git directory", got)
+	}
+	if err := os.WriteFile(filepath.Join(main, ".git", "worktrees", "linked", "commondir"), []byte("../..\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitprovider.CommonDir(filepath.Join(main, ".git", "worktrees", "linked")); got != filepath.Join(main, ".git") {
+		t.Errorf("CommonDir = %q, want %q", got, filepath.Join(main, ".git"))
+	}
+	if _, err := gitprovider.GitDir(root); err == nil {
+		t.Errorf("GitDir of a directory without .git succeeded")
+	}
+}
@@ -0,0 +1,279 @@
+package gitprovider
+
+import (
+	"container/heap"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GoGit reads repositories with go-git, without running git
+type GoGit struct{}
+
+// OpenRepo opens the repository at dir
+func (GoGit) OpenRepo(dir string) (Repo, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotRepository, dir, err)
+	}
+	return &goGitRepo{repo: repo, dir: dir, walks: make(map[[2]plumbing.Hash][]Commit)}, nil
+}
+
+// goGitRepo is a repository opened by GoGit
+type goGitRepo struct {
+	repo *git.Repository
+	dir  string
+
+	mu    sync.Mutex
+	walks map[[2]plumbing.Hash][]Commit // Log results a walk produced besides the one asked for
+}
+
+func (r *goGitRepo) Head() (Head, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return Head{}, err
+	}
+	head := Head{Branch: ref.Name().Short(), Hash: ref.Hash().String(), Detached: !ref.Name().IsBranch()}
+	if c, err := r.repo.CommitObject(ref.Hash()); err == nil {
+		head.Commit = commitOf(c)
+	}
+	if !head.Detached {
+		if upstream, ok := r.upstream(head.Branch); ok {
+			head.Upstream = upstream.Short()
+		}
+	}
+	return head, nil
+}
+
+// upstream returns the remote-tracking ref the given branch tracks, falling
+// back to origin/<branch> when no upstream is configured
+func (r *goGitRepo) upstream(branch string) (plumbing.ReferenceName, bool) {
+	if cfg, err := r.repo.Config(); err == nil {
+		if b, ok := cfg.Branches[branch]; ok && b.Remote != "" && b.Merge.IsBranch() {
+			name := plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short())
+			if _, err := r.repo.Reference(name, true); err == nil {
+				return name, true
+			}
+		}
+	}
+
+	name := plumbing.NewRemoteReferenceName("origin", branch)
+	if _, err := r.repo.Reference(name, true); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+func (r *goGitRepo) Remotes() ([]Remote, error) {
+	remotes, err := r.repo.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	var out []Remote
+	for _, remote := range remotes {
+		cfg := remote.Config()
+		rem := Remote{Name: cfg.Name, URLs: cfg.URLs}
+		if ref, err := r.repo.Reference(plumbing.NewRemoteHEADReferenceName(cfg.Name), false); err == nil && ref.Type() == plumbing.SymbolicReference {
+			rem.Head = strings.TrimPrefix(ref.Target().String(), "refs/remotes/"+cfg.Name+"/")
+		}
+		out = append(out, rem)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *goGitRepo) Status() ([]FileStatus, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return nil, err
+	}
+	var files []FileStatus
+	for path, s := range status {
+		if s.Staging == git.Unmodified && s.Worktree == git.Unmodified {
+			continue
+		}
+		files = append(files, FileStatus{Path: path, Staging: byte(s.Staging), Worktree: byte(s.Worktree)})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func (r *goGitRepo) Log(rev, exclude string) ([]Commit, error) {
+	from, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, err
+	}
+	if exclude == "" {
+		iter, err := r.repo.Log(&git.LogOptions{From: *from})
+		if err != nil {
+			return nil, err
+		}
+		var commits []Commit
+		err = iter.ForEach(func(c *object.Commit) error {
+			commits = append(commits, commitOf(c))
+			return nil
+		})
+		return commits, err
+	}
+	hidden, err := r.repo.ResolveRevision(plumbing.Revision(exclude))
+	if err != nil {
+		return nil, err
+	}
+
+	// A walk yields both sides, so the ahead/behind pair of Log calls the
+	// scanner makes walks once
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if commits, ok := r.walks[[2]plumbing.Hash{*from, *hidden}]; ok {
+		delete(r.walks, [2]plumbing.Hash{*from, *hidden})
+		return commits, nil
+	}
+	only, other, err := r.walk(*from, *hidden)
+	if err != nil {
+		return nil, err
+	}
+	r.walks[[2]plumbing.Hash{*hidden, *from}] = other
+	return only, nil
+}
+
+// commit flags used while walking history
+const (
+	flagLocal = 1 << iota
+	flagUpstream
+)
+
+// commitQueue is a max-heap of commits ordered by committer time, newest first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int           { return len(q) }
+func (q commitQueue) Less(i, j int) bool { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)        { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// walk compares local and upstream the way git rev-list --left-right does:
+// it walks both histories newest-first, painting each commit with the
+// side(s) it is reachable from, and stops once every pending commit is
+// reachable from both and older than any commit reachable from only one side
+// (so commits sharing a timestamp can't be miscounted). Returns the commits
+// only reachable from local and those only reachable from upstream, newest
+// first.
+func (r *goGitRepo) walk(local, upstream plumbing.Hash) ([]Commit, []Commit, error) {
+	flags := make(map[plumbing.Hash]int)
+	commits := make(map[plumbing.Hash]*object.Commit)
+	queue := &commitQueue{}
+
+	// push paints a commit with flag and queues it so the flag reaches its parents
+	push := func(hash plumbing.Hash, flag int) error {
+		if flags[hash]&flag == flag {
+			return nil
+		}
+		flags[hash] |= flag
+		c, ok := commits[hash]
+		if !ok {
+			var err error
+			if c, err = r.repo.CommitObject(hash); err != nil {
+				return err
+			}
+			commits[hash] = c
+		}
+		heap.Push(queue, c)
+		return nil
+	}
+
+	if err := push(local, flagLocal); err != nil {
+		return nil, nil, err
+	}
+	if err := push(upstream, flagUpstream); err != nil {
+		return nil, nil, err
+	}
+
+	for queue.Len() > 0 && !settled(*queue, flags, commits) {
+		c := heap.Pop(queue).(*object.Commit)
+		for _, parent := range c.ParentHashes {
+			if err := push(parent, flags[c.Hash]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var ahead, behind []Commit
+	for hash, flag := range flags {
+		switch flag {
+		case flagLocal:
+			ahead = append(ahead, commitOf(commits[hash]))
+		case flagUpstream:
+			behind = append(behind, commitOf(commits[hash]))
+		}
+	}
+	sortNewestFirst(ahead)
+	sortNewestFirst(behind)
+	return ahead, behind, nil
+}
+
+// settled reports whether the walk can stop: every queued commit is
+// reachable from both sides, and all of them are strictly older than every
+// commit reachable from only one side, so none can still be an ancestor of one
+func settled(queue commitQueue, flags map[plumbing.Hash]int, commits map[plumbing.Hash]*object.Commit) bool {
+	var newestQueued time.Time
+	for _, c := range queue {
+		if flags[c.Hash] != flagLocal|flagUpstream {
+			return false
+		}
+		if c.Committer.When.After(newestQueued) {
+			newestQueued = c.Committer.When
+		}
+	}
+	for hash, flag := range flags {
+		if flag != flagLocal|flagUpstream && !commits[hash].Committer.When.After(newestQueued) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortNewestFirst orders commits by committer time, newest first, and by
+// hash among commits made at the same time
+func sortNewestFirst(commits []Commit) {
+	sort.Slice(commits, func(i, j int) bool {
+		if !commits[i].Time.Equal(commits[j].Time) {
+			return commits[i].Time.After(commits[j].Time)
+		}
+		return commits[i].Hash < commits[j].Hash
+	})
+}
+
+// commitOf describes a go-git commit
+func commitOf(c *object.Commit) Commit {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	commit := Commit{Hash: c.Hash.String(), Subject: subject, Time: c.Committer.When}
+	for _, parent := range c.ParentHashes {
+		commit.Parents = append(commit.Parents, parent.String())
+	}
+	return commit
+}
+
+// Reflog reads the reflog file, which go-git doesn't maintain or parse
+func (r *goGitRepo) Reflog() ([]ReflogEntry, error) {
+	gitDir, err := GitDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+	return readReflog(filepath.Join(gitDir, "logs", "HEAD"))
+}
@@ -0,0 +1,61 @@
+package gitprovider
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readReflog reads the reflog file at path, newest entry first. A missing
+// file is an empty reflog.
+func readReflog(path string) ([]ReflogEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+	defer f.Close()
+
+	var entries []ReflogEntry
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lines.Scan() {
+		if e, ok := parseReflogLine(lines.Text()); ok {
+			entries = append(entries, e)
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+
+	// The reflog is appended to, so reversing it puts the newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// parseReflogLine parses a reflog line:
+// "<old> <new> <name> <<email>> <unix time> <zone>\t<message>"
+func parseReflogLine(line string) (ReflogEntry, bool) {
+	header, message, ok := strings.Cut(line, "\t")
+	if !ok {
+		return ReflogEntry{}, false
+	}
+	fields := strings.Fields(header)
+	if len(fields) < 4 {
+		return ReflogEntry{}, false
+	}
+	unix, err := strconv.ParseInt(fields[len(fields)-2], 10, 64)
+	if err != nil {
+		return ReflogEntry{}, false
+	}
+	return ReflogEntry{Old: fields[0], New: fields[1], Time: time.Unix(unix, 0), Message: message}, true
+}
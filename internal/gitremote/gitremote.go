@@ -0,0 +1,80 @@
+package gitremote
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Remote is a parsed git remote URL
+type Remote struct {
+	Host string // Host name without port or user, lowercased (e.g. github.com)
+	Path string // Repository path without leading slash or .git suffix (e.g. acme/api)
+}
+
+// Owner returns the namespace the repository belongs to (everything but the last path segment)
+func (r Remote) Owner() string {
+	if i := strings.LastIndexByte(r.Path, '/'); i >= 0 {
+		return r.Path[:i]
+	}
+	return ""
+}
+
+// Name returns the repository name (the last path segment)
+func (r Remote) Name() string {
+	return r.Path[strings.LastIndexByte(r.Path, '/')+1:]
+}
+
+// String returns the normalized form host/path, e.g. github.com/acme/api
+func (r Remote) String() string {
+	return r.Host + "/" + r.Path
+}
+
+// Parse parses a remote URL in any of the forms git accepts:
+// https://host/owner/repo.git, ssh://git@host:22/owner/repo.git and the
+// scp-like git@host:owner/repo.git
+func Parse(rawURL string) (Remote, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return Remote{}, fmt.Errorf("empty remote URL")
+	}
+
+	var host, path string
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return Remote{}, fmt.Errorf("invalid remote URL %q: %w", rawURL, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if i := strings.IndexByte(rawURL, ':'); i > 0 && !strings.Contains(rawURL[:i], "/") {
+		// scp-like syntax: [user@]host:path
+		host, path = rawURL[:i], rawURL[i+1:]
+		if at := strings.LastIndexByte(host, '@'); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return Remote{}, fmt.Errorf("unrecognized remote URL %q", rawURL)
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	if host == "" || path == "" {
+		return Remote{}, fmt.Errorf("unrecognized remote URL %q", rawURL)
+	}
+
+	return Remote{
+		Host: strings.ToLower(host),
+		Path: strings.ToLower(path),
+	}, nil
+}
+
+// Normalize returns the normalized host/path form of a remote URL so that
+// HTTPS and SSH URLs of the same repository compare equal. Unparseable URLs
+// are returned unchanged.
+func Normalize(rawURL string) string {
+	remote, err := Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return remote.String()
+}
@@ -0,0 +1,80 @@
+package gitremote
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rewrite is a remote URL of a repository that starts with a rewritten prefix
+type Rewrite struct {
+	Remote string // Remote name, e.g. origin
+	Push   bool   // Whether the URL is a pushurl rather than a (fetch) url
+	Old    string
+	New    string
+}
+
+// FindRewrites returns the url and pushurl entries of the remotes of the
+// repository at dir that start with from, with from replaced by to
+func FindRewrites(dir, from, to string) ([]Rewrite, error) {
+	out, err := git(dir, "config", "--get-regexp", `^remote\..*\.(url|pushurl)$`)
+	if err != nil {
+		return nil, err
+	}
+
+	var rewrites []Rewrite
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		key, value, ok := strings.Cut(line, " ")
+		if !ok || !strings.HasPrefix(value, from) {
+			continue
+		}
+		// remote.<name>.url, where the name may itself contain dots
+		name := strings.TrimPrefix(key, "remote.")
+		name, field := name[:strings.LastIndexByte(name, '.')], name[strings.LastIndexByte(name, '.')+1:]
+		rewrites = append(rewrites, Rewrite{
+			Remote: name,
+			Push:   field == "pushurl",
+			Old:    value,
+			New:    to + strings.TrimPrefix(value, from),
+		})
+	}
+	sort.SliceStable(rewrites, func(i, j int) bool { return rewrites[i].Remote < rewrites[j].Remote })
+	return rewrites, nil
+}
+
+// ApplyRewrite replaces the old URL of the remote with the new one in the
+// repository at dir, leaving its other URLs alone
+func ApplyRewrite(dir string, r Rewrite) error {
+	args := []string{"remote", "set-url"}
+	if r.Push {
+		args = append(args, "--push")
+	}
+	args = append(args, r.Remote, r.New, "^"+regexp.QuoteMeta(r.Old)+"$")
+	_, err := git(dir, args...)
+	return err
+}
+
+// git runs a git command in dir, returning its output or an error carrying
+// git's message. `git config --get-regexp` exits with 1 when nothing
+// matches, which is not an error.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && args[0] == "config" {
+			return "", nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			msg = strings.ReplaceAll(msg, "\n", "; ")
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
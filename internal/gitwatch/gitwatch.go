@@ -0,0 +1,178 @@
+// Package gitwatch notices commits and branch switches as they happen by
+// watching the index and HEAD of repositories, so the daemon can rescan just
+// the repository that changed instead of waiting for the next full scan.
+package gitwatch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/fsnotify/fsnotify"
+)
+
+// log is the gitwatch component's logger
+var log = logger.For("gitwatch")
+
+// Watcher watches the git directories of up to a limit of repositories, one
+// watch each, keeping the most recently active ones when there are more
+type Watcher struct {
+	fs       *fsnotify.Watcher
+	limit    int
+	debounce time.Duration
+
+	mu       sync.Mutex
+	repos    map[string]string    // Watched git directory -> repository
+	active   map[string]time.Time // Repository -> last commit or change seen
+	pending  map[string]*time.Timer
+	ignoring map[string]time.Time // Repository -> ignore its events until, while it is rescanned
+}
+
+// New returns a Watcher of at most limit repositories that reports a change
+// once no more events came for debounce, so that a commit, which rewrites
+// the index and refs in several steps, is reported once
+func New(limit int, debounce time.Duration) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		fs:       fs,
+		limit:    limit,
+		debounce: debounce,
+		repos:    make(map[string]string),
+		active:   make(map[string]time.Time),
+		pending:  make(map[string]*time.Timer),
+		ignoring: make(map[string]time.Time),
+	}, nil
+}
+
+// Sync sets the repositories to watch, with the time each was last active
+// (e.g. its last commit). If there are more than the limit, those least
+// recently active, counting changes the Watcher saw itself, are not watched.
+// Returns the number of repositories watched.
+func (w *Watcher) Sync(repos map[string]time.Time) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for repo, t := range repos {
+		if seen, ok := w.active[repo]; !ok || t.After(seen) {
+			w.active[repo] = t
+		}
+	}
+	for repo := range w.active {
+		if _, ok := repos[repo]; !ok {
+			delete(w.active, repo)
+		}
+	}
+	ranked := make([]string, 0, len(w.active))
+	for repo := range w.active {
+		ranked = append(ranked, repo)
+	}
+	sort.Slice(ranked, func(i, j int) bool { return w.active[ranked[i]].After(w.active[ranked[j]]) })
+	if len(ranked) > w.limit {
+		ranked = ranked[:w.limit]
+	}
+
+	keep := make(map[string]bool, len(ranked))
+	for _, repo := range ranked {
+		keep[repo] = true
+	}
+	watching := make(map[string]bool, len(w.repos))
+	for gitDir, repo := range w.repos {
+		if keep[repo] {
+			watching[repo] = true
+			continue
+		}
+		_ = w.fs.Remove(gitDir)
+		delete(w.repos, gitDir)
+	}
+	for _, repo := range ranked {
+		if watching[repo] {
+			continue
+		}
+		gitDir, err := gitprovider.GitDir(repo)
+		if err != nil {
+			continue
+		}
+		if err := w.fs.Add(gitDir); err != nil {
+			log.Warn("failed to watch repository", "path", repo, "error", err)
+			continue
+		}
+		w.repos[gitDir] = repo
+	}
+	return len(w.repos)
+}
+
+// Run calls changed with the repository whose index or HEAD changed until
+// ctx is cancelled, one call at a time. Events caused by changed itself,
+// e.g. git refreshing the index while it is rescanned, are ignored.
+func (w *Watcher) Run(ctx context.Context, changed func(repo string)) {
+	var calls sync.Mutex
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Warn("missed repository changes, too many at once", "error", err)
+			} else {
+				log.Warn("repository watch failed", "error", err)
+			}
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if (name != "index" && name != "HEAD") || !event.Has(fsnotify.Create|fsnotify.Write) {
+				continue
+			}
+
+			w.mu.Lock()
+			repo, watched := w.repos[filepath.Dir(event.Name)]
+			if !watched || time.Now().Before(w.ignoring[repo]) {
+				w.mu.Unlock()
+				continue
+			}
+			w.active[repo] = time.Now()
+			if timer, ok := w.pending[repo]; ok {
+				timer.Reset(w.debounce)
+				w.mu.Unlock()
+				continue
+			}
+			w.pending[repo] = time.AfterFunc(w.debounce, func() {
+				calls.Lock()
+				defer calls.Unlock()
+				w.mu.Lock()
+				delete(w.pending, repo)
+				w.ignoring[repo] = time.Now().Add(24 * time.Hour)
+				w.mu.Unlock()
+				if ctx.Err() == nil {
+					changed(repo)
+				}
+				w.mu.Lock()
+				w.ignoring[repo] = time.Now().Add(w.debounce)
+				w.mu.Unlock()
+			})
+			w.mu.Unlock()
+		}
+	}
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, timer := range w.pending {
+		timer.Stop()
+	}
+	return w.fs.Close()
+}
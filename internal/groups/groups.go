@@ -0,0 +1,169 @@
+package groups
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitconfig"
+)
+
+// Groups assigns the directories of a workspace to the configured groups
+type Groups struct {
+	workspace string
+	groups    []config.GroupConfig
+}
+
+// New validates the group config and returns the groups of workspace
+func New(groups []config.GroupConfig, workspace string) (*Groups, error) {
+	seen := make(map[string]bool)
+	for i, group := range groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("groups[%d]: name is required", i)
+		}
+		key := normalize(group.Name)
+		if seen[key] {
+			return nil, fmt.Errorf("groups[%d]: duplicate group name %q", i, group.Name)
+		}
+		seen[key] = true
+		for _, pattern := range group.Paths {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("groups[%d]: invalid path pattern %q: %w", i, pattern, err)
+			}
+		}
+		for _, entry := range group.GitConfig {
+			if _, _, err := gitconfig.Parse(entry); err != nil {
+				return nil, fmt.Errorf("groups[%d]: %w", i, err)
+			}
+		}
+	}
+	return &Groups{workspace: workspace, groups: groups}, nil
+}
+
+// Names returns the group names in config order
+func (g *Groups) Names() []string {
+	names := make([]string, len(g.groups))
+	for i, group := range g.groups {
+		names[i] = group.Name
+	}
+	return names
+}
+
+// Lookup returns the configured spelling of a group name. Names match
+// case-insensitively, with "-" standing in for spaces ("side-projects").
+func (g *Groups) Lookup(name string) (string, bool) {
+	for _, group := range g.groups {
+		if normalize(group.Name) == normalize(name) {
+			return group.Name, true
+		}
+	}
+	return "", false
+}
+
+// Of returns the names of the groups containing the directory at path, in
+// config order
+func (g *Groups) Of(path string) []string {
+	var names []string
+	for _, group := range g.groups {
+		if g.contains(group, path) {
+			names = append(names, group.Name)
+		}
+	}
+	return names
+}
+
+// Branch returns the expected branch of the directory at path: that of the
+// first group containing it that sets one, or ""
+func (g *Groups) Branch(path string) string {
+	for _, group := range g.groups {
+		if group.Branch != "" && g.contains(group, path) {
+			return group.Branch
+		}
+	}
+	return ""
+}
+
+// EmailDomains returns the domains the user.email of the directory at path
+// must belong to: those of the first group containing it that sets any
+func (g *Groups) EmailDomains(path string) []string {
+	for _, group := range g.groups {
+		if len(group.EmailDomains) > 0 && g.contains(group, path) {
+			return group.EmailDomains
+		}
+	}
+	return nil
+}
+
+// EmailMatches reports whether email belongs to one of domains or their
+// subdomains
+func EmailMatches(email string, domains []string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return false
+	}
+	for _, want := range domains {
+		want = strings.ToLower(strings.TrimPrefix(want, "@"))
+		if domain == want || strings.HasSuffix(domain, "."+want) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitConfig returns the expected git config of the directory at path, keys
+// mapped to value globs. Each key is taken from the first group containing
+// the directory that sets it.
+func (g *Groups) GitConfig(path string) map[string]string {
+	expected := make(map[string]string)
+	for _, group := range g.groups {
+		if len(group.GitConfig) == 0 || !g.contains(group, path) {
+			continue
+		}
+		for _, entry := range group.GitConfig {
+			key, value, _ := gitconfig.Parse(entry)
+			if _, ok := expected[key]; !ok {
+				expected[key] = value
+			}
+		}
+	}
+	return expected
+}
+
+// Contains reports whether the directory at path belongs to the named group
+func (g *Groups) Contains(name, path string) bool {
+	for _, group := range g.groups {
+		if normalize(group.Name) == normalize(name) {
+			return g.contains(group, path)
+		}
+	}
+	return false
+}
+
+// contains reports whether a pattern of group matches path or one of its
+// parent directories, so that "platform" also covers platform/api
+func (g *Groups) contains(group config.GroupConfig, path string) bool {
+	rel, err := filepath.Rel(g.workspace, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = ""
+	}
+	for _, pattern := range group.Paths {
+		target := rel
+		if filepath.IsAbs(pattern) {
+			target = path
+		}
+		for target != "" && target != "." && target != string(filepath.Separator) {
+			if ok, _ := filepath.Match(pattern, target); ok {
+				return true
+			}
+			target = filepath.Dir(target)
+		}
+	}
+	return false
+}
+
+// normalize folds case and treats "-" as a space, so that group names can be
+// written in filter terms
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "-", " "))
+}
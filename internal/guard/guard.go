@@ -0,0 +1,217 @@
+package guard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+)
+
+// zeroHash is the object name git uses for a ref that doesn't exist
+const zeroHash = "0000000000000000000000000000000000000000"
+
+// HookMarker identifies pre-push hooks installed by Thandie
+const HookMarker = "# Installed by thandie guard install"
+
+// PushedRef is one line of the pre-push hook's stdin
+type PushedRef struct {
+	LocalRef  string
+	LocalSHA  string
+	RemoteRef string
+	RemoteSHA string
+}
+
+// CheckFailure describes a configured check that failed
+type CheckFailure struct {
+	Name   string
+	Output string // Tail of the check's combined output
+}
+
+// Result is the outcome of guarding a push
+type Result struct {
+	Secrets []Finding
+	Checks  []CheckFailure
+}
+
+// Blocked reports whether the push must be blocked
+func (r *Result) Blocked() bool {
+	return len(r.Secrets) > 0 || len(r.Checks) > 0
+}
+
+// ParsePushedRefs parses the "<local ref> <local sha> <remote ref> <remote sha>"
+// lines git feeds to a pre-push hook
+func ParsePushedRefs(r io.Reader) ([]PushedRef, error) {
+	var refs []PushedRef
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 4 {
+			continue
+		}
+		refs = append(refs, PushedRef{LocalRef: fields[0], LocalSHA: fields[1], RemoteRef: fields[2], RemoteSHA: fields[3]})
+	}
+	return refs, sc.Err()
+}
+
+// Run checks the commits being pushed from repoDir for secrets and runs the
+// configured checks
+func Run(repoDir string, refs []PushedRef, guardCfg config.GuardConfig) (*Result, error) {
+	result := &Result{}
+
+	if guardCfg.SecretsEnabled() {
+		for _, ref := range refs {
+			diff, err := outgoingDiff(repoDir, ref)
+			if err != nil {
+				return nil, err
+			}
+			result.Secrets = append(result.Secrets, DetectSecrets(diff)...)
+		}
+	}
+
+	for _, check := range guardCfg.Checks {
+		if failure := runCheck(repoDir, check); failure != nil {
+			result.Checks = append(result.Checks, *failure)
+		}
+	}
+
+	return result, nil
+}
+
+// outgoingDiff returns the diff of the commits a ref update would push.
+// Deleted refs push nothing; new refs push everything not yet on any remote.
+func outgoingDiff(repoDir string, ref PushedRef) (string, error) {
+	if ref.LocalSHA == zeroHash {
+		return "", nil
+	}
+
+	args := []string{"log", "-p", "--unified=0", "--no-color", "--format="}
+	if ref.RemoteSHA == zeroHash {
+		args = append(args, ref.LocalSHA, "--not", "--remotes")
+	} else {
+		args = append(args, ref.RemoteSHA+".."+ref.LocalSHA)
+	}
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read outgoing commits for %s: %w", ref.LocalRef, err)
+	}
+	return string(out), nil
+}
+
+// maxCheckOutput is the number of trailing output bytes kept for a failed check
+const maxCheckOutput = 2000
+
+// runCheck runs a configured check through the shell and returns a failure if it exits non-zero
+func runCheck(repoDir string, check config.GuardCheck) *CheckFailure {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", check.Run)
+	} else {
+		cmd = exec.Command("sh", "-c", check.Run)
+	}
+	cmd.Dir = repoDir
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err == nil {
+		return nil
+	}
+
+	tail := output.String()
+	if len(tail) > maxCheckOutput {
+		tail = "..." + tail[len(tail)-maxCheckOutput:]
+	}
+	return &CheckFailure{Name: check.Name, Output: strings.TrimSpace(tail)}
+}
+
+// HooksDir returns the hooks directory of the repository at repoDir,
+// honouring core.hooksPath and linked worktrees
+func HooksDir(repoDir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", "hooks")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository", repoDir)
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoDir, dir)
+	}
+	return dir, nil
+}
+
+// Install writes a pre-push hook into the repository at repoDir that runs
+// `<executable> guard`. An existing hook that wasn't installed by Thandie is
+// only replaced when force is set, and is backed up first (see BackupHook).
+func Install(repoDir, executable string, force bool) (string, error) {
+	hooksDir, err := HooksDir(repoDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	hookPath := filepath.Join(hooksDir, "pre-push")
+	if existing, err := os.ReadFile(hookPath); err == nil && !bytes.Contains(existing, []byte(HookMarker)) {
+		if !force {
+			return "", fmt.Errorf("%s already exists; use --force to replace it (a backup is kept)", hookPath)
+		}
+		if _, err := BackupHook(hookPath, existing); err != nil {
+			return "", err
+		}
+	}
+
+	hook := fmt.Sprintf("#!/bin/sh\n%s\nexec %q guard \"$@\"\n", HookMarker, executable)
+	if err := os.WriteFile(hookPath, []byte(hook), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+	return hookPath, nil
+}
+
+// BackupPath returns where BackupHook keeps content, the hook at hookPath
+// about to be replaced: <hook>.bak, or <hook>.bak.<timestamp> when <hook>.bak
+// already holds an earlier backup, so that no backup is ever overwritten.
+// saved is true when <hook>.bak already holds content.
+func BackupPath(hookPath string, content []byte) (path string, saved bool) {
+	path = hookPath + ".bak"
+	earlier, err := os.ReadFile(path)
+	switch {
+	case err != nil:
+		return path, false
+	case bytes.Equal(earlier, content):
+		return path, true
+	}
+	stamped := path + "." + time.Now().Format("20060102-150405")
+	path = stamped
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(path); err != nil {
+			return path, false
+		}
+		path = fmt.Sprintf("%s-%d", stamped, n)
+	}
+}
+
+// BackupHook keeps a copy of content, the hook at hookPath about to be
+// replaced, at BackupPath and returns that path
+func BackupHook(hookPath string, content []byte) (string, error) {
+	path, saved := BackupPath(hookPath, content)
+	if saved {
+		return path, nil
+	}
+	if err := os.WriteFile(path, content, 0755); err != nil {
+		return "", fmt.Errorf("failed to back up %s: %w", filepath.Base(hookPath), err)
+	}
+	return path, nil
+}
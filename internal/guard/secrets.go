@@ -0,0 +1,80 @@
+package guard
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Finding is a likely secret found in an added line of an outgoing diff
+type Finding struct {
+	Rule string // Name of the rule that matched
+	File string
+	Line string // The offending line, truncated and with the secret masked
+}
+
+// secretRule is a named pattern for a kind of credential
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// secretRules lists the credential patterns checked before a push
+var secretRules = []secretRule{
+	{"private key", regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"GitLab token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"Stripe key", regexp.MustCompile(`\b(sk|rk)_live_[A-Za-z0-9]{20,}\b`)},
+	{"generic secret assignment", regexp.MustCompile(`(?i)\b(api[_-]?key|secret|passw(or)?d|access[_-]?token|auth[_-]?token)\b\s*[:=]\s*["'][^"'\s]{12,}["']`)},
+}
+
+// maxLineLength is the longest excerpt of an offending line kept in a Finding
+const maxLineLength = 120
+
+// DetectSecrets scans a unified diff and reports added lines that look like
+// they contain credentials
+func DetectSecrets(diff string) []Finding {
+	var findings []Finding
+	file := ""
+
+	sc := bufio.NewScanner(strings.NewReader(diff))
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			continue
+		case !strings.HasPrefix(line, "+"):
+			continue
+		}
+
+		added := line[1:]
+		for _, rule := range secretRules {
+			if loc := rule.pattern.FindStringIndex(added); loc != nil {
+				findings = append(findings, Finding{
+					Rule: rule.name,
+					File: file,
+					Line: maskSecret(added, loc),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// maskSecret replaces the matched part of a line so the secret isn't echoed
+// back to the terminal, and truncates long lines
+func maskSecret(line string, loc []int) string {
+	matched := line[loc[0]:loc[1]]
+	keep := min(4, len(matched))
+	masked := line[:loc[0]] + matched[:keep] + strings.Repeat("*", len(matched)-keep) + line[loc[1]:]
+	masked = strings.TrimSpace(masked)
+	if len(masked) > maxLineLength {
+		masked = masked[:maxLineLength] + "..."
+	}
+	return masked
+}
@@ -0,0 +1,117 @@
+// Package hidden remembers the directories the user hid from listings, per
+// workspace, without excluding them from scans the way ignore_dirs does.
+package hidden
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+)
+
+// Set is the hidden directories of a workspace, by path
+type Set map[string]bool
+
+// Load returns the hidden directories of the workspace at wsPath, empty if
+// there are none or the state can't be read
+func Load(wsPath string) Set {
+	set := make(Set)
+	for _, path := range loadState()[wsPath] {
+		set[path] = true
+	}
+	return set
+}
+
+// Hide adds paths to the hidden directories of the workspace at wsPath
+func Hide(wsPath string, paths ...string) error {
+	st := loadState()
+	for _, path := range paths {
+		if !slices.Contains(st[wsPath], path) {
+			st[wsPath] = append(st[wsPath], path)
+		}
+	}
+	slices.Sort(st[wsPath])
+	return saveState(st)
+}
+
+// Unhide removes paths from the hidden directories of the workspace at
+// wsPath; without paths, it unhides them all
+func Unhide(wsPath string, paths ...string) error {
+	st := loadState()
+	if len(paths) == 0 {
+		delete(st, wsPath)
+	} else {
+		st[wsPath] = slices.DeleteFunc(st[wsPath], func(path string) bool { return slices.Contains(paths, path) })
+		if len(st[wsPath]) == 0 {
+			delete(st, wsPath)
+		}
+	}
+	return saveState(st)
+}
+
+// Split separates infos into the visible and the hidden directories,
+// keeping their order
+func (s Set) Split(infos []scanner.DirectoryInfo) (visible, hidden []scanner.DirectoryInfo) {
+	for _, info := range infos {
+		if s[info.Path] {
+			hidden = append(hidden, info)
+		} else {
+			visible = append(visible, info)
+		}
+	}
+	return visible, hidden
+}
+
+// state maps workspace paths to their hidden directories
+type state map[string][]string
+
+// statePath returns the file holding the hidden directories
+func statePath() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hidden.json"), nil
+}
+
+// loadState reads the state, returning an empty state if there is none
+func loadState() state {
+	s := make(state)
+	path, err := statePath()
+	if err != nil {
+		return s
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if data, err = seal.Open(data); err == nil {
+			_ = json.Unmarshal(data, &s)
+		}
+	}
+	return s
+}
+
+// saveState writes the state
+func saveState(s state) error {
+	path, err := statePath()
+	if err != nil {
+		return fmt.Errorf("failed to locate hidden directories file: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hidden directories: %w", err)
+	}
+	if data, err = seal.Seal(data); err != nil {
+		return fmt.Errorf("failed to encrypt hidden directories: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hidden directories file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Package hooks installs a standard set of git hooks into repositories.
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/guard"
+)
+
+// knownHooks are the client-side hook names git will run
+var knownHooks = map[string]bool{
+	"applypatch-msg":        true,
+	"pre-applypatch":        true,
+	"post-applypatch":       true,
+	"pre-commit":            true,
+	"pre-merge-commit":      true,
+	"prepare-commit-msg":    true,
+	"commit-msg":            true,
+	"post-commit":           true,
+	"pre-rebase":            true,
+	"post-checkout":         true,
+	"post-merge":            true,
+	"pre-push":              true,
+	"post-rewrite":          true,
+	"pre-auto-gc":           true,
+	"reference-transaction": true,
+	"push-to-checkout":      true,
+}
+
+// IsKnown reports whether name is a client-side hook git runs
+func IsKnown(name string) bool {
+	return knownHooks[name]
+}
+
+// Hook is a single hook script from a template directory
+type Hook struct {
+	Name    string
+	Content []byte
+}
+
+// Status describes what Apply did (or would do) with one hook
+type Status string
+
+const (
+	StatusInstalled Status = "installed" // hook was missing
+	StatusUpdated   Status = "updated"   // hook differed from the template; old one backed up
+	StatusUnchanged Status = "unchanged" // hook already matches the template
+)
+
+// HookResult is the outcome for one hook in one repository
+type HookResult struct {
+	Name   string
+	Status Status
+	Backup string // path of the backup of the previous hook, if any
+}
+
+// LoadTemplate reads the hook scripts in dir. Files must be named after a
+// git hook (e.g. pre-commit); anything else is rejected so typos don't go
+// unnoticed. Hidden files and *.sample files are ignored.
+func LoadTemplate(dir string) ([]Hook, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook template: %w", err)
+	}
+
+	var hooks []Hook
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".sample") {
+			continue
+		}
+		if !knownHooks[name] {
+			return nil, fmt.Errorf("%s is not a git hook name", filepath.Join(dir, name))
+		}
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read hook %s: %w", name, err)
+		}
+		hooks = append(hooks, Hook{Name: name, Content: content})
+	}
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("no hooks found in %s", dir)
+	}
+
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].Name < hooks[j].Name })
+	return hooks, nil
+}
+
+// Apply installs the template hooks into the repository at repoDir. Hooks
+// that differ from the template are backed up before being replaced, without
+// overwriting an earlier backup (see guard.BackupHook). With dryRun set
+// nothing is written, but the returned results still describe what would
+// change.
+func Apply(repoDir string, template []Hook, dryRun bool) ([]HookResult, error) {
+	hooksDir, err := guard.HooksDir(repoDir)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := os.MkdirAll(hooksDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create hooks directory: %w", err)
+		}
+	}
+
+	results := make([]HookResult, 0, len(template))
+	for _, hook := range template {
+		hookPath := filepath.Join(hooksDir, hook.Name)
+		result := HookResult{Name: hook.Name, Status: StatusInstalled}
+
+		existing, err := os.ReadFile(hookPath)
+		switch {
+		case err == nil && bytes.Equal(existing, hook.Content):
+			result.Status = StatusUnchanged
+		case err == nil:
+			result.Status = StatusUpdated
+			result.Backup, _ = guard.BackupPath(hookPath, existing)
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("failed to read hook %s: %w", hook.Name, err)
+		}
+
+		if !dryRun && result.Status != StatusUnchanged {
+			if result.Backup != "" {
+				if result.Backup, err = guard.BackupHook(hookPath, existing); err != nil {
+					return nil, err
+				}
+			}
+			if err := os.WriteFile(hookPath, hook.Content, 0755); err != nil {
+				return nil, fmt.Errorf("failed to write hook %s: %w", hook.Name, err)
+			}
+			// WriteFile keeps the mode of an existing file; hooks must be executable
+			if err := os.Chmod(hookPath, 0755); err != nil {
+				return nil, fmt.Errorf("failed to make hook %s executable: %w", hook.Name, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
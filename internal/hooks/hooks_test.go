@@ -0,0 +1,41 @@
+package hooks_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/ThandieOps/thandie-agent/internal/hooks"
+)
+
+func TestApplyKeepsOriginalBackup(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	hookPath := filepath.Join(repo, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hookPath, []byte("#!/bin/sh\n# mine\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"#!/bin/sh\n# v1\n", "#!/bin/sh\n# v2\n"} {
+		if _, err := hooks.Apply(repo, []hooks.Hook{{Name: "pre-commit", Content: []byte(content)}}, false); err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+	}
+
+	if data, _ := os.ReadFile(hookPath + ".bak"); string(data) != "#!/bin/sh\n# mine\n" {
+		t.Errorf("pre-commit.bak = %q, want the original hook", data)
+	}
+	stamped, _ := filepath.Glob(hookPath + ".bak.*")
+	if len(stamped) != 1 {
+		t.Fatalf("timestamped backups = %v, want one", stamped)
+	}
+	if data, _ := os.ReadFile(stamped[0]); string(data) != "#!/bin/sh\n# v1\n" {
+		t.Errorf("%s = %q, want the first template", filepath.Base(stamped[0]), data)
+	}
+}
@@ -0,0 +1,159 @@
+// Package jsonfields projects and flattens JSON documents, covering the
+// simple jq uses (picking a few fields, flat key/value rows) without jq.
+package jsonfields
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// aliases are shorter names for commonly used keys, keyed by the resolved
+// parent path ("" for the top level)
+var aliases = map[string]map[string]string{
+	"": {
+		"git": "git_metadata",
+	},
+	"git_metadata": {
+		"branch": "current_branch",
+		"dirty":  "has_uncommitted",
+		"remote": "remote_url",
+		"status": "status_summary",
+	},
+}
+
+// Options controls a projection
+type Options struct {
+	Fields  []string // Dotted paths to keep, e.g. path, git.branch; empty keeps everything
+	Flatten bool     // Emit flat objects with dotted keys instead of nested ones
+}
+
+// Enabled reports whether the options change the output at all
+func (o Options) Enabled() bool {
+	return len(o.Fields) > 0 || o.Flatten
+}
+
+// Apply marshals v and applies the projection to it, or to each element if
+// it is an array
+func Apply(v any, opts Options) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !opts.Enabled() {
+		return doc, nil
+	}
+
+	if items, ok := doc.([]any); ok {
+		out := make([]any, len(items))
+		for i, item := range items {
+			if out[i], err = project(item, opts); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return project(doc, opts)
+}
+
+// project applies the options to one object
+func project(doc any, opts Options) (any, error) {
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return doc, nil
+	}
+
+	if len(opts.Fields) == 0 {
+		flat := make(map[string]any)
+		flatten("", obj, flat)
+		return flat, nil
+	}
+
+	out := make(map[string]any)
+	for _, path := range opts.Fields {
+		value, err := lookup(obj, path)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Flatten {
+			if nested, ok := value.(map[string]any); ok {
+				flatten(path, nested, out)
+			} else {
+				out[path] = value
+			}
+			continue
+		}
+		set(out, strings.Split(path, "."), value)
+	}
+	return out, nil
+}
+
+// lookup resolves a dotted path, following aliases. Missing keys yield nil
+// (directories without git metadata simply have no git fields); a path that
+// descends into a non-object is an error.
+func lookup(obj map[string]any, path string) (any, error) {
+	var current any = obj
+	resolved := ""
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			return nil, fmt.Errorf("invalid field %q", path)
+		}
+		m, ok := current.(map[string]any)
+		if !ok {
+			if current == nil {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("field %q: %s is not an object", path, resolved)
+		}
+		if alias, ok := aliases[resolved][segment]; ok {
+			if _, exists := m[segment]; !exists {
+				segment = alias
+			}
+		}
+		current = m[segment]
+		if resolved != "" {
+			resolved += "."
+		}
+		resolved += segment
+	}
+	return current, nil
+}
+
+// set stores value at the nested path in out, creating objects as needed
+func set(out map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := out[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			out[key] = child
+		}
+		out = child
+	}
+	out[path[len(path)-1]] = value
+}
+
+// flatten copies the leaves of obj into out under dotted keys. Arrays are
+// kept as values.
+func flatten(prefix string, obj map[string]any, out map[string]any) {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		if nested, ok := obj[key].(map[string]any); ok {
+			flatten(name, nested, out)
+			continue
+		}
+		out[name] = obj[key]
+	}
+}
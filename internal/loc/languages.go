@@ -0,0 +1,105 @@
+package loc
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// language describes how to recognise comments in a language
+type language struct {
+	name       string
+	line       []string // Line comment prefixes
+	blockStart string
+	blockEnd   string
+}
+
+var (
+	cStyle    = language{line: []string{"//"}, blockStart: "/*", blockEnd: "*/"}
+	hashStyle = language{line: []string{"#"}}
+)
+
+func lang(name string, style language) language {
+	style.name = name
+	return style
+}
+
+// extensions maps lower-cased file extensions to languages
+var extensions = map[string]language{
+	".go":     lang("Go", cStyle),
+	".c":      lang("C", cStyle),
+	".h":      lang("C", cStyle),
+	".cc":     lang("C++", cStyle),
+	".cpp":    lang("C++", cStyle),
+	".cxx":    lang("C++", cStyle),
+	".hpp":    lang("C++", cStyle),
+	".cs":     lang("C#", cStyle),
+	".java":   lang("Java", cStyle),
+	".kt":     lang("Kotlin", cStyle),
+	".kts":    lang("Kotlin", cStyle),
+	".scala":  lang("Scala", cStyle),
+	".swift":  lang("Swift", cStyle),
+	".rs":     lang("Rust", cStyle),
+	".js":     lang("JavaScript", cStyle),
+	".jsx":    lang("JavaScript", cStyle),
+	".mjs":    lang("JavaScript", cStyle),
+	".cjs":    lang("JavaScript", cStyle),
+	".ts":     lang("TypeScript", cStyle),
+	".tsx":    lang("TypeScript", cStyle),
+	".php":    lang("PHP", language{line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/"}),
+	".css":    lang("CSS", language{blockStart: "/*", blockEnd: "*/"}),
+	".scss":   lang("SCSS", cStyle),
+	".less":   lang("Less", cStyle),
+	".proto":  lang("Protobuf", cStyle),
+	".py":     lang("Python", hashStyle),
+	".rb":     lang("Ruby", hashStyle),
+	".pl":     lang("Perl", hashStyle),
+	".sh":     lang("Shell", hashStyle),
+	".bash":   lang("Shell", hashStyle),
+	".zsh":    lang("Shell", hashStyle),
+	".ps1":    lang("PowerShell", language{line: []string{"#"}, blockStart: "<#", blockEnd: "#>"}),
+	".r":      lang("R", hashStyle),
+	".yml":    lang("YAML", hashStyle),
+	".yaml":   lang("YAML", hashStyle),
+	".toml":   lang("TOML", hashStyle),
+	".tf":     lang("HCL", language{line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"}),
+	".hcl":    lang("HCL", language{line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/"}),
+	".sql":    lang("SQL", language{line: []string{"--"}, blockStart: "/*", blockEnd: "*/"}),
+	".lua":    lang("Lua", language{line: []string{"--"}, blockStart: "--[[", blockEnd: "]]"}),
+	".hs":     lang("Haskell", language{line: []string{"--"}, blockStart: "{-", blockEnd: "-}"}),
+	".ex":     lang("Elixir", hashStyle),
+	".exs":    lang("Elixir", hashStyle),
+	".erl":    lang("Erlang", language{line: []string{"%"}}),
+	".clj":    lang("Clojure", language{line: []string{";"}}),
+	".vim":    lang("Vim script", language{line: []string{`"`}}),
+	".html":   lang("HTML", language{blockStart: "<!--", blockEnd: "-->"}),
+	".htm":    lang("HTML", language{blockStart: "<!--", blockEnd: "-->"}),
+	".xml":    lang("XML", language{blockStart: "<!--", blockEnd: "-->"}),
+	".vue":    lang("Vue", language{line: []string{"//"}, blockStart: "<!--", blockEnd: "-->"}),
+	".svelte": lang("Svelte", language{line: []string{"//"}, blockStart: "<!--", blockEnd: "-->"}),
+	".md":     lang("Markdown", language{}),
+	".rst":    lang("reStructuredText", language{}),
+	".json":   lang("JSON", language{}),
+}
+
+// filenames maps well-known file names to languages
+var filenames = map[string]language{
+	"makefile":      lang("Makefile", hashStyle),
+	"gnumakefile":   lang("Makefile", hashStyle),
+	"dockerfile":    lang("Dockerfile", hashStyle),
+	"containerfile": lang("Dockerfile", hashStyle),
+	"rakefile":      lang("Ruby", hashStyle),
+	"gemfile":       lang("Ruby", hashStyle),
+}
+
+// detect returns the language of a file, or false if it isn't counted
+func detect(path string) (language, bool) {
+	base := strings.ToLower(filepath.Base(path))
+	if l, ok := filenames[base]; ok {
+		return l, true
+	}
+	if strings.HasPrefix(base, "dockerfile.") {
+		return filenames["dockerfile"], true
+	}
+	l, ok := extensions[filepath.Ext(base)]
+	return l, ok
+}
@@ -0,0 +1,161 @@
+// Package loc counts lines of code per language, in the spirit of tokei.
+package loc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// log logs under the scanner component, as counting is part of a scan
+var log = logger.For("scanner")
+
+// Bounds keep counting cheap on very large repositories
+const (
+	maxFiles    = 20000   // Files counted per repository
+	maxFileSize = 1 << 20 // Larger files are usually generated or data
+)
+
+// Collect sets the code stats of every git repository in infos. Stats from
+// previous are reused when the repository's HEAD hasn't moved. At most
+// concurrency repositories are counted at once.
+func Collect(infos, previous []scanner.DirectoryInfo, concurrency int) {
+	prior := make(map[string]*scanner.CodeStats, len(previous))
+	for _, info := range previous {
+		if info.Extras != nil && info.Extras.Code != nil {
+			prior[info.Path] = info.Extras.Code
+		}
+	}
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range infos {
+		info := &infos[i]
+		git := info.GitMetadata
+		if git == nil || !git.IsGitRepo || git.Head == "" {
+			continue
+		}
+		if info.Extras == nil {
+			info.Extras = &scanner.Extras{}
+		}
+
+		if old := prior[info.Path]; old != nil && old.Commit == git.Head {
+			info.Extras.Code = old
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			stats, err := Count(info.Path)
+			if err != nil {
+				log.Warn("failed to count lines of code", "path", info.Path, "error", err)
+				return
+			}
+			stats.Commit = git.Head
+			info.Extras.Code = stats
+		}()
+	}
+	wg.Wait()
+
+	// Drop Extras that were only created for a count that failed
+	for i := range infos {
+		if e := infos[i].Extras; e != nil && *e == (scanner.Extras{}) {
+			infos[i].Extras = nil
+		}
+	}
+}
+
+// Count counts the lines of the files tracked by git in the repository at dir
+func Count(dir string) (*scanner.CodeStats, error) {
+	cmd := exec.Command("git", "ls-files", "-z")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	stats := &scanner.CodeStats{Languages: make(map[string]scanner.LangStats)}
+	files := 0
+	for _, name := range strings.Split(string(out), "\x00") {
+		l, ok := detect(name)
+		if !ok {
+			continue
+		}
+		if files == maxFiles {
+			stats.Truncated = true
+			break
+		}
+		files++
+
+		counts, ok := countFile(filepath.Join(dir, name), l)
+		if !ok {
+			continue
+		}
+		total := stats.Languages[l.name]
+		total.Files++
+		total.Code += counts.Code
+		total.Comments += counts.Comments
+		total.Blanks += counts.Blanks
+		stats.Languages[l.name] = total
+	}
+	return stats, nil
+}
+
+// countFile counts the lines of one file. It returns false for files that
+// are missing, too large or binary.
+func countFile(path string, l language) (scanner.LangStats, bool) {
+	var counts scanner.LangStats
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+		return counts, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return counts, false
+	}
+
+	inBlock := false
+	lines := bufio.NewScanner(bytes.NewReader(data))
+	lines.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		switch {
+		case line == "":
+			counts.Blanks++
+		case inBlock:
+			counts.Comments++
+			if strings.Contains(line, l.blockEnd) {
+				inBlock = false
+			}
+		case l.blockStart != "" && strings.HasPrefix(line, l.blockStart):
+			counts.Comments++
+			inBlock = !strings.Contains(line[len(l.blockStart):], l.blockEnd)
+		case hasAnyPrefix(line, l.line):
+			counts.Comments++
+		default:
+			counts.Code++
+		}
+	}
+	return counts, true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
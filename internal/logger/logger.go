@@ -1,38 +1,65 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var (
-	// Logger is the global logger instance
-	Logger  *slog.Logger
-	logFile *os.File
-)
+// current is the logger set up by the last Init. It is replaced as a whole so
+// that Init can run while other goroutines log.
+var current atomic.Pointer[state]
+
+// state is a configured logger
+type state struct {
+	logger     *slog.Logger          // Handles every level any component logs at
+	level      slog.Level            // Level of messages without a component override
+	components map[string]slog.Level // Per-component overrides
+	file       *os.File              // Log file, if logging to one
+}
 
-// Init initializes the logger with the specified level, format, and file output
-func Init(level string, jsonOutput bool, logToFile bool) error {
-	var logLevel slog.Level
+// enabled reports whether a message of component at level is logged
+func (s *state) enabled(component string, level slog.Level) bool {
+	min := s.level
+	if l, ok := s.components[component]; ok {
+		min = l
+	}
+	return level >= min
+}
+
+// parseLevel returns the named level, info for unknown names
+func parseLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
 	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
+		return slog.LevelDebug
 	case "warn":
-		logLevel = slog.LevelWarn
+		return slog.LevelWarn
 	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Init initializes the logger with the specified level, format, file output
+// and per-component levels (e.g. scanner: debug), replacing the previous
+// configuration. Safe to call while other goroutines log.
+func Init(level string, jsonOutput bool, logToFile bool, components map[string]string) error {
+	s := &state{level: parseLevel(level), components: make(map[string]slog.Level)}
+	lowest := s.level
+	for name, l := range components {
+		s.components[strings.ToLower(name)] = parseLevel(l)
+		lowest = min(lowest, parseLevel(l))
 	}
 
 	opts := &slog.HandlerOptions{
-		Level: logLevel,
+		Level: lowest,
 	}
 
 	var writer io.Writer = os.Stderr // Default to stderr
@@ -51,13 +78,13 @@ func Init(level string, jsonOutput bool, logToFile bool) error {
 		}
 
 		// Open log file in append mode
-		logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		s.file, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
 			return fmt.Errorf("failed to open log file %s: %w", logPath, err)
 		}
 
 		// Write to both file and stderr
-		writer = io.MultiWriter(os.Stderr, logFile)
+		writer = io.MultiWriter(os.Stderr, s.file)
 	}
 
 	var handler slog.Handler
@@ -66,8 +93,13 @@ func Init(level string, jsonOutput bool, logToFile bool) error {
 	} else {
 		handler = slog.NewTextHandler(writer, opts)
 	}
+	s.logger = slog.New(handler)
 
-	Logger = slog.New(handler)
+	// A goroutine still logging with the previous state gets a write error
+	// from its closed file, which slog ignores
+	if prev := current.Swap(s); prev != nil && prev.file != nil {
+		prev.file.Close()
+	}
 	return nil
 }
 
@@ -96,44 +128,155 @@ func GetLogFilePath() (string, error) {
 
 // Sync flushes the log file to disk if it was opened
 func Sync() error {
-	if logFile != nil {
-		return logFile.Sync()
+	if s := current.Load(); s != nil && s.file != nil {
+		return s.file.Sync()
 	}
 	return nil
 }
 
 // Close closes the log file if it was opened
 func Close() error {
-	if logFile != nil {
-		return logFile.Close()
+	if s := current.Load(); s != nil && s.file != nil {
+		return s.file.Close()
 	}
 	return nil
 }
 
+// log logs a message of component ("" for none) if its level is enabled
+func log(component string, level slog.Level, msg string, args []any) {
+	s := current.Load()
+	if s == nil || !s.enabled(component, level) {
+		return
+	}
+	recent.add(Entry{Time: time.Now(), Level: level, Component: component, Message: msg, Attrs: formatAttrs(args)})
+	if component != "" {
+		args = append([]any{"component", component}, args...)
+	}
+	s.logger.Log(context.Background(), level, msg, args...)
+}
+
 // Debug logs a debug message
 func Debug(msg string, args ...any) {
-	if Logger != nil {
-		Logger.Debug(msg, args...)
-	}
+	log("", slog.LevelDebug, msg, args)
 }
 
 // Info logs an info message
 func Info(msg string, args ...any) {
-	if Logger != nil {
-		Logger.Info(msg, args...)
-	}
+	log("", slog.LevelInfo, msg, args)
 }
 
 // Warn logs a warning message
 func Warn(msg string, args ...any) {
-	if Logger != nil {
-		Logger.Warn(msg, args...)
-	}
+	log("", slog.LevelWarn, msg, args)
 }
 
 // Error logs an error message
 func Error(msg string, args ...any) {
-	if Logger != nil {
-		Logger.Error(msg, args...)
+	log("", slog.LevelError, msg, args)
+}
+
+// Component logs the messages of one part of the program, at the level
+// logging.components sets for it or else the global level
+type Component struct {
+	name string
+}
+
+// For returns the logger of the named component, e.g. scanner
+func For(name string) *Component {
+	return &Component{name: name}
+}
+
+// Debug logs a debug message
+func (c *Component) Debug(msg string, args ...any) {
+	log(c.name, slog.LevelDebug, msg, args)
+}
+
+// Info logs an info message
+func (c *Component) Info(msg string, args ...any) {
+	log(c.name, slog.LevelInfo, msg, args)
+}
+
+// Warn logs a warning message
+func (c *Component) Warn(msg string, args ...any) {
+	log(c.name, slog.LevelWarn, msg, args)
+}
+
+// Error logs an error message
+func (c *Component) Error(msg string, args ...any) {
+	log(c.name, slog.LevelError, msg, args)
+}
+
+// recentSize is the number of messages Recent keeps
+const recentSize = 500
+
+// Entry is a logged message kept for Recent
+type Entry struct {
+	Time      time.Time  `json:"time"`
+	Level     slog.Level `json:"level"`
+	Component string     `json:"component,omitempty"`
+	Message   string     `json:"message"`
+	Attrs     string     `json:"attrs,omitempty"` // key=value pairs, space-separated
+}
+
+// ring keeps the last recentSize logged messages
+type ring struct {
+	mu      sync.Mutex
+	entries [recentSize]Entry
+	next    int // Index the next entry goes to
+	full    bool
+}
+
+// recent holds the messages logged by this process
+var recent ring
+
+// add appends e, dropping the oldest message when full
+func (r *ring) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % recentSize
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns up to the last n messages this process logged, oldest
+// first, e.g. for `thandie daemon logs`
+func Recent(n int) []Entry {
+	recent.mu.Lock()
+	defer recent.mu.Unlock()
+	var entries []Entry
+	if recent.full {
+		entries = append(entries, recent.entries[recent.next:]...)
+	}
+	entries = append(entries, recent.entries[:recent.next]...)
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries
+}
+
+// formatAttrs formats slog key-value arguments as key=value pairs
+func formatAttrs(args []any) string {
+	var b strings.Builder
+	for len(args) > 0 {
+		var attr slog.Attr
+		switch a := args[0].(type) {
+		case slog.Attr:
+			attr, args = a, args[1:]
+		case string:
+			if len(args) == 1 {
+				attr, args = slog.Any("!BADKEY", a), nil
+			} else {
+				attr, args = slog.Any(a, args[1]), args[2:]
+			}
+		default:
+			attr, args = slog.Any("!BADKEY", a), args[1:]
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%v", attr.Key, attr.Value)
 	}
+	return b.String()
 }
@@ -0,0 +1,175 @@
+// Package lsp is a JSON-RPC 2.0 server over stdio using the framing of the
+// Language Server Protocol (Content-Length headers), so that editor
+// extensions can use their existing LSP client libraries to talk to thandie.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error; handlers may return one to choose the code
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// message is a JSON-RPC request, response or notification
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Handler answers a request. Params are the raw JSON params, if any.
+type Handler func(params json.RawMessage) (any, error)
+
+// Conn is a JSON-RPC connection over a reader and writer
+type Conn struct {
+	reader   *bufio.Reader
+	writeMu  sync.Mutex
+	writer   io.Writer
+	handlers map[string]Handler
+}
+
+// NewConn returns a connection reading requests from r and writing
+// responses and notifications to w
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{reader: bufio.NewReader(r), writer: w, handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler of a method. Handlers of notifications
+// (requests without an id) are run too, but their results are dropped.
+func (c *Conn) Handle(method string, h Handler) {
+	c.handlers[method] = h
+}
+
+// Notify sends a notification to the client
+func (c *Conn) Notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return c.write(message{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// Serve reads and answers requests until the input ends or a handler
+// returns ErrExit. Requests are answered in order.
+func (c *Conn) Serve() error {
+	for {
+		body, err := c.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req message
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := c.write(message{JSONRPC: "2.0", ID: nullID(), Error: &Error{Code: CodeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "" {
+			continue // A response to something we never send
+		}
+
+		result, err := c.call(req)
+		if errors.Is(err, ErrExit) {
+			return nil
+		}
+		if req.ID == nil {
+			continue
+		}
+		resp := message{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			var rpcErr *Error
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+			}
+			resp.Result, resp.Error = nil, rpcErr
+		} else if result == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		if err := c.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// ErrExit is returned by a handler to stop Serve, e.g. for the exit notification
+var ErrExit = errors.New("exit")
+
+// call runs the handler of a request
+func (c *Conn) call(req message) (any, error) {
+	h, ok := c.handlers[req.Method]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	return h(req.Params)
+}
+
+// read reads one message body, framed by a Content-Length header
+func (c *Conn) read() ([]byte, error) {
+	header, err := textproto.NewReader(c.reader).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// write sends one message with a Content-Length header
+func (c *Conn) write(msg message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// nullID is the id of responses to requests whose id couldn't be read
+func nullID() *json.RawMessage {
+	id := json.RawMessage("null")
+	return &id
+}
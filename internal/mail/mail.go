@@ -0,0 +1,148 @@
+// Package mail sends HTML email over SMTP.
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+)
+
+// ErrNotConfigured is returned by Send when SMTP settings are incomplete
+var ErrNotConfigured = errors.New("email is not configured (set smtp.host, smtp.from and smtp.to)")
+
+// Message is an email with HTML and plain-text bodies
+type Message struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Send delivers msg to the recipients in cfg. Port 465 uses implicit TLS;
+// other ports upgrade with STARTTLS when the server offers it.
+func Send(cfg config.SMTPConfig, msg Message) error {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return ErrNotConfigured
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+
+	password, err := secrets.Resolve(cfg.Password)
+	if err != nil {
+		return fmt.Errorf("failed to resolve smtp.password: %w", err)
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, password, cfg.Host)
+	}
+
+	body, err := build(cfg.From, cfg.To, msg)
+	if err != nil {
+		return err
+	}
+
+	if port != 465 {
+		if err := smtp.SendMail(addr, auth, cfg.From, cfg.To, body); err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: cfg.Host})
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	client, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(cfg.From); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	for _, to := range cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// build renders msg as a multipart/alternative MIME message
+func build(from string, to []string, msg Message) ([]byte, error) {
+	var token [12]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return nil, err
+	}
+	boundary := "thandie-" + hex.EncodeToString(token[:])
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&b)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+var mimeWordEncoder = mime.QEncoding
+
+// mimeHeader encodes a header value that contains non-ASCII characters
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mimeWordEncoder.Encode("utf-8", s)
+		}
+	}
+	return s
+}
@@ -0,0 +1,155 @@
+// Package mounts tells which filesystem a path is on, to recognize network
+// mounts (NFS, SMB, FUSE) where reading a repository is slow or may hang.
+package mounts
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Mount is a mounted filesystem
+type Mount struct {
+	Point  string // Where it is mounted
+	FSType string // e.g. ext4, nfs4, smbfs
+}
+
+// Table lists the mounted filesystems
+type Table []Mount
+
+// networkTypes holds the filesystem types served over the network, as named
+// on Linux, macOS and the BSDs. FUSE filesystems, which are usually remote
+// (sshfs, rclone, s3fs), are matched by prefix in IsNetwork.
+var networkTypes = map[string]bool{
+	"nfs": true, "nfs4": true, "cifs": true, "smb3": true, "smbfs": true,
+	"afpfs": true, "webdav": true, "davfs": true, "9p": true, "afs": true,
+	"ceph": true, "lustre": true, "sshfs": true, "osxfuse": true, "macfuse": true,
+}
+
+// IsNetwork reports whether fsType is a network or FUSE filesystem. fuseblk,
+// FUSE over a local block device (e.g. NTFS), is local.
+func IsNetwork(fsType string) bool {
+	if fsType == "fuseblk" {
+		return false
+	}
+	return networkTypes[fsType] || fsType == "fuse" || strings.HasPrefix(fsType, "fuse.")
+}
+
+// Load reads the mount table: /proc/self/mounts on Linux and the output of
+// mount(8) on macOS and the BSDs. Elsewhere, e.g. on Windows, the table is
+// empty and every path reads as local.
+func Load() (Table, error) {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/self/mounts")
+		if err != nil {
+			return nil, err
+		}
+		return parseProcMounts(data), nil
+	case "darwin", "freebsd", "openbsd", "netbsd", "dragonfly":
+		out, err := exec.Command("mount").Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseMountOutput(out), nil
+	}
+	return nil, nil
+}
+
+// Find returns the mount path is on: the one with the longest mount point
+// containing it, after resolving symlinks
+func (t Table) Find(path string) (Mount, bool) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	var best Mount
+	found := false
+	for _, m := range t {
+		if !contains(m.Point, path) || (found && len(m.Point) <= len(best.Point)) {
+			continue
+		}
+		best, found = m, true
+	}
+	return best, found
+}
+
+// Network returns the type of the network filesystem path is on, or "" if
+// it is on a local one
+func (t Table) Network(path string) string {
+	m, ok := t.Find(path)
+	if !ok || !IsNetwork(m.FSType) {
+		return ""
+	}
+	return m.FSType
+}
+
+// contains reports whether path is point or below it
+func contains(point, path string) bool {
+	if point == "/" || point == path {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(point, "/")+"/")
+}
+
+// parseProcMounts parses /proc/self/mounts: device, mount point and type
+// separated by spaces, with spaces in the mount point escaped as \040
+func parseProcMounts(data []byte) Table {
+	var table Table
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		table = append(table, Mount{Point: unescapeOctal(fields[1]), FSType: fields[2]})
+	}
+	return table
+}
+
+// unescapeOctal decodes the \ooo escapes of /proc/self/mounts
+func unescapeOctal(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// mountLine matches a line of mount(8) output, in the BSD form
+// "dev on /point (type, options)" and the "dev on /point type type (options)"
+// form of OpenBSD
+var mountLine = regexp.MustCompile(`^.+? on (.+?) (?:type (\S+) \(|\(([^,)]+))`)
+
+// parseMountOutput parses the output of mount(8)
+func parseMountOutput(out []byte) Table {
+	var table Table
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := mountLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		fsType := m[2]
+		if fsType == "" {
+			fsType = m[3]
+		}
+		table = append(table, Mount{Point: m[1], FSType: fsType})
+	}
+	return table
+}
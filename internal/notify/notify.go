@@ -0,0 +1,114 @@
+// Package notify posts messages to Slack and Microsoft Teams incoming webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+)
+
+// ErrNotConfigured is returned by New when no webhook is configured
+var ErrNotConfigured = errors.New("no notification webhook configured (set notify.slack_webhook or notify.teams_webhook)")
+
+// Message is a notification: a bold title followed by bullet lines
+type Message struct {
+	Title string
+	Lines []string
+}
+
+// Notifier posts messages to the configured webhooks
+type Notifier struct {
+	slack  string
+	teams  string
+	client *http.Client
+}
+
+// New creates a notifier for the webhooks in cfg, resolving keychain references
+func New(cfg config.NotifyConfig) (*Notifier, error) {
+	n := &Notifier{client: &http.Client{Timeout: 15 * time.Second}}
+
+	var err error
+	if n.slack, err = secrets.Resolve(cfg.SlackWebhook); err != nil {
+		return nil, fmt.Errorf("failed to resolve notify.slack_webhook: %w", err)
+	}
+	if n.teams, err = secrets.Resolve(cfg.TeamsWebhook); err != nil {
+		return nil, fmt.Errorf("failed to resolve notify.teams_webhook: %w", err)
+	}
+	if n.slack == "" && n.teams == "" {
+		return nil, ErrNotConfigured
+	}
+	return n, nil
+}
+
+// Send posts msg to every configured webhook. All webhooks are tried even if
+// one fails; the errors are joined.
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	var errs []error
+	if n.slack != "" {
+		if err := n.post(ctx, n.slack, map[string]string{"text": SlackText(msg)}); err != nil {
+			errs = append(errs, fmt.Errorf("slack: %w", err))
+		}
+	}
+	if n.teams != "" {
+		if err := n.post(ctx, n.teams, map[string]string{"text": TeamsText(msg)}); err != nil {
+			errs = append(errs, fmt.Errorf("teams: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SlackText formats a message with Slack mrkdwn
+func SlackText(msg Message) string {
+	var b strings.Builder
+	if msg.Title != "" {
+		fmt.Fprintf(&b, "*%s*\n", msg.Title)
+	}
+	for _, line := range msg.Lines {
+		fmt.Fprintf(&b, "• %s\n", line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// TeamsText formats a message with the Markdown subset Teams renders
+func TeamsText(msg Message) string {
+	var b strings.Builder
+	if msg.Title != "" {
+		fmt.Fprintf(&b, "**%s**\n\n", msg.Title)
+	}
+	for _, line := range msg.Lines {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (n *Notifier) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
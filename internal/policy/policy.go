@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+)
+
+// Actions that can be restricted per repository
+const (
+	ActionPush   = "push"   // git push, enforced by `thandie guard`
+	ActionHooks  = "hooks"  // thandie hooks apply
+	ActionGuard  = "guard"  // thandie guard install
+	ActionTest   = "test"   // thandie test
+	ActionBranch = "branch" // thandie branch create
+	ActionRemote = "remote" // thandie remotes rewrite
+)
+
+// Actions lists the valid action names
+var Actions = []string{ActionPush, ActionHooks, ActionGuard, ActionTest, ActionBranch, ActionRemote}
+
+// Policy decides which actions are allowed in which repositories
+type Policy struct {
+	workspace string
+	rules     []config.PolicyRule
+}
+
+// DeniedError is returned by Check when a rule refuses an action
+type DeniedError struct {
+	Action string
+	Repo   string // Remote (host/path) for remote rules, otherwise the repository path
+	Rule   int    // Index of the rule in the policy config
+	Match  string // The pattern of the rule that matched
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s is not allowed for %s by policy[%d] (%s)", e.Action, e.Repo, e.Rule, e.Match)
+}
+
+// New validates rules and returns a policy for repositories in workspace
+func New(rules []config.PolicyRule, workspace string) (*Policy, error) {
+	for i, rule := range rules {
+		if rule.Path == "" && rule.Remote == "" {
+			return nil, fmt.Errorf("policy[%d]: path or remote is required", i)
+		}
+		if _, err := path.Match(rule.Remote, ""); err != nil {
+			return nil, fmt.Errorf("policy[%d]: invalid remote pattern %q: %w", i, rule.Remote, err)
+		}
+		if _, err := filepath.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("policy[%d]: invalid path pattern %q: %w", i, rule.Path, err)
+		}
+		for _, action := range append(slices.Clone(rule.Allow), rule.Deny...) {
+			if !slices.Contains(Actions, action) {
+				return nil, fmt.Errorf("policy[%d]: unknown action %q (expected one of %s)", i, action, strings.Join(Actions, ", "))
+			}
+		}
+	}
+	return &Policy{workspace: workspace, rules: rules}, nil
+}
+
+// Check returns a *DeniedError if any rule matching the repository at
+// repoPath (with remote URL remoteURL, which may be empty) refuses action
+func (p *Policy) Check(action, repoPath, remoteURL string) error {
+	remote := ""
+	if remoteURL != "" {
+		remote = gitremote.Normalize(remoteURL)
+	}
+	for i, rule := range p.rules {
+		match, ok := p.matches(rule, repoPath, remote)
+		if !ok {
+			continue
+		}
+		if slices.Contains(rule.Deny, action) || (len(rule.Allow) > 0 && !slices.Contains(rule.Allow, action)) {
+			repo := repoPath
+			if rule.Remote != "" {
+				repo = remote
+			}
+			return &DeniedError{Action: action, Repo: repo, Rule: i, Match: match}
+		}
+	}
+	return nil
+}
+
+// matches reports whether rule applies to a repository, returning the
+// pattern that matched. When a rule has both patterns, both must match.
+func (p *Policy) matches(rule config.PolicyRule, repoPath, remote string) (string, bool) {
+	var matched []string
+	if rule.Remote != "" {
+		if remote == "" {
+			return "", false
+		}
+		if ok, _ := path.Match(strings.ToLower(rule.Remote), remote); !ok {
+			return "", false
+		}
+		matched = append(matched, "remote "+rule.Remote)
+	}
+	if rule.Path != "" {
+		target := repoPath
+		if !filepath.IsAbs(rule.Path) {
+			rel, err := filepath.Rel(p.workspace, repoPath)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				return "", false
+			}
+			target = rel
+		}
+		if ok, _ := filepath.Match(rule.Path, target); !ok {
+			return "", false
+		}
+		matched = append(matched, "path "+rule.Path)
+	}
+	return strings.Join(matched, ", "), true
+}
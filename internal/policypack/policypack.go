@@ -0,0 +1,293 @@
+// Package policypack fetches a team's signed policy pack (required hooks,
+// default branch, compliance files, signing and git config rules) and checks
+// repositories against it.
+package policypack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/attest"
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/gitconfig"
+	"github.com/ThandieOps/thandie-agent/internal/guard"
+	"github.com/ThandieOps/thandie-agent/internal/hooks"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/signing"
+	"gopkg.in/yaml.v3"
+)
+
+// fetchTimeout bounds downloading the pack and its signature
+const fetchTimeout = 30 * time.Second
+
+// Pack is a policy bundle, written in YAML
+type Pack struct {
+	Name       string               `yaml:"name"`
+	Version    string               `yaml:"version,omitempty"`
+	Hooks      []string             `yaml:"hooks,omitempty"`      // Git hooks every repository must have installed
+	Branch     string               `yaml:"branch,omitempty"`     // Expected default branch
+	Compliance []string             `yaml:"compliance,omitempty"` // Required files: license, readme, codeowners
+	Signing    config.SigningConfig `yaml:"signing,omitempty"`
+	GitConfig  []string             `yaml:"git_config,omitempty"` // Expected git config as key=value, as for groups
+}
+
+// Loaded is a verified pack and where it came from
+type Loaded struct {
+	Pack      *Pack
+	Signer    string    // Principal of allowed_signers that signed the pack
+	FetchedAt time.Time // When the pack was downloaded
+	Stale     error     // Why the cached pack was used although it is older than the TTL
+}
+
+// Load returns the pack configured in packCfg. A pack downloaded within the
+// TTL is reused unless refresh is set; when downloading fails, an older
+// cached pack is used and the failure recorded in Stale. The pack is verified
+// against the allowed signers every time it is loaded, cached or not.
+func Load(packCfg config.PolicyPackConfig, refresh bool) (*Loaded, error) {
+	if packCfg.URL == "" {
+		return nil, fmt.Errorf("policy_pack.url is not set")
+	}
+	if packCfg.AllowedSigners == "" {
+		return nil, fmt.Errorf("policy_pack.allowed_signers is required to verify the pack")
+	}
+	ttl := 24 * time.Hour
+	if packCfg.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(packCfg.TTL); err != nil {
+			return nil, fmt.Errorf("invalid policy_pack.ttl %q: %w", packCfg.TTL, err)
+		}
+	}
+
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	packPath := filepath.Join(dir, packFile(packCfg.URL))
+	sigPath := packPath + ".sig"
+
+	var stale error
+	fetchedAt := time.Time{}
+	if info, err := os.Stat(packPath); err == nil {
+		fetchedAt = info.ModTime()
+	}
+	if refresh || fetchedAt.IsZero() || time.Since(fetchedAt) >= ttl {
+		data, sig, err := fetch(packCfg.URL)
+		if err == nil {
+			// Only cache what verifies, so a bad download can't replace a good pack
+			if _, err = attest.Verify(packCfg.AllowedSigners, attest.PolicyNamespace, data, sig); err == nil {
+				err = store(dir, packPath, sigPath, data, sig)
+				fetchedAt = time.Now()
+			}
+		}
+		if err != nil {
+			if fetchedAt.IsZero() {
+				return nil, err
+			}
+			stale = err
+		}
+	}
+
+	data, err := os.ReadFile(packPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached policy pack: %w", err)
+	}
+	sig, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached policy pack signature: %w", err)
+	}
+	signer, err := attest.Verify(packCfg.AllowedSigners, attest.PolicyNamespace, data, sig)
+	if err != nil {
+		return nil, fmt.Errorf("cached policy pack: %w", err)
+	}
+	pack, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Loaded{Pack: pack, Signer: signer, FetchedAt: fetchedAt, Stale: stale}, nil
+}
+
+// Parse parses and validates a pack
+func Parse(data []byte) (*Pack, error) {
+	var pack Pack
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("failed to parse policy pack: %w", err)
+	}
+	for _, name := range pack.Hooks {
+		if !hooks.IsKnown(name) {
+			return nil, fmt.Errorf("policy pack: unknown hook %q", name)
+		}
+	}
+	for _, name := range pack.Compliance {
+		if !scanner.IsKnownFile(name) {
+			return nil, fmt.Errorf("policy pack: unknown compliance entry %q (expected license, readme or codeowners)", name)
+		}
+	}
+	if pack.Signing.Format != "" && !signing.ValidFormat(pack.Signing.Format) {
+		return nil, fmt.Errorf("policy pack: unknown signing format %q", pack.Signing.Format)
+	}
+	for _, entry := range pack.GitConfig {
+		if _, _, err := gitconfig.Parse(entry); err != nil {
+			return nil, fmt.Errorf("policy pack: %w", err)
+		}
+	}
+	return &pack, nil
+}
+
+// Check returns how the repository of info violates the pack, or nothing if
+// it complies
+func (p *Pack) Check(info scanner.DirectoryInfo) ([]string, error) {
+	var violations []string
+
+	if len(p.Hooks) > 0 {
+		dir, err := guard.HooksDir(info.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range p.Hooks {
+			stat, err := os.Stat(filepath.Join(dir, name))
+			if err != nil || (runtime.GOOS != "windows" && stat.Mode()&0111 == 0) {
+				violations = append(violations, "hook "+name+" not installed")
+			}
+		}
+	}
+
+	if p.Branch != "" {
+		defaultBranch := scanner.DefaultBranch(info.Path)
+		if defaultBranch == "" && info.Enrichment != nil {
+			defaultBranch = info.Enrichment.DefaultBranch
+		}
+		if defaultBranch != "" && defaultBranch != p.Branch {
+			violations = append(violations, fmt.Sprintf("default branch %s, expected %s", defaultBranch, p.Branch))
+		}
+	}
+
+	if len(p.Compliance) > 0 {
+		files := info.Files
+		if files == nil {
+			files = scanner.CollectRepoFiles(info.Path)
+		}
+		for _, name := range p.Compliance {
+			if !files.Has(name) {
+				violations = append(violations, "missing "+name)
+			}
+		}
+	}
+
+	if p.Signing.Required || p.Signing.Format != "" || p.Signing.Commits > 0 {
+		status, err := signing.Inspect(info.Path, p.Signing.Commits)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, status.Violations(p.Signing)...)
+	}
+
+	if len(p.GitConfig) > 0 {
+		expected := make(map[string]string)
+		for _, entry := range p.GitConfig {
+			key, value, _ := gitconfig.Parse(entry)
+			expected[key] = value
+		}
+		mismatches, err := gitconfig.Check(info.Path, expected)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range mismatches {
+			actual, want := m.Actual, m.Expected
+			if !m.Set {
+				actual = "unset"
+			}
+			if want == "" {
+				want = "unset"
+			}
+			violations = append(violations, fmt.Sprintf("%s is %s, expected %s", m.Key, actual, want))
+		}
+	}
+
+	return violations, nil
+}
+
+// fetch downloads the pack at url and its signature at url.sig. Plain paths
+// are read from disk, e.g. a pack on a shared drive.
+func fetch(url string) ([]byte, []byte, error) {
+	data, err := get(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, err := get(url + ".sig")
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, sig, nil
+}
+
+// get reads a URL or file
+func get(url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		data, err := os.ReadFile(strings.TrimPrefix(url, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy pack: %w", err)
+		}
+		return data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy pack: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy pack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch policy pack: %w", err)
+	}
+	return data, nil
+}
+
+// store caches a verified pack and its signature
+func store(dir, packPath, sigPath string, data, sig []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create policy cache directory: %w", err)
+	}
+	if err := os.WriteFile(packPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to cache policy pack: %w", err)
+	}
+	if err := os.WriteFile(sigPath, sig, 0644); err != nil {
+		return fmt.Errorf("failed to cache policy pack signature: %w", err)
+	}
+	return nil
+}
+
+// packFile returns the name of the cached pack downloaded from url. It is
+// keyed by the URL so that after policy_pack.url changes, the previous pack is
+// neither reused within its TTL nor used as a fallback when the new URL fails.
+func packFile(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("pack_%s.yml", hex.EncodeToString(sum[:8]))
+}
+
+// cacheDir returns the directory holding the downloaded packs
+func cacheDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "policy"), nil
+}
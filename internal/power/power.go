@@ -0,0 +1,195 @@
+// Package power reports battery, network and user activity state so the
+// daemon can defer heavy jobs.
+package power
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+)
+
+// Status is the state of the machine at one point in time. Values that
+// can't be detected on this platform are reported as false/unknown.
+type Status struct {
+	OnBattery bool
+	Metered   bool
+	Idle      time.Duration // Time since the last keyboard/mouse input
+	IdleKnown bool
+}
+
+// Current detects the current status
+func Current() Status {
+	st := Status{OnBattery: onBattery(), Metered: metered()}
+	st.Idle, st.IdleKnown = idleTime()
+	return st
+}
+
+// Policy decides when heavy jobs may run
+type Policy struct {
+	cfg         config.PowerConfig
+	activeHours bool
+	start, end  time.Duration // Active hours as offsets from midnight
+	MaxDefer    time.Duration // Run deferred jobs anyway after this long; 0 waits indefinitely
+}
+
+// DefaultMaxDefer is used when power.max_defer is unset
+const DefaultMaxDefer = 6 * time.Hour
+
+// NewPolicy validates the power settings
+func NewPolicy(cfg config.PowerConfig) (*Policy, error) {
+	p := &Policy{cfg: cfg, MaxDefer: DefaultMaxDefer}
+	if cfg.ActiveHours != "" {
+		from, to, ok := strings.Cut(cfg.ActiveHours, "-")
+		var errFrom, errTo error
+		p.start, errFrom = parseClock(from)
+		p.end, errTo = parseClock(to)
+		if !ok || errFrom != nil || errTo != nil {
+			return nil, fmt.Errorf("invalid power.active_hours %q (expected e.g. 09:00-18:00)", cfg.ActiveHours)
+		}
+		p.activeHours = true
+	}
+	if cfg.MaxDefer != "" {
+		d, err := time.ParseDuration(cfg.MaxDefer)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid power.max_defer %q", cfg.MaxDefer)
+		}
+		p.MaxDefer = d
+	}
+	return p, nil
+}
+
+// parseClock parses "9", "09" or "09:30" as an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	hour, minute, hasMinute := strings.Cut(strings.TrimSpace(s), ":")
+	h, err := strconv.Atoi(hour)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour %q", hour)
+	}
+	m := 0
+	if hasMinute {
+		if m, err = strconv.Atoi(minute); err != nil || m < 0 || m > 59 {
+			return 0, fmt.Errorf("invalid minute %q", minute)
+		}
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// DeferReason returns why a heavy job should wait, or "" if it can run now
+func (p *Policy) DeferReason(now time.Time, st Status) string {
+	if !p.cfg.ScanOnBattery && st.OnBattery {
+		return "running on battery"
+	}
+	if !p.cfg.ScanOnMetered && st.Metered {
+		return "network connection is metered"
+	}
+	if p.activeHours && inHours(now, p.start, p.end) {
+		return fmt.Sprintf("within active hours (%s)", p.cfg.ActiveHours)
+	}
+	if p.cfg.IdleMinutes > 0 && st.IdleKnown && st.Idle < time.Duration(p.cfg.IdleMinutes)*time.Minute {
+		return fmt.Sprintf("user active %s ago", st.Idle.Round(time.Second))
+	}
+	return ""
+}
+
+// inHours reports whether the time of day of now is in [start, end), where
+// start and end are offsets from midnight. Ranges may wrap past midnight.
+func inHours(now time.Time, start, end time.Duration) bool {
+	y, m, d := now.Date()
+	t := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if start <= end {
+		return t >= start && t < end
+	}
+	return t >= start || t < end
+}
+
+// onBattery reports whether the machine is running on battery power
+func onBattery() bool {
+	switch runtime.GOOS {
+	case "linux":
+		supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+		battery := false
+		for _, dir := range supplies {
+			switch readFile(filepath.Join(dir, "type")) {
+			case "Mains", "USB":
+				if readFile(filepath.Join(dir, "online")) == "1" {
+					return false
+				}
+			case "Battery":
+				if readFile(filepath.Join(dir, "status")) == "Discharging" {
+					battery = true
+				}
+			}
+		}
+		return battery
+	case "darwin":
+		out, err := exec.Command("pmset", "-g", "batt").Output()
+		return err == nil && strings.Contains(string(out), "'Battery Power'")
+	}
+	return false
+}
+
+// metered reports whether NetworkManager considers the connection metered
+func metered() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	out, err := exec.Command("busctl", "get-property", "org.freedesktop.NetworkManager",
+		"/org/freedesktop/NetworkManager", "org.freedesktop.NetworkManager", "Metered").Output()
+	if err != nil {
+		return false
+	}
+	// NMMetered: 1 yes, 3 guessed yes
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(out)), "u"))
+	return value == "1" || value == "3"
+}
+
+// hidIdleTime matches the idle counter in `ioreg -c IOHIDSystem` output
+var hidIdleTime = regexp.MustCompile(`"HIDIdleTime" = (\d+)`)
+
+// idleTime returns the time since the last user input, if it can be detected
+func idleTime() (time.Duration, bool) {
+	switch runtime.GOOS {
+	case "linux":
+		out, err := exec.Command("xprintidle").Output()
+		if err != nil {
+			return 0, false
+		}
+		ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	case "darwin":
+		out, err := exec.Command("ioreg", "-c", "IOHIDSystem").Output()
+		if err != nil {
+			return 0, false
+		}
+		m := hidIdleTime.FindSubmatch(out)
+		if m == nil {
+			return 0, false
+		}
+		ns, err := strconv.ParseInt(string(m[1]), 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(ns), true
+	}
+	return 0, false
+}
+
+// readFile returns the trimmed contents of a small sysfs file
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,111 @@
+// Package progress writes scan progress as JSON lines, one event per line,
+// for wrappers such as editor extensions that show their own progress.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventStart     = "start"     // Scanning began; Total directories are planned, in the order of Queue
+	EventPhase     = "phase"     // A step of the scan began, see Phase
+	EventDirectory = "directory" // A directory was scanned; Done of Total
+	EventDone      = "done"      // The scan finished
+	EventError     = "error"     // The scan failed
+)
+
+// Event is one line of the progress stream. Fields that don't apply to an
+// event type are omitted.
+type Event struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Workspace  string    `json:"workspace,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	Done       int       `json:"done,omitempty"`
+	Total      int       `json:"total,omitempty"`
+	Queue      []string  `json:"queue,omitempty"`  // start: paths in the order they will be scanned
+	Status     string    `json:"status,omitempty"` // done: "full" or "skipped (unchanged)"
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Reporter writes progress events to a writer. Its methods are safe for
+// concurrent use, and do nothing on a nil Reporter, so callers needn't check
+// whether progress was requested.
+type Reporter struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	workspace string
+	start     time.Time
+	done      int
+	total     int
+}
+
+// New returns a Reporter writing to w
+func New(w io.Writer) *Reporter {
+	return &Reporter{enc: json.NewEncoder(w)}
+}
+
+// Start reports that scanning workspace began, with the directories queued
+// for scanning in the order they will be scanned. Without a queue, e.g. when
+// a scan is skipped, only total is reported.
+func (r *Reporter) Start(workspace string, queue []string, total int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.workspace, r.start, r.done, r.total = workspace, time.Now(), 0, total
+	r.mu.Unlock()
+	r.write(Event{Event: EventStart, Total: total, Queue: queue})
+}
+
+// Phase reports that a step of the scan began, e.g. "directories" or "enrich"
+func (r *Reporter) Phase(name string) {
+	if r == nil {
+		return
+	}
+	r.write(Event{Event: EventPhase, Phase: name})
+}
+
+// Directory reports that the directory at path was scanned
+func (r *Reporter) Directory(path string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.done++
+	done, total := r.done, r.total
+	r.mu.Unlock()
+	r.write(Event{Event: EventDirectory, Path: path, Done: done, Total: total})
+}
+
+// Done reports that the scan finished with status
+func (r *Reporter) Done(status string, directories int) {
+	if r == nil {
+		return
+	}
+	r.write(Event{Event: EventDone, Status: status, Total: directories, DurationMS: time.Since(r.start).Milliseconds()})
+}
+
+// Error reports that the scan failed
+func (r *Reporter) Error(err error) {
+	if r == nil {
+		return
+	}
+	r.write(Event{Event: EventError, Error: err.Error()})
+}
+
+// write stamps and writes an event. Write errors are ignored: progress is
+// advisory and must not fail the scan.
+func (r *Reporter) write(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.Time = time.Now()
+	e.Workspace = r.workspace
+	_ = r.enc.Encode(e)
+}
@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// CI states reported by CIStatus
+const (
+	CIPassing = "passing"
+	CIFailing = "failing"
+	CIPending = "pending"
+	CINone    = "none" // No CI configured or no runs on the default branch
+)
+
+// RepoStatus is the state of a repository's default branch on the provider
+type RepoStatus struct {
+	DefaultBranch string
+	CI            string // One of the CI* states
+}
+
+// RepoStatus returns the default branch of a repository and the latest CI
+// state on it. repoPath is the owner/name path of the repository.
+func (p *Provider) RepoStatus(ctx context.Context, repoPath string) (*RepoStatus, error) {
+	switch p.Name {
+	case "github":
+		return p.githubRepoStatus(ctx, repoPath)
+	case "gitlab":
+		return p.gitlabRepoStatus(ctx, repoPath)
+	}
+	return nil, fmt.Errorf("unsupported provider: %s", p.Name)
+}
+
+// githubRepoStatus combines check runs and commit statuses on the default branch
+func (p *Provider) githubRepoStatus(ctx context.Context, repoPath string) (*RepoStatus, error) {
+	var repo struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := p.GetJSON(ctx, "/repos/"+repoPath, &repo); err != nil {
+		return nil, err
+	}
+	ref := url.PathEscape(repo.DefaultBranch)
+
+	var checks struct {
+		CheckRuns []struct {
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+		} `json:"check_runs"`
+	}
+	if err := p.GetJSON(ctx, fmt.Sprintf("/repos/%s/commits/%s/check-runs?per_page=100", repoPath, ref), &checks); err != nil {
+		return nil, err
+	}
+
+	var combined struct {
+		State      string `json:"state"`
+		TotalCount int    `json:"total_count"`
+	}
+	if err := p.GetJSON(ctx, fmt.Sprintf("/repos/%s/commits/%s/status", repoPath, ref), &combined); err != nil {
+		return nil, err
+	}
+
+	status := &RepoStatus{DefaultBranch: repo.DefaultBranch, CI: CINone}
+	pending := false
+	for _, run := range checks.CheckRuns {
+		if run.Status != "completed" {
+			pending = true
+			continue
+		}
+		switch run.Conclusion {
+		case "failure", "timed_out", "cancelled", "action_required", "startup_failure":
+			status.CI = CIFailing
+			return status, nil
+		}
+		status.CI = CIPassing
+	}
+
+	if combined.TotalCount > 0 {
+		switch combined.State {
+		case "failure", "error":
+			status.CI = CIFailing
+			return status, nil
+		case "pending":
+			pending = true
+		case "success":
+			status.CI = CIPassing
+		}
+	}
+	if pending {
+		status.CI = CIPending
+	}
+	return status, nil
+}
+
+// gitlabRepoStatus uses the latest pipeline on the default branch
+func (p *Provider) gitlabRepoStatus(ctx context.Context, repoPath string) (*RepoStatus, error) {
+	id := url.PathEscape(repoPath)
+
+	var project struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := p.GetJSON(ctx, "/projects/"+id, &project); err != nil {
+		return nil, err
+	}
+
+	var pipelines []struct {
+		Status string `json:"status"`
+	}
+	path := fmt.Sprintf("/projects/%s/pipelines?per_page=1&ref=%s", id, url.QueryEscape(project.DefaultBranch))
+	if err := p.GetJSON(ctx, path, &pipelines); err != nil {
+		return nil, err
+	}
+
+	status := &RepoStatus{DefaultBranch: project.DefaultBranch, CI: CINone}
+	if len(pipelines) > 0 {
+		switch pipelines[0].Status {
+		case "success":
+			status.CI = CIPassing
+		case "failed", "canceled":
+			status.CI = CIFailing
+		case "skipped", "manual":
+			status.CI = CINone
+		default:
+			status.CI = CIPending
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,435 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+)
+
+const (
+	// defaultRequestInterval is the minimum time between two requests to the same host
+	defaultRequestInterval = 100 * time.Millisecond
+
+	// maxRetries is the number of times a failed request is retried
+	maxRetries = 4
+
+	// baseBackoff is the delay before the first retry; it doubles on every attempt
+	baseBackoff = 500 * time.Millisecond
+
+	// maxRateLimitWait is the longest the client waits for a rate limit to reset
+	// before giving up with ErrRateLimited
+	maxRateLimitWait = 2 * time.Minute
+)
+
+// ErrRateLimited is returned when a host's rate limit is exhausted and won't reset soon
+var ErrRateLimited = errors.New("provider rate limit exceeded")
+
+// StatusError is returned for non-retryable, unsuccessful HTTP responses
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request to %s failed: HTTP %d", e.URL, e.StatusCode)
+}
+
+// Client is an HTTP client shared by all provider integrations. It limits the
+// request rate per host, caches responses on disk keyed by URL and credentials
+// and revalidates them with ETags, and retries transient failures with
+// exponential backoff.
+type Client struct {
+	http     *http.Client
+	cacheDir string
+
+	mu       sync.Mutex
+	limiters map[string]*hostLimiter
+}
+
+// hostLimiter tracks when the next request to a host may be sent
+type hostLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// cachedResponse is the on-disk representation of a cached response
+type cachedResponse struct {
+	URL      string          `json:"url"`
+	ETag     string          `json:"etag"`
+	Link     string          `json:"link,omitempty"`
+	Body     json.RawMessage `json:"body"`
+	StoredAt time.Time       `json:"stored_at"`
+}
+
+// Response is the result of a successful request
+type Response struct {
+	Body      []byte
+	NextURL   string // URL of the next page, from the Link header
+	FromCache bool   // True when the server answered 304 Not Modified
+
+	etag string // ETag header, kept for caching
+	link string // Raw Link header, kept for caching
+}
+
+// NewClient creates a provider client that caches responses in the
+// platform-appropriate cache directory
+func NewClient() (*Client, error) {
+	cacheDir, err := getCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider cache directory: %w", err)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create provider cache directory: %w", err)
+	}
+
+	return &Client{
+		http:     &http.Client{Timeout: 30 * time.Second},
+		cacheDir: cacheDir,
+		limiters: make(map[string]*hostLimiter),
+	}, nil
+}
+
+// getCacheDir returns the platform-appropriate provider response cache directory
+func getCacheDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "providers"), nil
+}
+
+// Get performs a GET request with the given headers and returns the response
+// body, serving it from the cache when the server reports it unchanged
+func (c *Client) Get(ctx context.Context, url string, headers map[string]string) (*Response, error) {
+	cacheFile := c.cacheFilePath(url, headers)
+	cached := c.readCache(cacheFile)
+
+	var lastErr error
+	var wait time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, retryDelay(attempt, wait)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, retry, rateWait, err := c.do(ctx, http.MethodGet, url, nil, headers, cached)
+		wait = rateWait
+		if err == nil {
+			if !resp.FromCache {
+				c.writeCache(cacheFile, url, resp)
+			}
+			return resp, nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// GetJSON performs a GET request and decodes the JSON response body into out
+func (c *Client) GetJSON(ctx context.Context, url string, headers map[string]string, out any) (*Response, error) {
+	resp, err := c.Get(ctx, url, headers)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(resp.Body, out); err != nil {
+		return nil, fmt.Errorf("failed to decode response from %s: %w", url, err)
+	}
+	return resp, nil
+}
+
+// PostJSON sends body as JSON and decodes the JSON response into out. POST
+// responses are never cached, but rate limiting and retries still apply.
+func (c *Client) PostJSON(ctx context.Context, url string, headers map[string]string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request to %s: %w", url, err)
+	}
+
+	postHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		postHeaders[key] = value
+	}
+
+	var lastErr error
+	var wait time.Duration
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, retryDelay(attempt, wait)); err != nil {
+				return err
+			}
+		}
+
+		resp, retry, rateWait, err := c.do(ctx, http.MethodPost, url, data, postHeaders, nil)
+		wait = rateWait
+		if err == nil {
+			if err := json.Unmarshal(resp.Body, out); err != nil {
+				return fmt.Errorf("failed to decode response from %s: %w", url, err)
+			}
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// GetAllPages follows Link: rel="next" headers starting at url, decoding each
+// page as a JSON array and appending the elements to the returned slice
+func GetAllPages[T any](ctx context.Context, c *Client, url string, headers map[string]string) ([]T, error) {
+	var all []T
+	for url != "" {
+		var page []T
+		resp, err := c.GetJSON(ctx, url, headers, &page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		url = resp.NextURL
+	}
+	return all, nil
+}
+
+// do sends a single request. It reports whether a failure is worth retrying
+// and, when rate limited, how long to wait for the limit to reset first.
+func (c *Client) do(ctx context.Context, method, url string, body []byte, headers map[string]string, cached *cachedResponse) (*Response, bool, time.Duration, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	if err := c.limiter(req.URL.Host).wait(ctx); err != nil {
+		return nil, false, 0, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Network errors are usually transient
+		return nil, true, 0, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	c.limiter(req.URL.Host).observe(resp)
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return &Response{Body: cached.Body, NextURL: parseNextLink(cached.Link), FromCache: true}, false, 0, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, true, 0, fmt.Errorf("failed to read response from %s: %w", url, err)
+		}
+		return &Response{
+			Body:    body,
+			NextURL: parseNextLink(resp.Header.Get("Link")),
+			etag:    resp.Header.Get("ETag"),
+			link:    resp.Header.Get("Link"),
+		}, false, 0, nil
+	case isRateLimited(resp):
+		wait := rateLimitWait(resp)
+		if wait > maxRateLimitWait {
+			return nil, false, 0, fmt.Errorf("%w for %s (resets in %s)", ErrRateLimited, req.URL.Host, wait.Round(time.Second))
+		}
+		return nil, true, wait, fmt.Errorf("%w for %s", ErrRateLimited, req.URL.Host)
+	case resp.StatusCode >= 500:
+		return nil, true, 0, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	default:
+		return nil, false, 0, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+}
+
+// limiter returns the rate limiter for a host, creating it on first use
+func (c *Client) limiter(host string) *hostLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	l, ok := c.limiters[host]
+	if !ok {
+		l = &hostLimiter{}
+		c.limiters[host] = l
+	}
+	return l
+}
+
+// wait blocks until a request to the host may be sent and reserves the slot
+func (l *hostLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(defaultRequestInterval)
+	l.mu.Unlock()
+
+	return sleep(ctx, time.Until(start))
+}
+
+// observe pushes back the next request slot when the host reports that its
+// rate limit is exhausted
+func (l *hostLimiter) observe(resp *http.Response) {
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	wait := rateLimitWait(resp)
+	if wait <= 0 || wait > maxRateLimitWait {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if reset := time.Now().Add(wait); reset.After(l.next) {
+		l.next = reset
+	}
+}
+
+// isRateLimited reports whether a response indicates an exhausted rate limit
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0"
+}
+
+// rateLimitWait returns how long to wait before retrying a rate-limited
+// request, based on the Retry-After or X-RateLimit-Reset headers
+func rateLimitWait(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return time.Until(time.Unix(reset, 0))
+	}
+	return baseBackoff
+}
+
+// retryDelay returns how long to wait before the given retry attempt: until
+// the rate limit resets if the last attempt was rate limited (rateWait),
+// otherwise the backoff
+func retryDelay(attempt int, rateWait time.Duration) time.Duration {
+	if rateWait > 0 {
+		return rateWait
+	}
+	return backoff(attempt)
+}
+
+// backoff returns the exponential backoff delay (with jitter) before the given retry attempt
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << (attempt - 1)
+	jitter := time.Duration(rand.Int64N(int64(delay) / 2))
+	return delay + jitter
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// nextLinkPattern matches the rel="next" entry of a Link header
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// parseNextLink extracts the next page URL from a Link header
+func parseNextLink(link string) string {
+	if m := nextLinkPattern.FindStringSubmatch(link); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// cacheFilePath returns the cache file for a request. The key includes the
+// request headers so responses fetched with different tokens are never shared.
+func (c *Client) cacheFilePath(url string, headers map[string]string) string {
+	h := sha256.New()
+	h.Write([]byte(url))
+	for _, key := range []string{"Authorization", "PRIVATE-TOKEN", "Accept"} {
+		h.Write([]byte{0})
+		h.Write([]byte(headers[key]))
+	}
+	return filepath.Join(c.cacheDir, hex.EncodeToString(h.Sum(nil))[:32]+".json")
+}
+
+// readCache loads a cached response, returning nil if there is none
+func (c *Client) readCache(cacheFile string) *cachedResponse {
+	data, err := os.ReadFile(cacheFile)
+	if err != nil {
+		return nil
+	}
+	if data, err = seal.Open(data); err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// writeCache stores a response that carries an ETag so it can be revalidated later.
+// Cache write failures are not fatal; the response is simply refetched next time.
+func (c *Client) writeCache(cacheFile, url string, resp *Response) {
+	if resp.etag == "" || !json.Valid(resp.Body) {
+		return
+	}
+	data, err := json.Marshal(cachedResponse{
+		URL:      url,
+		ETag:     resp.etag,
+		Link:     resp.link,
+		Body:     resp.Body,
+		StoredAt: time.Now(),
+	})
+	if err != nil {
+		return
+	}
+	if data, err = seal.Seal(data); err != nil {
+		return
+	}
+	_ = os.WriteFile(cacheFile, data, 0600)
+}
+
+// IsNotFound reports whether err is an HTTP 404 response
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Issue is an open issue on a provider
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// IssueSummary counts a repository's open issues and lists the ones assigned
+// to the authenticated user
+type IssueSummary struct {
+	Open     int
+	Assigned []Issue
+}
+
+// Issues returns the issue summary of a repository. repoPath is the
+// owner/name path of the repository.
+func (p *Provider) Issues(ctx context.Context, repoPath string) (*IssueSummary, error) {
+	switch p.Name {
+	case "github":
+		return p.githubIssues(ctx, repoPath)
+	case "gitlab":
+		return p.gitlabIssues(ctx, repoPath)
+	}
+	return nil, fmt.Errorf("unsupported provider: %s", p.Name)
+}
+
+// githubIssues counts open issues with a search, as the repository's
+// open_issues_count includes pull requests, and lists the ones assigned to
+// the user, skipping pull requests which GitHub returns from the same
+// endpoint
+func (p *Provider) githubIssues(ctx context.Context, repoPath string) (*IssueSummary, error) {
+	var count struct {
+		TotalCount int `json:"total_count"`
+	}
+	query := url.QueryEscape("is:issue is:open repo:" + repoPath)
+	if err := p.GetJSON(ctx, "/search/issues?per_page=1&q="+query, &count); err != nil {
+		return nil, err
+	}
+
+	login, err := p.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type githubIssue struct {
+		Number      int    `json:"number"`
+		Title       string `json:"title"`
+		HTMLURL     string `json:"html_url"`
+		PullRequest *struct {
+		} `json:"pull_request"`
+	}
+	issues, err := getAllPages[githubIssue](ctx, p, fmt.Sprintf("/repos/%s/issues?state=open&assignee=%s&per_page=100", repoPath, url.QueryEscape(login)))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &IssueSummary{Open: count.TotalCount}
+	for _, issue := range issues {
+		if issue.PullRequest == nil {
+			summary.Assigned = append(summary.Assigned, Issue{Number: issue.Number, Title: issue.Title, URL: issue.HTMLURL})
+		}
+	}
+	return summary, nil
+}
+
+// gitlabIssues uses the project's open issue count and lists issues assigned to the user
+func (p *Provider) gitlabIssues(ctx context.Context, repoPath string) (*IssueSummary, error) {
+	id := url.PathEscape(repoPath)
+
+	var project struct {
+		OpenIssuesCount int `json:"open_issues_count"`
+	}
+	if err := p.GetJSON(ctx, "/projects/"+id, &project); err != nil {
+		return nil, err
+	}
+
+	type gitlabIssue struct {
+		IID    int    `json:"iid"`
+		Title  string `json:"title"`
+		WebURL string `json:"web_url"`
+	}
+	issues, err := getAllPages[gitlabIssue](ctx, p, fmt.Sprintf("/projects/%s/issues?state=opened&scope=assigned_to_me&per_page=100", id))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &IssueSummary{Open: project.OpenIssuesCount}
+	for _, issue := range issues {
+		summary.Assigned = append(summary.Assigned, Issue{Number: issue.IID, Title: issue.Title, URL: issue.WebURL})
+	}
+	return summary, nil
+}
+
+// login returns the username of the authenticated user, fetched once per
+// Provider
+func (p *Provider) login(ctx context.Context) (string, error) {
+	p.loginMu.Lock()
+	defer p.loginMu.Unlock()
+	if p.loginName != "" {
+		return p.loginName, nil
+	}
+
+	var user struct {
+		Login    string `json:"login"`
+		Username string `json:"username"`
+	}
+	if err := p.GetJSON(ctx, "/user", &user); err != nil {
+		return "", err
+	}
+	p.loginName = user.Login
+	if p.loginName == "" {
+		p.loginName = user.Username
+	}
+	return p.loginName, nil
+}
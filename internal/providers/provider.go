@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+)
+
+// log is the providers component's logger, whose level logging.components can set
+var log = logger.For("providers")
+
+// Provider is an authenticated API endpoint of a git hosting provider
+type Provider struct {
+	Name   string // github or gitlab
+	APIURL string
+
+	client *Client
+	token  string
+
+	loginMu   sync.Mutex // Guards loginName
+	loginName string     // Cached by login
+}
+
+// New returns the named provider configured from cfg. The token is resolved
+// through the secrets layer, so keychain references are supported.
+func New(client *Client, name string, cfg *config.Config) (*Provider, error) {
+	providerCfg := cfg.Provider(name)
+	if providerCfg == nil {
+		return nil, fmt.Errorf("unsupported provider: %s", name)
+	}
+
+	token, err := secrets.Resolve(providerCfg.Token)
+	if errors.Is(err, secrets.ErrNotFound) {
+		return nil, fmt.Errorf("not logged in to %s; run 'thandie auth login %s'", name, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		Name:   name,
+		APIURL: strings.TrimSuffix(providerCfg.URL, "/"),
+		client: client,
+		token:  token,
+	}, nil
+}
+
+// headers returns the authentication and content negotiation headers for the provider
+func (p *Provider) headers() map[string]string {
+	switch p.Name {
+	case "gitlab":
+		return map[string]string{"PRIVATE-TOKEN": p.token}
+	default:
+		return map[string]string{
+			"Authorization": "Bearer " + p.token,
+			"Accept":        "application/vnd.github+json",
+		}
+	}
+}
+
+// url returns the absolute API URL for path
+func (p *Provider) url(path string) string {
+	return p.APIURL + "/" + strings.TrimPrefix(path, "/")
+}
+
+// GetJSON fetches an API path and decodes the JSON response into out
+func (p *Provider) GetJSON(ctx context.Context, path string, out any) error {
+	_, err := p.client.GetJSON(ctx, p.url(path), p.headers(), out)
+	return err
+}
+
+// getAllPages fetches every page of a paginated API path
+func getAllPages[T any](ctx context.Context, p *Provider, path string) ([]T, error) {
+	return GetAllPages[T](ctx, p.client, p.url(path), p.headers())
+}
+
+// ByHost returns the providers the user is logged in to, keyed by the host
+// of their web_url (e.g. github.com) so they can be matched against remote
+// URLs. Providers without a usable token or web_url are left out.
+func ByHost(client *Client, cfg *config.Config) map[string]*Provider {
+	byHost := make(map[string]*Provider)
+	for _, name := range []string{"github", "gitlab"} {
+		provider, err := New(client, name, cfg)
+		if err != nil {
+			log.Debug("provider unavailable", "provider", name, "reason", err)
+			continue
+		}
+		webURL, err := url.Parse(cfg.Provider(name).WebURL)
+		if err != nil || webURL.Hostname() == "" {
+			log.Warn("invalid provider web_url, skipping provider", "provider", name, "web_url", cfg.Provider(name).WebURL)
+			continue
+		}
+		byHost[webURL.Hostname()] = provider
+	}
+	return byHost
+}
@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PullRequest is an open pull request (GitHub) or merge request (GitLab)
+// that involves the authenticated user
+type PullRequest struct {
+	Repo      string    `json:"repo"`     // owner/name
+	RepoURL   string    `json:"repo_url"` // Web URL of the repository
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Author    string    `json:"author"`
+	Draft     bool      `json:"draft"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Reason    string    `json:"reason"` // ReasonAuthored or ReasonReviewRequested
+}
+
+// Reasons a pull request appears in the inbox
+const (
+	ReasonAuthored        = "authored"
+	ReasonReviewRequested = "review_requested"
+)
+
+// githubSearchResult is the GitHub issue search response
+type githubSearchResult struct {
+	Items []struct {
+		Number        int       `json:"number"`
+		Title         string    `json:"title"`
+		HTMLURL       string    `json:"html_url"`
+		RepositoryURL string    `json:"repository_url"`
+		Draft         bool      `json:"draft"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		User          struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"items"`
+}
+
+// gitlabMergeRequest is the subset of the GitLab merge request object Thandie uses
+type gitlabMergeRequest struct {
+	IID       int       `json:"iid"`
+	Title     string    `json:"title"`
+	WebURL    string    `json:"web_url"`
+	Draft     bool      `json:"draft"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Author    struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// Inbox returns open pull requests authored by the authenticated user or
+// awaiting their review. Pull requests matching both are reported once, as
+// awaiting review.
+func (p *Provider) Inbox(ctx context.Context) ([]PullRequest, error) {
+	var reviews, authored []PullRequest
+	var err error
+	switch p.Name {
+	case "github":
+		if reviews, err = p.githubSearchPulls(ctx, "review-requested:@me", ReasonReviewRequested); err != nil {
+			return nil, err
+		}
+		if authored, err = p.githubSearchPulls(ctx, "author:@me", ReasonAuthored); err != nil {
+			return nil, err
+		}
+	case "gitlab":
+		username, err := p.login(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if reviews, err = p.gitlabMergeRequests(ctx, "scope=all&reviewer_username="+url.QueryEscape(username), ReasonReviewRequested); err != nil {
+			return nil, err
+		}
+		if authored, err = p.gitlabMergeRequests(ctx, "scope=created_by_me", ReasonAuthored); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", p.Name)
+	}
+
+	seen := make(map[string]bool)
+	for _, pr := range reviews {
+		seen[pr.URL] = true
+	}
+	for _, pr := range authored {
+		if !seen[pr.URL] {
+			reviews = append(reviews, pr)
+		}
+	}
+	return reviews, nil
+}
+
+// githubSearchPulls returns open pull requests matching a search qualifier
+func (p *Provider) githubSearchPulls(ctx context.Context, qualifier, reason string) ([]PullRequest, error) {
+	query := url.QueryEscape("is:pr is:open archived:false " + qualifier)
+
+	var result githubSearchResult
+	if err := p.GetJSON(ctx, "/search/issues?per_page=100&q="+query, &result); err != nil {
+		return nil, err
+	}
+
+	pulls := make([]PullRequest, 0, len(result.Items))
+	for _, item := range result.Items {
+		repoURL := item.HTMLURL
+		if i := strings.Index(repoURL, "/pull/"); i >= 0 {
+			repoURL = repoURL[:i]
+		}
+		pulls = append(pulls, PullRequest{
+			Repo:      item.RepositoryURL[strings.Index(item.RepositoryURL, "/repos/")+len("/repos/"):],
+			RepoURL:   repoURL,
+			Number:    item.Number,
+			Title:     item.Title,
+			URL:       item.HTMLURL,
+			Author:    item.User.Login,
+			Draft:     item.Draft,
+			UpdatedAt: item.UpdatedAt,
+			Reason:    reason,
+		})
+	}
+	return pulls, nil
+}
+
+// gitlabMergeRequests returns open merge requests matching the given query parameters
+func (p *Provider) gitlabMergeRequests(ctx context.Context, params, reason string) ([]PullRequest, error) {
+	mrs, err := getAllPages[gitlabMergeRequest](ctx, p, "/merge_requests?state=opened&per_page=100&"+params)
+	if err != nil {
+		return nil, err
+	}
+
+	pulls := make([]PullRequest, 0, len(mrs))
+	for _, mr := range mrs {
+		repoURL, _, _ := strings.Cut(mr.WebURL, "/-/")
+		repo := repoURL
+		if u, err := url.Parse(repoURL); err == nil {
+			repo = strings.TrimPrefix(u.Path, "/")
+		}
+		pulls = append(pulls, PullRequest{
+			Repo:      repo,
+			RepoURL:   repoURL,
+			Number:    mr.IID,
+			Title:     mr.Title,
+			URL:       mr.WebURL,
+			Author:    mr.Author.Username,
+			Draft:     mr.Draft,
+			UpdatedAt: mr.UpdatedAt,
+			Reason:    reason,
+		})
+	}
+	return pulls, nil
+}
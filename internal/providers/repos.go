@@ -0,0 +1,96 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// Repo is a repository hosted on a provider
+type Repo struct {
+	FullName string `json:"full_name"` // owner/name
+	WebURL   string `json:"web_url"`
+	CloneURL string `json:"clone_url"` // HTTPS clone URL
+	SSHURL   string `json:"ssh_url"`
+	Archived bool   `json:"archived"`
+}
+
+// githubRepo is the subset of the GitHub repository object Thandie uses
+type githubRepo struct {
+	FullName string `json:"full_name"`
+	HTMLURL  string `json:"html_url"`
+	CloneURL string `json:"clone_url"`
+	SSHURL   string `json:"ssh_url"`
+	Archived bool   `json:"archived"`
+}
+
+// gitlabProject is the subset of the GitLab project object Thandie uses
+type gitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	WebURL            string `json:"web_url"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+	Archived          bool   `json:"archived"`
+}
+
+// ListRepos lists the repositories of an organization (GitHub) or group
+// (GitLab, including subgroups). With an empty org it lists every repository
+// the authenticated user has access to.
+func (p *Provider) ListRepos(ctx context.Context, org string) ([]Repo, error) {
+	switch p.Name {
+	case "github":
+		path := "/user/repos?per_page=100&affiliation=owner,collaborator,organization_member"
+		if org != "" {
+			path = fmt.Sprintf("/orgs/%s/repos?per_page=100", url.PathEscape(org))
+		}
+		ghRepos, err := getAllPages[githubRepo](ctx, p, path)
+		if err != nil {
+			return nil, err
+		}
+		repos := make([]Repo, len(ghRepos))
+		for i, r := range ghRepos {
+			repos[i] = Repo{FullName: r.FullName, WebURL: r.HTMLURL, CloneURL: r.CloneURL, SSHURL: r.SSHURL, Archived: r.Archived}
+		}
+		return repos, nil
+
+	case "gitlab":
+		path := "/projects?per_page=100&membership=true"
+		if org != "" {
+			path = fmt.Sprintf("/groups/%s/projects?per_page=100&include_subgroups=true", url.PathEscape(org))
+		}
+		projects, err := getAllPages[gitlabProject](ctx, p, path)
+		if err != nil {
+			return nil, err
+		}
+		repos := make([]Repo, len(projects))
+		for i, r := range projects {
+			repos[i] = Repo{FullName: r.PathWithNamespace, WebURL: r.WebURL, CloneURL: r.HTTPURLToRepo, SSHURL: r.SSHURLToRepo, Archived: r.Archived}
+		}
+		return repos, nil
+	}
+
+	return nil, fmt.Errorf("unsupported provider: %s", p.Name)
+}
+
+// GetRepo returns a single repository by its owner/name path. Providers
+// answer for renamed and transferred repositories under their new name, and
+// with a *StatusError of 404 for deleted ones (and ones the token can't see).
+func (p *Provider) GetRepo(ctx context.Context, repoPath string) (*Repo, error) {
+	switch p.Name {
+	case "github":
+		var r githubRepo
+		if err := p.GetJSON(ctx, "/repos/"+repoPath, &r); err != nil {
+			return nil, err
+		}
+		return &Repo{FullName: r.FullName, WebURL: r.HTMLURL, CloneURL: r.CloneURL, SSHURL: r.SSHURL, Archived: r.Archived}, nil
+
+	case "gitlab":
+		var r gitlabProject
+		if err := p.GetJSON(ctx, "/projects/"+url.PathEscape(repoPath), &r); err != nil {
+			return nil, err
+		}
+		return &Repo{FullName: r.PathWithNamespace, WebURL: r.WebURL, CloneURL: r.HTTPURLToRepo, SSHURL: r.SSHURLToRepo, Archived: r.Archived}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported provider: %s", p.Name)
+}
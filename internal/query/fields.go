@@ -0,0 +1,187 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// fieldDef describes a queryable field
+type fieldDef struct {
+	help string
+	get  func(info scanner.DirectoryInfo) any
+}
+
+// fields holds the queryable fields. Numbers are float64, text is string and
+// lists are []string; missing data reads as the zero value of the type.
+var fields = map[string]fieldDef{
+	"name":  {"directory name", func(i scanner.DirectoryInfo) any { return filepath.Base(i.Path) }},
+	"path":  {"absolute path", func(i scanner.DirectoryInfo) any { return i.Path }},
+	"git":   {"is a git repository", func(i scanner.DirectoryInfo) any { return isRepo(i) }},
+	"dirty": {"has uncommitted changes", func(i scanner.DirectoryInfo) any { return isRepo(i) && i.GitMetadata.HasUncommitted }},
+	"branch": {"current branch", func(i scanner.DirectoryInfo) any {
+		return gitField(i, func(g *scanner.GitMetadata) string { return g.CurrentBranch })
+	}},
+	"remote": {"remote URL", func(i scanner.DirectoryInfo) any {
+		return gitField(i, func(g *scanner.GitMetadata) string { return g.RemoteURL })
+	}},
+	"host":  {"remote host, e.g. github.com", func(i scanner.DirectoryInfo) any { return remote(i).Host }},
+	"owner": {"remote owner or group path", func(i scanner.DirectoryInfo) any { return remote(i).Owner() }},
+	"repo":  {"remote repository path, owner/name", func(i scanner.DirectoryInfo) any { return remote(i).Path }},
+	"upstream": {"remote-tracking branch", func(i scanner.DirectoryInfo) any {
+		return gitField(i, func(g *scanner.GitMetadata) string { return g.Upstream })
+	}},
+	"ahead": {"commits ahead of upstream", func(i scanner.DirectoryInfo) any {
+		if !isRepo(i) {
+			return 0.0
+		}
+		return float64(i.GitMetadata.Ahead)
+	}},
+	"behind": {"commits behind upstream", func(i scanner.DirectoryInfo) any {
+		if !isRepo(i) {
+			return 0.0
+		}
+		return float64(i.GitMetadata.Behind)
+	}},
+	"in_progress": {"operation git is in the middle of: merge, rebase, cherry-pick, revert, bisect, or empty", func(i scanner.DirectoryInfo) any {
+		return gitField(i, func(g *scanner.GitMetadata) string { return g.InProgress })
+	}},
+	"conflicts": {"files with unresolved conflicts", func(i scanner.DirectoryInfo) any {
+		if !isRepo(i) {
+			return 0.0
+		}
+		return float64(i.GitMetadata.Conflicts)
+	}},
+	"ci": {"default-branch CI state: passing, failing, pending, none", func(i scanner.DirectoryInfo) any {
+		if i.Enrichment == nil {
+			return ""
+		}
+		return i.Enrichment.CI
+	}},
+	"open_issues": {"open issues on the provider", func(i scanner.DirectoryInfo) any {
+		if i.Enrichment == nil {
+			return 0.0
+		}
+		return float64(i.Enrichment.OpenIssues)
+	}},
+	"assigned": {"open issues assigned to you", func(i scanner.DirectoryInfo) any {
+		if i.Enrichment == nil {
+			return 0.0
+		}
+		return float64(len(i.Enrichment.AssignedIssues))
+	}},
+	"ticket": {"ticket key named in the branch", func(i scanner.DirectoryInfo) any {
+		if i.Ticket == nil {
+			return ""
+		}
+		return i.Ticket.Key
+	}},
+	"lang": {"languages from project files (use: \"go\" in lang)", func(i scanner.DirectoryInfo) any {
+		if i.Languages == nil {
+			return []string{}
+		}
+		return i.Languages
+	}},
+	"loc": {"lines of code, if counted", func(i scanner.DirectoryInfo) any {
+		if i.Extras == nil {
+			return 0.0
+		}
+		return float64(i.Extras.Code.TotalCode())
+	}},
+	"tests": {"last test run: passed, failed or empty", func(i scanner.DirectoryInfo) any {
+		switch {
+		case i.Tests == nil:
+			return ""
+		case i.Tests.Passed:
+			return "passed"
+		}
+		return "failed"
+	}},
+	"vulns":         {"vulnerabilities found by the last audit", func(i scanner.DirectoryInfo) any { return float64(i.Audit.Total()) }},
+	"docker":        {"has a Dockerfile or compose file", func(i scanner.DirectoryInfo) any { return i.Docker != nil }},
+	"running":       {"containers running at scan time", func(i scanner.DirectoryInfo) any { return runningCount(i) }},
+	"terraform":     {"has Terraform configurations", func(i scanner.DirectoryInfo) any { return i.Extras != nil && i.Extras.Terraform != nil }},
+	"risk":          {"has uncommitted Terraform state", func(i scanner.DirectoryInfo) any { return i.Extras != nil && i.Extras.Terraform.HighRisk() }},
+	"license":       {"has a LICENSE file", func(i scanner.DirectoryInfo) any { return i.Files.Has(scanner.FileLicense) }},
+	"readme":        {"has a README file", func(i scanner.DirectoryInfo) any { return i.Files.Has(scanner.FileReadme) }},
+	"codeowners":    {"has a CODEOWNERS file", func(i scanner.DirectoryInfo) any { return i.Files.Has(scanner.FileCodeowners) }},
+	"sparse":        {"is a sparse checkout, whose status covers only the checked out files", func(i scanner.DirectoryInfo) any { return isRepo(i) && i.GitMetadata.Sparse != nil }},
+	"network":       {"network filesystem the directory is on (scanned without git status), or empty", func(i scanner.DirectoryInfo) any { return i.Network }},
+	"access_denied": {"the directory or its .git can't be read", func(i scanner.DirectoryInfo) any { return i.AccessDenied }},
+	"scan_error":    {"why the last scan of the directory failed, or empty", func(i scanner.DirectoryInfo) any { return i.ScanError }},
+	"unscanned":     {"skipped by the last scan with --skip, so its fields are from the scan before", func(i scanner.DirectoryInfo) any { return i.Unscanned }},
+}
+
+func isRepo(i scanner.DirectoryInfo) bool {
+	return i.GitMetadata != nil && i.GitMetadata.IsGitRepo
+}
+
+func gitField(i scanner.DirectoryInfo, get func(*scanner.GitMetadata) string) string {
+	if !isRepo(i) {
+		return ""
+	}
+	return get(i.GitMetadata)
+}
+
+func remote(i scanner.DirectoryInfo) gitremote.Remote {
+	if !isRepo(i) {
+		return gitremote.Remote{}
+	}
+	r, err := gitremote.Parse(i.GitMetadata.RemoteURL)
+	if err != nil {
+		return gitremote.Remote{}
+	}
+	return r
+}
+
+func runningCount(i scanner.DirectoryInfo) float64 {
+	if i.Docker == nil {
+		return 0
+	}
+	return float64(len(i.Docker.Running))
+}
+
+// Match reports whether the expression is true for a directory
+func (q *Query) Match(info scanner.DirectoryInfo) (bool, error) {
+	v, err := q.root.eval(func(name string) any { return fields[name].get(info) })
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// Apply returns the directories the expression is true for, in their
+// original order. Evaluation errors (e.g. comparing text with a number)
+// abort the query.
+func (q *Query) Apply(infos []scanner.DirectoryInfo) ([]scanner.DirectoryInfo, error) {
+	var matched []scanner.DirectoryInfo
+	for _, info := range infos {
+		ok, err := q.Match(info)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, info)
+		}
+	}
+	return matched, nil
+}
+
+// FieldHelp returns one line per field, sorted, for command help
+func FieldHelp() string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-12s %s\n", name, fields[name].help)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,329 @@
+// Package query implements a small expression language for selecting
+// directories from cached scan data, e.g.
+//
+//	dirty && behind > 0 && host == "github.com"
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// node is a parsed expression
+type node interface {
+	eval(env fieldGetter) (any, error)
+}
+
+// fieldGetter returns the value of a field for the directory being evaluated
+type fieldGetter func(name string) any
+
+type (
+	literal struct{ value any }
+	field   struct{ name string }
+	not     struct{ expr node }
+	binary  struct {
+		op          string
+		left, right node
+	}
+)
+
+// Query is a compiled expression
+type Query struct {
+	root node
+}
+
+// Parse compiles an expression. Field names are checked against Fields.
+func Parse(expr string) (*Query, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("at %d: unexpected %q", tok.pos, tok.text)
+	}
+	return &Query{root: root}, nil
+}
+
+// parser is a recursive-descent parser over the token stream. Precedence,
+// lowest first: ||, &&, comparisons (== != < <= > >= =~ in), !.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOp && p.peek().text == "&&" {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	tok := p.peek()
+	switch tok.text {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "in":
+		if tok.kind != tokOp {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if tok.text == "=~" {
+			lit, ok := right.(literal)
+			pattern, isString := lit.value.(string)
+			if !ok || !isString {
+				return nil, fmt.Errorf("at %d: =~ needs a quoted regular expression on the right", tok.pos)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("at %d: %w", tok.pos, err)
+			}
+			right = literal{value: re}
+		}
+		return binary{op: tok.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return not{expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("at %d: expected )", closing.pos)
+		}
+		return expr, nil
+	case tokString:
+		return literal{value: tok.text}, nil
+	case tokNumber:
+		return literal{value: tok.num}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literal{value: true}, nil
+		case "false":
+			return literal{value: false}, nil
+		}
+		if _, ok := fields[tok.text]; !ok {
+			return nil, fmt.Errorf("at %d: unknown field %q (see 'thandie query --help')", tok.pos, tok.text)
+		}
+		return field{name: tok.text}, nil
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("at %d: unexpected %q", tok.pos, tok.text)
+}
+
+func (l literal) eval(fieldGetter) (any, error)   { return l.value, nil }
+func (f field) eval(get fieldGetter) (any, error) { return get(f.name), nil }
+
+func (n not) eval(get fieldGetter) (any, error) {
+	v, err := n.expr.eval(get)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+func (b binary) eval(get fieldGetter) (any, error) {
+	left, err := b.left.eval(get)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit the logical operators
+	switch b.op {
+	case "&&":
+		if !truthy(left) {
+			return false, nil
+		}
+		right, err := b.right.eval(get)
+		return err == nil && truthy(right), err
+	case "||":
+		if truthy(left) {
+			return true, nil
+		}
+		right, err := b.right.eval(get)
+		return err == nil && truthy(right), err
+	}
+
+	right, err := b.right.eval(get)
+	if err != nil {
+		return nil, err
+	}
+	return compare(b.op, left, right)
+}
+
+// compare applies a comparison operator to two values
+func compare(op string, left, right any) (bool, error) {
+	switch op {
+	case "=~":
+		s, ok := left.(string)
+		if !ok {
+			return false, fmt.Errorf("=~ needs a text field on the left, got %s", typeName(left))
+		}
+		return right.(*regexp.Regexp).MatchString(s), nil
+	case "in":
+		s, ok := left.(string)
+		if !ok {
+			return false, fmt.Errorf("in needs text on the left, got %s", typeName(left))
+		}
+		switch r := right.(type) {
+		case []string:
+			for _, item := range r {
+				if strings.EqualFold(item, s) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case string:
+			return strings.Contains(r, s), nil
+		}
+		return false, fmt.Errorf("in needs a list or text on the right, got %s", typeName(right))
+	}
+
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false, mismatch(op, left, right)
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false, mismatch(op, left, right)
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (op != "==" && op != "!=") {
+			return false, mismatch(op, left, right)
+		}
+		return (l == r) == (op == "=="), nil
+	}
+	return false, mismatch(op, left, right)
+}
+
+func mismatch(op string, left, right any) error {
+	return fmt.Errorf("cannot compare %s %s %s", typeName(left), op, typeName(right))
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case string:
+		return "text"
+	case bool:
+		return "boolean"
+	case []string:
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// truthy converts a value to a boolean: zero numbers, empty text and empty
+// lists are false
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package query_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ThandieOps/thandie-agent/internal/query"
+	"github.com/ThandieOps/thandie-agent/pkg/scannertest"
+)
+
+func TestQueryScannedWorkspace(t *testing.T) {
+	w := scannertest.New(t, scannertest.Mixed(7)...)
+	infos, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`dirty`, []string{"repo-0001"}},
+		{`!git`, []string{"repo-0004"}},
+		{`git && remote == ""`, []string{"repo-0002"}},
+		{`branch == "HEAD"`, []string{"repo-0003"}},
+		{`branch == "develop" || owner == "nobody"`, []string{"repo-0005"}},
+		{`git && owner == "example" && !dirty && branch == "main"`, []string{"repo-0000", "repo-0006"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			q, err := query.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			matched, err := q.Apply(infos)
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			var names []string
+			for _, info := range matched {
+				names = append(names, filepath.Base(info.Path))
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("matched %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package remotecheck verifies that the origin remotes of repositories still
+// exist, through the provider API for GitHub and GitLab remotes and with
+// `git ls-remote` for any other.
+package remotecheck
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitremote"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// lsRemoteTimeout bounds a single `git ls-remote`
+const lsRemoteTimeout = 30 * time.Second
+
+// goneMessages are substrings of git errors meaning the repository doesn't
+// exist (hosts report private repositories the user can't see the same way)
+var goneMessages = []string{
+	"not found",
+	"does not exist",
+	"could not be found",
+	"does not appear to be a git repository",
+}
+
+// Checker checks remotes
+type Checker struct {
+	providers map[string]*providers.Provider // Keyed by web host, see providers.ByHost
+}
+
+// New creates a checker that asks the given providers about the remotes
+// they host. Remotes on other hosts are checked with git.
+func New(byHost map[string]*providers.Provider) *Checker {
+	return &Checker{providers: byHost}
+}
+
+// Run checks the origin remote of every git repository in infos that has
+// one, setting its Remote. At most concurrency remotes are checked at once.
+func (c *Checker) Run(ctx context.Context, infos []scanner.DirectoryInfo, concurrency int) {
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range infos {
+		info := &infos[i]
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo || info.GitMetadata.RemoteURL == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info.Remote = c.Check(ctx, info.Path, info.GitMetadata.RemoteURL)
+		}()
+	}
+	wg.Wait()
+}
+
+// Check checks a single remote URL of the repository at dir. Provider API
+// failures other than 404 fall back to git.
+func (c *Checker) Check(ctx context.Context, dir, remoteURL string) *scanner.RemoteCheck {
+	if remote, err := gitremote.Parse(remoteURL); err == nil {
+		if provider, ok := c.providers[remote.Host]; ok {
+			check, err := checkProvider(ctx, provider, remote, remoteURL)
+			if err == nil {
+				return check
+			}
+			logger.Warn("provider check of remote failed, trying git", "provider", provider.Name, "remote", remoteURL, "error", err)
+		}
+	}
+	return checkGit(ctx, dir, remoteURL)
+}
+
+// checkProvider looks the repository up on its provider
+func checkProvider(ctx context.Context, provider *providers.Provider, remote gitremote.Remote, remoteURL string) (*scanner.RemoteCheck, error) {
+	check := &scanner.RemoteCheck{CheckedAt: time.Now(), URL: remoteURL, State: scanner.RemoteOK, Via: provider.Name}
+	repo, err := provider.GetRepo(ctx, remote.Path)
+	switch {
+	case providers.IsNotFound(err):
+		check.State = scanner.RemoteGone
+		return check, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if !strings.EqualFold(repo.FullName, remote.Path) {
+		check.State = scanner.RemoteMoved
+		check.NewURL = repo.CloneURL
+		if !strings.HasPrefix(remoteURL, "http://") && !strings.HasPrefix(remoteURL, "https://") {
+			check.NewURL = repo.SSHURL
+		}
+	} else if repo.Archived {
+		check.State = scanner.RemoteArchived
+	}
+	return check, nil
+}
+
+// checkGit asks the remote for its HEAD with `git ls-remote`, without
+// prompting for credentials
+func checkGit(ctx context.Context, dir, remoteURL string) *scanner.RemoteCheck {
+	check := &scanner.RemoteCheck{CheckedAt: time.Now(), URL: remoteURL, State: scanner.RemoteOK, Via: "git"}
+
+	ctx, cancel := context.WithTimeout(ctx, lsRemoteTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "ls-remote", remoteURL, "HEAD")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if os.Getenv("GIT_SSH_COMMAND") == "" {
+		cmd.Env = append(cmd.Env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		check.State = scanner.RemoteUnreachable
+		lower := strings.ToLower(msg)
+		for _, gone := range goneMessages {
+			if strings.Contains(lower, gone) {
+				check.State = scanner.RemoteGone
+				break
+			}
+		}
+		check.Error = firstLine(msg)
+	}
+	return check
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,212 @@
+// Package rpc is the local socket the daemon answers queries on, so that
+// quick commands such as `thandie prompt` can use its in-memory state
+// instead of reading caches from disk.
+//
+// Requests and responses are single lines of JSON. The socket is a unix
+// domain socket, which Windows 10 and later support as well.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+)
+
+// log is the rpc component's logger
+var log = logger.For("rpc")
+
+// Timeouts of client calls: connecting fails fast when no daemon is
+// listening, and Call waits longer for a busy one to answer. Callers that
+// must not wait, such as the shell prompt, use CallWithin.
+const (
+	dialTimeout = 100 * time.Millisecond
+	callTimeout = 2 * time.Second
+)
+
+// ErrNotRunning is returned by Call when no daemon is listening
+var ErrNotRunning = errors.New("daemon is not running")
+
+// ErrAlreadyRunning is returned by Listen when another daemon answers the socket
+var ErrAlreadyRunning = errors.New("another daemon is already running")
+
+// Request is a query sent to the daemon
+type Request struct {
+	Method    string `json:"method"`
+	Workspace string `json:"workspace,omitempty"`
+	Path      string `json:"path,omitempty"`
+}
+
+// Response is the daemon's answer to a Request
+type Response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Handler answers a request; the result is encoded as JSON
+type Handler func(req Request) (any, error)
+
+// SocketPath returns the path of the daemon's socket
+func SocketPath() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "daemon.sock"), nil
+}
+
+// Server answers requests on the daemon's socket
+type Server struct {
+	listener net.Listener
+	path     string
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// Listen creates the daemon's socket. A socket left behind by a daemon that
+// is no longer running is replaced; a live one is an error.
+func Listen() (*Server, error) {
+	path, err := SocketPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate daemon socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w (listening on %s)", ErrAlreadyRunning, path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	// Only the user may query the daemon
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return &Server{listener: listener, path: path, handlers: make(map[string]Handler)}, nil
+}
+
+// Path returns the socket path the server listens on
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Handle registers the handler of a method
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[method] = h
+}
+
+// Serve answers requests until ctx is cancelled, then removes the socket
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+	defer os.Remove(s.path)
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("daemon socket stopped accepting connections", "error", err)
+			}
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers the requests of one connection, one per line
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	encoder := json.NewEncoder(conn)
+	for scanner.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = s.answer(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+// answer runs the handler of a request
+func (s *Server) answer(req Request) Response {
+	s.mu.RLock()
+	h, ok := s.handlers[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		return Response{Error: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	result, err := h(req)
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return Response{Error: fmt.Sprintf("failed to encode result: %v", err)}
+	}
+	return Response{Result: data}
+}
+
+// Call sends a request to the daemon and decodes the result into result.
+// Returns ErrNotRunning if no daemon answers the socket.
+func Call(req Request, result any) error {
+	return CallWithin(req, result, callTimeout)
+}
+
+// CallWithin is Call with the whole exchange, connecting included, limited
+// to timeout. A daemon that doesn't answer in time returns an error other
+// than ErrNotRunning.
+func CallWithin(req Request, result any, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	path, err := SocketPath()
+	if err != nil {
+		return ErrNotRunning
+	}
+	conn, err := net.DialTimeout("unix", path, min(dialTimeout, timeout))
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
@@ -0,0 +1,132 @@
+// Package scanhooks runs the user's shell commands around workspace scans,
+// passing a JSON description of the event on stdin.
+package scanhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// Events, named as in the config
+const (
+	PreScan    = "pre_scan"
+	PostScan   = "post_scan"
+	DirtyFound = "on_dirty_found"
+)
+
+// timeout bounds a hook so a hung script can't stall scans
+const timeout = time.Minute
+
+// maxOutput is the number of trailing output bytes logged for a failed hook
+const maxOutput = 2000
+
+// Payload is written to a hook's stdin as JSON
+type Payload struct {
+	Event       string                  `json:"event"`
+	Workspace   string                  `json:"workspace"`
+	Alias       string                  `json:"alias,omitempty"` // Alias of the workspace profile, if it has one
+	Time        time.Time               `json:"time"`
+	Status      string                  `json:"status,omitempty"`      // post_scan: "full" or "skipped (unchanged)"
+	DurationMS  int64                   `json:"duration_ms,omitempty"` // post_scan
+	Counts      *Counts                 `json:"counts,omitempty"`      // post_scan
+	Directories []scanner.DirectoryInfo `json:"directories,omitempty"` // on_dirty_found: the newly dirty repositories
+}
+
+// Counts summarizes a scan for post_scan hooks
+type Counts struct {
+	Directories  int `json:"directories"`
+	Dirty        int `json:"dirty"`
+	Missing      int `json:"missing"`
+	Errored      int `json:"errored"`       // Directories whose metadata collection was abandoned
+	AccessDenied int `json:"access_denied"` // Directories that couldn't be read
+}
+
+// Command returns the command configured for event, or ""
+func Command(hooks config.HooksConfig, event string) string {
+	switch event {
+	case PreScan:
+		return hooks.PreScan
+	case PostScan:
+		return hooks.PostScan
+	case DirtyFound:
+		return hooks.OnDirtyFound
+	}
+	return ""
+}
+
+// Run runs the hook configured for payload.Event, if any, in the workspace
+// with payload on stdin and THANDIE_EVENT and THANDIE_WORKSPACE set. Hooks
+// only observe scans: a failing hook is returned as an error for the caller
+// to log, and never stops the scan.
+func Run(hooks config.HooksConfig, payload Payload) error {
+	command := Command(hooks, payload.Event)
+	if command == "" {
+		return nil
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", payload.Event, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = payload.Workspace
+	cmd.Env = append(os.Environ(), "THANDIE_EVENT="+payload.Event, "THANDIE_WORKSPACE="+payload.Workspace, "THANDIE_WORKSPACE_ALIAS="+payload.Alias)
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	start := time.Now()
+	err = cmd.Run()
+	logger.Debug("scan hook finished", "event", payload.Event, "duration", time.Since(start), "output", output.String())
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook timed out after %s", payload.Event, timeout)
+	}
+	tail := strings.TrimSpace(output.String())
+	if len(tail) > maxOutput {
+		tail = "..." + tail[len(tail)-maxOutput:]
+	}
+	if tail != "" {
+		return fmt.Errorf("%s hook failed: %w: %s", payload.Event, err, tail)
+	}
+	return fmt.Errorf("%s hook failed: %w", payload.Event, err)
+}
+
+// NewlyDirty returns the repositories of infos with uncommitted changes that
+// were clean, or not scanned, in previous
+func NewlyDirty(infos, previous []scanner.DirectoryInfo) []scanner.DirectoryInfo {
+	wasDirty := make(map[string]bool, len(previous))
+	for _, info := range previous {
+		if info.GitMetadata != nil && info.GitMetadata.HasUncommitted {
+			wasDirty[info.Path] = true
+		}
+	}
+	var dirty []scanner.DirectoryInfo
+	for _, info := range infos {
+		if info.GitMetadata != nil && info.GitMetadata.HasUncommitted && !wasDirty[info.Path] {
+			dirty = append(dirty, info)
+		}
+	}
+	return dirty
+}
@@ -0,0 +1,11 @@
+package scanner
+
+// maxUnpushedCommits caps the number of unpushed commit subjects recorded per repository
+const maxUnpushedCommits = 20
+
+// CommitInfo is a short description of a commit
+type CommitInfo struct {
+	Hash    string `json:"hash"` // Abbreviated hash
+	Subject string `json:"subject"`
+	Merge   bool   `json:"merge,omitempty"`
+}
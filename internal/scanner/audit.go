@@ -0,0 +1,54 @@
+package scanner
+
+import "time"
+
+// Vulnerability severities, most severe first
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityModerate = "moderate"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// SeverityRank orders severities for sorting; lower is more severe
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 0
+	case SeverityHigh:
+		return 1
+	case SeverityModerate:
+		return 2
+	case SeverityLow:
+		return 3
+	}
+	return 4
+}
+
+// Audit holds the results of the last `thandie audit` run for a repository
+type Audit struct {
+	FetchedAt       time.Time       `json:"fetched_at"`
+	Tools           []string        `json:"tools,omitempty"`  // Tools that ran successfully
+	Counts          map[string]int  `json:"counts,omitempty"` // Vulnerabilities per severity
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+	Missing         []string        `json:"missing,omitempty"` // Applicable tools that aren't installed
+	Errors          []string        `json:"errors,omitempty"`  // Tools that failed to run
+}
+
+// Vulnerability is a single advisory affecting a repository's dependencies
+type Vulnerability struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Severity string `json:"severity"`
+	Summary  string `json:"summary,omitempty"`
+	Tool     string `json:"tool"`
+}
+
+// Total returns the number of vulnerabilities found
+func (a *Audit) Total() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.Vulnerabilities)
+}
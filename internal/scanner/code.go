@@ -0,0 +1,28 @@
+package scanner
+
+// CodeStats holds line counts per language for a repository
+type CodeStats struct {
+	Commit    string               `json:"commit"` // HEAD when counted; counts are reused until it changes
+	Languages map[string]LangStats `json:"languages"`
+	Truncated bool                 `json:"truncated,omitempty"` // The file limit was reached
+}
+
+// LangStats holds line counts for one language
+type LangStats struct {
+	Files    int `json:"files"`
+	Code     int `json:"code"`
+	Comments int `json:"comments"`
+	Blanks   int `json:"blanks"`
+}
+
+// TotalCode returns the number of code lines across all languages
+func (c *CodeStats) TotalCode() int {
+	if c == nil {
+		return 0
+	}
+	total := 0
+	for _, stats := range c.Languages {
+		total += stats.Code
+	}
+	return total
+}
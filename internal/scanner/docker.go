@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dockerSearchDepth is how many directory levels below the repository root
+// are searched for Dockerfiles and compose files
+const dockerSearchDepth = 2
+
+// Docker records the container definitions found in a directory and the
+// containers running from them when the directory was scanned
+type Docker struct {
+	Dockerfiles  []string `json:"dockerfiles,omitempty"`   // Paths relative to the directory
+	ComposeFiles []string `json:"compose_files,omitempty"` // Paths relative to the directory
+	Running      []string `json:"running,omitempty"`       // Names of running containers
+}
+
+// CollectDocker looks for Dockerfiles and compose files in dir and up to
+// dockerSearchDepth levels below it. It returns nil if there are none.
+func CollectDocker(dir string) *Docker {
+	docker := &Docker{}
+	walkDocker(dir, "", 0, docker)
+	if len(docker.Dockerfiles) == 0 && len(docker.ComposeFiles) == 0 {
+		return nil
+	}
+	return docker
+}
+
+func walkDocker(root, rel string, depth int, docker *Docker) {
+	entries, err := os.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(rel, name)
+		if entry.IsDir() {
+			if depth < dockerSearchDepth && !strings.HasPrefix(name, ".") && name != "node_modules" && name != "vendor" {
+				walkDocker(root, path, depth+1, docker)
+			}
+			continue
+		}
+		switch {
+		case isComposeFile(name):
+			docker.ComposeFiles = append(docker.ComposeFiles, path)
+		case isDockerfile(name):
+			docker.Dockerfiles = append(docker.Dockerfiles, path)
+		}
+	}
+}
+
+// isDockerfile matches Dockerfile, Dockerfile.<suffix>, <prefix>.Dockerfile and Containerfile
+func isDockerfile(name string) bool {
+	lower := strings.ToLower(name)
+	return lower == "dockerfile" || lower == "containerfile" ||
+		strings.HasPrefix(lower, "dockerfile.") || strings.HasSuffix(lower, ".dockerfile")
+}
+
+// isComposeFile matches compose.yaml, docker-compose.yml and overrides such as docker-compose.dev.yml
+func isComposeFile(name string) bool {
+	lower := strings.ToLower(name)
+	ext := filepath.Ext(lower)
+	if ext != ".yml" && ext != ".yaml" {
+		return false
+	}
+	return strings.HasPrefix(lower, "docker-compose") || strings.HasPrefix(lower, "compose.")
+}
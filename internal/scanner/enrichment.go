@@ -0,0 +1,43 @@
+package scanner
+
+import "time"
+
+// Enrichment holds data about a repository fetched from its hosting provider
+type Enrichment struct {
+	Provider       string    `json:"provider"`
+	Repo           string    `json:"repo"` // owner/name path on the provider
+	FetchedAt      time.Time `json:"fetched_at"`
+	DefaultBranch  string    `json:"default_branch,omitempty"`
+	CI             string    `json:"ci,omitempty"` // passing, failing, pending or none
+	OpenIssues     int       `json:"open_issues"`
+	AssignedIssues []Issue   `json:"assigned_issues,omitempty"` // Open issues assigned to the authenticated user
+	Error          string    `json:"error,omitempty"`           // Set when the last fetch failed
+}
+
+// Issue is a reference to an issue on the repository's provider
+type Issue struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// Fresh reports whether the enrichment was fetched less than ttl ago
+func (e *Enrichment) Fresh(ttl time.Duration) bool {
+	return e != nil && e.Error == "" && time.Since(e.FetchedAt) < ttl
+}
+
+// Ticket is an issue-tracker ticket referenced by the current branch name
+type Ticket struct {
+	Key       string    `json:"key"`
+	Tracker   string    `json:"tracker"`
+	Title     string    `json:"title,omitempty"`
+	Status    string    `json:"status,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Error     string    `json:"error,omitempty"` // Set when the last lookup failed
+}
+
+// Fresh reports whether the ticket was fetched less than ttl ago
+func (t *Ticket) Fresh(ttl time.Duration) bool {
+	return t != nil && t.Error == "" && time.Since(t.FetchedAt) < ttl
+}
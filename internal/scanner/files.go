@@ -0,0 +1,87 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repository files tracked for compliance reporting
+const (
+	FileLicense    = "license"
+	FileReadme     = "readme"
+	FileCodeowners = "codeowners"
+)
+
+// RepoFiles records which well-known files a directory contains. Each field
+// holds the path (relative to the directory) of the file found, or is empty
+// if the file is missing.
+type RepoFiles struct {
+	License    string `json:"license,omitempty"`
+	Readme     string `json:"readme,omitempty"`
+	Codeowners string `json:"codeowners,omitempty"`
+}
+
+// codeownersLocations are the places GitHub and GitLab look for CODEOWNERS
+var codeownersLocations = []string{"CODEOWNERS", ".github/CODEOWNERS", ".gitlab/CODEOWNERS", "docs/CODEOWNERS"}
+
+// Path returns the path recorded for the named file (see the File* constants)
+func (f *RepoFiles) Path(name string) string {
+	if f == nil {
+		return ""
+	}
+	switch name {
+	case FileLicense:
+		return f.License
+	case FileReadme:
+		return f.Readme
+	case FileCodeowners:
+		return f.Codeowners
+	}
+	return ""
+}
+
+// Has reports whether the named file (see the File* constants) is present
+func (f *RepoFiles) Has(name string) bool {
+	return f.Path(name) != ""
+}
+
+// IsKnownFile reports whether name is one of the File* constants
+func IsKnownFile(name string) bool {
+	switch name {
+	case FileLicense, FileReadme, FileCodeowners:
+		return true
+	}
+	return false
+}
+
+// CollectRepoFiles looks for a license, readme and CODEOWNERS file in dir
+func CollectRepoFiles(dir string) *RepoFiles {
+	files := &RepoFiles{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		upper := strings.ToUpper(name)
+		switch {
+		case files.License == "" && (strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || strings.HasPrefix(upper, "COPYING")):
+			files.License = name
+		case files.Readme == "" && strings.HasPrefix(upper, "README"):
+			files.Readme = name
+		}
+	}
+
+	for _, location := range codeownersLocations {
+		if info, err := os.Stat(filepath.Join(dir, location)); err == nil && !info.IsDir() {
+			files.Codeowners = location
+			break
+		}
+	}
+	return files
+}
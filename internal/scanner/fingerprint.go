@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+)
+
+// gitStatePaths are the files and directories, relative to the git
+// directory, whose modification times change when a repository's branches,
+// remotes or index do
+var gitStatePaths = []string{"HEAD", "index", "packed-refs", "FETCH_HEAD", "refs/heads", "refs/tags"}
+
+// Fingerprint cheaply summarizes the state of the planned directories using
+// modification times only: the workspace root, every planned directory and,
+// for repositories, the git index, HEAD and ref directories. Two equal
+// fingerprints mean that nothing was added, removed, committed, fetched or
+// staged in between. Edits to existing files in subdirectories are not
+// detected, so callers should still scan fully from time to time.
+func Fingerprint(root string, plan []PlanEntry) string {
+	h := sha256.New()
+	writeMtime(h, root)
+	for _, entry := range plan {
+		fmt.Fprintf(h, "%s\x00%t\x00", entry.Path, entry.Scan)
+		if !entry.Scan {
+			continue
+		}
+		writeMtime(h, entry.Path)
+
+		gitDir, err := gitprovider.GitDir(entry.Path)
+		if err != nil {
+			continue
+		}
+		// A linked worktree has its own HEAD and index, and shares the refs
+		// of the main git directory
+		commonDir := gitprovider.CommonDir(gitDir)
+		for _, rel := range gitStatePaths {
+			writeMtime(h, filepath.Join(gitDir, rel))
+			if commonDir != gitDir {
+				writeMtime(h, filepath.Join(commonDir, rel))
+			}
+		}
+		// Fetches update remote-tracking refs, one directory per remote
+		remotes, _ := os.ReadDir(filepath.Join(commonDir, "refs", "remotes"))
+		for _, remote := range remotes {
+			writeMtime(h, filepath.Join(commonDir, "refs", "remotes", remote.Name()))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// writeMtime adds the path and its modification time, or its absence, to h
+func writeMtime(h io.Writer, path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(h, "%s\x00-\x00", path)
+		return
+	}
+	fmt.Fprintf(h, "%s\x00%d\x00", path, fi.ModTime().UnixNano())
+}
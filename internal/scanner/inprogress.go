@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// Operations a repository can be in the middle of, see GitMetadata.InProgress
+const (
+	OpMerge      = "merge"
+	OpRebase     = "rebase"
+	OpCherryPick = "cherry-pick"
+	OpRevert     = "revert"
+	OpBisect     = "bisect"
+)
+
+// inProgress returns the operation the repository whose git directory is
+// gitDir is in the middle of, from the state files git keeps there until it
+// is finished or aborted, or "" if there is none. A rebase stopped at a
+// conflict also has the cherry-pick or merge files of the commit it stopped
+// at, so it is checked first.
+func inProgress(gitDir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+	switch {
+	case exists("rebase-merge"), exists("rebase-apply"):
+		return OpRebase
+	case exists("MERGE_HEAD"):
+		return OpMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return OpCherryPick
+	case exists("REVERT_HEAD"):
+		return OpRevert
+	case exists("BISECT_LOG"):
+		return OpBisect
+	}
+	return ""
+}
+
+// maxConflictFiles is the number of conflicted files recorded per repository
+const maxConflictFiles = 50
+
+// conflictedFiles returns the files with unresolved conflicts in the index of
+// the repository whose git directory is gitDir, in index (path) order: those
+// with entries of the merge stages (ancestor, ours, theirs) rather than a
+// resolved one. Read from the index rather than the status, which go-git
+// reports as modified.
+func conflictedFiles(gitDir string) []string {
+	f, err := os.Open(filepath.Join(gitDir, "index"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var idx index.Index
+	if err := index.NewDecoder(f).Decode(&idx); err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range idx.Entries {
+		// Resolved entries are stage 0 (go-git's index.Merged is 1, the
+		// common ancestor stage), and the stages of a file are adjacent
+		if entry.Stage != 0 && (len(files) == 0 || files[len(files)-1] != entry.Name) {
+			files = append(files, entry.Name)
+		}
+	}
+	return files
+}
@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+)
+
+// Queue returns the paths of the directories plan marks for scanning, in
+// the order ScanPlanned should scan them: those at or below first, in the
+// order of first, then the others in scanOrder given the previous scan's
+// results
+func Queue(plan []PlanEntry, previous []DirectoryInfo, first []string) []string {
+	var dirs []string
+	for _, entry := range plan {
+		if entry.Scan {
+			dirs = append(dirs, entry.Path)
+		}
+	}
+	prior := make(map[string]*GitMetadata, len(previous))
+	for _, info := range previous {
+		prior[info.Path] = info.GitMetadata
+	}
+	ordered := make([]string, len(dirs))
+	for n, i := range scanOrder(dirs, prior) {
+		ordered[n] = dirs[i]
+	}
+
+	queue := make([]string, 0, len(dirs))
+	queued := make(map[string]bool, len(dirs))
+	for _, dir := range first {
+		for _, path := range ordered {
+			if !queued[path] && within(path, dir) {
+				queue = append(queue, path)
+				queued[path] = true
+			}
+		}
+	}
+	for _, path := range ordered {
+		if !queued[path] {
+			queue = append(queue, path)
+		}
+	}
+	return queue
+}
+
+// within reports whether path is dir or below it
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// scanOrder returns the indexes of dirs in the order they should be scanned:
+// repositories that had uncommitted changes or were in the middle of a merge,
+// rebase or the like in the previous scan (prior) first, then the others by most recent git activity. On a long scan this
+// gets the repositories most likely to matter to progress consumers first;
+// results keep the order of dirs regardless.
+func scanOrder(dirs []string, prior map[string]*GitMetadata) []int {
+	order := make([]int, len(dirs))
+	activity := make([]time.Time, len(dirs))
+	for i, dir := range dirs {
+		order[i] = i
+		activity[i] = lastActivity(dir)
+	}
+	wasDirty := func(i int) bool {
+		prev := prior[dirs[i]]
+		return prev != nil && (prev.HasUncommitted || prev.InProgress != "")
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if wasDirty(i) != wasDirty(j) {
+			return wasDirty(i)
+		}
+		return activity[i].After(activity[j])
+	})
+	return order
+}
+
+// lastActivity returns when git last touched the repository at dirPath: the
+// later of the index (staging, checkouts) and the HEAD reflog (commits,
+// merges, rebases) modification times. Zero for directories that aren't
+// repositories.
+func lastActivity(dirPath string) time.Time {
+	gitDir, err := gitprovider.GitDir(dirPath)
+	if err != nil {
+		return time.Time{}
+	}
+	var latest time.Time
+	for _, name := range []string{"index", filepath.Join("logs", "HEAD")} {
+		if fi, err := os.Stat(filepath.Join(gitDir, name)); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
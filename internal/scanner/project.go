@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// projectMarkers maps files found at a repository root to the language or
+// toolchain they indicate
+var projectMarkers = []struct {
+	file     string
+	language string
+}{
+	{"go.mod", "go"},
+	{"Cargo.toml", "rust"},
+	{"package.json", "node"},
+	{"pyproject.toml", "python"},
+	{"setup.py", "python"},
+	{"requirements.txt", "python"},
+	{"pom.xml", "java"},
+	{"build.gradle", "java"},
+	{"build.gradle.kts", "java"},
+	{"Gemfile", "ruby"},
+	{"composer.json", "php"},
+	{"mix.exs", "elixir"},
+}
+
+// DetectLanguages returns the languages a directory's project files
+// indicate, e.g. ["go", "node"], in marker order and without duplicates
+func DetectLanguages(dir string) []string {
+	var languages []string
+	seen := make(map[string]bool)
+	for _, marker := range projectMarkers {
+		if seen[marker.language] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			seen[marker.language] = true
+			languages = append(languages, marker.language)
+		}
+	}
+	return languages
+}
+
+// TestRun records the outcome of the last `thandie test` run in a repository
+type TestRun struct {
+	Commit     string        `json:"commit"`          // HEAD the tests ran against
+	Dirty      bool          `json:"dirty,omitempty"` // The worktree had uncommitted changes, so the result isn't reusable
+	Command    string        `json:"command"`
+	Passed     bool          `json:"passed"`
+	Duration   time.Duration `json:"duration"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Log        string        `json:"log"` // Path of the file holding the command's output
+}
@@ -0,0 +1,28 @@
+package scanner
+
+import "time"
+
+// Remote states recorded by `thandie remotes`
+const (
+	RemoteOK          = "ok"
+	RemoteGone        = "gone"        // Deleted, or no longer accessible
+	RemoteMoved       = "moved"       // Renamed or transferred; NewURL has the new location
+	RemoteArchived    = "archived"    // Read-only on the provider
+	RemoteUnreachable = "unreachable" // Could not be checked, e.g. while offline
+)
+
+// RemoteCheck holds the result of the last `thandie remotes` check of a
+// repository's origin remote
+type RemoteCheck struct {
+	CheckedAt time.Time `json:"checked_at"`
+	URL       string    `json:"url"` // The remote URL that was checked
+	State     string    `json:"state"`
+	NewURL    string    `json:"new_url,omitempty"` // Location of a moved repository, in the same form (SSH or HTTPS) as URL
+	Via       string    `json:"via"`               // Provider name, or git for `git ls-remote`
+	Error     string    `json:"error,omitempty"`
+}
+
+// NeedsAttention reports whether the remote is gone, moved or archived
+func (r *RemoteCheck) NeedsAttention() bool {
+	return r != nil && (r.State == RemoteGone || r.State == RemoteMoved || r.State == RemoteArchived)
+}
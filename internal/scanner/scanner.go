@@ -1,58 +1,197 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	"github.com/go-git/go-git/v5"
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+	"github.com/ThandieOps/thandie-agent/internal/mounts"
+	"github.com/ThandieOps/thandie-agent/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ListTopLevelDirs scans a directory and returns top-level directories,
 // respecting the provided scanner configuration
 func ListTopLevelDirs(path string, ignoreDirs []string, includeHidden bool) ([]string, error) {
-	entries, err := os.ReadDir(path)
+	return ListDirs(path, ignoreDirs, includeHidden, 1)
+}
+
+// ListDirs scans a directory and returns the directories that would be scanned
+// down to maxDepth levels. Git repositories are treated as leaves: their
+// subdirectories belong to the repository and are never listed separately.
+func ListDirs(path string, ignoreDirs []string, includeHidden bool, maxDepth int) ([]string, error) {
+	plan, err := PlanScan(path, ignoreDirs, includeHidden, maxDepth)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a map for faster lookups
-	ignoreMap := make(map[string]bool)
-	for _, dir := range ignoreDirs {
-		ignoreMap[dir] = true
+	var dirs []string
+	for _, entry := range plan {
+		if entry.Scan {
+			dirs = append(dirs, entry.Path)
+		}
+	}
+	return dirs, nil
+}
+
+// SkipReason explains why a directory is excluded from a scan
+type SkipReason string
+
+const (
+	SkipHidden  SkipReason = "hidden"
+	SkipIgnored SkipReason = "ignored"
+	SkipDepth   SkipReason = "depth"
+
+	SkipNotFound  SkipReason = "not found" // Listed by a workspace manifest but missing
+	SkipRequested SkipReason = "requested" // Skipped for one scan with scan --skip, see Skip
+)
+
+// PlanEntry describes a directory encountered while planning a scan and
+// whether it would be scanned
+type PlanEntry struct {
+	Path    string     `json:"path"`
+	Depth   int        `json:"depth"`
+	Scan    bool       `json:"scan"`
+	Reason  SkipReason `json:"reason,omitempty"`
+	Rule    string     `json:"rule,omitempty"`    // The ignore pattern or setting that excluded the directory
+	Network string     `json:"network,omitempty"` // Type of the network filesystem the directory is on, e.g. nfs; scanned light
+}
+
+// PlanScan walks the workspace without collecting any metadata and reports
+// every directory it encounters, marking which would be scanned and why the
+// others would be skipped. Directories one level below maxDepth are reported
+// as skipped for depth so that users can see what a deeper scan would pick up.
+// Directories on network filesystems are marked with their type.
+func PlanScan(path string, ignoreDirs []string, includeHidden bool, maxDepth int) ([]PlanEntry, error) {
+	if maxDepth < 1 {
+		maxDepth = 1
+	}
+
+	var plan []PlanEntry
+	if err := planDir(path, path, 1, ignoreDirs, includeHidden, maxDepth, &plan); err != nil {
+		return nil, err
+	}
+
+	MarkNetwork(plan)
+	return plan, nil
+}
+
+// MarkNetwork sets the network filesystem type of the planned directories
+// that are on one. Without a mount table, every directory is treated as local.
+func MarkNetwork(plan []PlanEntry) {
+	table, _ := mounts.Load()
+	for i := range plan {
+		if plan[i].Scan {
+			plan[i].Network = table.Network(plan[i].Path)
+		}
+	}
+}
+
+// planDir appends plan entries for the subdirectories of dir, which sits at
+// depth-1 below root
+func planDir(root, dir string, depth int, ignoreDirs []string, includeHidden bool, maxDepth int, plan *[]PlanEntry) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
 	}
 
-	var dirs []string
 	for _, e := range entries {
 		if !e.IsDir() {
 			continue
 		}
 
-		dirName := e.Name()
+		dirPath := filepath.Join(dir, e.Name())
+		entry := PlanEntry{Path: dirPath, Depth: depth}
 
-		// Skip hidden directories if includeHidden is false
-		if !includeHidden && strings.HasPrefix(dirName, ".") {
+		relPath, err := filepath.Rel(root, dirPath)
+		if err != nil {
+			relPath = e.Name()
+		}
+		if reason, rule := skipReason(e.Name(), relPath, ignoreDirs, includeHidden); reason != "" {
+			entry.Reason = reason
+			entry.Rule = rule
+			*plan = append(*plan, entry)
 			continue
 		}
 
-		// Skip directories in the ignore list
-		if ignoreMap[dirName] {
+		if depth > maxDepth {
+			entry.Reason = SkipDepth
+			entry.Rule = fmt.Sprintf("max_depth=%d", maxDepth)
+			*plan = append(*plan, entry)
 			continue
 		}
 
-		dirs = append(dirs, filepath.Join(path, dirName))
+		entry.Scan = true
+		*plan = append(*plan, entry)
+
+		// Descend into plain directories only; a repository's subdirectories
+		// are part of the repository itself. Unreadable subdirectories are
+		// left out rather than invalidating the rest of the plan.
+		if !IsGitRepository(dirPath) {
+			_ = planDir(root, dirPath, depth+1, ignoreDirs, includeHidden, maxDepth, plan)
+		}
 	}
-	return dirs, nil
+	return nil
+}
+
+// skipReason reports whether a directory should be skipped according to the
+// scanner configuration, along with the rule responsible. Ignore entries are
+// matched as glob patterns against the directory name, or against the path
+// relative to the workspace when the pattern contains a separator.
+func skipReason(name, relPath string, ignoreDirs []string, includeHidden bool) (SkipReason, string) {
+	if !includeHidden && strings.HasPrefix(name, ".") {
+		return SkipHidden, "include_hidden=false"
+	}
+
+	for _, pattern := range ignoreDirs {
+		target := name
+		if strings.ContainsRune(pattern, '/') {
+			target = filepath.ToSlash(relPath)
+		}
+		if pattern == target {
+			return SkipIgnored, pattern
+		}
+		if matched, err := filepath.Match(pattern, target); err == nil && matched {
+			return SkipIgnored, pattern
+		}
+	}
+
+	return "", ""
 }
 
 // GitMetadata represents git repository metadata for a directory
 type GitMetadata struct {
-	IsGitRepo      bool   `json:"is_git_repo"`
-	RemoteURL      string `json:"remote_url,omitempty"`
-	CurrentBranch  string `json:"current_branch,omitempty"`
-	HasUncommitted bool   `json:"has_uncommitted,omitempty"`
-	StatusSummary  string `json:"status_summary,omitempty"`
+	IsGitRepo      bool            `json:"is_git_repo"`
+	RemoteURL      string          `json:"remote_url,omitempty"`
+	UserEmail      string          `json:"user_email,omitempty"` // Effective user.email, i.e. the author of new commits; only collected when a group sets email_domains
+	CurrentBranch  string          `json:"current_branch,omitempty"`
+	Head           string          `json:"head,omitempty"`          // Commit hash HEAD points to
+	LastCommitAt   time.Time       `json:"last_commit_at,omitzero"` // Committer time of HEAD
+	LastSubject    string          `json:"last_subject,omitempty"`  // Subject line of HEAD
+	HasUncommitted bool            `json:"has_uncommitted,omitempty"`
+	DirtySince     time.Time       `json:"dirty_since,omitzero"` // First scan that saw uncommitted changes
+	StatusSummary  string          `json:"status_summary,omitempty"`
+	DirtyFiles     int             `json:"dirty_files,omitempty"`    // Files with uncommitted changes
+	IndexModTime   time.Time       `json:"index_mtime,omitzero"`     // Together with Head and StatusHash, decides whether the status can be reused
+	StatusHash     string          `json:"status_hash,omitempty"`    // See worktreeHash
+	Sparse         *SparseCheckout `json:"sparse,omitempty"`         // Set for sparse checkouts, whose status covers only the checked out files
+	InProgress     string          `json:"in_progress,omitempty"`    // Operation git is in the middle of (OpMerge, OpRebase, ...), or empty
+	Conflicts      int             `json:"conflicts,omitempty"`      // Files with unresolved conflicts
+	ConflictFiles  []string        `json:"conflict_files,omitempty"` // Paths of the conflicted files, capped at maxConflictFiles
+
+	Upstream        string       `json:"upstream,omitempty"` // Remote-tracking branch compared against, e.g. origin/main
+	Ahead           int          `json:"ahead,omitempty"`
+	Behind          int          `json:"behind,omitempty"`
+	UnpushedCommits []CommitInfo `json:"unpushed_commits,omitempty"` // Newest first, capped at maxUnpushedCommits
 }
 
 // IsGitRepository checks if a directory contains a git repository
@@ -62,11 +201,89 @@ func IsGitRepository(dirPath string) bool {
 	return err == nil && info.IsDir()
 }
 
-// CollectGitMetadata collects git metadata for a directory using go-git
+// gitProvider reads the repositories the scanner collects metadata for
+var gitProvider gitprovider.Provider = gitprovider.GoGit{}
+
+// SetGitProvider sets how the scanner reads git repositories (go-git by
+// default). Not safe to call while a scan is running.
+func SetGitProvider(p gitprovider.Provider) {
+	gitProvider = p
+}
+
+// collectEmail is whether scans record each repository's user.email
+var collectEmail bool
+
+// SetCollectEmail sets whether scans record each repository's user.email,
+// which takes a git process per repository, so only when something checks
+// it. Not safe to call while a scan is running.
+func SetCollectEmail(enabled bool) {
+	collectEmail = enabled
+}
+
+// GitProvider returns the provider set by SetGitProvider, for commands that
+// read repositories the way the scanner does
+func GitProvider() gitprovider.Provider {
+	return gitProvider
+}
+
+// CollectGitMetadata collects git metadata for a directory
 // Returns metadata with IsGitRepo=false if the directory is not a git repository
 func CollectGitMetadata(dirPath string) (*GitMetadata, error) {
-	// Try to open the repository using go-git
-	repo, err := git.PlainOpen(dirPath)
+	return collectGitMetadata(context.Background(), dirPath, nil, true)
+}
+
+// RemoteURL returns the URL of the origin remote of the repository at
+// dirPath (or of its first remote), or "" if it has none
+func RemoteURL(dirPath string) string {
+	repo, err := gitProvider.OpenRepo(dirPath)
+	if err != nil {
+		return ""
+	}
+	return remoteURL(repo)
+}
+
+// DefaultBranch returns the branch refs/remotes/origin/HEAD points to (set
+// by git clone or 'git remote set-head'), or "" if it isn't set
+func DefaultBranch(dirPath string) string {
+	repo, err := gitProvider.OpenRepo(dirPath)
+	if err != nil {
+		return ""
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return ""
+	}
+	for _, remote := range remotes {
+		if remote.Name == "origin" {
+			return remote.Head
+		}
+	}
+	return ""
+}
+
+// remoteURL returns the URL of repo's origin remote, falling back to the
+// first remote
+func remoteURL(repo gitprovider.Repo) string {
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return ""
+	}
+	for _, remote := range remotes {
+		if remote.Name == "origin" && len(remote.URLs) > 0 {
+			return remote.URLs[0]
+		}
+	}
+	if len(remotes) > 0 && len(remotes[0].URLs) > 0 {
+		return remotes[0].URLs[0]
+	}
+	return ""
+}
+
+// collectGitMetadata collects git metadata for a directory, reusing the
+// worktree status of prev, the previous scan's metadata, if it still applies.
+// Without withStatus, the worktree status is not collected at all.
+func collectGitMetadata(ctx context.Context, dirPath string, prev *GitMetadata, withStatus bool) (*GitMetadata, error) {
+	repo, err := gitProvider.OpenRepo(dirPath)
 	if err != nil {
 		// Not a git repository or can't be opened
 		return &GitMetadata{IsGitRepo: false}, nil
@@ -76,68 +293,70 @@ func CollectGitMetadata(dirPath string) (*GitMetadata, error) {
 		IsGitRepo: true,
 	}
 
-	// Get remote URL (prefer origin)
-	remotes, err := repo.Remotes()
-	if err == nil {
-		for _, remote := range remotes {
-			if remote.Config().Name == "origin" {
-				urls := remote.Config().URLs
-				if len(urls) > 0 {
-					metadata.RemoteURL = urls[0]
-					break
-				}
-			}
-		}
-		// If no origin found, use the first remote
-		if metadata.RemoteURL == "" && len(remotes) > 0 {
-			urls := remotes[0].Config().URLs
-			if len(urls) > 0 {
-				metadata.RemoteURL = urls[0]
-			}
+	metadata.RemoteURL = remoteURL(repo)
+	if collectEmail {
+		metadata.UserEmail = userEmail(ctx, dirPath)
+	}
+	// Worktrees and submodules keep their state outside <dir>/.git
+	if gitDir, err := gitprovider.GitDir(dirPath); err == nil {
+		metadata.Sparse = sparseCheckout(ctx, dirPath, gitDir)
+		if metadata.InProgress = inProgress(gitDir); metadata.InProgress != "" {
+			files := conflictedFiles(gitDir)
+			metadata.Conflicts = len(files)
+			metadata.ConflictFiles = files[:min(len(files), maxConflictFiles)]
 		}
 	}
 
 	// Get current branch
 	head, err := repo.Head()
 	if err == nil {
-		metadata.CurrentBranch = head.Name().Short()
+		metadata.CurrentBranch = head.Branch
+		metadata.Head = head.Hash
+		metadata.LastCommitAt = head.Commit.Time
+		metadata.LastSubject = head.Commit.Subject
+	}
+
+	// Compare the current branch with its upstream
+	if err == nil && !head.Detached && head.Upstream != "" {
+		_, span := tracing.Start(ctx, "git.upstream")
+		collectUpstreamState(repo, head, metadata)
+		span.End()
+	}
+
+	if !withStatus {
+		return metadata, nil
 	}
 
-	// Get git status (uncommitted changes)
-	worktree, err := repo.Worktree()
+	// Get git status (uncommitted changes). Status is expensive on large
+	// repositories, so skip it when nothing it depends on has changed.
+	_, span := tracing.Start(ctx, "git.status")
+	defer span.End()
+	metadata.IndexModTime = indexModTime(dirPath)
+	metadata.StatusHash = worktreeHash(dirPath)
+	reused := reuseStatus(metadata, prev)
+	span.SetAttributes(attribute.Bool("reused", reused))
+	if reused {
+		return metadata, nil
+	}
+
+	status, err := repo.Status()
 	if err == nil {
-		status, err := worktree.Status()
-		if err == nil {
-			metadata.HasUncommitted = !status.IsClean()
-
-			// Build status summary similar to git status --porcelain format
-			if !status.IsClean() {
-				var statusLines []string
-				count := 0
-				for file, fileStatus := range status {
-					if count >= 5 {
-						break
-					}
-					// Format: XY filename (X = index status, Y = worktree status)
-					// StatusCode.String() returns the single character code
-					stagingCode := string(fileStatus.Staging)
-					worktreeCode := string(fileStatus.Worktree)
-					statusLine := fmt.Sprintf("%s%s %s", stagingCode, worktreeCode, file)
-					statusLines = append(statusLines, statusLine)
-					count++
-				}
-
-				if len(statusLines) > 0 {
-					metadata.StatusSummary = strings.Join(statusLines, "; ")
-					totalFiles := len(status)
-					if totalFiles > 5 {
-						metadata.StatusSummary += fmt.Sprintf(" ... (%d more)", totalFiles-5)
-					}
-				} else {
-					metadata.StatusSummary = "clean"
-				}
-			} else {
-				metadata.StatusSummary = "clean"
+		metadata.HasUncommitted = len(status) > 0
+		metadata.DirtyFiles = len(status)
+		metadata.StatusSummary = "clean"
+		if metadata.HasUncommitted {
+			// Replaced by the previous scan's time if it was already dirty then
+			metadata.DirtySince = time.Now()
+
+			// Build status summary similar to git status --porcelain format:
+			// XY filename (X = index status, Y = worktree status)
+			var statusLines []string
+			for _, file := range status[:min(len(status), 5)] {
+				statusLines = append(statusLines, fmt.Sprintf("%c%c %s", file.Staging, file.Worktree, file.Path))
+			}
+			metadata.StatusSummary = strings.Join(statusLines, "; ")
+			if len(status) > 5 {
+				metadata.StatusSummary += fmt.Sprintf(" ... (%d more)", len(status)-5)
 			}
 		}
 	}
@@ -145,33 +364,317 @@ func CollectGitMetadata(dirPath string) (*GitMetadata, error) {
 	return metadata, nil
 }
 
+// userEmail returns the user.email git uses for commits in dirPath, or "" if
+// none is set. Asks git rather than go-git, which ignores the includeIf
+// sections commonly used to set a work email per directory.
+func userEmail(ctx context.Context, dirPath string) string {
+	cmd := exec.CommandContext(ctx, "git", "config", "--get", "user.email")
+	cmd.Dir = dirPath
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// collectUpstreamState records the upstream branch, ahead/behind counts and
+// unpushed commit subjects. Failures leave the fields unset.
+func collectUpstreamState(repo gitprovider.Repo, head gitprovider.Head, metadata *GitMetadata) {
+	ahead, err := repo.Log(head.Hash, head.Upstream)
+	if err != nil {
+		return
+	}
+	behind, err := repo.Log(head.Upstream, head.Hash)
+	if err != nil {
+		return
+	}
+
+	metadata.Upstream = head.Upstream
+	metadata.Ahead = len(ahead)
+	metadata.Behind = len(behind)
+	for _, c := range ahead[:min(len(ahead), maxUnpushedCommits)] {
+		metadata.UnpushedCommits = append(metadata.UnpushedCommits, CommitInfo{
+			Hash:    c.Hash[:min(len(c.Hash), 7)],
+			Subject: c.Subject,
+			Merge:   c.Merge(),
+		})
+	}
+}
+
 // DirectoryInfo represents metadata about a directory
 type DirectoryInfo struct {
-	Path        string       `json:"path"`
-	GitMetadata *GitMetadata `json:"git_metadata,omitempty"`
+	Path         string       `json:"path"`
+	GitMetadata  *GitMetadata `json:"git_metadata,omitempty"`
+	Enrichment   *Enrichment  `json:"enrichment,omitempty"`
+	Ticket       *Ticket      `json:"ticket,omitempty"` // Ticket referenced by the current branch name
+	Files        *RepoFiles   `json:"files,omitempty"`
+	Docker       *Docker      `json:"docker,omitempty"` // Container definitions, if any
+	Audit        *Audit       `json:"audit,omitempty"`  // Set by `thandie audit`
+	Extras       *Extras      `json:"extras,omitempty"`
+	Languages    []string     `json:"languages,omitempty"`     // Detected from project files, see DetectLanguages
+	Tests        *TestRun     `json:"tests,omitempty"`         // Set by `thandie test`
+	Remote       *RemoteCheck `json:"remote,omitempty"`        // Set by `thandie remotes`
+	ScanError    string       `json:"scan_error,omitempty"`    // Why metadata collection was abandoned (timeout or crash); GitMetadata is then the previous scan's and the other fields are unset
+	Network      string       `json:"network,omitempty"`       // Network filesystem the directory is on; git status was not collected
+	AccessDenied bool         `json:"access_denied,omitempty"` // The directory or its .git can't be read; the other fields are then unset
+	Unscanned    bool         `json:"unscanned,omitempty"`     // Skipped by the last scan (see SkipRequested); the other fields are from the scan before
 }
 
-// ScanDirectoriesWithMetadata scans a directory and returns top-level directories
-// with their git metadata, respecting the provided scanner configuration
-func ScanDirectoriesWithMetadata(path string, ignoreDirs []string, includeHidden bool) ([]DirectoryInfo, error) {
-	dirs, err := ListTopLevelDirs(path, ignoreDirs, includeHidden)
+// Extras holds tool-specific metadata that only some directories have
+type Extras struct {
+	Terraform *Terraform `json:"terraform,omitempty"`
+	Code      *CodeStats `json:"code,omitempty"` // Set when scanner.loc is enabled
+}
+
+// collectExtras gathers the tool-specific metadata for dir, or nil if there is none
+func collectExtras(ctx context.Context, dir string, isGitRepo bool) *Extras {
+	extras := &Extras{
+		Terraform: CollectTerraform(ctx, dir, isGitRepo),
+	}
+	if extras.Terraform == nil {
+		return nil
+	}
+	return extras
+}
+
+// networkTimeoutFactor multiplies the per-directory timeout on network
+// filesystems, where even a light scan is slower
+const networkTimeoutFactor = 3
+
+// ScanDirectoriesWithMetadata scans a directory and returns directories down to
+// maxDepth with their git metadata, respecting the provided scanner configuration
+func ScanDirectoriesWithMetadata(path string, ignoreDirs []string, includeHidden bool, maxDepth int) ([]DirectoryInfo, error) {
+	dirs, err := ListDirs(path, ignoreDirs, includeHidden, maxDepth)
 	if err != nil {
 		return nil, err
 	}
 
-	infos := make([]DirectoryInfo, len(dirs))
-	for i, dir := range dirs {
-		gitMetadata, err := CollectGitMetadata(dir)
-		if err != nil {
-			// If metadata collection fails, still include the directory but without metadata
-			infos[i] = DirectoryInfo{Path: dir}
-			continue
+	return CollectDirectoryInfos(dirs, 1, 0), nil
+}
+
+// ScanPlanned collects metadata for the directories marked for scanning in plan,
+// in the order of queue (see Queue; nil for the default order), using up to
+// concurrency parallel workers and giving up on a directory after
+// timeout (see ScanError), or never if timeout is 0. Directories on network
+// filesystems are scanned light: without git status, which reads every file
+// of the worktree, and with networkTimeoutFactor times the timeout. Git status is reused from previous, the last
+// scan's results, for repositories that haven't changed. Directories skipped
+// with SkipRequested keep their previous results, marked Unscanned. Results
+// keep the order of plan. done, if not nil,
+// is called with each directory's path as it finishes, possibly from several
+// goroutines at once.
+func ScanPlanned(ctx context.Context, plan []PlanEntry, queue []string, concurrency int, timeout time.Duration, previous []DirectoryInfo, done func(path string)) []DirectoryInfo {
+	if queue == nil {
+		queue = Queue(plan, previous, nil)
+	}
+	var dirs []string
+	index := make(map[string]int)
+	network := make(map[string]string)
+	for _, entry := range plan {
+		if entry.Scan {
+			index[entry.Path] = len(dirs)
+			dirs = append(dirs, entry.Path)
+			if entry.Network != "" {
+				network[entry.Path] = entry.Network
+			}
 		}
-		infos[i] = DirectoryInfo{
-			Path:        dir,
-			GitMetadata: gitMetadata,
+	}
+	order := make([]int, 0, len(dirs))
+	for _, path := range queue {
+		if i, ok := index[path]; ok {
+			order = append(order, i)
+			delete(index, path)
+		}
+	}
+	for _, i := range index {
+		order = append(order, i) // Not in queue; scanned last
+	}
+	scanned := collectDirectoryInfos(ctx, dirs, order, concurrency, timeout, network, previous, done)
+
+	prevInfos := make(map[string]DirectoryInfo, len(previous))
+	for _, info := range previous {
+		prevInfos[info.Path] = info
+	}
+	infos := make([]DirectoryInfo, 0, len(scanned))
+	for _, entry := range plan {
+		switch {
+		case entry.Scan:
+			infos = append(infos, scanned[0])
+			scanned = scanned[1:]
+		case entry.Reason == SkipRequested:
+			if info, ok := prevInfos[entry.Path]; ok {
+				info.Unscanned = true
+				infos = append(infos, info)
+			}
+		}
+	}
+	return infos
+}
+
+// CollectDirectoryInfos collects git metadata for each of the given directories
+// using up to concurrency parallel workers, giving up on a directory after
+// timeout as ScanPlanned does. Results keep the order of dirs.
+func CollectDirectoryInfos(dirs []string, concurrency int, timeout time.Duration) []DirectoryInfo {
+	return collectDirectoryInfos(context.Background(), dirs, scanOrder(dirs, nil), concurrency, timeout, nil, nil, nil)
+}
+
+// collectDirectoryInfos is CollectDirectoryInfos scanning the directories in
+// the order of order, indexes of dirs, scanning those in network, mapped to
+// their filesystem type, light, reusing git status from previous and
+// calling done, if not nil, after each directory
+func collectDirectoryInfos(ctx context.Context, dirs []string, order []int, concurrency int, timeout time.Duration, network map[string]string, previous []DirectoryInfo, done func(path string)) []DirectoryInfo {
+	prior := make(map[string]*GitMetadata, len(previous))
+	for _, info := range previous {
+		prior[info.Path] = info.GitMetadata
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	infos := make([]DirectoryInfo, len(dirs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, i := range order {
+		dir := dirs[i]
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, dir string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			infos[i] = collectIsolated(ctx, dir, prior[dir], timeout, network[dir])
+			if done != nil {
+				done(dir)
+			}
+		}(i, dir)
+	}
+	wg.Wait()
+
+	return infos
+}
+
+// maxAbandoned caps the timed-out collections still running in the
+// background. go-git can't be interrupted, so on a hanging mount each keeps
+// its goroutine until the mount recovers; past the cap, directories are
+// skipped rather than leaving more calls stuck, including across the
+// daemon's rescans.
+const maxAbandoned = 16
+
+// abandoned counts the timed-out collections still running
+var abandoned atomic.Int32
+
+// collectIsolated runs collectDirectoryInfo so that a pathological
+// directory (a corrupt repository, a hanging network mount) can't crash or
+// stall the scan: a panic or running past timeout abandons the directory
+// with ScanError set and prev, the previous scan's git metadata, kept. An
+// abandoned collection keeps running in the background until it returns,
+// and while maxAbandoned of them are, directories are skipped the same way.
+// A directory on a network filesystem of type network is scanned light.
+func collectIsolated(ctx context.Context, dir string, prev *GitMetadata, timeout time.Duration, network string) DirectoryInfo {
+	if network != "" {
+		timeout *= networkTimeoutFactor
+	}
+	if n := abandoned.Load(); n >= maxAbandoned {
+		return abandonedInfo(dir, prev, network, fmt.Sprintf("skipped: %d directories that timed out are still being read", n))
+	}
+	// Kills the git processes of a collection abandoned below
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make(chan DirectoryInfo, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- abandonedInfo(dir, prev, network, fmt.Sprintf("crashed: %v", r))
+			}
+		}()
+		result <- collectDirectoryInfo(ctx, dir, prev, network)
+	}()
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	select {
+	case info := <-result:
+		return info
+	case <-deadline:
+		abandoned.Add(1)
+		go func() {
+			<-result
+			abandoned.Add(-1)
+		}()
+		return abandonedInfo(dir, prev, network, fmt.Sprintf("timed out after %s", timeout))
+	}
+}
+
+// abandonedInfo is the result for a directory whose collection was abandoned
+// for reason. It keeps prev, the previous scan's git metadata, so that a
+// repository isn't listed as a plain directory until it can be read again.
+func abandonedInfo(dir string, prev *GitMetadata, network, reason string) DirectoryInfo {
+	return DirectoryInfo{Path: dir, GitMetadata: prev, ScanError: reason, Network: network}
+}
+
+// collectDirectoryInfo collects metadata for a single directory, given its
+// git metadata from the previous scan, if any, and the type of the network
+// filesystem it is on, if any
+func collectDirectoryInfo(ctx context.Context, dir string, prev *GitMetadata, network string) DirectoryInfo {
+	ctx, span := tracing.Start(ctx, "scan.directory", attribute.String("path", dir), attribute.String("network", network))
+	defer span.End()
+
+	if accessDenied(dir) || accessDenied(filepath.Join(dir, ".git")) {
+		span.SetAttributes(attribute.Bool("access_denied", true))
+		return DirectoryInfo{Path: dir, Network: network, AccessDenied: true}
+	}
+
+	info := DirectoryInfo{
+		Path:      dir,
+		Files:     CollectRepoFiles(dir),
+		Docker:    CollectDocker(dir),
+		Languages: DetectLanguages(dir),
+		Network:   network,
+	}
+
+	// If metadata collection fails, still include the directory but without metadata
+	if gitMetadata, err := collectGitMetadata(ctx, dir, prev, network == ""); err == nil {
+		info.GitMetadata = gitMetadata
+	}
+	info.Extras = collectExtras(ctx, dir, info.GitMetadata != nil && info.GitMetadata.IsGitRepo)
+	return info
+}
+
+// accessDenied reports whether listing the directory at path fails for lack
+// of permission. A missing path is not denied.
+func accessDenied(path string) bool {
+	f, err := os.Open(path)
+	if err == nil {
+		_, err = f.Readdirnames(1)
+		f.Close()
+	}
+	return errors.Is(err, fs.ErrPermission)
+}
+
+// Skip marks the directories of plan at or below any of dirs as skipped with
+// SkipRequested, for a scan that should leave them alone this time
+func Skip(plan []PlanEntry, dirs []string) {
+	for i, entry := range plan {
+		for _, dir := range dirs {
+			if entry.Scan && within(entry.Path, dir) {
+				plan[i].Scan, plan[i].Reason, plan[i].Rule = false, SkipRequested, "--skip "+dir
+				break
+			}
 		}
 	}
+}
 
-	return infos, nil
+// Skipped returns the plan entries that were excluded from the scan
+func Skipped(plan []PlanEntry) []PlanEntry {
+	var skipped []PlanEntry
+	for _, entry := range plan {
+		if !entry.Scan {
+			skipped = append(skipped, entry)
+		}
+	}
+	return skipped
 }
@@ -0,0 +1,330 @@
+package scanner_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/pkg/scannertest"
+)
+
+func TestScanRepositoryStates(t *testing.T) {
+	w := scannertest.New(t,
+		scannertest.Repo{Name: "clean", Remote: "https://github.com/example/clean.git"},
+		scannertest.Repo{Name: "dirty", Dirty: true},
+		scannertest.Repo{Name: "detached", Detached: true, Commits: 2},
+		scannertest.Repo{Name: "develop", Branch: "develop"},
+		scannertest.Repo{Name: "notes", Plain: true},
+		scannertest.Repo{Name: "parent", Submodules: []string{"clean"}},
+	)
+	infos, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	byName := make(map[string]scanner.DirectoryInfo)
+	for _, info := range infos {
+		byName[filepath.Base(info.Path)] = info
+	}
+	if len(byName) != len(w.Repos) {
+		t.Fatalf("scanned %d directories, want %d", len(byName), len(w.Repos))
+	}
+
+	tests := []struct {
+		name      string
+		repo      bool
+		branch    string
+		remote    string
+		dirty     bool
+		dirtyFile int
+	}{
+		{name: "clean", repo: true, branch: "main", remote: "https://github.com/example/clean.git"},
+		{name: "dirty", repo: true, branch: "main", dirty: true, dirtyFile: 1},
+		{name: "detached", repo: true, branch: "HEAD"},
+		{name: "develop", repo: true, branch: "develop"},
+		{name: "notes"},
+		{name: "parent", repo: true, branch: "main"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := byName[tt.name]
+			git := info.GitMetadata
+			if git == nil || git.IsGitRepo != tt.repo {
+				t.Fatalf("IsGitRepo = %v, want %v", git != nil && git.IsGitRepo, tt.repo)
+			}
+			if !tt.repo {
+				return
+			}
+			if git.CurrentBranch != tt.branch {
+				t.Errorf("CurrentBranch = %q, want %q", git.CurrentBranch, tt.branch)
+			}
+			if git.RemoteURL != tt.remote {
+				t.Errorf("RemoteURL = %q, want %q", git.RemoteURL, tt.remote)
+			}
+			if git.HasUncommitted != tt.dirty || git.DirtyFiles != tt.dirtyFile {
+				t.Errorf("HasUncommitted, DirtyFiles = %v, %d, want %v, %d", git.HasUncommitted, git.DirtyFiles, tt.dirty, tt.dirtyFile)
+			}
+			for _, r := range w.Repos {
+				if r.Name == tt.name && !git.LastCommitAt.Equal(r.LastCommitAt()) {
+					t.Errorf("LastCommitAt = %v, want %v", git.LastCommitAt, r.LastCommitAt())
+				}
+			}
+		})
+	}
+}
+
+func TestScanReusesStatus(t *testing.T) {
+	w := scannertest.New(t, scannertest.Mixed(14)...)
+	first, err := w.Scan()
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	plan, err := scanner.PlanScan(w.Root, nil, false, 1)
+	if err != nil {
+		t.Fatalf("PlanScan: %v", err)
+	}
+	second := scanner.ScanPlanned(t.Context(), plan, nil, 4, 0, first, nil)
+	if len(second) != len(first) {
+		t.Fatalf("rescan found %d directories, want %d", len(second), len(first))
+	}
+	for i := range first {
+		a, b := first[i].GitMetadata, second[i].GitMetadata
+		if a == nil || b == nil {
+			continue
+		}
+		if a.HasUncommitted != b.HasUncommitted || a.StatusSummary != b.StatusSummary || a.DirtyFiles != b.DirtyFiles {
+			t.Errorf("%s: rescan status %v %q %d differs from %v %q %d", first[i].Path,
+				b.HasUncommitted, b.StatusSummary, b.DirtyFiles, a.HasUncommitted, a.StatusSummary, a.DirtyFiles)
+		}
+	}
+}
+
+// hangingProvider blocks opening any repository until release is closed,
+// like go-git on a hanging mount, after sending on opened
+type hangingProvider struct {
+	opened  chan struct{}
+	release chan struct{}
+}
+
+func (p hangingProvider) OpenRepo(dir string) (gitprovider.Repo, error) {
+	p.opened <- struct{}{}
+	<-p.release
+	return nil, gitprovider.ErrNotRepository
+}
+
+func TestScanTimeoutKeepsPreviousMetadata(t *testing.T) {
+	provider := hangingProvider{opened: make(chan struct{}, 1), release: make(chan struct{})}
+	scanner.SetGitProvider(provider)
+	defer scanner.SetGitProvider(gitprovider.GoGit{})
+	defer func() {
+		// Let the abandoned collection finish before restoring the provider
+		<-provider.opened
+		close(provider.release)
+	}()
+
+	dir := t.TempDir()
+	prev := &scanner.GitMetadata{IsGitRepo: true, CurrentBranch: "main"}
+	previous := []scanner.DirectoryInfo{{Path: dir, GitMetadata: prev}}
+	infos := scanner.ScanPlanned(t.Context(), []scanner.PlanEntry{{Path: dir, Scan: true}}, nil, 1, 20*time.Millisecond, previous, nil)
+	if len(infos) != 1 {
+		t.Fatalf("ScanPlanned returned %d directories, want 1", len(infos))
+	}
+	if infos[0].ScanError == "" {
+		t.Error("ScanError is empty, want the timeout")
+	}
+	if infos[0].GitMetadata != prev {
+		t.Errorf("GitMetadata = %+v, want the previous scan's", infos[0].GitMetadata)
+	}
+}
+
+func TestCollectGitMetadataFromProvider(t *testing.T) {
+	at := func(hour int) time.Time { return scannertest.Epoch.Add(time.Duration(hour) * time.Hour) }
+	repo := &gitprovider.FakeRepo{
+		HeadState: gitprovider.Head{Branch: "main", Hash: "c3", Upstream: "origin/main"},
+		RemoteSet: []gitprovider.Remote{{Name: "origin", URLs: []string{"https://github.com/example/fake.git"}, Head: "main"}},
+		Commits: []gitprovider.Commit{
+			{Hash: "c1", Subject: "Start", Time: at(0)},
+			{Hash: "c2", Subject: "Local work", Time: at(1), Parents: []string{"c1"}},
+			{Hash: "c3", Subject: "Merge upstream", Time: at(3), Parents: []string{"c2", "u1"}},
+			{Hash: "u1", Subject: "Upstream work", Time: at(2), Parents: []string{"c1"}},
+			{Hash: "u2", Subject: "More upstream work", Time: at(4), Parents: []string{"u1"}},
+		},
+		Refs: map[string]string{"origin/main": "u2"},
+	}
+	for i := range 6 {
+		repo.Files = append(repo.Files, gitprovider.FileStatus{Path: fmt.Sprintf("file%d", i), Staging: ' ', Worktree: 'M'})
+	}
+	scanner.SetGitProvider(gitprovider.Fake{Repos: map[string]*gitprovider.FakeRepo{"/fake": repo}})
+	defer scanner.SetGitProvider(gitprovider.GoGit{})
+
+	git, err := scanner.CollectGitMetadata("/fake")
+	if err != nil {
+		t.Fatalf("CollectGitMetadata: %v", err)
+	}
+	if !git.IsGitRepo || git.CurrentBranch != "main" || git.RemoteURL != "https://github.com/example/fake.git" {
+		t.Errorf("IsGitRepo, CurrentBranch, RemoteURL = %v, %q, %q", git.IsGitRepo, git.CurrentBranch, git.RemoteURL)
+	}
+	if git.LastSubject != "Merge upstream" || !git.LastCommitAt.Equal(at(3)) {
+		t.Errorf("LastSubject, LastCommitAt = %q, %v", git.LastSubject, git.LastCommitAt)
+	}
+	if git.Upstream != "origin/main" || git.Ahead != 2 || git.Behind != 1 {
+		t.Errorf("Upstream, Ahead, Behind = %q, %d, %d, want origin/main, 2, 1", git.Upstream, git.Ahead, git.Behind)
+	}
+	wantUnpushed := []scanner.CommitInfo{{Hash: "c3", Subject: "Merge upstream", Merge: true}, {Hash: "c2", Subject: "Local work"}}
+	if !reflect.DeepEqual(git.UnpushedCommits, wantUnpushed) {
+		t.Errorf("UnpushedCommits = %+v, want %+v", git.UnpushedCommits, wantUnpushed)
+	}
+	wantSummary := " M file0;  M file1;  M file2;  M file3;  M file4 ... (1 more)"
+	if !git.HasUncommitted || git.DirtyFiles != 6 || git.StatusSummary != wantSummary {
+		t.Errorf("HasUncommitted, DirtyFiles, StatusSummary = %v, %d, %q, want true, 6, %q", git.HasUncommitted, git.DirtyFiles, git.StatusSummary, wantSummary)
+	}
+	if branch := scanner.DefaultBranch("/fake"); branch != "main" {
+		t.Errorf("DefaultBranch = %q, want main", branch)
+	}
+
+	if git, _ := scanner.CollectGitMetadata("/elsewhere"); git.IsGitRepo {
+		t.Error("a directory the provider doesn't know is a git repository")
+	}
+}
+
+func TestSparseCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	w := scannertest.New(t, scannertest.Repo{Name: "full"}, scannertest.Repo{Name: "mono"})
+	// go-git leaves out the format version, without which git ignores the
+	// config.worktree that sparse-checkout writes
+	for _, args := range [][]string{
+		{"config", "core.repositoryformatversion", "1"},
+		{"sparse-checkout", "set", "--cone", "services/api", "libs"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", w.Dir("mono")}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+
+	for name, want := range map[string]*scanner.SparseCheckout{
+		"full": nil,
+		"mono": {Cone: true, Patterns: []string{"libs", "services/api"}},
+	} {
+		metadata, err := scanner.CollectGitMetadata(w.Dir(name))
+		if err != nil {
+			t.Fatalf("%s: CollectGitMetadata: %v", name, err)
+		}
+		if !reflect.DeepEqual(metadata.Sparse, want) {
+			t.Errorf("%s: Sparse = %+v, want %+v", name, metadata.Sparse, want)
+		}
+		if metadata.HasUncommitted {
+			t.Errorf("%s: status %q, want clean", name, metadata.StatusSummary)
+		}
+	}
+}
+
+func TestOperationInProgress(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	w := scannertest.New(t,
+		scannertest.Repo{Name: "idle", Commits: 3},
+		scannertest.Repo{Name: "bisecting", Commits: 3},
+		scannertest.Repo{Name: "merging", Files: map[string]string{"a.txt": "base\n", "b.txt": "base\n"}},
+	)
+	git := func(name string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = w.Dir(name)
+		// A failed merge exits 1 with the conflict left in place
+		if out, err := cmd.CombinedOutput(); err != nil && args[0] != "merge" {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+	git("bisecting", "bisect", "start", "HEAD", "HEAD~2")
+	git("merging", "checkout", "-q", "-b", "side")
+	write(t, w.Dir("merging"), "a.txt", "side\n")
+	write(t, w.Dir("merging"), "b.txt", "side\n")
+	git("merging", "commit", "-q", "-am", "Change on side")
+	git("merging", "checkout", "-q", "main")
+	write(t, w.Dir("merging"), "a.txt", "main\n")
+	write(t, w.Dir("merging"), "b.txt", "main\n")
+	git("merging", "commit", "-q", "-am", "Change on main")
+	git("merging", "merge", "side")
+
+	// A linked worktree keeps its merge state in .git/worktrees/<name> of
+	// the main one
+	worktree := filepath.Join(t.TempDir(), "worktree")
+	git("idle", "worktree", "add", "-q", "-b", "other", worktree, "HEAD~1")
+	git("idle", "-C", worktree, "merge", "-q", "--no-commit", "--no-ff", "main")
+
+	for name, want := range map[string]struct {
+		op        string
+		conflicts int
+	}{
+		"idle":      {"", 0},
+		"bisecting": {scanner.OpBisect, 0},
+		"merging":   {scanner.OpMerge, 2},
+		worktree:    {scanner.OpMerge, 0},
+	} {
+		dir := name
+		if !filepath.IsAbs(dir) {
+			dir = w.Dir(name)
+		}
+		metadata, err := scanner.CollectGitMetadata(dir)
+		if err != nil {
+			t.Fatalf("%s: CollectGitMetadata: %v", name, err)
+		}
+		if metadata.InProgress != want.op || metadata.Conflicts != want.conflicts || len(metadata.ConflictFiles) != want.conflicts {
+			t.Errorf("%s: InProgress, Conflicts = %q, %d, want %q, %d", name, metadata.InProgress, metadata.Conflicts, want.op, want.conflicts)
+		}
+	}
+}
+
+// write replaces the content of a file of a repository
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSkipAndFirst(t *testing.T) {
+	root := t.TempDir()
+	var plan []scanner.PlanEntry
+	for _, name := range []string{"a", "b", "b/nested", "c"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		plan = append(plan, scanner.PlanEntry{Path: filepath.Join(root, name), Scan: true})
+	}
+	previous := []scanner.DirectoryInfo{{Path: filepath.Join(root, "b"), Languages: []string{"go"}}}
+
+	scanner.Skip(plan, []string{filepath.Join(root, "b")})
+	if plan[1].Scan || plan[1].Reason != scanner.SkipRequested || plan[2].Scan || !plan[3].Scan {
+		t.Fatalf("plan after Skip = %+v", plan)
+	}
+
+	queue := scanner.Queue(plan, previous, []string{filepath.Join(root, "c")})
+	if want := []string{filepath.Join(root, "c"), filepath.Join(root, "a")}; !reflect.DeepEqual(queue, want) {
+		t.Errorf("Queue = %v, want %v", queue, want)
+	}
+
+	var scanned []string
+	infos := scanner.ScanPlanned(t.Context(), plan, queue, 1, 0, previous, func(path string) { scanned = append(scanned, path) })
+	if !reflect.DeepEqual(scanned, queue) {
+		t.Errorf("scanned %v, want the order of the queue %v", scanned, queue)
+	}
+	var paths []string
+	for _, info := range infos {
+		paths = append(paths, filepath.Base(info.Path))
+	}
+	if !reflect.DeepEqual(paths, []string{"a", "b", "c"}) {
+		t.Fatalf("ScanPlanned returned %v, want a, b (unscanned) and c", paths)
+	}
+	if !infos[1].Unscanned || !reflect.DeepEqual(infos[1].Languages, []string{"go"}) {
+		t.Errorf("skipped directory = %+v, want its previous results marked Unscanned", infos[1])
+	}
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SparseCheckout describes the sparse checkout of a repository. Only the
+// files it selects are in the worktree, so its status says nothing about the
+// others.
+type SparseCheckout struct {
+	Cone     bool     `json:"cone,omitempty"`     // Patterns are directories (git sparse-checkout --cone)
+	Patterns []string `json:"patterns,omitempty"` // Directories checked out in cone mode, gitignore-style patterns otherwise
+}
+
+// sparseCheckout returns the sparse checkout of the repository at dirPath,
+// whose git directory is gitDir, or nil if it has none. Repositories without
+// a sparse-checkout file aren't sparse, whatever the config says; for the
+// others it asks git, like userEmail, as core.sparseCheckout may be set in
+// config.worktree or an included file.
+func sparseCheckout(ctx context.Context, dirPath, gitDir string) *SparseCheckout {
+	patternsPath := filepath.Join(gitDir, "info", "sparse-checkout")
+	if _, err := os.Stat(patternsPath); err != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "config", "--get-regexp", `^core\.sparsecheckout`)
+	cmd.Dir = dirPath
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	settings := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		settings[strings.ToLower(key)] = strings.EqualFold(value, "true")
+	}
+	if !settings["core.sparsecheckout"] {
+		return nil
+	}
+
+	sparse := &SparseCheckout{Cone: settings["core.sparsecheckoutcone"]}
+	patterns := readSparsePatterns(patternsPath)
+	if sparse.Cone {
+		sparse.Patterns = coneDirectories(patterns)
+	} else {
+		sparse.Patterns = patterns
+	}
+	return sparse
+}
+
+// readSparsePatterns returns the patterns of a sparse-checkout file, without
+// blank lines and comments
+func readSparsePatterns(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// coneDirectories returns the directories checked out in full by cone mode
+// patterns, as `git sparse-checkout list` does. Cone mode writes "/dir/" for
+// each such directory and for its parents, which are followed by "!/dir/*/"
+// to take only their files.
+func coneDirectories(patterns []string) []string {
+	excluded := make(map[string]bool)
+	for _, p := range patterns {
+		if dir, ok := strings.CutPrefix(p, "!"); ok {
+			excluded[strings.TrimSuffix(dir, "*/")] = true
+		}
+	}
+	var dirs []string
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") || p == "/*" || !strings.HasSuffix(p, "/") || excluded[p] {
+			continue
+		}
+		dirs = append(dirs, strings.Trim(p, "/"))
+	}
+	return dirs
+}
@@ -0,0 +1,91 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/gitprovider"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// indexModTime returns the modification time of the repository's index, or
+// the zero time if it has none
+func indexModTime(dirPath string) time.Time {
+	gitDir, err := gitprovider.GitDir(dirPath)
+	if err != nil {
+		return time.Time{}
+	}
+	fi, err := os.Stat(filepath.Join(gitDir, "index"))
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
+// worktreeHash hashes the path, size, mode and modification time of every
+// file in the worktree that isn't ignored. Git status depends only on these,
+// HEAD and the index, so an unchanged hash means an unchanged status.
+// Returns "" if the worktree can't be walked.
+func worktreeHash(dirPath string) string {
+	patterns, _ := gitignore.ReadPatterns(osfs.New(dirPath), nil)
+	matcher := gitignore.NewMatcher(patterns)
+
+	h := sha256.New()
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dirPath {
+			return nil
+		}
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if matcher.Match(strings.Split(filepath.ToSlash(rel), "/"), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\x00%d\x00", rel, fi.Size(), fi.Mode(), fi.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// reuseStatus copies the worktree status from prev if the repository's HEAD,
+// index and worktree files are unchanged since it was collected. Without an
+// index time there is no telling whether changes were staged, so the status
+// is never reused then.
+func reuseStatus(metadata, prev *GitMetadata) bool {
+	if prev == nil || metadata.StatusHash == "" || metadata.IndexModTime.IsZero() || prev.StatusHash != metadata.StatusHash ||
+		prev.Head != metadata.Head || !prev.IndexModTime.Equal(metadata.IndexModTime) {
+		return false
+	}
+	metadata.HasUncommitted = prev.HasUncommitted
+	metadata.StatusSummary = prev.StatusSummary
+	metadata.DirtyFiles = prev.DirtyFiles
+	metadata.DirtySince = prev.DirtySince
+	return true
+}
@@ -0,0 +1,152 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// terraformSearchDepth is how many directory levels below the repository
+// root are searched for Terraform configurations
+const terraformSearchDepth = 3
+
+// Drift check outcomes
+const (
+	DriftNone    = "none"    // Infrastructure matches the configuration
+	DriftDrifted = "drifted" // `terraform plan` reported changes
+	DriftError   = "error"   // The plan could not be run
+)
+
+// Terraform describes the Terraform configurations found in a directory
+type Terraform struct {
+	Modules          []TerraformModule `json:"modules"`
+	UncommittedState []string          `json:"uncommitted_state,omitempty"` // *.tfstate files that git would commit
+}
+
+// TerraformModule is a directory containing *.tf files
+type TerraformModule struct {
+	Path           string    `json:"path"`                 // Relative to the repository
+	Workspace      string    `json:"workspace"`            // Currently selected workspace
+	Workspaces     []string  `json:"workspaces,omitempty"` // Non-default workspaces with local state
+	Backend        string    `json:"backend,omitempty"`    // Backend type from `terraform init`, e.g. s3
+	LocalState     bool      `json:"local_state"`          // terraform.tfstate exists
+	Initialized    bool      `json:"initialized"`          // .terraform exists
+	DriftCheckedAt time.Time `json:"drift_checked_at,omitzero"`
+	Drift          string    `json:"drift,omitempty"` // none, drifted or error
+	DriftError     string    `json:"drift_error,omitempty"`
+}
+
+// HighRisk reports whether the directory has state files that could be
+// committed by accident. State files routinely contain secrets.
+func (t *Terraform) HighRisk() bool {
+	return t != nil && len(t.UncommittedState) > 0
+}
+
+// CollectTerraform looks for Terraform configurations in dir and up to
+// terraformSearchDepth levels below it. It returns nil if there are none.
+// ctx bounds the git call that looks for uncommitted state.
+func CollectTerraform(ctx context.Context, dir string, isGitRepo bool) *Terraform {
+	tf := &Terraform{}
+	walkTerraform(dir, "", 0, tf)
+	if len(tf.Modules) == 0 {
+		return nil
+	}
+	if isGitRepo {
+		tf.UncommittedState = uncommittedState(ctx, dir)
+	}
+	return tf
+}
+
+func walkTerraform(root, rel string, depth int, tf *Terraform) {
+	dir := filepath.Join(root, rel)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	isModule := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			if depth < terraformSearchDepth && !strings.HasPrefix(name, ".") && name != "node_modules" && name != "vendor" {
+				walkTerraform(root, filepath.Join(rel, name), depth+1, tf)
+			}
+			continue
+		}
+		if strings.HasSuffix(name, ".tf") {
+			isModule = true
+		}
+	}
+	if isModule {
+		tf.Modules = append(tf.Modules, collectTerraformModule(dir, rel))
+	}
+}
+
+func collectTerraformModule(dir, rel string) TerraformModule {
+	if rel == "" {
+		rel = "."
+	}
+	module := TerraformModule{Path: rel, Workspace: "default"}
+
+	if data, err := os.ReadFile(filepath.Join(dir, ".terraform", "environment")); err == nil {
+		module.Workspace = strings.TrimSpace(string(data))
+	}
+	if info, err := os.Stat(filepath.Join(dir, ".terraform")); err == nil && info.IsDir() {
+		module.Initialized = true
+	}
+	if _, err := os.Stat(filepath.Join(dir, "terraform.tfstate")); err == nil {
+		module.LocalState = true
+	}
+
+	// `terraform init` records the configured backend here
+	if data, err := os.ReadFile(filepath.Join(dir, ".terraform", "terraform.tfstate")); err == nil {
+		var backend struct {
+			Backend struct {
+				Type string `json:"type"`
+			} `json:"backend"`
+		}
+		if json.Unmarshal(data, &backend) == nil {
+			module.Backend = backend.Backend.Type
+		}
+	}
+
+	if entries, err := os.ReadDir(filepath.Join(dir, "terraform.tfstate.d")); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				module.Workspaces = append(module.Workspaces, entry.Name())
+			}
+		}
+		sort.Strings(module.Workspaces)
+	}
+	return module
+}
+
+// uncommittedState lists state files that are untracked or modified and not
+// ignored, i.e. that `git add -A` would commit
+func uncommittedState(ctx context.Context, dir string) []string {
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "-uall", "--", "*.tfstate", "*.tfstate.backup")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if len(line) <= 3 {
+			continue
+		}
+		file := strings.Trim(line[3:], `"`)
+		// .terraform/terraform.tfstate holds backend settings written by `terraform init`, not state
+		if strings.HasPrefix(file, ".terraform/") || strings.Contains(file, "/.terraform/") {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
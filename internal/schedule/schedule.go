@@ -0,0 +1,111 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/config"
+)
+
+// jobAliases expands the short job names to thandie command lines
+var jobAliases = map[string]string{
+	"scan":   "scan --if-changed",
+	"report": "report summary --email",
+	"notify": "notify --alerts",
+}
+
+// Job is a thandie command run on a cron schedule
+type Job struct {
+	Name string // Job as written in the config, e.g. scan
+	Cron *Cron
+	Args []string // thandie arguments, e.g. [report summary --email]
+}
+
+// Load parses the configured schedule
+func Load(entries []config.ScheduleEntry) ([]Job, error) {
+	jobs := make([]Job, 0, len(entries))
+	for i, entry := range entries {
+		if strings.TrimSpace(entry.Job) == "" {
+			return nil, fmt.Errorf("schedule[%d]: job is required", i)
+		}
+		cron, err := Parse(entry.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule[%d]: %w", i, err)
+		}
+		command := entry.Job
+		if alias, ok := jobAliases[command]; ok {
+			command = alias
+		}
+		jobs = append(jobs, Job{Name: entry.Job, Cron: cron, Args: strings.Fields(command)})
+	}
+	return jobs, nil
+}
+
+// heavyCommands are the thandie commands deferred by the power settings
+var heavyCommands = map[string]bool{"scan": true, "audit": true, "test": true, "terraform": true}
+
+// Heavy reports whether the job is expensive enough to defer on battery,
+// metered connections or while the user is active
+func (j Job) Heavy() bool {
+	return len(j.Args) > 0 && heavyCommands[j.Args[0]]
+}
+
+// unsafeChars are replaced in log file names
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// LogPath returns the log file of the job in logDir
+func (j Job) LogPath(logDir string) string {
+	return filepath.Join(logDir, strings.Trim(unsafeChars.ReplaceAllString(j.Name, "-"), "-")+".log")
+}
+
+// Run runs the job as `exe globalArgs... j.Args...`, appending its output to
+// the job's log file in logDir. It returns the command's exit code, or an
+// error if the command could not be started.
+func (j Job) Run(ctx context.Context, exe string, globalArgs []string, logDir string) (int, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	logFile, err := os.OpenFile(j.LogPath(logDir), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open job log: %w", err)
+	}
+	defer logFile.Close()
+
+	args := append(append([]string{}, globalArgs...), j.Args...)
+	start := time.Now()
+	fmt.Fprintf(logFile, "=== %s thandie %s\n", start.Format(time.RFC3339), strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	err = cmd.Run()
+
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		fmt.Fprintf(logFile, "=== failed to run: %v\n", err)
+		return 0, err
+	}
+	fmt.Fprintf(logFile, "=== exit %d after %s\n\n", code, time.Since(start).Round(time.Millisecond))
+	return code, nil
+}
+
+// LogDir returns the directory holding per-job logs
+func LogDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jobs"), nil
+}
@@ -0,0 +1,127 @@
+// Package seal encrypts thandie's cache files at rest with AES-256-GCM, for
+// users whose workspace paths and remote URLs are themselves confidential.
+// The key is generated on first use and kept in the OS keychain.
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+)
+
+// KeyAlias is the keychain alias of the encryption key
+const KeyAlias = "cache-key"
+
+// magic prefixes sealed files so they can be told apart from plain ones
+var magic = []byte("thandie-sealed-v1\n")
+
+var (
+	enabled bool
+
+	loadOnce sync.Once
+	aead     cipher.AEAD
+	loadErr  error
+)
+
+// Enable makes Seal encrypt. Call it before any file is sealed or opened.
+func Enable() {
+	enabled = true
+}
+
+// Enabled reports whether Seal encrypts
+func Enabled() bool {
+	return enabled
+}
+
+// IsSealed reports whether data was written by Seal with encryption enabled
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Seal encrypts data when encryption is enabled and returns it unchanged otherwise
+func Seal(data []byte) ([]byte, error) {
+	if !enabled {
+		return data, nil
+	}
+	gcm, err := key()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, magic...), nonce...)
+	return gcm.Seal(out, nonce, data, nil), nil
+}
+
+// Open decrypts data written by Seal. Plain data is returned unchanged, so
+// files written before encryption was enabled stay readable; sealed files
+// stay readable after it is disabled as long as the key is in the keychain.
+func Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return data, nil
+	}
+	gcm, err := key()
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(magic):]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted file is truncated")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt file (was the %q keychain key replaced?): %w", KeyAlias, err)
+	}
+	return plain, nil
+}
+
+// key returns the cipher of the keychain key, creating the key if
+// encryption is enabled and there is none yet
+func key() (cipher.AEAD, error) {
+	loadOnce.Do(func() {
+		var raw []byte
+		if raw, loadErr = keychainKey(enabled); loadErr != nil {
+			return
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			loadErr = fmt.Errorf("invalid %q key in keychain: %w", KeyAlias, err)
+			return
+		}
+		aead, loadErr = cipher.NewGCM(block)
+	})
+	return aead, loadErr
+}
+
+// keychainKey reads the 256-bit key from the keychain, generating and
+// storing one if there is none and create is true
+func keychainKey(create bool) ([]byte, error) {
+	encoded, err := secrets.Get(KeyAlias)
+	if errors.Is(err, secrets.ErrNotFound) && create {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate encryption key: %w", err)
+		}
+		if err := secrets.Set(KeyAlias, base64.StdEncoding.EncodeToString(raw)); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cache files are encrypted but the key is unavailable: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(raw) != 32 {
+		return nil, fmt.Errorf("invalid %q key in keychain", KeyAlias)
+	}
+	return raw, nil
+}
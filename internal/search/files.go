@@ -0,0 +1,165 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/logger"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+)
+
+// log logs under the scanner component, as indexing is part of a scan
+var log = logger.For("scanner")
+
+// fileIndex is the cached list of tracked files of a repository
+type fileIndex struct {
+	Head  string   `json:"head"` // Commit the list was taken at
+	Files []string `json:"files"`
+}
+
+// IndexDir returns the directory holding the file indexes
+func IndexDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "files"), nil
+}
+
+// indexPath returns the index file of the repository at dir. Encrypted
+// indexes are named by hash only so the name doesn't reveal the repository.
+func indexPath(indexDir, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	if seal.Enabled() {
+		return filepath.Join(indexDir, hex.EncodeToString(sum[:8])+".json")
+	}
+	return filepath.Join(indexDir, fmt.Sprintf("%s-%s.json", filepath.Base(dir), hex.EncodeToString(sum[:4])))
+}
+
+// Files returns the paths, relative to the repository root, of the files
+// tracked by the repository at dir when HEAD was head. The list is read from
+// the index if it was taken at head, and otherwise listed and indexed.
+func Files(indexDir, dir, head string) ([]string, error) {
+	path := indexPath(indexDir, dir)
+	if data, err := os.ReadFile(path); err == nil {
+		var index fileIndex
+		if data, err = seal.Open(data); err == nil && json.Unmarshal(data, &index) == nil && head != "" && index.Head == head {
+			return index.Files, nil
+		}
+	}
+
+	files, err := trackedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if head == "" {
+		return files, nil
+	}
+	data, err := json.Marshal(fileIndex{Head: head, Files: files})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal file index: %w", err)
+	}
+	if data, err = seal.Seal(data); err != nil {
+		return nil, fmt.Errorf("failed to encrypt file index: %w", err)
+	}
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create file index directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write file index: %w", err)
+	}
+	return files, nil
+}
+
+// Index refreshes the file index of every git repository in infos whose HEAD
+// moved since it was indexed, at most concurrency at a time
+func Index(infos []scanner.DirectoryInfo, concurrency int) {
+	indexDir, err := IndexDir()
+	if err != nil {
+		log.Warn("failed to locate file index directory", "error", err)
+		return
+	}
+
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for _, info := range infos {
+		if info.GitMetadata == nil || !info.GitMetadata.IsGitRepo || info.GitMetadata.Head == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if _, err := Files(indexDir, info.Path, info.GitMetadata.Head); err != nil {
+				log.Warn("failed to index files", "path", info.Path, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Prune removes the file indexes of repositories other than repos, e.g.
+// after their workspace caches were garbage collected, and returns how many
+// files it removed and their size. With dryRun nothing is removed.
+func Prune(indexDir string, repos []string, dryRun bool) (int, int64, error) {
+	entries, err := os.ReadDir(indexDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read file index directory: %w", err)
+	}
+
+	// Indexes are named differently with encryption on; keep either name
+	keep := make(map[string]bool)
+	for _, dir := range repos {
+		sum := sha256.Sum256([]byte(dir))
+		keep[hex.EncodeToString(sum[:8])+".json"] = true
+		keep[fmt.Sprintf("%s-%s.json", filepath.Base(dir), hex.EncodeToString(sum[:4]))] = true
+	}
+
+	removed, size := 0, int64(0)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" || keep[entry.Name()] {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(filepath.Join(indexDir, entry.Name())); err != nil {
+				return removed, size, fmt.Errorf("failed to remove file index: %w", err)
+			}
+		}
+		removed++
+		size += fi.Size()
+	}
+	return removed, size, nil
+}
+
+// trackedFiles lists the files tracked by the repository at dir
+func trackedFiles(dir string) ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00"), nil
+}
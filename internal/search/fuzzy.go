@@ -0,0 +1,87 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Scoring of fuzzy matches: every matched character scores, with bonuses for
+// runs of consecutive characters and for matches starting a path segment or
+// word, and a small penalty for long candidates so that shorter paths win ties
+const (
+	scoreMatch       = 16
+	bonusConsecutive = 24
+	bonusBoundary    = 32
+	bonusBasename    = 8
+	penaltyPerRune   = 1
+)
+
+// Candidate is a path considered by Rank
+type Candidate struct {
+	Repo string // Repository the file belongs to, relative to the workspace
+	Path string // File path relative to the repository root
+}
+
+// Ranked is a candidate that matched the query, with its score
+type Ranked struct {
+	Candidate
+	Score int
+}
+
+// Rank returns the candidates matching query, best first, keeping at most
+// limit (all if limit <= 0). A candidate matches if the characters of query
+// appear in order in "<repo>/<path>", ignoring case and spaces.
+func Rank(query string, candidates []Candidate, limit int) []Ranked {
+	query = strings.ToLower(strings.ReplaceAll(query, " ", ""))
+	var ranked []Ranked
+	for _, c := range candidates {
+		if score, ok := Score(query, c.Repo+"/"+c.Path); ok {
+			ranked = append(ranked, Ranked{Candidate: c, Score: score})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// Score reports whether the lower-cased query is a subsequence of target
+// and how well it matches. Characters are matched greedily from the end so
+// that matches prefer the file name over the directories leading to it.
+func Score(query, target string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	lower := strings.ToLower(target)
+	base := strings.LastIndexByte(lower, '/') + 1
+
+	score := 0
+	qi := len(query)
+	prevMatch := -1
+	for ti := len(lower); ti > 0 && qi > 0; {
+		r, size := utf8.DecodeLastRuneInString(lower[:ti])
+		q, qsize := utf8.DecodeLastRuneInString(query[:qi])
+		ti -= size
+		if r != q {
+			continue
+		}
+		qi -= qsize
+		score += scoreMatch
+		if prevMatch == ti+size {
+			score += bonusConsecutive
+		}
+		if ti == 0 || strings.ContainsRune("/._- ", rune(lower[ti-1])) {
+			score += bonusBoundary
+		}
+		if ti >= base {
+			score += bonusBasename
+		}
+		prevMatch = ti
+	}
+	if qi > 0 {
+		return 0, false
+	}
+	return score - penaltyPerRune*utf8.RuneCountInString(target), true
+}
@@ -0,0 +1,81 @@
+// Package search searches the tracked files of workspace repositories.
+package search
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// maxLineLength is the number of bytes of a matching line that are kept
+const maxLineLength = 300
+
+// Options controls how a pattern is matched
+type Options struct {
+	IgnoreCase bool // Match case-insensitively
+	Fixed      bool // Treat the pattern as a literal string instead of a regular expression
+}
+
+// Match is a line matching the pattern
+type Match struct {
+	File string `json:"file"` // Relative to the repository root
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// Grep searches the files tracked by the git repository at repoDir with git
+// grep, so untracked and ignored files are skipped. Binary files are
+// skipped. No matches is not an error.
+func Grep(ctx context.Context, repoDir, pattern string, opts Options) ([]Match, error) {
+	args := []string{"grep", "--null", "--line-number", "-I", "--no-color", "--extended-regexp"}
+	if opts.IgnoreCase {
+		args = append(args, "--ignore-case")
+	}
+	if opts.Fixed {
+		args = append(args, "--fixed-strings")
+	}
+	args = append(args, "-e", pattern)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// git grep exits with 1 when nothing matched
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return nil, nil
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git grep: %s", msg)
+		}
+		return nil, fmt.Errorf("git grep: %w", err)
+	}
+	return parse(out), nil
+}
+
+// parse parses git grep --null --line-number output: file NUL line NUL text
+func parse(out []byte) []Match {
+	var matches []Match
+	for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+		parts := strings.SplitN(line, "\x00", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(parts[2])
+		if len(text) > maxLineLength {
+			text = strings.ToValidUTF8(text[:maxLineLength], "") + "…"
+		}
+		matches = append(matches, Match{File: parts[0], Line: n, Text: text})
+	}
+	return matches
+}
@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// service is the keychain service name under which all Thandie secrets are stored
+const service = "thandie"
+
+// RefPrefix marks a config value as a reference to a keychain secret,
+// e.g. `token: keychain:github` refers to the secret stored under alias "github"
+const RefPrefix = "keychain:"
+
+// ErrNotFound is returned when no secret is stored under the requested alias
+var ErrNotFound = errors.New("secret not found")
+
+// Set stores a secret in the OS keychain under the given alias
+func Set(alias, value string) error {
+	if alias == "" {
+		return fmt.Errorf("secret alias must not be empty")
+	}
+	if err := keyring.Set(service, alias, value); err != nil {
+		return fmt.Errorf("failed to store secret %q in keychain: %w", alias, err)
+	}
+	return nil
+}
+
+// Get returns the secret stored in the OS keychain under the given alias
+func Get(alias string) (string, error) {
+	value, err := keyring.Get(service, alias)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, alias)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %q from keychain: %w", alias, err)
+	}
+	return value, nil
+}
+
+// Delete removes the secret stored under the given alias from the OS keychain
+func Delete(alias string) error {
+	err := keyring.Delete(service, alias)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, alias)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to remove secret %q from keychain: %w", alias, err)
+	}
+	return nil
+}
+
+// IsRef reports whether a config value refers to a keychain secret
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// Ref returns the config value that refers to the secret stored under alias
+func Ref(alias string) string {
+	return RefPrefix + alias
+}
+
+// Resolve returns the secret a config value refers to. Values without the
+// keychain: prefix are returned unchanged so plaintext tokens keep working.
+func Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	return Get(strings.TrimPrefix(value, RefPrefix))
+}
@@ -0,0 +1,161 @@
+// Package session opens tmux or zellij sessions for repositories.
+package session
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+)
+
+// Supported launchers
+const (
+	LauncherTmux   = "tmux"
+	LauncherZellij = "zellij"
+)
+
+// Session is a multiplexer session for a repository
+type Session struct {
+	Launcher string
+	Name     string // Session name, derived from the repository directory
+	Dir      string
+	Windows  []config.SessionWindow
+}
+
+// New returns the session of the repository at dir. Without windows the
+// session has a single shell window.
+func New(launcher, dir string, windows []config.SessionWindow) (*Session, error) {
+	switch launcher {
+	case "":
+		launcher = LauncherTmux
+	case LauncherTmux, LauncherZellij:
+	default:
+		return nil, fmt.Errorf("unknown session launcher %q (expected tmux or zellij)", launcher)
+	}
+	if len(windows) == 0 {
+		windows = []config.SessionWindow{{Name: "shell"}}
+	}
+	for i := range windows {
+		if windows[i].Name == "" {
+			windows[i].Name = strconv.Itoa(i + 1)
+		}
+	}
+	return &Session{Launcher: launcher, Name: Name(dir), Dir: dir, Windows: windows}, nil
+}
+
+// Name returns the session name of the repository at dir: its directory
+// name, with the characters tmux doesn't allow in session names replaced
+func Name(dir string) string {
+	return strings.NewReplacer(".", "_", ":", "_", " ", "_").Replace(filepath.Base(dir))
+}
+
+// Open attaches to the session, creating it with its windows first if it
+// doesn't exist. Inside tmux the client switches to the session instead.
+// With dryRun the commands are written to out instead of run.
+func (s *Session) Open(out io.Writer, dryRun bool) error {
+	if s.Launcher == LauncherZellij {
+		return s.openZellij(out, dryRun)
+	}
+	return s.openTmux(out, dryRun)
+}
+
+// openTmux creates the session with tmux commands and attaches to it
+func (s *Session) openTmux(out io.Writer, dryRun bool) error {
+	var steps [][]string
+	if dryRun || exec.Command("tmux", "has-session", "-t", "="+s.Name).Run() != nil {
+		for i, w := range s.Windows {
+			target := s.Name + ":" + w.Name
+			if i == 0 {
+				steps = append(steps, []string{"tmux", "new-session", "-d", "-s", s.Name, "-n", w.Name, "-c", s.Dir})
+			} else {
+				steps = append(steps, []string{"tmux", "new-window", "-t", s.Name + ":", "-n", w.Name, "-c", s.Dir})
+			}
+			if w.Command != "" {
+				steps = append(steps, []string{"tmux", "send-keys", "-t", target, w.Command, "Enter"})
+			}
+		}
+		steps = append(steps, []string{"tmux", "select-window", "-t", s.Name + ":" + s.Windows[0].Name})
+	}
+	if os.Getenv("TMUX") != "" {
+		steps = append(steps, []string{"tmux", "switch-client", "-t", s.Name})
+	} else {
+		steps = append(steps, []string{"tmux", "attach-session", "-t", s.Name})
+	}
+	return run(out, steps, dryRun)
+}
+
+// openZellij attaches to the session, creating it from a generated layout
+func (s *Session) openZellij(out io.Writer, dryRun bool) error {
+	if !dryRun {
+		if names, err := exec.Command("zellij", "list-sessions", "--short").Output(); err == nil {
+			for _, name := range strings.Fields(string(names)) {
+				if name == s.Name {
+					return run(out, [][]string{{"zellij", "attach", s.Name}}, false)
+				}
+			}
+		}
+	}
+
+	layout := s.zellijLayout()
+	if dryRun {
+		fmt.Fprintf(out, "# layout.kdl\n%s", layout)
+		return run(out, [][]string{{"zellij", "--session", s.Name, "--layout", "layout.kdl"}}, true)
+	}
+	file, err := os.CreateTemp("", "thandie-layout-*.kdl")
+	if err != nil {
+		return fmt.Errorf("failed to create zellij layout: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(layout); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write zellij layout: %w", err)
+	}
+	file.Close()
+	return run(out, [][]string{{"zellij", "--session", s.Name, "--layout", file.Name()}}, false)
+}
+
+// zellijLayout returns a KDL layout with one tab per window
+func (s *Session) zellijLayout() string {
+	var b strings.Builder
+	b.WriteString("layout {\n")
+	for _, w := range s.Windows {
+		fmt.Fprintf(&b, "    tab name=%s cwd=%s {\n", strconv.Quote(w.Name), strconv.Quote(s.Dir))
+		if w.Command != "" {
+			fmt.Fprintf(&b, "        pane command=\"sh\" {\n            args \"-c\" %s\n        }\n", strconv.Quote(w.Command))
+		} else {
+			b.WriteString("        pane\n")
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// run runs each command, attached to the terminal, stopping at the first
+// failure. With dryRun the commands are written to out instead.
+func run(out io.Writer, steps [][]string, dryRun bool) error {
+	for _, step := range steps {
+		if dryRun {
+			quoted := make([]string, len(step))
+			for i, arg := range step {
+				quoted[i] = arg
+				if strings.ContainsAny(arg, " \t'\"$") {
+					quoted[i] = strconv.Quote(arg)
+				}
+			}
+			fmt.Fprintln(out, strings.Join(quoted, " "))
+			continue
+		}
+		cmd := exec.Command(step[0], step[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %w", step[0], step[1], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package signing inspects the commit signing setup of git repositories and
+// checks it against a signing policy.
+package signing
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+)
+
+// Formats are the values of gpg.format
+var Formats = []string{"openpgp", "ssh", "x509"}
+
+// Status is the signing setup of a repository, as git sees it from there
+// (repository, global and system config combined)
+type Status struct {
+	Sign     bool   `json:"sign"`            // commit.gpgsign
+	Key      string `json:"key,omitempty"`   // user.signingkey
+	Format   string `json:"format"`          // gpg.format, openpgp if unset
+	Checked  int    `json:"checked"`         // Recent commits by user.email on HEAD that were inspected
+	Unsigned int    `json:"unsigned"`        // Of those, commits without a signature
+	Email    string `json:"email,omitempty"` // user.email the commits were matched against
+}
+
+// Inspect reads the signing config of the repository at dir and, if commits
+// is positive, whether the last commits commits on HEAD authored by
+// user.email are signed. Signatures are only checked for presence: a commit
+// signed with a key that can't be verified here still counts as signed.
+func Inspect(dir string, commits int) (*Status, error) {
+	s := &Status{Format: "openpgp"}
+	s.Sign = gitConfig(dir, "--type=bool", "commit.gpgsign") == "true"
+	s.Key = gitConfig(dir, "user.signingkey")
+	if format := gitConfig(dir, "gpg.format"); format != "" {
+		s.Format = format
+	}
+	s.Email = gitConfig(dir, "user.email")
+
+	if commits <= 0 || s.Email == "" {
+		return s, nil
+	}
+	if _, err := git(dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return s, nil // No commits yet
+	}
+	out, err := git(dir, "log", "-n", strconv.Itoa(commits), "--author=<"+s.Email+">", "--format=%G?")
+	if err != nil {
+		return nil, err
+	}
+	for _, mark := range strings.Fields(out) {
+		s.Checked++
+		if mark == "N" {
+			s.Unsigned++
+		}
+	}
+	return s, nil
+}
+
+// Violations lists how the repository violates policy, or nothing if it
+// complies
+func (s *Status) Violations(policy config.SigningConfig) []string {
+	var problems []string
+	if policy.Required {
+		if !s.Sign {
+			problems = append(problems, "commit.gpgsign is off")
+		}
+		if s.Key == "" {
+			problems = append(problems, "no user.signingkey")
+		}
+	}
+	if policy.Format != "" && s.Format != policy.Format {
+		problems = append(problems, fmt.Sprintf("gpg.format is %s, not %s", s.Format, policy.Format))
+	}
+	if policy.Commits > 0 && s.Unsigned > 0 {
+		problems = append(problems, fmt.Sprintf("%d of the last %d own commits unsigned", s.Unsigned, s.Checked))
+	}
+	return problems
+}
+
+// ValidFormat reports whether format is a value of gpg.format
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// gitConfig returns the effective value of a config key in dir, or "" if
+// it isn't set
+func gitConfig(dir string, args ...string) string {
+	out, err := git(dir, append([]string{"config", "--get"}, args...)...)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
+
+// git runs a git command in dir, returning its output or an error carrying
+// git's message
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			msg = strings.ReplaceAll(msg, "\n", "; ")
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,319 @@
+// Package state bundles thandie's cache directory (scan caches and their
+// history, file indexes, job and test logs, the audit log, resume state) and
+// its config into a tar.gz archive, for moving to another machine or
+// attaching to a bug report. Secrets in the config are redacted.
+package state
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/seal"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Names inside the archive
+const (
+	manifestName = "manifest.json"
+	configName   = "config.yml"
+	cachePrefix  = "cache/"
+)
+
+// Version is the archive format version written by Export
+const Version = 1
+
+// Redacted replaces secret config values in exported archives
+const Redacted = "REDACTED"
+
+// Manifest describes an archive
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Hostname  string    `json:"hostname,omitempty"`
+	Files     []string  `json:"files"`              // Relative to the cache directory, slash-separated
+	Config    bool      `json:"config"`             // Whether the archive holds the config
+	Redacted  []string  `json:"redacted,omitempty"` // Config keys whose values were redacted
+	Sealed    []string  `json:"sealed,omitempty"`   // Files encrypted with the exporting machine's key, see internal/seal
+}
+
+// Export writes the regular files under cacheDir and the config at
+// configPath, redacted, to w as a tar.gz archive. Sockets and other special
+// files are skipped, as is a missing config. Encrypted cache files are copied
+// as they are and listed in the manifest's Sealed.
+func Export(w io.Writer, cacheDir, configPath string) (*Manifest, error) {
+	hostname, _ := os.Hostname()
+	manifest := &Manifest{Version: Version, CreatedAt: time.Now(), Hostname: hostname, Files: []string{}}
+
+	err := filepath.WalkDir(cacheDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == cacheDir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(cacheDir, p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, filepath.ToSlash(rel))
+		if isSealed(p) {
+			manifest.Sealed = append(manifest.Sealed, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", cacheDir, err)
+	}
+
+	var config []byte
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+		case err != nil:
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		default:
+			if config, manifest.Redacted, err = Redact(data); err != nil {
+				return nil, err
+			}
+			manifest.Config = true
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeEntry(tw, manifestName, data, 0644); err != nil {
+		return nil, err
+	}
+	if manifest.Config {
+		if err := writeEntry(tw, configName, config, 0600); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(cacheDir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := writeEntry(tw, cachePrefix+name, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// isSealed reports whether the file at path is encrypted, judging by its
+// first bytes
+func isSealed(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 64)
+	n, _ := io.ReadFull(f, head)
+	return seal.IsSealed(head[:n])
+}
+
+// writeEntry adds a file to the archive
+func writeEntry(tw *tar.Writer, name string, data []byte, mode int64) error {
+	header := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
+
+// Imported is the outcome of Import
+type Imported struct {
+	Manifest *Manifest
+	Files    []string // Written to the cache directory
+	Kept     []string // Left alone because a local copy exists, see Import
+	Config   []byte   // The archive's config, for the caller to place; nil if none
+}
+
+// Import extracts the cache files of an archive read from r into cacheDir,
+// overwriting existing ones, except logs (logs/ and jobs/) that already
+// exist locally: the audit log is append-only and is never replaced. The
+// archive's config is returned rather than written.
+func Import(r io.Reader, cacheDir string) (*Imported, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a thandie state archive: %w", err)
+	}
+	defer gz.Close()
+
+	imported := &Imported{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch name := header.Name; {
+		case name == manifestName:
+			var manifest Manifest
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			if manifest.Version > Version {
+				return nil, fmt.Errorf("archive format %d is newer than this thandie supports (%d); upgrade thandie", manifest.Version, Version)
+			}
+			imported.Manifest = &manifest
+		case name == configName:
+			if imported.Config, err = io.ReadAll(tr); err != nil {
+				return nil, fmt.Errorf("failed to read config from archive: %w", err)
+			}
+		case strings.HasPrefix(name, cachePrefix):
+			rel := strings.TrimPrefix(name, cachePrefix)
+			if rel == "" || path.IsAbs(rel) || path.Clean(rel) != rel || rel == ".." || strings.HasPrefix(rel, "../") {
+				return nil, fmt.Errorf("refusing unsafe path %q in archive", name)
+			}
+			if imported.Manifest == nil {
+				return nil, fmt.Errorf("not a thandie state archive: %s precedes the manifest", name)
+			}
+			target := filepath.Join(cacheDir, filepath.FromSlash(rel))
+			if isLog(rel) {
+				if _, err := os.Stat(target); err == nil {
+					imported.Kept = append(imported.Kept, rel)
+					continue
+				}
+			}
+			if err := extract(tr, target); err != nil {
+				return nil, err
+			}
+			imported.Files = append(imported.Files, rel)
+		}
+	}
+	if imported.Manifest == nil {
+		return nil, fmt.Errorf("not a thandie state archive: no %s", manifestName)
+	}
+	return imported, nil
+}
+
+// isLog reports whether a cache file is a log that Import never overwrites
+func isLog(rel string) bool {
+	return strings.HasPrefix(rel, "logs/") || strings.HasPrefix(rel, "jobs/")
+}
+
+// extract writes the current archive entry to target
+func extract(r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return file.Close()
+}
+
+// sensitiveKeys are the dotted config keys whose values are secrets, with
+// [] standing for any index of a list. They are all string settings, so a
+// token YAML reads as a number is redacted too.
+var sensitiveKeys = map[string]bool{
+	"providers.github.token": true,
+	"providers.gitlab.token": true,
+	"trackers[].token":       true,
+	"notify.slack_webhook":   true,
+	"notify.teams_webhook":   true,
+	"smtp.password":          true,
+	"webhook.secret":         true,
+}
+
+// listIndex matches the index of a list element in a dotted key
+var listIndex = regexp.MustCompile(`\[\d+\]`)
+
+// Redact replaces the secret values of a YAML config with Redacted and
+// returns the dotted keys it redacted. Keychain references (keychain:<alias>)
+// are kept since they aren't secrets themselves.
+func Redact(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	var redacted []string
+	redactNode(&doc, "", &redacted)
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return out.Bytes(), redacted, nil
+}
+
+// redactNode redacts the secret scalars under node, whose dotted key is key
+func redactNode(node *yaml.Node, key string, redacted *[]string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for i, child := range node.Content {
+			childKey := key
+			if node.Kind == yaml.SequenceNode {
+				childKey = fmt.Sprintf("%s[%d]", key, i)
+			}
+			redactNode(child, childKey, redacted)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name, value := node.Content[i].Value, node.Content[i+1]
+			childKey := name
+			if key != "" {
+				childKey = key + "." + name
+			}
+			if isSensitive(childKey) && value.Kind == yaml.ScalarNode && value.ShortTag() != "!!null" &&
+				value.Value != "" && !secrets.IsRef(value.Value) {
+				value.Value, value.Tag, value.Style = Redacted, "!!str", 0
+				*redacted = append(*redacted, childKey)
+				continue
+			}
+			redactNode(value, childKey, redacted)
+		}
+	}
+}
+
+// isSensitive reports whether the config key, e.g. trackers[0].token, holds
+// a secret
+func isSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(listIndex.ReplaceAllString(key, "[]"))]
+}
@@ -0,0 +1,96 @@
+package state_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/state"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExportImportConfig(t *testing.T) {
+	cacheDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(cacheDir, "cache"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "cache", "ws.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, "cache", "sealed.json"), []byte("thandie-sealed-v1\nciphertext"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	err := os.WriteFile(configPath, []byte(`providers:
+  github:
+    url: https://api.github.com
+    token: ghp_plaintext
+  gitlab:
+    token: keychain:gitlab
+trackers:
+  - name: jira
+    type: jira
+    token: 12345
+guard:
+  secrets: true
+notify:
+  slack_webhook: https://hooks.slack.com/services/x
+  dirty_days: 3
+smtp:
+  username: me
+  password: hunter2
+webhook:
+  secret: shared
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := state.Export(&archive, cacheDir, configPath)
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	wantRedacted := []string{"providers.github.token", "trackers[0].token", "notify.slack_webhook", "smtp.password", "webhook.secret"}
+	if !slices.Equal(manifest.Redacted, wantRedacted) {
+		t.Errorf("Redacted = %v, want %v", manifest.Redacted, wantRedacted)
+	}
+	if !slices.Equal(manifest.Sealed, []string{"cache/sealed.json"}) {
+		t.Errorf("Sealed = %v, want [cache/sealed.json]", manifest.Sealed)
+	}
+
+	imported, err := state.Import(&archive, t.TempDir())
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if !slices.Equal(imported.Files, []string{"cache/sealed.json", "cache/ws.json"}) {
+		t.Errorf("Files = %v, want [cache/sealed.json cache/ws.json]", imported.Files)
+	}
+	var cfg config.Config
+	if err := yaml.Unmarshal(imported.Config, &cfg); err != nil {
+		t.Fatalf("imported config doesn't parse: %v\n%s", err, imported.Config)
+	}
+	if !cfg.Guard.SecretsEnabled() || cfg.Guard.Secrets == nil {
+		t.Errorf("guard.secrets = %v, want true", cfg.Guard.Secrets)
+	}
+	for key, got := range map[string]string{
+		"providers.github.token": cfg.Providers.GitHub.Token,
+		"trackers[0].token":      cfg.Trackers[0].Token,
+		"notify.slack_webhook":   cfg.Notify.SlackWebhook,
+		"smtp.password":          cfg.SMTP.Password,
+		"webhook.secret":         cfg.Webhook.Secret,
+	} {
+		if got != state.Redacted {
+			t.Errorf("%s = %q, want %q", key, got, state.Redacted)
+		}
+	}
+	if cfg.Providers.GitLab.Token != "keychain:gitlab" {
+		t.Errorf("providers.gitlab.token = %q, want the keychain reference kept", cfg.Providers.GitLab.Token)
+	}
+	if cfg.Providers.GitHub.URL != "https://api.github.com" || cfg.Notify.DirtyDays != 3 || cfg.SMTP.Username != "me" {
+		t.Errorf("other settings changed: %+v %+v %+v", cfg.Providers.GitHub, cfg.Notify, cfg.SMTP)
+	}
+}
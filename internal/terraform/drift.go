@@ -0,0 +1,51 @@
+// Package terraform runs drift checks against Terraform configurations.
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// CheckDrift runs `terraform plan -detailed-exitcode` in moduleDir and
+// records the outcome on module. The plan never takes the state lock or
+// prompts for input, so it is safe to run alongside other work.
+func CheckDrift(ctx context.Context, moduleDir string, module *scanner.TerraformModule) {
+	module.DriftCheckedAt = time.Now()
+	module.DriftError = ""
+
+	cmd := exec.CommandContext(ctx, "terraform", "plan", "-detailed-exitcode", "-lock=false", "-input=false", "-no-color")
+	cmd.Dir = moduleDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		module.Drift = scanner.DriftNone
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 2:
+		// Exit code 2 means the plan succeeded and contains changes
+		module.Drift = scanner.DriftDrifted
+	default:
+		module.Drift = scanner.DriftError
+		module.DriftError = planError(err, stderr.String())
+	}
+}
+
+// planError extracts a one-line reason from a failed plan
+func planError(err error, stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if msg, ok := strings.CutPrefix(line, "Error: "); ok {
+			return msg
+		}
+	}
+	return fmt.Sprintf("terraform plan failed: %v", err)
+}
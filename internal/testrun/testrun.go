@@ -0,0 +1,182 @@
+// Package testrun detects and runs repositories' test commands.
+package testrun
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/cache"
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// makeTestTarget matches a `test:` rule in a Makefile
+var makeTestTarget = regexp.MustCompile(`(?m)^test\s*:`)
+
+// DetectCommand returns the command that runs the tests of the repository
+// at dir, or nil if none is known. A Makefile test target wins over the
+// language's default because it is what the project's authors chose.
+func DetectCommand(dir string, languages []string) []string {
+	if data, err := os.ReadFile(filepath.Join(dir, "Makefile")); err == nil && makeTestTarget.Match(data) {
+		return []string{"make", "test"}
+	}
+
+	for _, language := range languages {
+		switch language {
+		case "go":
+			return []string{"go", "test", "./..."}
+		case "rust":
+			return []string{"cargo", "test"}
+		case "node":
+			if hasNpmTestScript(dir) {
+				return []string{"npm", "test"}
+			}
+		case "python":
+			return []string{"python", "-m", "pytest"}
+		case "java":
+			if exists(filepath.Join(dir, "gradlew")) {
+				return []string{"./gradlew", "test"}
+			}
+			if exists(filepath.Join(dir, "pom.xml")) {
+				return []string{"mvn", "-q", "test"}
+			}
+			return []string{"gradle", "test"}
+		case "ruby":
+			return []string{"bundle", "exec", "rake", "test"}
+		case "elixir":
+			return []string{"mix", "test"}
+		}
+	}
+	return nil
+}
+
+// hasNpmTestScript reports whether package.json defines a real test script
+// (npm init's placeholder just fails)
+func hasNpmTestScript(dir string) bool {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+	script := pkg.Scripts["test"]
+	return script != "" && !strings.Contains(script, "no test specified")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// State returns the HEAD commit of the repository at dir and whether its
+// worktree has uncommitted changes
+func State(dir string) (string, bool, error) {
+	head, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	status, err := exec.Command("git", "-C", dir, "status", "--porcelain").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get status: %w", err)
+	}
+	return strings.TrimSpace(string(head)), len(status) > 0, nil
+}
+
+// Reusable reports whether a previous run still applies to a repository
+// whose HEAD is commit and whose worktree is clean
+func Reusable(previous *scanner.TestRun, commit string, dirty bool) bool {
+	return previous != nil && !previous.Dirty && !dirty && previous.Commit == commit
+}
+
+// LogDir returns the directory holding per-repository test logs
+func LogDir() (string, error) {
+	dir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "test-logs"), nil
+}
+
+// logPath returns the log file for the repository at dir
+func logPath(logDir, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return filepath.Join(logDir, fmt.Sprintf("%s-%s.log", filepath.Base(dir), hex.EncodeToString(sum[:4])))
+}
+
+// Run runs command in dir, writing its combined output to the repository's
+// log file in logDir. Each line is also passed to onLine, if set, as it is
+// written so callers can stream progress.
+func Run(ctx context.Context, dir string, command []string, logDir string, onLine func(line string)) (*scanner.TestRun, error) {
+	commit, dirty, err := State(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	run := &scanner.TestRun{
+		Commit:  commit,
+		Dirty:   dirty,
+		Command: strings.Join(command, " "),
+		Log:     logPath(logDir, dir),
+	}
+	logFile, err := os.Create(run.Log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "$ %s\n# %s at %s\n\n", run.Command, dir, commit)
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		reader.Close()
+		fmt.Fprintf(logFile, "failed to start: %v\n", err)
+		run.Duration = time.Since(start)
+		run.FinishedAt = time.Now()
+		return run, nil
+	}
+	writer.Close()
+
+	lines := bufio.NewScanner(reader)
+	lines.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for lines.Scan() {
+		fmt.Fprintln(logFile, lines.Text())
+		if onLine != nil {
+			onLine(lines.Text())
+		}
+	}
+	reader.Close()
+
+	err = cmd.Wait()
+	run.Passed = err == nil
+	run.Duration = time.Since(start).Round(time.Millisecond)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		fmt.Fprintf(logFile, "\n%v\n", err)
+	}
+	return run, nil
+}
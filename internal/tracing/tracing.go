@@ -0,0 +1,97 @@
+// Package tracing records OpenTelemetry spans for scans and exports them to
+// an OTLP endpoint or a local file. Without Init, spans are no-ops.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies thandie's spans
+const tracerName = "github.com/ThandieOps/thandie-agent"
+
+var (
+	provider *sdktrace.TracerProvider
+	file     *os.File
+
+	// rootCtx carries the span of the running command
+	rootCtx  = context.Background()
+	rootSpan trace.Span
+)
+
+// Init starts exporting spans to target and opens a root span named name
+// for the running command. target is an http(s):// OTLP endpoint, "otlp" to
+// use the OTEL_EXPORTER_OTLP_* environment variables, or a file path that
+// receives one JSON span per line. An empty target leaves tracing disabled.
+func Init(ctx context.Context, target, name string) error {
+	if target == "" {
+		return nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch {
+	case target == "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://"):
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(target))
+	default:
+		file, err = os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("thandie"))),
+	)
+	otel.SetTracerProvider(provider)
+	rootCtx, rootSpan = Start(ctx, name)
+	return nil
+}
+
+// Context returns the context carrying the running command's root span
+func Context() context.Context {
+	return rootCtx
+}
+
+// Start opens a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Shutdown ends the root span and flushes buffered spans. Safe to call
+// more than once and when tracing is disabled.
+func Shutdown() {
+	if provider == nil {
+		return
+	}
+	rootSpan.End()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to flush traces: %v\n", err)
+	}
+	provider = nil
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
@@ -0,0 +1,187 @@
+package trackers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/config"
+	"github.com/ThandieOps/thandie-agent/internal/providers"
+	"github.com/ThandieOps/thandie-agent/internal/secrets"
+)
+
+// Ticket is an issue in an external tracker
+type Ticket struct {
+	Key    string
+	Title  string
+	Status string
+	URL    string
+}
+
+// tracker resolves ticket keys against one tracker instance
+type tracker interface {
+	resolve(ctx context.Context, key string) (*Ticket, error)
+}
+
+// entry is a configured tracker together with the ticket prefixes it owns
+type entry struct {
+	name     string
+	prefixes map[string]bool
+	tracker  tracker
+}
+
+// Set resolves ticket keys found in branch names against the configured trackers
+type Set struct {
+	entries []entry
+}
+
+// ticketPattern matches ticket keys such as PROJ-123 in a branch name
+var ticketPattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])([a-z][a-z0-9]+)-(\d+)`)
+
+// NewSet creates a tracker set from config. Trackers whose token can't be
+// resolved or whose type is unknown are left out of the set and reported in
+// the error, so misconfiguration isn't silent; the set holds the others even
+// when an error is returned.
+func NewSet(cfgs []config.TrackerConfig, client *providers.Client) (*Set, error) {
+	set := &Set{}
+	var errs []error
+	for _, trackerCfg := range cfgs {
+		token, err := secrets.Resolve(trackerCfg.Token)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("tracker %s: %w", trackerCfg.Name, err))
+			continue
+		}
+
+		var t tracker
+		switch trackerCfg.Type {
+		case "jira":
+			t = &jira{client: client, baseURL: strings.TrimSuffix(trackerCfg.URL, "/"), email: trackerCfg.Email, token: token}
+		case "linear":
+			t = &linear{client: client, token: token}
+		default:
+			errs = append(errs, fmt.Errorf("tracker %s: unsupported type %q (expected jira or linear)", trackerCfg.Name, trackerCfg.Type))
+			continue
+		}
+
+		prefixes := make(map[string]bool)
+		for _, project := range trackerCfg.Projects {
+			prefixes[strings.ToUpper(project)] = true
+		}
+		set.entries = append(set.entries, entry{name: trackerCfg.Name, prefixes: prefixes, tracker: t})
+	}
+	return set, errors.Join(errs...)
+}
+
+// Empty reports whether no trackers are configured
+func (s *Set) Empty() bool {
+	return len(s.entries) == 0
+}
+
+// FindKey returns the first ticket key in a branch name whose prefix belongs
+// to a configured tracker, along with that tracker's name
+func (s *Set) FindKey(branch string) (key, trackerName string) {
+	for _, m := range ticketPattern.FindAllStringSubmatch(branch, -1) {
+		prefix := strings.ToUpper(m[1])
+		for _, e := range s.entries {
+			if e.prefixes[prefix] {
+				return prefix + "-" + m[2], e.name
+			}
+		}
+	}
+	return "", ""
+}
+
+// Resolve looks up a ticket key in the named tracker
+func (s *Set) Resolve(ctx context.Context, trackerName, key string) (*Ticket, error) {
+	for _, e := range s.entries {
+		if e.name == trackerName {
+			return e.tracker.resolve(ctx, key)
+		}
+	}
+	return nil, fmt.Errorf("unknown tracker %s", trackerName)
+}
+
+// jira resolves tickets with the Jira REST API. Jira Cloud uses email and API
+// token (basic auth); Jira Server/Data Center uses a personal access token.
+type jira struct {
+	client  *providers.Client
+	baseURL string
+	email   string
+	token   string
+}
+
+func (j *jira) resolve(ctx context.Context, key string) (*Ticket, error) {
+	headers := map[string]string{"Accept": "application/json"}
+	if j.email != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(j.email+":"+j.token))
+	} else {
+		headers["Authorization"] = "Bearer " + j.token
+	}
+
+	var issue struct {
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	apiURL := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=summary,status", j.baseURL, url.PathEscape(key))
+	if _, err := j.client.GetJSON(ctx, apiURL, headers, &issue); err != nil {
+		return nil, err
+	}
+
+	return &Ticket{
+		Key:    key,
+		Title:  issue.Fields.Summary,
+		Status: issue.Fields.Status.Name,
+		URL:    j.baseURL + "/browse/" + key,
+	}, nil
+}
+
+// linear resolves tickets with the Linear GraphQL API
+type linear struct {
+	client *providers.Client
+	token  string
+}
+
+// linearAPIURL is the Linear GraphQL endpoint
+const linearAPIURL = "https://api.linear.app/graphql"
+
+func (l *linear) resolve(ctx context.Context, key string) (*Ticket, error) {
+	request := map[string]any{
+		"query":     `query($id: String!) { issue(id: $id) { title url state { name } } }`,
+		"variables": map[string]string{"id": key},
+	}
+
+	var response struct {
+		Data struct {
+			Issue *struct {
+				Title string `json:"title"`
+				URL   string `json:"url"`
+				State struct {
+					Name string `json:"name"`
+				} `json:"state"`
+			} `json:"issue"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := l.client.PostJSON(ctx, linearAPIURL, map[string]string{"Authorization": l.token}, request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Errors) > 0 {
+		return nil, errors.New(response.Errors[0].Message)
+	}
+	if response.Data.Issue == nil {
+		return nil, fmt.Errorf("ticket %s not found", key)
+	}
+
+	issue := response.Data.Issue
+	return &Ticket{Key: key, Title: issue.Title, Status: issue.State.Name, URL: issue.URL}, nil
+}
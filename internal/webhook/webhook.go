@@ -0,0 +1,168 @@
+// Package webhook receives push events from CI and git providers so the
+// daemon can rescan the repositories they concern right away.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// RescanPath is the endpoint that triggers rescans
+const RescanPath = "/hooks/rescan"
+
+// maxBody is the largest payload read; push events are well below it
+const maxBody = 5 << 20
+
+// ErrNoMatch is returned by a RescanFunc when no scanned repository matches the target
+var ErrNoMatch = errors.New("no scanned repository matches")
+
+// Target identifies the repository a webhook is about
+type Target struct {
+	Remotes []string `json:"remotes,omitempty"` // Remote URLs from the payload, in any form
+	Path    string   `json:"path,omitempty"`    // Path of the repository, from ?path= or the payload
+}
+
+// RescanFunc rescans the repositories matching target and returns their paths
+type RescanFunc func(target Target) ([]string, error)
+
+// Handler returns the HTTP handler of RescanPath. Requests must carry
+// secret as a bearer token or in X-Gitlab-Token, or sign the body with it
+// in X-Hub-Signature-256 as GitHub and Gitea do.
+func Handler(secret string, rescan RescanFunc) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RescanPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if !Authenticate(r, body, secret) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("X-GitHub-Event") == "ping" {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+			return
+		}
+
+		target, err := Parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if path := r.URL.Query().Get("path"); path != "" {
+			target.Path = path
+		}
+		if remote := r.URL.Query().Get("remote"); remote != "" {
+			target.Remotes = append(target.Remotes, remote)
+		}
+		if target.Path == "" && len(target.Remotes) == 0 {
+			http.Error(w, "no repository in payload; pass ?path= or ?remote=", http.StatusBadRequest)
+			return
+		}
+
+		paths, err := rescan(target)
+		switch {
+		case errors.Is(err, ErrNoMatch):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, map[string][]string{"rescanned": paths})
+		}
+	})
+	return mux
+}
+
+// Authenticate reports whether r carries secret, comparing in constant time
+func Authenticate(r *http.Request, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	if signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// payload holds the fields of push events that name the repository, for
+// GitHub and Gitea (repository), GitLab (project) and Bitbucket (links)
+type payload struct {
+	Path       string `json:"path"`
+	Remote     string `json:"remote"`
+	Repository *struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+		Links    *struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	} `json:"repository"`
+	Project *struct {
+		HTTPURL string `json:"git_http_url"`
+		SSHURL  string `json:"git_ssh_url"`
+		WebURL  string `json:"web_url"`
+	} `json:"project"`
+}
+
+// Parse returns the repository named by a webhook payload. An empty body
+// names none; the caller then relies on query parameters.
+func Parse(body []byte) (Target, error) {
+	var target Target
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return target, nil
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return target, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	target.Path = p.Path
+	add := func(urls ...string) {
+		for _, url := range urls {
+			if url != "" {
+				target.Remotes = append(target.Remotes, url)
+			}
+		}
+	}
+	add(p.Remote)
+	if repo := p.Repository; repo != nil {
+		add(repo.CloneURL, repo.SSHURL, repo.HTMLURL)
+		if repo.Links != nil {
+			add(repo.Links.HTML.Href)
+		}
+	}
+	if project := p.Project; project != nil {
+		add(project.HTTPURL, project.SSHURL, project.WebURL)
+	}
+	return target, nil
+}
+
+// writeJSON writes v as the JSON body of a response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,112 @@
+// Package workspace enumerates the directories of a workspace. A Provider
+// decides which directories a workspace has; the scanner collects their
+// metadata and commands read the cached result the same way whatever the
+// provider. Directories under a root (Local) and directories listed in a
+// file (Manifest) are supported; sources such as an SSH host or a provider
+// organization can be added as further providers.
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+)
+
+// Provider enumerates the directories of a workspace
+type Provider interface {
+	// Plan reports the directories of the workspace, marking which would be
+	// scanned and why the others would be skipped
+	Plan() ([]scanner.PlanEntry, error)
+	// Describe names the source of the directories for messages, e.g. the
+	// root directory or the manifest file
+	Describe() string
+}
+
+// Local is a workspace made of the directories under Root, down to MaxDepth
+// levels, excluding hidden and ignored ones as configured
+type Local struct {
+	Root          string
+	IgnoreDirs    []string
+	IncludeHidden bool
+	MaxDepth      int
+}
+
+// Plan walks Root, see scanner.PlanScan
+func (l Local) Plan() ([]scanner.PlanEntry, error) {
+	return scanner.PlanScan(l.Root, l.IgnoreDirs, l.IncludeHidden, l.MaxDepth)
+}
+
+// Describe returns the root directory
+func (l Local) Describe() string {
+	return l.Root
+}
+
+// Manifest is a workspace made of the directories listed in the file at
+// Path, one per line. Blank lines and lines starting with # are skipped;
+// relative paths are relative to the directory of the file and ~ is the home
+// directory. Listed directories that don't exist are skipped as not found.
+type Manifest struct {
+	Path string
+}
+
+// Plan reads the manifest
+func (m Manifest) Plan() ([]scanner.PlanEntry, error) {
+	f, err := os.Open(m.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace manifest: %w", err)
+	}
+	defer f.Close()
+
+	var plan []scanner.PlanEntry
+	seen := make(map[string]bool)
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		path, err := m.resolve(line)
+		if err != nil {
+			return nil, err
+		}
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		entry := scanner.PlanEntry{Path: path, Depth: 1, Scan: true}
+		if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+			entry.Scan, entry.Reason, entry.Rule = false, scanner.SkipNotFound, m.Path
+		}
+		plan = append(plan, entry)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read workspace manifest: %w", err)
+	}
+	scanner.MarkNetwork(plan)
+	return plan, nil
+}
+
+// Describe returns the manifest path
+func (m Manifest) Describe() string {
+	return m.Path
+}
+
+// resolve returns the absolute path of a directory listed in the manifest
+func (m Manifest) resolve(dir string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand %s: %w", dir, err)
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(m.Path), dir)
+	}
+	return filepath.Abs(dir)
+}
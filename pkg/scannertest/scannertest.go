@@ -0,0 +1,228 @@
+// Package scannertest builds workspaces of repositories in known states, for
+// end-to-end tests of the scanner and of the code consuming its results,
+// including collectors written outside this module. It is the one public
+// package of the agent; the scanner results it returns are aliased here so
+// that such tests can name them.
+package scannertest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThandieOps/thandie-agent/internal/scanner"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// DirectoryInfo is a scanned directory, as returned by Workspace.Scan
+type DirectoryInfo = scanner.DirectoryInfo
+
+// GitMetadata is the git state of a scanned repository
+type GitMetadata = scanner.GitMetadata
+
+// Epoch is the time of the first commit of every repository; each further
+// commit is an hour later, so commit times are known in advance
+var Epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Repo describes a directory of a workspace
+type Repo struct {
+	Name       string
+	Plain      bool              // A plain directory rather than a git repository
+	Branch     string            // Branch checked out; main if empty
+	Commits    int               // Commits on the branch; at least one
+	Dirty      bool              // An uncommitted change to a tracked file
+	Detached   bool              // HEAD detached at the last commit
+	Remote     string            // URL of the origin remote; none if empty
+	Submodules []string          // Repositories of the workspace added as submodules; they must come before this one
+	Files      map[string]string // Files committed besides README.md, by path
+}
+
+// submoduleCommitAt is the time of the commit adding submodules, after the
+// others
+var submoduleCommitAt = Epoch.Add(24 * time.Hour)
+
+// LastCommitAt returns the time of the last commit of r
+func (r Repo) LastCommitAt() time.Time {
+	if len(r.Submodules) > 0 {
+		return submoduleCommitAt
+	}
+	return Epoch.Add(time.Duration(max(r.Commits, 1)-1) * time.Hour)
+}
+
+// Workspace is a workspace built by Build or New
+type Workspace struct {
+	Root  string
+	Repos []Repo
+}
+
+// Dir returns the path of the directory named name
+func (w *Workspace) Dir(name string) string {
+	return filepath.Join(w.Root, name)
+}
+
+// Scan scans the workspace the way `thandie scan` does, with the default
+// scanner settings
+func (w *Workspace) Scan() ([]DirectoryInfo, error) {
+	plan, err := scanner.PlanScan(w.Root, nil, false, 1)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.ScanPlanned(context.Background(), plan, nil, 4, 0, nil, nil), nil
+}
+
+// New builds a workspace of repos in a temporary directory removed when the
+// test ends, failing the test if it can't
+func New(tb testing.TB, repos ...Repo) *Workspace {
+	tb.Helper()
+	w, err := Build(tb.TempDir(), repos...)
+	if err != nil {
+		tb.Fatalf("failed to build workspace: %v", err)
+	}
+	return w
+}
+
+// Build creates repos in the directory root, in order. Submodules are added
+// with the git command; everything else is written with go-git.
+func Build(root string, repos ...Repo) (*Workspace, error) {
+	w := &Workspace{Root: root, Repos: repos}
+	for _, r := range repos {
+		if err := w.build(r); err != nil {
+			return nil, fmt.Errorf("failed to build %s: %w", r.Name, err)
+		}
+	}
+	return w, nil
+}
+
+// Mixed returns n repositories named repo-0000 onwards that cycle through
+// the states Build supports: clean, dirty, without a remote, detached, a
+// plain directory, on another branch and with several commits
+func Mixed(n int) []Repo {
+	repos := make([]Repo, n)
+	for i := range repos {
+		r := Repo{Name: fmt.Sprintf("repo-%04d", i), Remote: fmt.Sprintf("https://github.com/example/repo-%04d.git", i)}
+		switch i % 7 {
+		case 1:
+			r.Dirty = true
+		case 2:
+			r.Remote = ""
+		case 3:
+			r.Detached = true
+		case 4:
+			r = Repo{Name: r.Name, Plain: true}
+		case 5:
+			r.Branch = "develop"
+		case 6:
+			r.Commits = 3
+		}
+		repos[i] = r
+	}
+	return repos
+}
+
+// build creates the directory of r
+func (w *Workspace) build(r Repo) error {
+	path := w.Dir(r.Name)
+	files := map[string]string{"README.md": "# " + r.Name + "\n"}
+	for name, content := range r.Files {
+		files[name] = content
+	}
+	for name, content := range files {
+		if err := writeFile(filepath.Join(path, name), content); err != nil {
+			return err
+		}
+	}
+	if r.Plain {
+		return nil
+	}
+
+	branch := r.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	repo, err := git.PlainInitWithOptions(path, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName(branch)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init: %w", err)
+	}
+	if r.Remote != "" {
+		if _, err := repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{r.Remote}}); err != nil {
+			return fmt.Errorf("failed to add remote: %w", err)
+		}
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	var head plumbing.Hash
+	for i := range max(r.Commits, 1) {
+		if i > 0 {
+			if err := writeFile(filepath.Join(path, "CHANGELOG.md"), fmt.Sprintf("Change %d\n", i)); err != nil {
+				return err
+			}
+		}
+		if err := worktree.AddGlob("."); err != nil {
+			return fmt.Errorf("failed to stage: %w", err)
+		}
+		when := Epoch.Add(time.Duration(i) * time.Hour)
+		signature := &object.Signature{Name: "scannertest", Email: "scannertest@example.com", When: when}
+		if head, err = worktree.Commit(fmt.Sprintf("Commit %d", i+1), &git.CommitOptions{Author: signature, Committer: signature}); err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+	}
+
+	if len(r.Submodules) > 0 {
+		for _, name := range r.Submodules {
+			if err := runGit(path, "-c", "protocol.file.allow=always", "submodule", "add", "--quiet", w.Dir(name), name); err != nil {
+				return err
+			}
+		}
+		if err := runGit(path, "commit", "--quiet", "-m", "Add submodules"); err != nil {
+			return err
+		}
+		ref, err := repo.Head()
+		if err != nil {
+			return err
+		}
+		head = ref.Hash()
+	}
+
+	if r.Detached {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, head)); err != nil {
+			return fmt.Errorf("failed to detach HEAD: %w", err)
+		}
+	}
+	if r.Dirty {
+		return writeFile(filepath.Join(path, "README.md"), "# "+r.Name+"\n\nUncommitted.\n")
+	}
+	return nil
+}
+
+// writeFile writes content to path, creating its directory
+func writeFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// runGit runs git in dir, isolated from the user's git config, committing at
+// submoduleCommitAt
+func runGit(dir string, args ...string) error {
+	date := submoduleCommitAt.Format(time.RFC3339)
+	cmd := exec.Command("git", append([]string{"-c", "user.name=scannertest", "-c", "user.email=scannertest@example.com"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_NOSYSTEM=1", "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %v failed: %w: %s", args, err, out)
+	}
+	return nil
+}